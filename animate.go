@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// animationInterval is the delay between tab color updates, which keeps the
+// escape sequence rate low enough for the terminal and tmux to keep up
+const animationInterval = 100 * time.Millisecond
+
+// animationStep is how long the fade from one color to the next takes
+const animationStep = time.Second
+
+// animationSleep waits between frames; tests replace it to run instantly
+var animationSleep = time.Sleep
+
+// parseCycleColors resolves the comma-separated colors of -cycle to hex
+func parseCycleColors(list string) ([]string, error) {
+	var colors []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		hex := normalizeColor(name)
+		if hex == "" || hex == "default" {
+			return nil, fmt.Errorf("invalid color %q in -cycle", name)
+		}
+		colors = append(colors, hex)
+	}
+	if len(colors) < 2 {
+		return nil, fmt.Errorf("-cycle needs at least two colors, got %q", list)
+	}
+	return colors, nil
+}
+
+// pulseColors returns the colors -pulse alternates between: a shade of the tab
+// color, then the tab color itself so the animation ends on it
+func pulseColors(tab string) ([]string, error) {
+	hex := normalizeColor(tab)
+	if hex == "" || hex == "default" {
+		return nil, fmt.Errorf("invalid color %q for -pulse", tab)
+	}
+
+	// Dark colors pulse lighter, since darkening them would barely show
+	r, g, b, err := hexToRGB(hex)
+	if err != nil {
+		return nil, err
+	}
+	modifier := "@dark(30%)"
+	if _, _, l := rgbToHSL(r, g, b); l < 0.3 {
+		modifier = "@light(30%)"
+	}
+	shade, _ := applyColorModifiers(hex + modifier)
+	return []string{shade, hex}, nil
+}
+
+// interpolateHex blends two hex colors in RGB; t runs from 0 (a) to 1 (b)
+func interpolateHex(a, b string, t float64) string {
+	ar, ag, ab, _ := hexToRGB(a)
+	br, bg, bb, _ := hexToRGB(b)
+	mix := func(x, y int) int {
+		return int(math.Round(float64(x) + (float64(y)-float64(x))*t))
+	}
+	return fmt.Sprintf("%02x%02x%02x", mix(ar, br), mix(ag, bg), mix(ab, bb))
+}
+
+// animationFrames returns the tab colors to show, one per animationInterval, fading
+// through colors in a loop for duration. The last frame is always the last color.
+func animationFrames(colors []string, duration time.Duration) []string {
+	count := int(duration / animationInterval)
+	frames := make([]string, 0, count+1)
+	for i := 0; i < count; i++ {
+		position := float64(time.Duration(i)*animationInterval) / float64(animationStep)
+		segment := int(position) % len(colors)
+		next := (segment + 1) % len(colors)
+		frames = append(frames, interpolateHex(colors[segment], colors[next], position-math.Floor(position)))
+	}
+	return append(frames, colors[len(colors)-1])
+}
+
+// runTabAnimation fades the tab color through colors for duration
+func runTabAnimation(colors []string, duration time.Duration) error {
+	if duration <= 0 {
+		return fmt.Errorf("-duration must be positive, got %v", duration)
+	}
+
+	if err := checkProfileLock(""); err != nil {
+		return err
+	}
+	if !backendSupports(FeatureTab) {
+		return skipUnsupported("tab animation")
+	}
+
+	frames := animationFrames(colors, duration)
+	debugf("Animating tab color through %s for %v (%d frames)\n", strings.Join(colors, ", "), duration, len(frames))
+
+	for i, frame := range frames {
+		if err := runSetColor(TabColor, frame); err != nil {
+			return err
+		}
+		// Dry runs only list the frames
+		if i < len(frames)-1 && !dryRunMode {
+			animationSleep(animationInterval)
+		}
+	}
+	return nil
+}
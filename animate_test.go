@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseCycleColors tests resolving the -cycle color list
+func TestParseCycleColors(t *testing.T) {
+	colors, err := parseCycleColors("red, #00ff00,blue")
+	if err != nil {
+		t.Fatalf("parseCycleColors() failed: %v", err)
+	}
+	if strings.Join(colors, ",") != "ff0000,00ff00,0000ff" {
+		t.Errorf("Unexpected colors: %v", colors)
+	}
+
+	for _, list := range []string{"red", "red,notacolor", "red,default", ""} {
+		if _, err := parseCycleColors(list); err == nil {
+			t.Errorf("Expected error for %q", list)
+		}
+	}
+}
+
+// TestPulseColors tests that -pulse fades toward a shade and ends on the tab color
+func TestPulseColors(t *testing.T) {
+	tests := []struct {
+		tab      string
+		expected []string
+	}{
+		{"red", []string{"660000", "ff0000"}},
+		{"navy", []string{"1a1aff", "000080"}}, // Too dark to darken, so it pulses lighter
+	}
+
+	for _, test := range tests {
+		colors, err := pulseColors(test.tab)
+		if err != nil {
+			t.Fatalf("pulseColors(%q) failed: %v", test.tab, err)
+		}
+		if strings.Join(colors, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("pulseColors(%q) = %v, expected %v", test.tab, colors, test.expected)
+		}
+	}
+
+	if _, err := pulseColors("default"); err == nil {
+		t.Error("Expected error pulsing the default tab color")
+	}
+}
+
+// TestAnimationFrames tests frame timing and interpolation
+func TestAnimationFrames(t *testing.T) {
+	frames := animationFrames([]string{"000000", "ffffff"}, 2*time.Second)
+
+	// One frame per interval plus the final color
+	if len(frames) != 21 {
+		t.Fatalf("Expected 21 frames, got %d", len(frames))
+	}
+	checks := map[int]string{
+		0:  "000000", // Start of the first fade
+		5:  "808080", // Halfway to white
+		10: "ffffff", // Start of the fade back
+		15: "808080",
+		20: "ffffff", // Always ends on the last color
+	}
+	for i, expected := range checks {
+		if frames[i] != expected {
+			t.Errorf("Frame %d = %s, expected %s", i, frames[i], expected)
+		}
+	}
+}
+
+// TestRunTabAnimation tests the emitted frames and the rate limiting between them
+func TestRunTabAnimation(t *testing.T) {
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	var sleeps []time.Duration
+	animationSleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	defer func() {
+		escapeWriter = originalWriter
+		animationSleep = time.Sleep
+	}()
+
+	if err := runTabAnimation([]string{"ff0000", "0000ff"}, 500*time.Millisecond); err != nil {
+		t.Fatalf("runTabAnimation() failed: %v", err)
+	}
+
+	frames := strings.Count(buf.String(), "6;1;bg;red;brightness;")
+	if frames != 6 {
+		t.Errorf("Expected 6 frames, got %d: %q", frames, buf.String())
+	}
+	if len(sleeps) != frames-1 {
+		t.Errorf("Expected %d sleeps between frames, got %d", frames-1, len(sleeps))
+	}
+	for _, d := range sleeps {
+		if d != animationInterval {
+			t.Errorf("Slept %v, expected %v", d, animationInterval)
+		}
+	}
+	if !strings.HasSuffix(buf.String(), "6;1;bg;blue;brightness;255\a") {
+		t.Errorf("Expected the animation to end on blue, got %q", buf.String())
+	}
+
+	if err := runTabAnimation([]string{"ff0000", "0000ff"}, 0); err == nil {
+		t.Error("Expected error for a zero duration")
+	}
+}
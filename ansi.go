@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // hexToRGB converts a hex color string to RGB values
@@ -47,3 +48,38 @@ func colorText(text, hexColor string) string {
 	// Reset sequence: \033[0m
 	return fmt.Sprintf("\033[38;2;%d;%d;%dm%s\033[0m", r, g, b, text)
 }
+
+// colorSwatch renders text on a hex background, in whichever contrast color reads better
+func colorSwatch(text, hexColor string) string {
+	r, g, b, err := hexToRGB(hexColor)
+	if err != nil {
+		return text
+	}
+	fg, err := pickContrastColor(strings.TrimPrefix(hexColor, "#"))
+	if err != nil {
+		return text
+	}
+	tr, tg, tb, err := hexToRGB(fg)
+	if err != nil {
+		return text
+	}
+	return fmt.Sprintf("\033[48;2;%d;%d;%dm\033[38;2;%d;%d;%dm%s\033[0m", r, g, b, tr, tg, tb, text)
+}
+
+// colorPair renders text in one hex color on another. A color that is empty or
+// "default" is left to the terminal.
+func colorPair(text, fgHex, bgHex string) string {
+	var codes []string
+	for _, color := range []struct{ hex, code string }{{fgHex, "38"}, {bgHex, "48"}} {
+		if color.hex == "" {
+			continue
+		}
+		if r, g, b, err := hexToRGB(color.hex); err == nil {
+			codes = append(codes, fmt.Sprintf("%s;2;%d;%d;%d", color.code, r, g, b))
+		}
+	}
+	if len(codes) == 0 {
+		return text
+	}
+	return "\033[" + strings.Join(codes, ";") + "m" + text + "\033[0m"
+}
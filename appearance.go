@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Appearance is the light or dark mode that selects a profile's appearance sub-profile
+type Appearance string
+
+const (
+	AppearanceUnknown Appearance = ""
+	AppearanceDark    Appearance = "dark"
+	AppearanceLight   Appearance = "light"
+)
+
+// appearanceEnvVar overrides the detected appearance, like -mode
+const appearanceEnvVar = "SET_TAB_COLOR_MODE"
+
+// Global -mode flag value; empty or "auto" detects the appearance
+var appearanceOverride string
+
+// knownAppearances lists the appearance sub-profile keys
+var knownAppearances = []Appearance{AppearanceDark, AppearanceLight}
+
+// parseAppearanceMode converts a -mode or SET_TAB_COLOR_MODE value to an Appearance.
+// "auto" and "" mean the appearance should be detected.
+func parseAppearanceMode(mode string) (Appearance, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "auto":
+		return AppearanceUnknown, nil
+	case "dark":
+		return AppearanceDark, nil
+	case "light":
+		return AppearanceLight, nil
+	}
+	return AppearanceUnknown, fmt.Errorf("unknown mode %q (expected dark, light, or auto)", mode)
+}
+
+// detectAppearance returns the light/dark mode from -mode, then $SET_TAB_COLOR_MODE,
+// then the macOS system appearance. Elsewhere it is unknown unless overridden.
+func detectAppearance() Appearance {
+	for _, mode := range []string{appearanceOverride, getDetectionEnv(appearanceEnvVar)} {
+		if appearance, err := parseAppearanceMode(mode); err == nil && appearance != AppearanceUnknown {
+			return appearance
+		}
+	}
+
+	// Fixtures must reproduce the same result on any machine
+	if getFakeEnvironment() != nil || runtime.GOOS != "darwin" {
+		return AppearanceUnknown
+	}
+	return macOSAppearance()
+}
+
+// macOSAppearance reads the system appearance. AppleInterfaceStyle is only set
+// in dark mode, so a failed read means light mode.
+func macOSAppearance() Appearance {
+	out, err := exec.Command("defaults", "read", "-g", "AppleInterfaceStyle").Output()
+	if err == nil && strings.EqualFold(strings.TrimSpace(string(out)), "dark") {
+		return AppearanceDark
+	}
+	return AppearanceLight
+}
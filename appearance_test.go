@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestParseAppearanceMode tests the accepted -mode values
+func TestParseAppearanceMode(t *testing.T) {
+	tests := []struct {
+		mode        string
+		expected    Appearance
+		expectError bool
+	}{
+		{"dark", AppearanceDark, false},
+		{"Light", AppearanceLight, false},
+		{"auto", AppearanceUnknown, false},
+		{"", AppearanceUnknown, false},
+		{"dusk", AppearanceUnknown, true},
+	}
+
+	for _, test := range tests {
+		appearance, err := parseAppearanceMode(test.mode)
+		if (err != nil) != test.expectError {
+			t.Errorf("parseAppearanceMode(%q) error = %v, expectError %v", test.mode, err, test.expectError)
+		}
+		if appearance != test.expected {
+			t.Errorf("parseAppearanceMode(%q) = %q, expected %q", test.mode, appearance, test.expected)
+		}
+	}
+}
+
+// TestDetectAppearance tests that -mode takes precedence over $SET_TAB_COLOR_MODE
+func TestDetectAppearance(t *testing.T) {
+	defer setFakeEnvironment(nil)
+	defer func() { appearanceOverride = "" }()
+
+	// A fixture keeps the system appearance out of the result
+	setFakeEnvironment(&FakeEnvironment{Env: map[string]string{appearanceEnvVar: "light"}})
+	if appearance := detectAppearance(); appearance != AppearanceLight {
+		t.Errorf("Expected light from the environment, got %q", appearance)
+	}
+
+	appearanceOverride = "dark"
+	if appearance := detectAppearance(); appearance != AppearanceDark {
+		t.Errorf("Expected -mode to override the environment, got %q", appearance)
+	}
+
+	appearanceOverride = "auto"
+	setFakeEnvironment(&FakeEnvironment{Env: map[string]string{}})
+	if appearance := detectAppearance(); appearance != AppearanceUnknown {
+		t.Errorf("Expected unknown appearance in a fixture without a mode, got %q", appearance)
+	}
+
+	// Without a fixture the real environment variable is read
+	setFakeEnvironment(nil)
+	appearanceOverride = ""
+	original, had := os.LookupEnv(appearanceEnvVar)
+	os.Setenv(appearanceEnvVar, "dark")
+	defer func() {
+		if had {
+			os.Setenv(appearanceEnvVar, original)
+		} else {
+			os.Unsetenv(appearanceEnvVar)
+		}
+	}()
+	if appearance := detectAppearance(); appearance != AppearanceDark {
+		t.Errorf("Expected dark from $%s, got %q", appearanceEnvVar, appearance)
+	}
+}
+
+// TestResolveAppearanceSubProfile tests that the mode sub-profile sits between the base and the shell
+func TestResolveAppearanceSubProfile(t *testing.T) {
+	data := map[string]interface{}{
+		"tab":   "blue",
+		"fg":    "black",
+		"dark":  map[string]interface{}{"tab": "navy", "fg": "white", "bg": "black"},
+		"light": map[string]interface{}{"tab": "lightblue"},
+		"zsh":   map[string]interface{}{"bg": "gray"},
+	}
+
+	tests := []struct {
+		appearance Appearance
+		tab, fg    string
+		bg         string
+		steps      int
+	}{
+		{AppearanceDark, "navy", "white", "gray", 3},
+		{AppearanceLight, "lightblue", "black", "gray", 3},
+		{AppearanceUnknown, "blue", "black", "gray", 2},
+	}
+
+	for _, test := range tests {
+		res, err := resolveProfile("dev", testProfile(t, data), &TerminalShellInfo{Shell: ShellTypeZsh, Appearance: test.appearance})
+		if err != nil {
+			t.Fatalf("resolveProfile() failed: %v", err)
+		}
+		if res.Result.Tab != test.tab || res.Result.Foreground != test.fg || res.Result.Background != test.bg {
+			t.Errorf("%q: unexpected result %+v", test.appearance, res.Result)
+		}
+		if len(res.Steps) != test.steps {
+			t.Errorf("%q: expected %d steps, got %+v", test.appearance, test.steps, res.Steps)
+		}
+		if test.appearance != AppearanceUnknown {
+			if step := res.Steps[1]; step.Layer != "appearance" || step.Key != string(test.appearance) || !step.Applied {
+				t.Errorf("%q: unexpected appearance step %+v", test.appearance, step)
+			}
+			if source := res.sourceName("tab"); source != "profiles.dev."+string(test.appearance) {
+				t.Errorf("%q: tab source = %q", test.appearance, source)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+// terminalBatch collects what applying a profile sends to the terminal, so the
+// preset, theme, palette, and colors arrive together: adjacent escape sequences
+// are written at once and adjacent it2setcolor calls are merged into one run
+// (it2setcolor takes any number of "target color" pairs). This keeps the tab
+// from flickering through intermediate states and spawns at most one process
+// per run of it2setcolor settings, which matters in prompt hooks.
+type terminalBatch struct {
+	steps []batchStep
+}
+
+// batchStep is either output for escapeWriter or arguments for it2setcolor
+type batchStep struct {
+	output          string
+	it2setcolorArgs []string
+}
+
+// activeBatch is the batch escape output and it2setcolor calls are queued on,
+// or nil to send them immediately
+var activeBatch *terminalBatch
+
+// startBatch queues terminal output until the returned batch is flushed.
+// A batch that is already active is shared, so only the outermost flush sends.
+func startBatch() (batch *terminalBatch, outermost bool) {
+	if activeBatch != nil {
+		return activeBatch, false
+	}
+	activeBatch = &terminalBatch{}
+	return activeBatch, true
+}
+
+// addOutput queues output for escapeWriter, joining it to the previous step
+func (b *terminalBatch) addOutput(output string) {
+	if n := len(b.steps); n > 0 && b.steps[n-1].it2setcolorArgs == nil {
+		b.steps[n-1].output += output
+		return
+	}
+	b.steps = append(b.steps, batchStep{output: output})
+}
+
+// addIt2setcolor queues it2setcolor arguments, joining them to the previous step
+func (b *terminalBatch) addIt2setcolor(args []string) {
+	if n := len(b.steps); n > 0 && b.steps[n-1].it2setcolorArgs != nil {
+		b.steps[n-1].it2setcolorArgs = append(b.steps[n-1].it2setcolorArgs, args...)
+		return
+	}
+	b.steps = append(b.steps, batchStep{it2setcolorArgs: append([]string{}, args...)})
+}
+
+// flush stops batching and sends the queued steps in order
+func (b *terminalBatch) flush() error {
+	if activeBatch == b {
+		activeBatch = nil
+	}
+	steps := b.steps
+	b.steps = nil
+	for _, step := range steps {
+		var err error
+		if step.it2setcolorArgs != nil {
+			err = execIt2setcolor(step.it2setcolorArgs)
+		} else {
+			err = writeWithTimeout(escapeWriter, step.output)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTerminalOutput sends escape sequences or dry-run descriptions to
+// escapeWriter, or queues them on the active batch
+func writeTerminalOutput(output string) error {
+	if activeBatch != nil {
+		activeBatch.addOutput(output)
+		return nil
+	}
+	return writeWithTimeout(escapeWriter, output)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+)
+
+// countingWriter counts the writes it receives
+type countingWriter struct {
+	writes int
+	data   []byte
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	w.data = append(w.data, p...)
+	return len(p), nil
+}
+
+// TestApplyProfileSingleWrite tests that a profile's escape sequences are written at once
+func TestApplyProfileSingleWrite(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+
+	var w countingWriter
+	originalWriter := escapeWriter
+	escapeWriter = &w
+	defer func() { escapeWriter = originalWriter }()
+
+	profile := &Profile{Preset: "Solarized Dark", Tab: "red", Foreground: "white", Background: "black", Title: "prod"}
+	if err := applyProfile(profile); err != nil {
+		t.Fatalf("applyProfile() failed: %v", err)
+	}
+	if w.writes != 1 {
+		t.Errorf("Expected 1 write, got %d", w.writes)
+	}
+
+	tab, _ := buildSetColorSequence(TabColor, "ff0000")
+	expected := buildSetPresetSequence("Solarized Dark") + tab +
+		wrapOSC("1337;SetColors=fg=ffffff") + wrapOSC("1337;SetColors=bg=000000") + buildSetTitleSequence("prod")
+	if string(w.data) != expected {
+		t.Errorf("Expected %q, got %q", expected, w.data)
+	}
+	if activeBatch != nil {
+		t.Error("Expected the batch to end with applyProfile")
+	}
+}
+
+// TestApplyProfileSingleIt2setcolor tests that it2setcolor runs once for a whole profile
+func TestApplyProfileSingleIt2setcolor(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv(it2setcolorEnvVar, "/opt/it2setcolor")
+
+	var w countingWriter
+	originalWriter := escapeWriter
+	escapeWriter = &w
+	dryRunMode = true
+	requireIt2setcolor = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+		requireIt2setcolor = false
+	}()
+
+	profile := &Profile{Preset: "Solarized Dark", Tab: "red", Foreground: "white", Background: "black", Badge: "prod"}
+	if err := applyProfile(profile); err != nil {
+		t.Fatalf("applyProfile() failed: %v", err)
+	}
+
+	expected := "[dry-run] exec: /opt/it2setcolor preset 'Solarized Dark' tab ff0000 fg ffffff bg 000000\n" +
+		"[dry-run] badge \"prod\": \\e]1337;SetBadgeFormat=cHJvZA==\\a\n"
+	if string(w.data) != expected {
+		t.Errorf("Expected dry-run output:\n%s\ngot:\n%s", expected, w.data)
+	}
+}
+
+// TestTerminalBatchOrder tests that batched steps keep their order and adjacent steps merge
+func TestTerminalBatchOrder(t *testing.T) {
+	batch := &terminalBatch{}
+	batch.addIt2setcolor([]string{"preset", "Dark"})
+	batch.addOutput("a")
+	batch.addOutput("b")
+	batch.addIt2setcolor([]string{"tab", "ff0000"})
+	batch.addIt2setcolor([]string{"fg", "ffffff"})
+
+	if len(batch.steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d: %+v", len(batch.steps), batch.steps)
+	}
+	if batch.steps[1].output != "ab" {
+		t.Errorf("Expected merged output \"ab\", got %q", batch.steps[1].output)
+	}
+	if args := batch.steps[2].it2setcolorArgs; len(args) != 4 || args[2] != "fg" {
+		t.Errorf("Expected merged it2setcolor arguments, got %v", args)
+	}
+}
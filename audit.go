@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultAuditThreshold is the CIE76 distance below which two colors are taken to
+// be indistinguishable at a glance. Around 2.3 is the smallest visible difference;
+// tab colors are judged in passing, so the bar is set well above that.
+const defaultAuditThreshold = 10.0
+
+// AuditFinding is a pair of profiles whose colors can't be told apart
+type AuditFinding struct {
+	Profiles [2]string `json:"profiles"`
+	Context  string    `json:"context"`          // "base", or the terminal whose sub-profiles were applied
+	Vision   string    `json:"vision,omitempty"` // The [colorblind] type the colors look alike with
+	Colors   []string  `json:"colors"`           // The compared colors, e.g. `tab #ff0000 vs #f00a0a (distance 3.2)`
+}
+
+// auditTargets are the colors compared between profiles: the ones that tell
+// sessions apart at a glance
+var auditTargets = []struct {
+	name  string
+	value func(*Profile) string
+}{
+	{"tab", func(p *Profile) string { return p.Tab }},
+	{"bg", func(p *Profile) string { return p.Background }},
+}
+
+// auditColor returns the hex of a profile color that can be compared, or "" for
+// unset, default, or computed colors
+func auditColor(target, value string) string {
+	if value == "" || isComputedColor(target, value) {
+		return ""
+	}
+	hex := normalizeColor(value)
+	if hex == "default" {
+		return ""
+	}
+	return hex
+}
+
+// compareProfileColors reports the colors of two profiles when every color set in
+// both is within threshold, as seen with cvdType (typical vision when empty).
+// Profiles sharing no color can't be confused by it.
+func compareProfileColors(a, b *Profile, threshold float64, cvdType string) ([]string, bool) {
+	var compared []string
+	for _, target := range auditTargets {
+		hexA, hexB := auditColor(target.name, target.value(a)), auditColor(target.name, target.value(b))
+		if hexA == "" || hexB == "" {
+			continue
+		}
+		labA, errA := perceivedLab(hexA, cvdType)
+		labB, errB := perceivedLab(hexB, cvdType)
+		if errA != nil || errB != nil {
+			continue
+		}
+		distance := labDistance(labA, labB)
+		if distance >= threshold {
+			return nil, false
+		}
+		compared = append(compared, fmt.Sprintf("%s #%s vs #%s (distance %.1f)", target.name, hexA, hexB, distance))
+	}
+	return compared, len(compared) > 0
+}
+
+// auditContexts returns the environments profiles are compared in: the base values,
+// then each terminal that has a sub-profile, by name or pattern, in some profile
+func auditContexts(profiles map[string]*ProfileConfig) []string {
+	seen := map[string]bool{}
+	for _, profile := range profiles {
+		for _, key := range subProfileKeys(profile) {
+			if terminal, ok := parseTerminalType(key); ok {
+				seen[string(terminal)] = true
+			}
+			if !isSubProfilePattern(key) {
+				continue
+			}
+			for _, terminal := range allTerminalTypes() {
+				if matchSubProfilePattern(key, string(terminal)) {
+					seen[string(terminal)] = true
+				}
+			}
+		}
+	}
+	contexts := make([]string, 0, len(seen)+1)
+	for key := range seen {
+		contexts = append(contexts, key)
+	}
+	sort.Strings(contexts)
+	return append([]string{"base"}, contexts...)
+}
+
+// auditProfiles finds pairs of profiles whose colors are closer than threshold,
+// with typical vision and then with the [colorblind] type if set. A pair is
+// reported once, in the first environment where it is ambiguous.
+func auditProfiles(profiles map[string]*ProfileConfig, threshold float64) []AuditFinding {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	visions := []string{""}
+	if colorblindMode.Type != "" {
+		visions = append(visions, colorblindMode.Type)
+	}
+
+	var findings []AuditFinding
+	reported := map[[2]string]bool{}
+	for _, vision := range visions {
+		findings = append(findings, auditProfilesWith(profiles, names, threshold, vision, reported)...)
+	}
+	return findings
+}
+
+// auditProfilesWith compares every pair of profiles not yet reported, as seen with vision
+func auditProfilesWith(profiles map[string]*ProfileConfig, names []string, threshold float64, vision string, reported map[[2]string]bool) []AuditFinding {
+	var findings []AuditFinding
+	for _, context := range auditContexts(profiles) {
+		info := &TerminalShellInfo{}
+		if context != "base" {
+			info.Terminals = []TerminalType{TerminalType(context)}
+		}
+
+		resolved := map[string]*Profile{}
+		for _, name := range names {
+			if res, err := resolveProfile(name, profiles[name], info); err == nil {
+				resolved[name] = &res.Result
+			}
+		}
+
+		for i, a := range names {
+			for _, b := range names[i+1:] {
+				pair := [2]string{a, b}
+				if reported[pair] || resolved[a] == nil || resolved[b] == nil {
+					continue
+				}
+				if colors, ambiguous := compareProfileColors(resolved[a], resolved[b], threshold, vision); ambiguous {
+					reported[pair] = true
+					findings = append(findings, AuditFinding{Profiles: pair, Context: context, Vision: vision, Colors: colors})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// runConfigAudit warns about profiles that can't be told apart by their colors
+func runConfigAudit(args []string) error {
+	threshold := defaultAuditThreshold
+	for i := 0; i < len(args); i++ {
+		value := ""
+		switch {
+		case args[i] == "--threshold" || args[i] == "-threshold":
+			if i+1 >= len(args) {
+				return fmt.Errorf("usage: config audit [--threshold N]")
+			}
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--threshold="):
+			value = strings.TrimPrefix(args[i], "--threshold=")
+		default:
+			return fmt.Errorf("unexpected argument %q", args[i])
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid threshold %q (expected a positive number)", value)
+		}
+		threshold = parsed
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	findings := auditProfiles(config.Profiles, threshold)
+	if jsonOutput {
+		if findings == nil {
+			findings = []AuditFinding{}
+		}
+		if err := printJSON(findings); err != nil {
+			return err
+		}
+	} else if len(findings) == 0 {
+		fmt.Printf("No profiles with indistinguishable colors (threshold %g)\n", threshold)
+	} else {
+		for _, finding := range findings {
+			context := finding.Context
+			if finding.Vision != "" {
+				context += ", with " + finding.Vision
+			}
+			warnf("profiles %q and %q look alike (%s): %s",
+				finding.Profiles[0], finding.Profiles[1], context, strings.Join(finding.Colors, ", "))
+		}
+	}
+
+	if len(findings) > 0 {
+		return withExitCode(exitConfigError, fmt.Errorf("found %d pair(s) of profiles with indistinguishable colors", len(findings)))
+	}
+	return nil
+}
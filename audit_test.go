@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAuditProfiles tests finding profiles whose colors look alike
+func TestAuditProfiles(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	config := decodeTestConfig(t, `
+[profiles.prod]
+tab = "red"
+
+[profiles.staging]
+tab = "#f00a0a"
+
+[profiles.dev]
+tab = "green"
+bg = "black"
+
+[profiles.dev.iterm2]
+tab = "#ff0505"
+
+[profiles.test]
+tab = "green"
+bg = "white"
+
+[profiles.auto]
+tab = "distinct"
+
+[profiles.reset]
+tab = "default"
+`)
+
+	findings := auditProfiles(config.Profiles, defaultAuditThreshold)
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.Profiles[0]+"/"+finding.Profiles[1]+"@"+finding.Context)
+	}
+
+	// dev and test share a tab color but not a background, so they can be told apart
+	expected := []string{"prod/staging@base", "dev/prod@iterm2", "dev/staging@iterm2"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("auditProfiles() = %v, expected %v", got, expected)
+	}
+	if len(findings) > 0 && !strings.Contains(findings[0].Colors[0], "tab #ff0000 vs #f00a0a") {
+		t.Errorf("Expected the compared colors to be listed, got %v", findings[0].Colors)
+	}
+
+	// A lower threshold accepts the near-identical reds
+	if findings := auditProfiles(config.Profiles, 1); len(findings) != 0 {
+		t.Errorf("Expected no findings with a threshold of 1, got %+v", findings)
+	}
+}
+
+// TestRunConfigAudit tests the config audit command's arguments and exit code
+func TestRunConfigAudit(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, []byte("[profiles.a]\ntab = \"red\"\n\n[profiles.b]\ntab = \"red\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+	setLogLevel(LogError)
+	defer setLogLevel(LogWarn)
+
+	err := runConfigAudit(nil)
+	if err == nil || exitCode(err) != exitConfigError {
+		t.Errorf("Expected a config error for identical profiles, got %v", err)
+	}
+
+	for _, args := range [][]string{{"--threshold"}, {"--threshold=-1"}, {"extra"}} {
+		if err := runConfigAudit(args); err == nil {
+			t.Errorf("Expected error for arguments %v", args)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+)
+
+// EscapeBackend selects the escape sequences colors are sent with
+type EscapeBackend string
+
+const (
+	// BackendITerm2 uses iTerm2's proprietary sequences, which also reach iTerm2
+	// through tmux, SSH, and Eternal Terminal
+	BackendITerm2 EscapeBackend = "iterm2"
+	// BackendXterm uses the standard xterm sequences most other terminals honor.
+	// There is no standard tab color, preset, badge, or attention sequence, so
+	// those are skipped.
+	BackendXterm EscapeBackend = "xterm"
+)
+
+// escapeBackend is chosen from the detected terminals before anything is applied
+var escapeBackend = BackendITerm2
+
+// xtermTerminals lists the terminals that ignore iTerm2's sequences but honor the
+// standard xterm color sequences
+var xtermTerminals = []TerminalType{
+	TerminalTypeVSCode,
+	TerminalTypeGhostty,
+	TerminalTypeWarp,
+	TerminalTypeHyper,
+	TerminalTypeRio,
+	TerminalTypeTabby,
+	TerminalTypeKitty,
+	TerminalTypeWezTerm,
+	TerminalTypeGnome,
+	TerminalTypeKonsole,
+	TerminalTypeXterm,
+	TerminalTypeWindowsTerminal,
+	TerminalTypeConEmu,
+}
+
+// selectEscapeBackend returns the backend for a detected terminal chain. Anything
+// running inside one of xtermTerminals is rendered by it, wherever it appears in
+// the chain.
+func selectEscapeBackend(terminals []TerminalType) EscapeBackend {
+	for _, terminal := range terminals {
+		if containsTerminal(xtermTerminals, terminal) {
+			return BackendXterm
+		}
+	}
+	return BackendITerm2
+}
+
+// useEscapeBackendFor selects the backend for a detected terminal chain
+func useEscapeBackendFor(info TerminalShellInfo) {
+	escapeBackend = selectEscapeBackend(info.Terminals)
+	if escapeBackend != BackendITerm2 {
+		infof("Using the %s escape sequences\n", escapeBackend)
+	}
+}
+
+// buildXtermColorSequence returns the standard xterm sequence for a normalized color:
+// OSC 10/11 for foreground/background, OSC 4 for the ANSI colors, and OSC 12/17/19
+// for the cursor and selection. ok is false for targets without one, such as the tab.
+func buildXtermColorSequence(target ColorTarget, normalizedColor string) (seq string, ok bool) {
+	if normalizedColor == "default" {
+		reset, ok := resetSequences[target]
+		if !ok {
+			return "", false
+		}
+		return wrapOSC(reset), true
+	}
+
+	switch target {
+	case ForegroundColor:
+		return wrapOSC("10;#" + normalizedColor), true
+	case BackgroundColor:
+		return wrapOSC("11;#" + normalizedColor), true
+	}
+	for i, key := range paletteKeys[:16] {
+		if string(target) == key {
+			return wrapOSC(fmt.Sprintf("4;%d;#%s", i, normalizedColor)), true
+		}
+	}
+	if number, ok := themeSequences[string(target)]; ok {
+		return wrapOSC(number + ";#" + normalizedColor), true
+	}
+	return "", false
+}
+
+// emitXtermColor writes the xterm sequence for a normalized color, skipping
+// targets without one. The tab color goes to the [unsupported] tab target if set.
+func emitXtermColor(target ColorTarget, normalizedColor string) error {
+	description := fmt.Sprintf("%s %s", target, normalizedColor)
+	seq, ok := buildXtermColorSequence(target, normalizedColor)
+	if !ok && target == TabColor && unsupportedPolicy.Tab != "" {
+		description = fmt.Sprintf("tab %s as %s", normalizedColor, unsupportedPolicy.Tab)
+		seq, ok = buildXtermColorSequence(ColorTarget(unsupportedPolicy.Tab), normalizedColor)
+	}
+	if !ok {
+		return skipUnsupported(description)
+	}
+	return writeSequence(description, seq)
+}
+
+// skipUnsupported notes a setting the current backend can't apply. It isn't an
+// error unless -strict is set, so profiles shared between terminals keep working.
+func skipUnsupported(description string) error {
+	if strictMode {
+		return withExitCode(exitUnsupported, fmt.Errorf("%s is not supported: no %s escape sequence", description, escapeBackend))
+	}
+	if dryRunMode {
+		return writeTerminalOutput(fmt.Sprintf("[dry-run] skip %s: no %s escape sequence\n", description, escapeBackend))
+	}
+	if unsupportedPolicy.Action == "warn" {
+		warnf("skipping %s: no %s escape sequence", description, escapeBackend)
+	} else {
+		debugf("Skipping %s: no %s escape sequence\n", description, escapeBackend)
+	}
+	return nil
+}
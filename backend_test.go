@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestSelectEscapeBackend tests choosing the xterm backend from the terminal chain
+func TestSelectEscapeBackend(t *testing.T) {
+	tests := []struct {
+		terminals []TerminalType
+		expected  EscapeBackend
+	}{
+		{nil, BackendITerm2},
+		{[]TerminalType{TerminalTypeTmux, TerminalTypeITerm2}, BackendITerm2},
+		{[]TerminalType{TerminalTypeVSCode}, BackendXterm},
+		{[]TerminalType{TerminalTypeTmux, TerminalTypeVSCode, TerminalTypeITerm2}, BackendXterm},
+		{[]TerminalType{TerminalTypeTmux, TerminalTypeGhostty}, BackendXterm},
+		{[]TerminalType{TerminalTypeJetBrains}, BackendITerm2},
+	}
+
+	for _, test := range tests {
+		if backend := selectEscapeBackend(test.terminals); backend != test.expected {
+			t.Errorf("selectEscapeBackend(%v) = %s, expected %s", test.terminals, backend, test.expected)
+		}
+	}
+}
+
+// TestBuildXtermColorSequence tests the standard sequences used by the xterm backend
+func TestBuildXtermColorSequence(t *testing.T) {
+	tests := []struct {
+		target   ColorTarget
+		color    string
+		expected string
+		ok       bool
+	}{
+		{ForegroundColor, "ffffff", "\033]10;#ffffff\a", true},
+		{BackgroundColor, "000000", "\033]11;#000000\a", true},
+		{BackgroundColor, "default", "\033]111\a", true},
+		{"br_red", "ff5555", "\033]4;9;#ff5555\a", true},
+		{"curbg", "00ff00", "\033]12;#00ff00\a", true},
+		{TabColor, "ff0000", "", false},
+		{TabColor, "default", "", false},
+		{"bold", "ffffff", "", false},
+	}
+
+	for _, test := range tests {
+		seq, ok := buildXtermColorSequence(test.target, test.color)
+		if seq != test.expected || ok != test.ok {
+			t.Errorf("buildXtermColorSequence(%s, %s) = %q, %v, expected %q, %v", test.target, test.color, seq, ok, test.expected, test.ok)
+		}
+	}
+}
+
+// TestApplyProfileXterm tests that unsupported settings are skipped instead of failing
+func TestApplyProfileXterm(t *testing.T) {
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	escapeBackend = BackendXterm
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+		escapeBackend = BackendITerm2
+	}()
+
+	profile := &Profile{Tab: "red", Foreground: "white", Background: "black", Preset: "Solarized", Badge: "prod", Title: "api"}
+	if err := applyProfile(profile); err != nil {
+		t.Fatalf("applyProfile() failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{
+		"[dry-run] skip preset \"Solarized\": no xterm escape sequence",
+		"[dry-run] skip tab ff0000: no xterm escape sequence",
+		"[dry-run] fg ffffff: \\e]10;#ffffff\\a",
+		"[dry-run] bg 000000: \\e]11;#000000\\a",
+		"[dry-run] skip badge \"prod\"",
+		"[dry-run] title \"api\"",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "1337;") || strings.Contains(output, "]6;1;bg") {
+		t.Errorf("Expected no iTerm2 sequences, got:\n%s", output)
+	}
+}
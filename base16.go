@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// base16Mapping maps terminal colors to base16 slots, following base16-shell.
+// Slots base12-base17 replace the bright colors for base24 schemes.
+var base16Mapping = []struct {
+	key    string // "fg", "bg", or a palette key
+	slot   string
+	base24 string
+}{
+	{"fg", "base05", ""},
+	{"bg", "base00", ""},
+	{"black", "base00", ""},
+	{"red", "base08", ""},
+	{"green", "base0B", ""},
+	{"yellow", "base0A", ""},
+	{"blue", "base0D", ""},
+	{"magenta", "base0E", ""},
+	{"cyan", "base0C", ""},
+	{"white", "base05", ""},
+	{"br_black", "base03", ""},
+	{"br_red", "base08", "base12"},
+	{"br_green", "base0B", "base14"},
+	{"br_yellow", "base0A", "base13"},
+	{"br_blue", "base0D", "base16"},
+	{"br_magenta", "base0E", "base17"},
+	{"br_cyan", "base0C", "base15"},
+	{"br_white", "base07", ""},
+	{"curbg", "base05", ""},
+	{"curfg", "base00", ""},
+	{"selbg", "base02", ""},
+	{"selfg", "base05", ""},
+}
+
+// parseBase16 reads a base16 or base24 YAML scheme. Both the classic flat format
+// (scheme: ..., base00: ...) and the newer one with a nested palette: block are
+// accepted. It returns the theme and the scheme's name, if any.
+func parseBase16(data []byte) (ThemeConfig, string, error) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return ThemeConfig{}, "", fmt.Errorf("line %d: expected key: value", lineNum)
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return ThemeConfig{}, "", err
+	}
+
+	name := values["scheme"]
+	if name == "" {
+		name = values["name"]
+	}
+
+	slot := func(s string) (string, error) {
+		value, ok := values[s]
+		if !ok {
+			return "", nil
+		}
+		hex := strings.ToLower(strings.TrimPrefix(value, "#"))
+		if len(hex) != 6 || !isHex(hex) {
+			return "", fmt.Errorf("invalid color %q for %s", value, s)
+		}
+		return hex, nil
+	}
+
+	theme := ThemeConfig{Palette: map[string]string{}}
+	for _, m := range base16Mapping {
+		hex, err := slot(m.base24)
+		if err == nil && hex == "" {
+			hex, err = slot(m.slot)
+		}
+		if err != nil {
+			return ThemeConfig{}, "", err
+		}
+		if hex == "" {
+			return ThemeConfig{}, "", fmt.Errorf("missing %s; not a base16 scheme", m.slot)
+		}
+
+		switch m.key {
+		case "fg":
+			theme.Foreground = hex
+		case "bg":
+			theme.Background = hex
+		default:
+			theme.Palette[m.key] = hex
+		}
+	}
+	return theme, name, nil
+}
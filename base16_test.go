@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testBase16Scheme is the classic flat base16 format
+const testBase16Scheme = `scheme: "Default Dark"
+author: "Chris Kempson (http://chriskempson.com)"
+base00: "181818" # background
+base01: "282828"
+base02: "383838"
+base03: "585858"
+base04: "b8b8b8"
+base05: "d8d8d8"
+base06: "e8e8e8"
+base07: "f8f8f8"
+base08: "ab4642"
+base09: "dc9656"
+base0A: "f7ca88"
+base0B: "a1b56c"
+base0C: "86c1b9"
+base0D: "7cafc2"
+base0E: "ba8baf"
+base0F: "a16946"
+`
+
+// testBase24Scheme is the newer nested format with base24 bright colors
+const testBase24Scheme = `system: "base24"
+name: "Example 24"
+variant: "dark"
+palette:
+  base00: "#000000"
+  base01: "#111111"
+  base02: "#222222"
+  base03: "#333333"
+  base04: "#444444"
+  base05: "#555555"
+  base06: "#666666"
+  base07: "#777777"
+  base08: "#880000"
+  base09: "#999999"
+  base0A: "#aaaa00"
+  base0B: "#00bb00"
+  base0C: "#00cccc"
+  base0D: "#0000dd"
+  base0E: "#ee00ee"
+  base0F: "#ffffff"
+  base10: "#101010"
+  base11: "#111111"
+  base12: "#ff1212"
+  base13: "#ffff13"
+  base14: "#14ff14"
+  base15: "#15ffff"
+  base16: "#1616ff"
+  base17: "#ff17ff"
+`
+
+// TestParseBase16 tests mapping base16 and base24 slots to terminal colors
+func TestParseBase16(t *testing.T) {
+	theme, name, err := parseBase16([]byte(testBase16Scheme))
+	if err != nil {
+		t.Fatalf("parseBase16() failed: %v", err)
+	}
+	if name != "Default Dark" {
+		t.Errorf("Expected scheme name %q, got %q", "Default Dark", name)
+	}
+	if theme.Foreground != "d8d8d8" || theme.Background != "181818" {
+		t.Errorf("Unexpected fg/bg: %q/%q", theme.Foreground, theme.Background)
+	}
+	for key, expected := range map[string]string{
+		"black": "181818", "red": "ab4642", "br_black": "585858", "br_red": "ab4642",
+		"br_white": "f8f8f8", "selbg": "383838",
+	} {
+		if theme.Palette[key] != expected {
+			t.Errorf("palette.%s = %q, expected %q", key, theme.Palette[key], expected)
+		}
+	}
+
+	theme, name, err = parseBase16([]byte(testBase24Scheme))
+	if err != nil {
+		t.Fatalf("parseBase16() failed for base24: %v", err)
+	}
+	if name != "Example 24" {
+		t.Errorf("Expected scheme name %q, got %q", "Example 24", name)
+	}
+	for key, expected := range map[string]string{
+		"red": "880000", "br_red": "ff1212", "br_yellow": "ffff13", "br_green": "14ff14",
+		"br_cyan": "15ffff", "br_blue": "1616ff", "br_magenta": "ff17ff",
+	} {
+		if theme.Palette[key] != expected {
+			t.Errorf("base24 palette.%s = %q, expected %q", key, theme.Palette[key], expected)
+		}
+	}
+
+	for _, bad := range []string{"scheme: x\nbase00: 123\n", "scheme: x\n", "not yaml"} {
+		if _, _, err := parseBase16([]byte(bad)); err == nil {
+			t.Errorf("Expected parseBase16(%q) to fail", bad)
+		}
+	}
+}
+
+// TestRunThemeImportBase16 tests that base16 schemes are imported under their scheme name
+func TestRunThemeImportBase16(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+	schemeFile := filepath.Join(tempDir, "default-dark.yaml")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+	defer setCustomThemes(nil)
+
+	if err := os.WriteFile(schemeFile, []byte(testBase16Scheme), 0644); err != nil {
+		t.Fatalf("Failed to create scheme file: %v", err)
+	}
+	if err := runThemeImport([]string{schemeFile}); err != nil {
+		t.Fatalf("runThemeImport() failed: %v", err)
+	}
+
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	theme, err := lookupTheme("Default Dark")
+	if err != nil {
+		t.Fatalf("lookupTheme() failed: %v", err)
+	}
+	if theme.Background != "181818" || theme.Palette["br_white"] != "f8f8f8" {
+		t.Errorf("Unexpected imported theme: %+v", theme)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Batch input for -stdin is one command per line, e.g. "tab red" or "profile prod".
+// Blank lines and lines starting with # are skipped.
+
+// batchCommands lists the commands -stdin accepts, with their usage
+var batchCommands = map[string]string{
+	"tab":     "tab <color>",
+	"fg":      "fg <color>",
+	"bg":      "bg <color>",
+	"preset":  "preset <name>",
+	"theme":   "theme <name>",
+	"badge":   "badge <text>",
+	"title":   "title <text>",
+	"profile": "profile <name>",
+	"reset":   "reset",
+}
+
+// parseBatchLine splits a batch line into its command and argument. The argument is
+// the rest of the line, so badges and titles can contain spaces.
+func parseBatchLine(line string) (command, arg string, err error) {
+	command, arg, _ = strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+	usage, ok := batchCommands[command]
+	if !ok {
+		return "", "", fmt.Errorf("unknown command %q", command)
+	}
+	if (command == "reset") != (arg == "") {
+		return "", "", fmt.Errorf("usage: %s", usage)
+	}
+	return command, arg, nil
+}
+
+// batchProfile returns the profile a batch command applies
+func batchProfile(command, arg string, info *TerminalShellInfo) (*Profile, string, error) {
+	switch command {
+	case "profile":
+		profile, err := getProfileWithTerminalInfo(arg, info)
+		return profile, arg, err
+	case "tab":
+		return &Profile{Tab: arg}, "", nil
+	case "fg":
+		return &Profile{Foreground: arg}, "", nil
+	case "bg":
+		return &Profile{Background: arg}, "", nil
+	case "preset":
+		return &Profile{Preset: arg}, "", nil
+	case "theme":
+		return &Profile{Theme: arg}, "", nil
+	case "badge":
+		return &Profile{Badge: arg}, "", nil
+	}
+	return &Profile{Title: arg}, "", nil
+}
+
+// batchState is what a batch has applied since it began or was last reset. It is
+// recorded once the batch ends, so a line that sets one color doesn't replace the
+// state of the lines before it.
+type batchState struct {
+	profile Profile
+	name    string // Profile name of the last line; empty for colors set directly
+	locked  bool   // Whether the last line applied a sticky profile
+	applied bool
+}
+
+// runBatchCommand applies one batch command with the terminal detected for the
+// batch, and merges what it applied into the batch's state
+func runBatchCommand(command, arg string, info *TerminalShellInfo, state *batchState) error {
+	if command == "reset" {
+		if err := resetTerminal(); err != nil {
+			return err
+		}
+		*state = batchState{}
+		return nil
+	}
+
+	profile, name, err := batchProfile(command, arg, info)
+	if err != nil {
+		return err
+	}
+	if err := checkProfileLock(name); err != nil {
+		return err
+	}
+	// A sticky profile from an earlier line isn't recorded yet, but holds the session
+	if !forceApply && !usesTmuxTargeting() {
+		if err := profileLockError(state.name, state.locked, name); err != nil {
+			return err
+		}
+	}
+	if err := resolveDistinctTab(profile); err != nil {
+		return err
+	}
+	if err := resolveAutoForeground(profile); err != nil {
+		return err
+	}
+	if err := applyProfile(profile); err != nil {
+		return err
+	}
+
+	state.profile = overlayProfile(state.profile, *profile)
+	state.name, state.locked, state.applied = name, isStickyProfile(name), true
+	return nil
+}
+
+// runBatch reads commands from r and applies them in order, detecting the terminal
+// only once. A failed command is reported and the rest still run; the exit code is
+// that of the first failure.
+func runBatch(r io.Reader, terminalType string) error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	info := detectTerminalAndShell(terminalType)
+	useEscapeBackendFor(info)
+
+	scanner := bufio.NewScanner(r)
+	lineNum, commands, failed := 0, 0, 0
+	var firstErr error
+	var state batchState
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		commands++
+		command, arg, err := parseBatchLine(line)
+		if err == nil {
+			debugf("Batch line %d: %s %q\n", lineNum, command, redact(arg))
+			err = runBatchCommand(command, arg, &info, &state)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			errorf("line %d: %v", lineNum, err)
+		}
+	}
+
+	// Remember what was applied for status bars; another tmux window's colors aren't ours
+	if state.applied && !usesTmuxTargeting() {
+		recordAppliedState(state.name, &state.profile)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading commands: %v", err)
+	}
+
+	// The first failure decides the exit code
+	if failed > 0 {
+		return withExitCode(exitCode(firstErr), fmt.Errorf("%d of %d commands failed", failed, commands))
+	}
+	return nil
+}
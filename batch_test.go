@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseBatchLine tests splitting -stdin lines into commands and arguments
+func TestParseBatchLine(t *testing.T) {
+	tests := []struct {
+		line        string
+		command     string
+		arg         string
+		expectError string
+	}{
+		{"tab red", "tab", "red", ""},
+		{"  fg   #ffffff ", "fg", "#ffffff", ""},
+		{"title api server (prod)", "title", "api server (prod)", ""},
+		{"profile team,prod", "profile", "team,prod", ""},
+		{"reset", "reset", "", ""},
+		{"reset now", "", "", "usage: reset"},
+		{"tab", "", "", "usage: tab <color>"},
+		{"blink red", "", "", `unknown command "blink"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			command, arg, err := parseBatchLine(tt.line)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("parseBatchLine(%q) error = %v, expected %q", tt.line, err, tt.expectError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseBatchLine(%q) failed: %v", tt.line, err)
+			}
+			if command != tt.command || arg != tt.arg {
+				t.Errorf("parseBatchLine(%q) = %q, %q, expected %q, %q", tt.line, command, arg, tt.command, tt.arg)
+			}
+		})
+	}
+}
+
+// TestRunBatch tests applying commands from stdin in dry-run mode
+func TestRunBatch(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "batch-config.toml")
+	configContent := `[profiles.prod]
+tab = "red"
+
+[profiles.prod.vscode]
+bg = "black"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+
+	setFakeEnvironment(&FakeEnvironment{
+		Env:          map[string]string{"TERM_PROGRAM": "iTerm.app"},
+		ProcessChain: []string{"set-tab-color", "zsh", "iTerm2"},
+	})
+	defer setFakeEnvironment(nil)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+	}()
+
+	input := `# colors for the deploy
+tab blue
+
+title deploy
+profile prod
+`
+	if err := runBatch(strings.NewReader(input), ""); err != nil {
+		t.Fatalf("runBatch() failed: %v", err)
+	}
+	output := buf.String()
+	for _, expected := range []string{"0000ff", "deploy", "ff0000"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "000000") {
+		t.Errorf("Expected the vscode sub-profile not to apply in iTerm2, got:\n%s", output)
+	}
+
+	// Failed lines are counted, and the rest still run
+	buf.Reset()
+	err := runBatch(strings.NewReader("tab not-a-color\nblink\nfg white\n"), "vscode")
+	if err == nil || err.Error() != "2 of 3 commands failed" {
+		t.Errorf("Expected 2 of 3 commands to fail, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "ffffff") {
+		t.Errorf("Expected the fg command to run, got:\n%s", buf.String())
+	}
+}
+
+// TestRunBatchState tests that the lines of a batch are recorded as one state, and
+// that a sticky profile from an earlier line holds the rest of the batch
+func TestRunBatchState(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+	if err := os.WriteFile(configFile, []byte(`[profiles.prod]
+tab = "red"
+sticky = true
+`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	t.Setenv("XDG_STATE_HOME", tempDir)
+	t.Setenv("XDG_CACHE_HOME", tempDir)
+	t.Setenv("ITERM_SESSION_ID", "w0t0p0:batch-test")
+	setFakeEnvironment(&FakeEnvironment{
+		Env:          map[string]string{"TERM_PROGRAM": "iTerm.app"},
+		ProcessChain: []string{"set-tab-color", "zsh", "iTerm2"},
+	})
+	defer setFakeEnvironment(nil)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+
+	if err := runBatch(strings.NewReader("tab blue\nfg white\nbg black\n"), ""); err != nil {
+		t.Fatalf("runBatch() failed: %v", err)
+	}
+	state, err := loadSessionState()
+	if err != nil || state == nil || state.Tab != "0000ff" || state.Foreground != "ffffff" || state.Background != "000000" {
+		t.Errorf("Expected every line in the recorded state, got %+v, %v", state, err)
+	}
+
+	// A reset starts over, and the sticky profile stops the line after it
+	err = runBatch(strings.NewReader("reset\nprofile prod\ntab blue\n"), "")
+	if err == nil || err.Error() != "1 of 3 commands failed" {
+		t.Errorf("Expected the line after the sticky profile to fail, got %v", err)
+	}
+	state, err = loadSessionState()
+	if err != nil || state == nil || state.Profile != "prod" || state.Tab != "ff0000" || state.Foreground != "" || !state.Locked {
+		t.Errorf("Expected the sticky profile recorded alone, got %+v, %v", state, err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// bundleHeader starts every exported config bundle so import can tell a bundle
+// from arbitrary input, such as an ssh error message
+const bundleHeader = "# set-tab-color config bundle v1"
+
+// bundleUsage follows the header as a reminder of how to use the bundle
+const bundleUsage = "# Import with: set-tab-color config import < bundle.toml"
+
+// buildConfigBundle wraps a config file's contents in a bundle. The config has no
+// includes, so the file alone carries the profiles, rules, colors, and themes.
+func buildConfigBundle(content string) string {
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return bundleHeader + "\n" + bundleUsage + "\n\n" + content
+}
+
+// parseConfigBundle checks the bundle header and returns the config contents
+func parseConfigBundle(data string) (string, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	if !strings.HasPrefix(data, bundleHeader+"\n") {
+		return "", fmt.Errorf("input is not a set-tab-color config bundle (expected it to start with %q)", bundleHeader)
+	}
+	content := strings.TrimPrefix(data, bundleHeader+"\n")
+	content = strings.TrimPrefix(content, bundleUsage+"\n")
+	return strings.TrimPrefix(content, "\n"), nil
+}
+
+// runConfigExport prints the config file as a bundle, refusing configs with
+// problems so a broken setup isn't pushed to other hosts
+func runConfigExport(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: config export")
+	}
+
+	configPath, err := getConfigPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return withExitCode(exitConfigError, fmt.Errorf("no config file at %s", configPath))
+	}
+	if err != nil {
+		return err
+	}
+
+	issues, err := validateConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		return withExitCode(exitConfigError, fmt.Errorf("found %d problem(s) in %s (run config validate for details)", len(issues), configPath))
+	}
+
+	fmt.Print(buildConfigBundle(string(data)))
+	return nil
+}
+
+// runConfigImport replaces the config file with a bundle read from a file or
+// stdin. The bundle is validated first, and the old config is kept as a .bak file.
+func runConfigImport(args []string) error {
+	var input io.Reader = os.Stdin
+	switch {
+	case len(args) > 1:
+		return fmt.Errorf("usage: config import [bundle-file]")
+	case len(args) == 1 && args[0] != "-":
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return fmt.Errorf("could not read bundle: %v", err)
+	}
+	bundle := strings.ReplaceAll(string(data), "\r\n", "\n")
+	content, err := parseConfigBundle(bundle)
+	if err != nil {
+		return err
+	}
+	headerLines := strings.Count(bundle[:len(bundle)-len(content)], "\n")
+
+	configPath, err := editableConfigPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return fmt.Errorf("could not create config directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(configPath), ".set-tab-color-*.toml")
+	if err != nil {
+		return fmt.Errorf("could not write config: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write config: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write config: %v", err)
+	}
+
+	issues, err := validateConfigFile(tmp.Name())
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		for _, issue := range issues {
+			if issue.Line > 0 {
+				fmt.Fprintf(os.Stderr, "bundle:%d: %s\n", issue.Line+headerLines, issue.Message)
+			} else {
+				fmt.Fprintf(os.Stderr, "bundle: %s\n", issue.Message)
+			}
+		}
+		return withExitCode(exitConfigError, fmt.Errorf("found %d problem(s) in bundle; %s was not changed", len(issues), configPath))
+	}
+
+	if old, err := os.ReadFile(configPath); err == nil {
+		if string(old) == content {
+			fmt.Printf("%s is already up to date\n", configPath)
+			return nil
+		}
+		if err := os.WriteFile(configPath+".bak", old, 0644); err != nil {
+			return fmt.Errorf("could not back up config: %v", err)
+		}
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("could not write config: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), configPath); err != nil {
+		return fmt.Errorf("could not write config: %v", err)
+	}
+
+	fmt.Printf("Imported bundle into %s\n", configPath)
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParseConfigBundle tests round-tripping a config through a bundle
+func TestParseConfigBundle(t *testing.T) {
+	content := "[profiles.prod]\ntab = \"red\"\n"
+
+	bundle := buildConfigBundle(content)
+	parsed, err := parseConfigBundle(bundle)
+	if err != nil {
+		t.Fatalf("parseConfigBundle() failed: %v", err)
+	}
+	if parsed != content {
+		t.Errorf("parseConfigBundle() = %q, expected %q", parsed, content)
+	}
+
+	if _, err := parseConfigBundle("Permission denied (publickey).\n"); err == nil {
+		t.Error("Expected parseConfigBundle() to reject input without the header")
+	}
+}
+
+// TestRunConfigImport tests importing a bundle over an existing config
+func TestRunConfigImport(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.toml")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+	defer setCustomColors(nil)
+
+	old := "[profiles.dev]\ntab = \"blue\"\n"
+	if err := os.WriteFile(configFile, []byte(old), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	// A bundle with problems leaves the config alone
+	badFile := filepath.Join(dir, "bad.toml")
+	if err := os.WriteFile(badFile, []byte(buildConfigBundle("[profiles.prod]\ntab = \"notacolor\"\n")), 0644); err != nil {
+		t.Fatalf("Failed to create bundle: %v", err)
+	}
+	if err := runConfigImport([]string{badFile}); err == nil {
+		t.Error("Expected import of an invalid bundle to fail")
+	}
+	if data, _ := os.ReadFile(configFile); string(data) != old {
+		t.Errorf("Expected config to be unchanged, got:\n%s", data)
+	}
+
+	content := "[colors]\ncorp = \"#f26522\"\n\n[profiles.prod]\ntab = \"corp\"\n"
+	bundleFile := filepath.Join(dir, "bundle.toml")
+	if err := os.WriteFile(bundleFile, []byte(buildConfigBundle(content)), 0644); err != nil {
+		t.Fatalf("Failed to create bundle: %v", err)
+	}
+	if err := runConfigImport([]string{bundleFile}); err != nil {
+		t.Fatalf("runConfigImport() failed: %v", err)
+	}
+	if data, _ := os.ReadFile(configFile); string(data) != content {
+		t.Errorf("Unexpected config after import:\n%s", data)
+	}
+	if data, _ := os.ReadFile(configFile + ".bak"); string(data) != old {
+		t.Errorf("Expected old config in backup, got:\n%s", data)
+	}
+
+	profile, err := getProfileWithTerminalInfo("prod", &TerminalShellInfo{})
+	if err != nil {
+		t.Fatalf("getProfileWithTerminalInfo() failed: %v", err)
+	}
+	if profile.Tab != "corp" {
+		t.Errorf("Expected imported profile, got %+v", profile)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TerminalCapabilities describes what set-tab-color can do in a terminal and how to set it up
+type TerminalCapabilities struct {
+	Name        string        // Human-readable terminal name
+	Targets     []ColorTarget // Color targets the terminal honors
+	Presets     bool          // Whether iTerm2 color presets can be selected
+	Badge       bool          // Whether the iTerm2 badge can be set
+	Title       bool          // Whether the tab/window title can be set
+	Setup       []string      // Steps needed before colors take effect
+	Limitations []string      // Known caveats
+}
+
+// terminalCapabilities holds the capability metadata for every known terminal type
+var terminalCapabilities = map[TerminalType]TerminalCapabilities{
+	TerminalTypeITerm2: {
+		Name:    "iTerm2",
+		Targets: []ColorTarget{TabColor, ForegroundColor, BackgroundColor},
+		Presets: true,
+		Badge:   true,
+		Title:   true,
+		Setup: []string{
+			"No setup needed: colors are set with iTerm2's proprietary escape sequences.",
+			"Optional: install the shell integration utilities (iTerm2 > Install Shell Integration) to use -prefer-it2setcolor.",
+		},
+		Limitations: []string{
+			"Presets must exist in Settings > Profiles > Colors > Color Presets.",
+			"-prefer-it2setcolor looks for it2setcolor in ~/.iterm2, then $PATH, and uses escape sequences without it; -require-it2setcolor fails instead.",
+		},
+	},
+	TerminalTypeTmux: {
+		Name:    "tmux",
+		Targets: []ColorTarget{TabColor, ForegroundColor, BackgroundColor},
+		Presets: true,
+		Badge:   true,
+		Title:   true,
+		Setup: []string{
+			"Allow escape sequence passthrough (tmux 3.3+): set -g allow-passthrough on",
+			"Keep TERM starting with tmux or screen so sequences are wrapped for passthrough.",
+			"To show titles in the outer terminal: set -g set-titles on; set -g set-titles-string '#T'",
+		},
+		Limitations: []string{
+			"Colors change the outer iTerm2 session, not the individual tmux window or pane.",
+			"Colors are not restored automatically when reattaching from another terminal.",
+			"-title sets the tmux pane title (#T) rather than the outer terminal's title.",
+		},
+	},
+	TerminalTypeSSH: {
+		Name:    "SSH",
+		Targets: []ColorTarget{TabColor, ForegroundColor, BackgroundColor},
+		Presets: true,
+		Badge:   true,
+		Title:   true,
+		Setup: []string{
+			"Install set-tab-color on the remote host; escape sequences travel back over the connection.",
+		},
+		Limitations: []string{
+			"The local terminal must be iTerm2 for colors to take effect.",
+		},
+	},
+	TerminalTypeETTerminal: {
+		Name:    "Eternal Terminal",
+		Targets: []ColorTarget{TabColor, ForegroundColor, BackgroundColor},
+		Presets: true,
+		Badge:   true,
+		Title:   true,
+		Setup: []string{
+			"Install set-tab-color on the remote host; escape sequences travel back over the connection.",
+		},
+		Limitations: []string{
+			"The local terminal must be iTerm2 for colors to take effect.",
+			"Colors are not reapplied automatically after a reconnect.",
+		},
+	},
+	TerminalTypeMosh: {
+		Name:  "Mosh",
+		Title: true,
+		Setup: []string{
+			"Install set-tab-color on the remote host and add a mosh sub-profile for mosh sessions.",
+		},
+		Limitations: []string{
+			"mosh-server redraws the screen with its own terminal emulator, which drops iTerm2 color, badge, and preset escape sequences.",
+			"Only the window title reaches the local terminal.",
+		},
+	},
+	TerminalTypeGhostty:         xtermTerminalCapabilities("Ghostty"),
+	TerminalTypeWarp:            xtermTerminalCapabilities("Warp"),
+	TerminalTypeHyper:           xtermTerminalCapabilities("Hyper"),
+	TerminalTypeRio:             xtermTerminalCapabilities("Rio"),
+	TerminalTypeTabby:           xtermTerminalCapabilities("Tabby"),
+	TerminalTypeKitty:           xtermTerminalCapabilities("kitty"),
+	TerminalTypeWezTerm:         xtermTerminalCapabilities("WezTerm"),
+	TerminalTypeGnome:           xtermTerminalCapabilities("GNOME Terminal"),
+	TerminalTypeKonsole:         xtermTerminalCapabilities("Konsole"),
+	TerminalTypeXterm:           xtermTerminalCapabilities("xterm"),
+	TerminalTypeWindowsTerminal: xtermTerminalCapabilities("Windows Terminal"),
+	TerminalTypeConEmu:          xtermTerminalCapabilities("ConEmu"),
+	TerminalTypeContainer: {
+		Name:    "Container",
+		Targets: []ColorTarget{TabColor, ForegroundColor, BackgroundColor},
+		Presets: true,
+		Badge:   true,
+		Title:   true,
+		Setup: []string{
+			"No setup needed: escape sequences pass through docker exec, kubectl exec, and devcontainer terminals to the outer terminal.",
+			"Add a container sub-profile for shells inside containers.",
+		},
+		Limitations: []string{
+			"The process tree ends at the container boundary, so the outer terminal is only found through variables passed in, e.g. docker exec -e TERM_PROGRAM.",
+			"Supported colors depend on the outer terminal.",
+		},
+	},
+	TerminalTypeWSL: {
+		Name:  "Windows Subsystem for Linux",
+		Title: true,
+		Setup: []string{
+			"Add a wsl sub-profile for WSL sessions; colors are shown by the Windows terminal hosting WSL.",
+			"Windows Terminal is detected from WT_SESSION. For ConEmu, share its variables with WSL: WSLENV=ConEmuPID",
+		},
+		Limitations: []string{
+			"The process tree ends at the WSL boundary, so Windows terminals are only found through their variables.",
+			"Supported colors depend on the hosting terminal; see help windows-terminal.",
+		},
+	},
+	TerminalTypeJetBrains: {
+		Name:  "JetBrains IDE terminal",
+		Title: true,
+		Setup: []string{
+			"Add a jetbrains sub-profile to adjust or reset colors when running inside IntelliJ IDEA, GoLand, PyCharm, and other JetBrains IDEs.",
+		},
+		Limitations: []string{
+			"The IDE's terminal ignores iTerm2 color, badge, and preset escape sequences.",
+			"Android Studio is detected by its \"studio\" launcher.",
+		},
+	},
+	TerminalTypeVSCode: {
+		Name:    "VS Code integrated terminal",
+		Targets: []ColorTarget{ForegroundColor, BackgroundColor},
+		Title:   true,
+		Setup: []string{
+			"No setup needed: fg, bg, and palette colors are sent as standard xterm sequences (OSC 10/11/4).",
+			"Add a vscode sub-profile to pick colors that suit VS Code's theme.",
+			"To show titles set with -title, set terminal.integrated.tabs.title to ${sequence}.",
+		},
+		Limitations: []string{
+			"There is no escape sequence for the tab color; tab colors, presets, badges, and attention cues are skipped.",
+			"Palette keys without a standard sequence (bold, link, underline) are skipped.",
+		},
+	},
+}
+
+// xtermTerminalCapabilities describes a terminal reached through the xterm backend
+func xtermTerminalCapabilities(name string) TerminalCapabilities {
+	return TerminalCapabilities{
+		Name:    name,
+		Targets: []ColorTarget{ForegroundColor, BackgroundColor},
+		Title:   true,
+		Setup: []string{
+			"No setup needed: fg, bg, and palette colors are sent as standard xterm sequences (OSC 10/11/4).",
+		},
+		Limitations: []string{
+			"There is no escape sequence for the tab color; tab colors, presets, badges, and attention cues are skipped.",
+		},
+	}
+}
+
+// getTerminalCapabilities looks up the capabilities of a terminal by its sub-profile key
+func getTerminalCapabilities(name string) (TerminalCapabilities, bool) {
+	caps, ok := terminalCapabilities[TerminalType(strings.ToLower(name))]
+	return caps, ok
+}
+
+// printTerminalHelp writes setup steps and limitations for a terminal
+func printTerminalHelp(w io.Writer, terminal TerminalType, caps TerminalCapabilities) {
+	fmt.Fprintf(w, "%s (%s)\n", caps.Name, terminal)
+
+	supported := "none"
+	if len(caps.Targets) > 0 {
+		targets := make([]string, len(caps.Targets))
+		for i, target := range caps.Targets {
+			targets[i] = string(target)
+		}
+		supported = strings.Join(targets, ", ")
+	}
+	fmt.Fprintf(w, "\nSupported colors: %s\n", supported)
+	for _, feature := range []struct {
+		name      string
+		supported bool
+	}{
+		{"Presets", caps.Presets},
+		{"Badge", caps.Badge},
+		{"Title", caps.Title},
+	} {
+		if feature.supported {
+			fmt.Fprintf(w, "%s: supported\n", feature.name)
+		} else {
+			fmt.Fprintf(w, "%s: not supported\n", feature.name)
+		}
+	}
+
+	if len(caps.Setup) > 0 {
+		fmt.Fprintf(w, "\nSetup:\n")
+		for _, step := range caps.Setup {
+			fmt.Fprintf(w, "  - %s\n", step)
+		}
+	}
+
+	if len(caps.Limitations) > 0 {
+		fmt.Fprintf(w, "\nKnown limitations:\n")
+		for _, limitation := range caps.Limitations {
+			fmt.Fprintf(w, "  - %s\n", limitation)
+		}
+	}
+}
+
+// knownTerminalNames returns the sub-profile keys of all known terminals
+func knownTerminalNames() []string {
+	terminals := allTerminalTypes()
+	names := make([]string, len(terminals))
+	for i, terminal := range terminals {
+		names[i] = string(terminal)
+	}
+	return names
+}
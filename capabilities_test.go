@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestTerminalCapabilitiesComplete tests that every known terminal has capability metadata
+func TestTerminalCapabilitiesComplete(t *testing.T) {
+	for _, terminal := range knownTerminalTypes {
+		caps, ok := terminalCapabilities[terminal]
+		if !ok {
+			t.Errorf("Missing capabilities for terminal %q", terminal)
+			continue
+		}
+		if caps.Name == "" {
+			t.Errorf("Capabilities for terminal %q have no name", terminal)
+		}
+		if len(caps.Setup) == 0 {
+			t.Errorf("Capabilities for terminal %q have no setup steps", terminal)
+		}
+	}
+}
+
+// TestPrintTerminalHelp tests the generated per-terminal help text
+func TestPrintTerminalHelp(t *testing.T) {
+	caps, ok := getTerminalCapabilities("TMUX")
+	if !ok {
+		t.Fatal("Expected tmux capabilities to be found case-insensitively")
+	}
+
+	var buf bytes.Buffer
+	printTerminalHelp(&buf, TerminalTypeTmux, caps)
+	output := buf.String()
+
+	for _, expected := range []string{"tmux (tmux)", "Supported colors: tab, fg, bg", "Title: supported", "allow-passthrough", "pane title", "Known limitations:"} {
+		if !contains(output, expected) {
+			t.Errorf("Expected help output to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	// Terminals without tab color support say so
+	buf.Reset()
+	printTerminalHelp(&buf, TerminalTypeVSCode, terminalCapabilities[TerminalTypeVSCode])
+	if !contains(buf.String(), "Supported colors: fg, bg") || !contains(buf.String(), "Badge: not supported") {
+		t.Errorf("Expected VS Code help to report fg/bg but no badge, got:\n%s", buf.String())
+	}
+	if !contains(buf.String(), "Title: supported") {
+		t.Errorf("Expected VS Code help to report title support, got:\n%s", buf.String())
+	}
+
+	if _, ok := getTerminalCapabilities("alacritty"); ok {
+		t.Error("Expected unknown terminal lookup to fail")
+	}
+}
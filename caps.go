@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Feature is a kind of setting an escape backend may be able to send
+type Feature string
+
+const (
+	FeatureTab          Feature = "tab"
+	FeatureColors       Feature = "fg/bg"
+	FeaturePalette      Feature = "palette"
+	FeaturePreset       Feature = "preset"
+	FeatureBadge        Feature = "badge"
+	FeatureAttention    Feature = "attention"
+	FeatureTitle        Feature = "title"
+	FeatureITermProfile Feature = "iterm-profile"
+	// Transparency and blur have no escape sequence; they are set through the Python API
+	FeatureTransparency Feature = "transparency"
+)
+
+// allFeatures lists the features in display order
+var allFeatures = []Feature{
+	FeatureTab, FeatureColors, FeaturePalette, FeaturePreset, FeatureBadge, FeatureAttention, FeatureTitle,
+	FeatureITermProfile, FeatureTransparency,
+}
+
+// backendFeatures maps each escape backend to the sequences it sends for the
+// features it supports. Settings of any other feature are skipped.
+var backendFeatures = map[EscapeBackend]map[Feature]string{
+	BackendITerm2: {
+		FeatureTab:          "OSC 6",
+		FeatureColors:       "OSC 1337 SetColors",
+		FeaturePalette:      "OSC 1337 SetColors",
+		FeaturePreset:       "OSC 1337 SetColors=preset",
+		FeatureBadge:        "OSC 1337 SetBadgeFormat",
+		FeatureAttention:    "OSC 1337 RequestAttention",
+		FeatureTitle:        "OSC 0",
+		FeatureITermProfile: "OSC 1337 SetProfile",
+		FeatureTransparency: "Python API",
+	},
+	BackendXterm: {
+		FeatureColors:  "OSC 10/11",
+		FeaturePalette: "OSC 4, 12, 17, 19",
+		FeatureTitle:   "OSC 0",
+	},
+}
+
+// backendSupports reports whether the selected escape backend can send a feature
+func backendSupports(feature Feature) bool {
+	_, ok := backendFeatures[escapeBackend][feature]
+	return ok
+}
+
+// FeatureSupport is whether the current session can show one feature
+type FeatureSupport struct {
+	Feature   Feature `json:"feature"`
+	Supported bool    `json:"supported"`
+	Sequence  string  `json:"sequence,omitempty"` // Escape sequence used when supported
+	Fallback  string  `json:"fallback,omitempty"` // Target used instead, from the [unsupported] table
+}
+
+// SessionCapabilities is what the current session can show, derived from the
+// detected terminals and the environment
+type SessionCapabilities struct {
+	Terminals   []TerminalType   `json:"terminals"`
+	Backend     EscapeBackend    `json:"backend"`
+	Passthrough bool             `json:"passthrough"` // Sequences are wrapped for tmux or screen
+	TrueColor   bool             `json:"truecolor"`   // $COLORTERM announces 24-bit color
+	Features    []FeatureSupport `json:"features"`
+}
+
+// deriveCapabilities works out what a session with the given terminals can show
+func deriveCapabilities(info TerminalShellInfo) SessionCapabilities {
+	caps := SessionCapabilities{
+		Terminals:   info.Terminals,
+		Backend:     selectEscapeBackend(info.Terminals),
+		Passthrough: isTmuxTerm(),
+	}
+	if caps.Terminals == nil {
+		caps.Terminals = []TerminalType{}
+	}
+	switch strings.ToLower(getDetectionEnv("COLORTERM")) {
+	case "truecolor", "24bit":
+		caps.TrueColor = true
+	}
+
+	for _, feature := range allFeatures {
+		sequence, ok := backendFeatures[caps.Backend][feature]
+		support := FeatureSupport{Feature: feature, Supported: ok, Sequence: sequence}
+		if !ok && feature == FeatureTab {
+			support.Fallback = unsupportedPolicy.Tab
+		}
+		caps.Features = append(caps.Features, support)
+	}
+	return caps
+}
+
+// printCapabilities writes a human-readable capability report
+func printCapabilities(w io.Writer, caps SessionCapabilities) {
+	terminals := make([]string, len(caps.Terminals))
+	for i, terminal := range caps.Terminals {
+		terminals[i] = string(terminal)
+	}
+	if len(terminals) == 0 {
+		terminals = []string{"none"}
+	}
+	yesNo := map[bool]string{true: "yes", false: "no"}
+
+	fmt.Fprintf(w, "Terminals: %s\n", strings.Join(terminals, ", "))
+	fmt.Fprintf(w, "Escape sequences: %s\n", caps.Backend)
+	fmt.Fprintf(w, "Multiplexer passthrough: %s\n", yesNo[caps.Passthrough])
+	fmt.Fprintf(w, "Truecolor: %s\n", yesNo[caps.TrueColor])
+
+	fmt.Fprintf(w, "\nFeatures:\n")
+	for _, support := range caps.Features {
+		switch {
+		case support.Supported:
+			fmt.Fprintf(w, "  %-10s yes  %s\n", support.Feature, support.Sequence)
+		case support.Fallback != "":
+			fmt.Fprintf(w, "  %-10s no   sent as %s ([unsupported] tab)\n", support.Feature, support.Fallback)
+		default:
+			fmt.Fprintf(w, "  %-10s no   skipped\n", support.Feature)
+		}
+	}
+}
+
+// runCaps prints what the current (or overridden) terminal can show
+func runCaps(terminalOverride string) error {
+	// Config provides detection rules and the [unsupported] tab target
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+
+	caps := deriveCapabilities(detectTerminalAndShell(terminalOverride))
+	if jsonOutput {
+		return printJSON(caps)
+	}
+	printCapabilities(os.Stdout, caps)
+	return nil
+}
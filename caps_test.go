@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestDeriveCapabilities tests deriving what a session can show from its terminals
+func TestDeriveCapabilities(t *testing.T) {
+	defer setFakeEnvironment(nil)
+	defer setUnsupportedPolicy(UnsupportedConfig{})
+
+	tests := []struct {
+		name            string
+		terminals       []TerminalType
+		env             map[string]string
+		tabTarget       string
+		expectBackend   EscapeBackend
+		expectTrueColor bool
+		expectSupported []Feature
+		expectFallback  string
+	}{
+		{
+			name:            "iterm2",
+			terminals:       []TerminalType{TerminalTypeITerm2},
+			env:             map[string]string{"COLORTERM": "truecolor"},
+			expectBackend:   BackendITerm2,
+			expectTrueColor: true,
+			expectSupported: allFeatures,
+		},
+		{
+			name:            "vscode",
+			terminals:       []TerminalType{TerminalTypeVSCode},
+			env:             map[string]string{"COLORTERM": "24bit"},
+			expectBackend:   BackendXterm,
+			expectTrueColor: true,
+			expectSupported: []Feature{FeatureColors, FeaturePalette, FeatureTitle},
+		},
+		{
+			name:            "vscode with tab mapped",
+			terminals:       []TerminalType{TerminalTypeVSCode},
+			env:             map[string]string{},
+			tabTarget:       "bg",
+			expectBackend:   BackendXterm,
+			expectSupported: []Feature{FeatureColors, FeaturePalette, FeatureTitle},
+			expectFallback:  "bg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFakeEnvironment(&FakeEnvironment{Env: tt.env})
+			if err := setUnsupportedPolicy(UnsupportedConfig{Tab: tt.tabTarget}); err != nil {
+				t.Fatalf("setUnsupportedPolicy() failed: %v", err)
+			}
+
+			caps := deriveCapabilities(TerminalShellInfo{Terminals: tt.terminals})
+			if caps.Backend != tt.expectBackend {
+				t.Errorf("Backend = %q, expected %q", caps.Backend, tt.expectBackend)
+			}
+			if caps.TrueColor != tt.expectTrueColor {
+				t.Errorf("TrueColor = %v, expected %v", caps.TrueColor, tt.expectTrueColor)
+			}
+			if len(caps.Features) != len(allFeatures) {
+				t.Fatalf("Expected %d features, got %d", len(allFeatures), len(caps.Features))
+			}
+			for _, support := range caps.Features {
+				expected := false
+				for _, f := range tt.expectSupported {
+					if f == support.Feature {
+						expected = true
+					}
+				}
+				if support.Supported != expected {
+					t.Errorf("%s supported = %v, expected %v", support.Feature, support.Supported, expected)
+				}
+				if support.Supported && support.Sequence == "" {
+					t.Errorf("%s is supported but has no sequence", support.Feature)
+				}
+				if support.Feature == FeatureTab && support.Fallback != tt.expectFallback {
+					t.Errorf("tab fallback = %q, expected %q", support.Fallback, tt.expectFallback)
+				}
+			}
+		})
+	}
+}
+
+// TestBackendSupports tests feature lookups for the selected escape backend
+func TestBackendSupports(t *testing.T) {
+	defer func() { escapeBackend = BackendITerm2 }()
+
+	escapeBackend = BackendITerm2
+	if !backendSupports(FeatureTab) || !backendSupports(FeatureBadge) {
+		t.Error("Expected iTerm2 sequences to support tab and badge")
+	}
+	escapeBackend = BackendXterm
+	if backendSupports(FeatureTab) || backendSupports(FeaturePreset) {
+		t.Error("Expected xterm sequences not to support tab or preset")
+	}
+	if !backendSupports(FeatureColors) {
+		t.Error("Expected xterm sequences to support fg/bg")
+	}
+}
+
+// TestPrintCapabilities tests the human-readable capability report
+func TestPrintCapabilities(t *testing.T) {
+	defer setFakeEnvironment(nil)
+	defer setUnsupportedPolicy(UnsupportedConfig{})
+	setFakeEnvironment(&FakeEnvironment{Env: map[string]string{}})
+	if err := setUnsupportedPolicy(UnsupportedConfig{Tab: "fg"}); err != nil {
+		t.Fatalf("setUnsupportedPolicy() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	printCapabilities(&buf, deriveCapabilities(TerminalShellInfo{Terminals: []TerminalType{TerminalTypeVSCode}}))
+	output := buf.String()
+
+	for _, expected := range []string{
+		"Terminals: vscode",
+		"Escape sequences: xterm",
+		"Truecolor: no",
+		"sent as fg ([unsupported] tab)",
+		"OSC 10/11",
+		"skipped",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Requests to a watcher are one JSON array of strings per line, e.g. ["apply","prod"];
+// the watcher answers each with "ok" or "error: <message>".
+
+// watchRequest is a request line waiting for the watcher's main loop
+type watchRequest struct {
+	args   []string
+	reply  chan error
+	events *eventSubscriber // Set for an events request, whose connection then streams events
+}
+
+// clientCommands lists the requests a watcher accepts, with their usage
+var clientCommands = map[string]string{
+	"apply":  "apply <profile>",
+	"set":    "set key=value...",
+	"reset":  "reset",
+	"cwd":    "cwd <dir>",
+	"ping":   "ping",
+	"events": "events [follow]",
+}
+
+// checkClientRequest reports a request with an unknown command or the wrong arguments
+func checkClientRequest(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("empty request")
+	}
+	usage, ok := clientCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown request %q", args[0])
+	}
+
+	switch args[0] {
+	case "apply", "cwd":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s", usage)
+		}
+	case "set":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: %s", usage)
+		}
+		if _, err := parseProfileAssignments(args[1:]); err != nil {
+			return err
+		}
+	case "reset", "ping":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: %s", usage)
+		}
+	case "events":
+		if len(args) > 2 || (len(args) == 2 && args[1] != "follow") {
+			return fmt.Errorf("usage: %s", usage)
+		}
+	}
+	return nil
+}
+
+// serve carries out one request in the watcher's main loop, telling subscribers
+// when it fails
+func (w *watcher) serve(req watchRequest) error {
+	var err error
+	if req.events != nil {
+		err = w.subscribe(req.args, req.events)
+	} else {
+		err = w.handle(req.args)
+	}
+	w.emitError(err)
+	return err
+}
+
+// handle carries out one request in the watcher's main loop
+func (w *watcher) handle(args []string) error {
+	if err := checkClientRequest(args); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "cwd":
+		if !filepath.IsAbs(args[1]) {
+			return fmt.Errorf("cwd must be an absolute path, got %q", args[1])
+		}
+		if args[1] == w.dir {
+			return nil
+		}
+		w.dir = args[1]
+		return w.reapply(false)
+	case "apply":
+		// An explicit profile stays until the [watch] rules select a different one
+		return w.applyNamed(args[1])
+	case "set":
+		assignments, _ := parseProfileAssignments(args[1:])
+		profile := &Profile{}
+		for _, kv := range assignments {
+			switch kv[0] {
+			case "tab":
+				profile.Tab = kv[1]
+			case "fg":
+				profile.Foreground = kv[1]
+			case "bg":
+				profile.Background = kv[1]
+			case "preset":
+				profile.Preset = kv[1]
+			}
+		}
+		if err := resolveDistinctTab(profile); err != nil {
+			return err
+		}
+		if err := resolveAutoForeground(profile); err != nil {
+			return err
+		}
+		if err := checkProfileLock(""); err != nil {
+			return err
+		}
+		useEscapeBackendFor(detectTerminals(w.terminalType))
+		if err := applyProfile(profile); err != nil {
+			return err
+		}
+		recordAppliedState("", profile)
+		w.emit(appliedEvent("", profile))
+		return nil
+	case "reset":
+		useEscapeBackendFor(detectTerminals(w.terminalType))
+		if err := resetTerminal(); err != nil {
+			return err
+		}
+		w.emit(appliedEvent("", &resetProfile))
+		return nil
+	}
+	return nil
+}
+
+// acceptWatchRequests reads request lines from each connection and passes them to
+// the watcher's main loop, writing back its reply
+func acceptWatchRequests(listener net.Listener, requests chan<- watchRequest) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go serveWatchConn(conn, requests)
+	}
+}
+
+// serveWatchConn answers the requests on one connection. After an events request
+// the connection carries only the event stream.
+func serveWatchConn(conn net.Conn, requests chan<- watchRequest) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var args []string
+		var events *eventSubscriber
+		err := json.Unmarshal(scanner.Bytes(), &args)
+		if err == nil {
+			if len(args) > 0 && args[0] == "events" {
+				events = newEventSubscriber()
+			}
+			req := watchRequest{args: args, reply: make(chan error, 1), events: events}
+			requests <- req
+			err = <-req.reply
+		}
+
+		reply := "ok"
+		if err != nil {
+			reply = "error: " + strings.ReplaceAll(err.Error(), "\n", " ")
+		}
+		if _, err := fmt.Fprintln(conn, reply); err != nil {
+			return
+		}
+		if events != nil && err == nil {
+			writeWatchEvents(conn, events)
+			return
+		}
+	}
+}
+
+// sendWatchRequest sends one request to a watcher and returns its error reply, if any
+func sendWatchRequest(socketPath string, args ...string) error {
+	conn, _, err := dialWatcher(socketPath, args)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// dialWatcher sends a request to a watcher and reads its reply. On success the
+// connection is returned with a reader for anything the watcher writes after the reply.
+func dialWatcher(socketPath string, args []string) (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return nil, nil, fmt.Errorf("no watcher is listening on %s", socketPath)
+	}
+
+	data, err := json.Marshal(args)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if _, err := fmt.Fprintf(conn, "%s\n", data); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	reply, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("no reply from watcher: %v", err)
+	}
+	reply = strings.TrimSpace(reply)
+	if message, ok := strings.CutPrefix(reply, "error: "); ok {
+		conn.Close()
+		return nil, nil, fmt.Errorf("%s", message)
+	}
+	return conn, reader, nil
+}
+
+// ttyName returns the terminal device escape sequences are written to: the -tty
+// path, or else stdout, or "" if stdout isn't a terminal
+func ttyName() string {
+	if ttyOutputPath != "" {
+		return ttyOutputPath
+	}
+	return stdoutTTYName()
+}
+
+// stdoutTTYName looks up the terminal on stdout once; several steps of a run ask
+var stdoutTTYName = sync.OnceValue(func() string {
+	return terminalName(int(os.Stdout.Fd()))
+})
+
+// getTTYSocketPath returns the link through which clients reach the watcher of a tty.
+// Both "/dev/ttys003" and "ttys003" are accepted.
+func getTTYSocketPath(tty string) (string, error) {
+	sessionsDir, err := getSessionsDir()
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimPrefix(tty, "/dev/")
+	return filepath.Join(sessionsDir, "tty-"+sanitizeFileName(name)+".sock"), nil
+}
+
+// linkTTYSocket makes the session's socket reachable by its tty name
+func linkTTYSocket(tty, socketPath string) error {
+	link, err := getTTYSocketPath(tty)
+	if err != nil {
+		return err
+	}
+	os.Remove(link)
+	return os.Symlink(socketPath, link)
+}
+
+// removeTTYSocket removes the tty link unless another watcher has taken it over
+func removeTTYSocket(tty, socketPath string) {
+	link, err := getTTYSocketPath(tty)
+	if err != nil {
+		return
+	}
+	if target, err := os.Readlink(link); err == nil && target == socketPath {
+		os.Remove(link)
+	}
+}
+
+// runClient sends a request to the watcher of the current session, or of the
+// terminal given with --tty, so colors can change without starting a new process
+// in that terminal
+func runClient(args []string) error {
+	usage := fmt.Errorf("usage: client [--tty TTY] apply <profile> | set key=value... | reset | ping | events [follow]")
+
+	tty := ""
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch {
+		case args[0] == "--tty" || args[0] == "-tty":
+			if len(args) < 2 {
+				return usage
+			}
+			tty = args[1]
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--tty="):
+			tty = strings.TrimPrefix(args[0], "--tty=")
+			args = args[1:]
+		default:
+			return fmt.Errorf("unexpected argument %q", args[0])
+		}
+	}
+	if len(args) == 0 {
+		return usage
+	}
+	if err := checkClientRequest(args); err != nil {
+		return err
+	}
+
+	var socketPath string
+	var err error
+	if tty != "" {
+		socketPath, err = getTTYSocketPath(tty)
+	} else {
+		socketPath, err = getWatchSocketPath()
+	}
+	if err != nil {
+		return err
+	}
+
+	if dryRunMode {
+		data, _ := json.Marshal(args)
+		_, err := fmt.Fprintf(escapeWriter, "[dry-run] send to %s: %s\n", socketPath, data)
+		return err
+	}
+	if args[0] == "events" {
+		return streamWatchEvents(socketPath, args, os.Stdout)
+	}
+	return sendWatchRequest(socketPath, args...)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheckClientRequest tests validating requests before they are sent
+func TestCheckClientRequest(t *testing.T) {
+	tests := []struct {
+		args        []string
+		expectError string
+	}{
+		{[]string{"apply", "prod"}, ""},
+		{[]string{"set", "tab=red", "fg=auto"}, ""},
+		{[]string{"reset"}, ""},
+		{[]string{"ping"}, ""},
+		{[]string{}, "empty request"},
+		{[]string{"frobnicate"}, `unknown request "frobnicate"`},
+		{[]string{"apply"}, "usage: apply <profile>"},
+		{[]string{"set"}, "usage: set key=value..."},
+		{[]string{"set", "tab=notacolor"}, `unknown color "notacolor" for tab`},
+		{[]string{"reset", "now"}, "usage: reset"},
+	}
+
+	for _, test := range tests {
+		err := checkClientRequest(test.args)
+		switch {
+		case test.expectError == "" && err != nil:
+			t.Errorf("checkClientRequest(%q) failed: %v", test.args, err)
+		case test.expectError != "" && (err == nil || !strings.Contains(err.Error(), test.expectError)):
+			t.Errorf("checkClientRequest(%q) = %v, expected error containing %q", test.args, err, test.expectError)
+		}
+	}
+}
+
+// TestClientRoundTrip tests sending requests to a watcher through a tty link
+func TestClientRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", tempDir)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+		os.Setenv("XDG_STATE_HOME", originalState)
+	}()
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+
+	if err := os.WriteFile(configFile, []byte("[profiles.prod]\ntab = \"red\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	socketPath, err := getWatchSocketPath()
+	if err != nil {
+		t.Fatalf("getWatchSocketPath() failed: %v", err)
+	}
+	listener, err := listenWatchSocket(socketPath)
+	if err != nil {
+		t.Fatalf("listenWatchSocket() failed: %v", err)
+	}
+	defer listener.Close()
+	if err := linkTTYSocket("/dev/pts/7", socketPath); err != nil {
+		t.Fatalf("linkTTYSocket() failed: %v", err)
+	}
+
+	// Serve requests like the watcher's main loop, writing escapes to buf in dry-run mode
+	requests := make(chan watchRequest)
+	go acceptWatchRequests(listener, requests)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		w := &watcher{terminalType: "iterm2", dir: "/"}
+		for {
+			select {
+			case req := <-requests:
+				dryRunMode = true
+				err := w.handle(req.args)
+				dryRunMode = false
+				req.reply <- err
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if err := runClient([]string{"--tty", "pts/7", "apply", "prod"}); err != nil {
+		t.Fatalf("client apply failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "6;1;bg;red;brightness;255") {
+		t.Errorf("Expected the prod tab color, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := runClient([]string{"--tty=/dev/pts/7", "set", "bg=black"}); err != nil {
+		t.Fatalf("client set failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "SetColors=bg=000000") {
+		t.Errorf("Expected the background color, got %q", buf.String())
+	}
+
+	err = runClient([]string{"--tty", "pts/7", "apply", "missing"})
+	if err == nil || !strings.Contains(err.Error(), `profile "missing" not found`) {
+		t.Errorf("Expected the watcher's error, got %v", err)
+	}
+
+	if err := runClient([]string{"--tty", "pts/8", "ping"}); err == nil {
+		t.Error("Expected error for a tty without a watcher")
+	}
+
+	removeTTYSocket("/dev/pts/7", socketPath)
+	if link, _ := getTTYSocketPath("pts/7"); fileExists(link) {
+		t.Error("Expected the tty link to be removed")
+	}
+}
+
+// fileExists reports whether a path exists, without following symlinks
+func fileExists(p string) bool {
+	_, err := os.Lstat(p)
+	return err == nil
+}
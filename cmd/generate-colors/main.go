@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,6 +14,13 @@ import (
 )
 
 func main() {
+	x11Path := flag.String("x11", "", "Generate the X11 color names from this rgb.txt instead of the CSS names")
+	flag.Parse()
+	if *x11Path != "" {
+		generateX11(*x11Path)
+		return
+	}
+
 	// Read the JSON file from the submodule
 	jsonPath := filepath.Join("css-color-names", "css-color-names.json")
 	data, err := os.ReadFile(jsonPath)
@@ -105,3 +114,76 @@ var CSSColors = map[string]string{
 	b.WriteString("}\n")
 	return b.String()
 }
+
+// generateX11 writes generated/x11_colors.go from an X11 rgb.txt. Names are folded to
+// lowercase without spaces, so "dodger blue" and "DodgerBlue" become one entry.
+func generateX11(rgbPath string) {
+	f, err := os.Open(rgbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", rgbPath, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	colors := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "!") {
+			continue
+		}
+		var r, g, b int
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		if _, err := fmt.Sscanf(strings.Join(fields[:3], " "), "%d %d %d", &r, &g, &b); err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping malformed line %q\n", line)
+			continue
+		}
+		name := strings.ToLower(strings.Join(fields[3:], ""))
+		hex := fmt.Sprintf("#%02x%02x%02x", r, g, b)
+		if existing, ok := colors[name]; ok && existing != hex {
+			fmt.Fprintf(os.Stderr, "Error: %s is both %s and %s\n", name, existing, hex)
+			os.Exit(1)
+		}
+		colors[name] = hex
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", rgbPath, err)
+		os.Exit(1)
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`// Code generated by go run cmd/generate-colors/main.go -x11 %s. DO NOT EDIT.
+// Generated on %s
+
+/*
+This file contains the X11 color names from rgb.txt, distributed with X.Org
+under the MIT/X11 license.
+*/
+
+package generated
+
+// X11Colors contains the X11 color names, lowercase without spaces, mapped to
+// their hex values
+var X11Colors = map[string]string{
+`, rgbPath, time.Now().Format(time.RFC3339)))
+
+	keys := make([]string, 0, len(colors))
+	for k := range colors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		b.WriteString(fmt.Sprintf("\t%q: %q,\n", name, colors[name]))
+	}
+	b.WriteString("}\n")
+
+	outputPath := filepath.Join("generated", "x11_colors.go")
+	if err := os.WriteFile(outputPath, []byte(b.String()), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", outputPath, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Generated %s with %d X11 colors\n", outputPath, len(colors))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ColorblindConfig is the [colorblind] table of the config file: the color vision
+// deficiency that profile colors must stay distinguishable for
+type ColorblindConfig struct {
+	Type  string `toml:"type"`  // "deuteranopia" or "protanopia"
+	Remap bool   `toml:"remap"` // Replace tab colors with the nearest colorblind-safe color
+}
+
+// cvdMatrices simulate full dichromacy in linear RGB (Machado, Oliveira, and
+// Fernandes 2009, severity 1.0)
+var cvdMatrices = map[string][3][3]float64{
+	"protanopia": {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+	"deuteranopia": {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+}
+
+// colorblindTypes lists the valid values of type
+var colorblindTypes = []string{"deuteranopia", "protanopia"}
+
+// colorblindSafePalette is the Okabe-Ito palette, whose colors stay distinct for
+// both protanopia and deuteranopia
+var colorblindSafePalette = []string{
+	"e69f00", // Orange
+	"56b4e9", // Sky blue
+	"009e73", // Bluish green
+	"f0e442", // Yellow
+	"0072b2", // Blue
+	"d55e00", // Vermillion
+	"cc79a7", // Reddish purple
+	"000000", // Black
+}
+
+// colorblindMode holds the [colorblind] table of the loaded config
+var colorblindMode ColorblindConfig
+
+// setColorblindMode replaces the color vision deficiency colors are checked for
+func setColorblindMode(config ColorblindConfig) error {
+	if config.Type != "" && !containsString(colorblindTypes, config.Type) {
+		return fmt.Errorf("invalid type %q (expected one of: %s)", config.Type, strings.Join(colorblindTypes, ", "))
+	}
+	if config.Remap && config.Type == "" {
+		return fmt.Errorf("remap needs a type")
+	}
+	colorblindMode = config
+	return nil
+}
+
+// simulateCVD returns how a hex color looks with a color vision deficiency
+func simulateCVD(hex, cvdType string) (string, error) {
+	matrix, ok := cvdMatrices[cvdType]
+	if !ok {
+		return "", fmt.Errorf("unknown color vision deficiency %q", cvdType)
+	}
+	r, g, b, err := hexToRGB(hex)
+	if err != nil {
+		return "", err
+	}
+
+	toLinear := func(c int) float64 {
+		v := float64(c) / 255
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	toSRGB := func(v float64) int {
+		v = math.Max(0, math.Min(1, v))
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		return int(math.Round(v * 255))
+	}
+
+	in := [3]float64{toLinear(r), toLinear(g), toLinear(b)}
+	var out [3]int
+	for i, row := range matrix {
+		out[i] = toSRGB(row[0]*in[0] + row[1]*in[1] + row[2]*in[2])
+	}
+	return fmt.Sprintf("%02x%02x%02x", out[0], out[1], out[2]), nil
+}
+
+// perceivedLab returns the Lab color of a hex color as seen with the given color
+// vision deficiency, or with typical vision when cvdType is empty
+func perceivedLab(hex, cvdType string) ([3]float64, error) {
+	if cvdType != "" {
+		simulated, err := simulateCVD(hex, cvdType)
+		if err != nil {
+			return [3]float64{}, err
+		}
+		hex = simulated
+	}
+	return hexToLab(hex)
+}
+
+// colorblindSafeTab returns the colorblind-safe color nearest to a tab color when
+// remap is enabled in the [colorblind] table; other values are returned unchanged
+func colorblindSafeTab(tab string) string {
+	if !colorblindMode.Remap || tab == "" || isComputedColor("tab", tab) {
+		return tab
+	}
+	hex := normalizeColor(tab)
+	if hex == "" || hex == "default" {
+		return tab
+	}
+
+	lab, err := hexToLab(hex)
+	if err != nil {
+		return tab
+	}
+	best, bestDistance := tab, math.Inf(1)
+	for _, safe := range colorblindSafePalette {
+		safeLab, _ := hexToLab(safe)
+		if distance := labDistance(lab, safeLab); distance < bestDistance {
+			best, bestDistance = safe, distance
+		}
+	}
+	if best != hex {
+		infof("Remapped tab color %q to colorblind-safe #%s\n", tab, best)
+	}
+	return best
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSetColorblindMode tests checking the [colorblind] table
+func TestSetColorblindMode(t *testing.T) {
+	defer setColorblindMode(ColorblindConfig{})
+
+	tests := []struct {
+		name        string
+		config      ColorblindConfig
+		expectError string
+	}{
+		{"empty", ColorblindConfig{}, ""},
+		{"deuteranopia", ColorblindConfig{Type: "deuteranopia", Remap: true}, ""},
+		{"protanopia", ColorblindConfig{Type: "protanopia"}, ""},
+		{"bad type", ColorblindConfig{Type: "tritanopia"}, `invalid type "tritanopia"`},
+		{"remap without type", ColorblindConfig{Remap: true}, "remap needs a type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setColorblindMode(tt.config)
+			switch {
+			case tt.expectError == "" && err != nil:
+				t.Errorf("setColorblindMode() failed: %v", err)
+			case tt.expectError != "" && (err == nil || !strings.Contains(err.Error(), tt.expectError)):
+				t.Errorf("setColorblindMode() = %v, expected error containing %q", err, tt.expectError)
+			}
+		})
+	}
+}
+
+// TestSimulateCVD tests that red and green become hard to tell apart
+func TestSimulateCVD(t *testing.T) {
+	// Grays are unaffected
+	if got, err := simulateCVD("808080", "deuteranopia"); err != nil || got != "808080" {
+		t.Errorf("simulateCVD(gray) = %q, %v; expected 808080", got, err)
+	}
+	if _, err := simulateCVD("ff0000", "tritanopia"); err == nil {
+		t.Error("Expected error for unknown deficiency")
+	}
+
+	distance := func(a, b, cvdType string) float64 {
+		labA, _ := perceivedLab(a, cvdType)
+		labB, _ := perceivedLab(b, cvdType)
+		return labDistance(labA, labB)
+	}
+	red, green := "d62728", "2ca02c"
+	if d := distance(red, green, ""); d < 100 {
+		t.Errorf("Expected red and green to be far apart with typical vision, got %.1f", d)
+	}
+	if d := distance(red, green, "deuteranopia"); d > 10 {
+		t.Errorf("Expected red and green to look alike with deuteranopia, got %.1f", d)
+	}
+}
+
+// TestColorblindSafeTab tests remapping tab colors to the safe palette
+func TestColorblindSafeTab(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+	defer setColorblindMode(ColorblindConfig{})
+
+	if got := colorblindSafeTab("red"); got != "red" {
+		t.Errorf("Expected no remapping without remap, got %q", got)
+	}
+
+	setColorblindMode(ColorblindConfig{Type: "deuteranopia", Remap: true})
+	tests := []struct {
+		tab      string
+		expected string
+	}{
+		{"red", "d55e00"},
+		{"navy", "0072b2"},
+		{"yellow", "f0e442"},
+		{"default", "default"},
+		{"distinct", "distinct"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := colorblindSafeTab(tt.tab); got != tt.expected {
+			t.Errorf("colorblindSafeTab(%q) = %q, expected %q", tt.tab, got, tt.expected)
+		}
+	}
+
+	// Distinct colors come from the safe palette too
+	if got := pickDistinctColor([]string{"d55e00"}); !containsString(colorblindSafePalette, got) {
+		t.Errorf("Expected a colorblind-safe distinct color, got %q", got)
+	}
+}
+
+// TestAuditProfilesColorblind tests reporting profiles that only look alike with a deficiency
+func TestAuditProfilesColorblind(t *testing.T) {
+	defer setColorblindMode(ColorblindConfig{})
+
+	config := decodeTestConfig(t, `
+[profiles.prod]
+tab = "#d62728"
+
+[profiles.dev]
+tab = "#2ca02c"
+`)
+
+	if findings := auditProfiles(config.Profiles, defaultAuditThreshold); len(findings) != 0 {
+		t.Errorf("Expected no findings with typical vision, got %+v", findings)
+	}
+
+	setColorblindMode(ColorblindConfig{Type: "deuteranopia"})
+	findings := auditProfiles(config.Profiles, defaultAuditThreshold)
+	if len(findings) != 1 || findings[0].Vision != "deuteranopia" || findings[0].Profiles != [2]string{"dev", "prod"} {
+		t.Errorf("Expected dev and prod to look alike with deuteranopia, got %+v", findings)
+	}
+
+	setColorblindMode(ColorblindConfig{Type: "protanopia"})
+	if findings := auditProfiles(config.Profiles, defaultAuditThreshold); len(findings) != 0 {
+		t.Errorf("Expected no findings with protanopia, got %+v", findings)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hueFamily is a named range of hues that -list-colors can filter by
+type hueFamily struct {
+	name string
+	max  float64 // Upper bound of the hue range in degrees; ranges start at the previous bound
+}
+
+// hueFamilies covers the color wheel in order; reds wrap around 360°
+var hueFamilies = []hueFamily{
+	{"red", 15},
+	{"orange", 45},
+	{"yellow", 70},
+	{"green", 165},
+	{"cyan", 195},
+	{"blue", 255},
+	{"purple", 290},
+	{"pink", 345},
+	{"red", 360},
+}
+
+// grayFamily holds colors with too little saturation to have a meaningful hue
+const grayFamily = "gray"
+
+// graySaturation is the saturation below which a color counts as gray
+const graySaturation = 0.1
+
+// colorHSL returns the hue, saturation, and lightness of a "#rrggbb" color
+func colorHSL(hex string) (h, s, l float64, err error) {
+	r, g, b, err := hexToRGB(hex)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	h, s, l = rgbToHSL(r, g, b)
+	return h, s, l, nil
+}
+
+// colorFamily returns the hue family of a color, or "gray" for unsaturated colors
+func colorFamily(hex string) string {
+	h, s, _, err := colorHSL(hex)
+	if err != nil || s < graySaturation {
+		return grayFamily
+	}
+	for _, family := range hueFamilies {
+		if h < family.max {
+			return family.name
+		}
+	}
+	return "red"
+}
+
+// isColorFamily reports whether term names a hue family
+func isColorFamily(term string) bool {
+	if term == grayFamily {
+		return true
+	}
+	for _, family := range hueFamilies {
+		if family.name == term {
+			return true
+		}
+	}
+	return false
+}
+
+// filterColorEntries keeps the colors matching any term: by name substring, or by
+// hue when the term names a hue family ("blue" also matches navy and steelblue)
+func filterColorEntries(entries []ColorEntry, terms []string) []ColorEntry {
+	if len(terms) == 0 {
+		return entries
+	}
+	var matched []ColorEntry
+	for _, entry := range entries {
+		for _, term := range terms {
+			term = strings.ToLower(term)
+			if term == "grey" {
+				term = grayFamily
+			}
+			if strings.Contains(entry.Name, term) || (isColorFamily(term) && colorFamily(entry.Hex) == term) {
+				matched = append(matched, entry)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// colorSortOrders lists the values -sort accepts
+var colorSortOrders = []string{"name", "hue"}
+
+// sortColorEntries sorts colors by name, or by hue with grays first and then
+// lightness within a hue
+func sortColorEntries(entries []ColorEntry, order string) error {
+	switch order {
+	case "", "name":
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	case "hue":
+		type keyed struct {
+			entry ColorEntry
+			gray  bool
+			h, l  float64
+		}
+		sorted := make([]keyed, len(entries))
+		for i, entry := range entries {
+			h, s, l, _ := colorHSL(entry.Hex)
+			sorted[i] = keyed{entry, s < graySaturation, h, l}
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			switch {
+			case a.gray != b.gray:
+				return a.gray
+			case !a.gray && a.h != b.h:
+				return a.h < b.h
+			case a.l != b.l:
+				return a.l < b.l
+			}
+			return a.entry.Name < b.entry.Name
+		})
+		for i := range sorted {
+			entries[i] = sorted[i].entry
+		}
+	default:
+		return fmt.Errorf("invalid sort order %q (expected one of: %s)", order, strings.Join(colorSortOrders, ", "))
+	}
+	return nil
+}
+
+// defaultTerminalWidth is used when the terminal width can't be determined
+const defaultTerminalWidth = 80
+
+// terminalWidth returns the width of the terminal: $COLUMNS, then stty, then 80
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		defer tty.Close()
+		if size, err := runStty(tty, "size"); err == nil {
+			if fields := strings.Fields(size); len(fields) == 2 {
+				if columns, err := strconv.Atoi(fields[1]); err == nil && columns > 0 {
+					return columns
+				}
+			}
+		}
+	}
+	return defaultTerminalWidth
+}
+
+// formatColorColumns lays out color names in columns filling the width, top to
+// bottom like ls. Each name is drawn in its color, or on it as a swatch.
+func formatColorColumns(entries []ColorEntry, width int, swatch bool) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	cellWidth := 0
+	for _, entry := range entries {
+		if len(entry.Name) > cellWidth {
+			cellWidth = len(entry.Name)
+		}
+	}
+	if swatch {
+		cellWidth += 2 // A space of color on either side of the name
+	}
+	const gap = 2
+	columns := (width + gap) / (cellWidth + gap)
+	if columns < 1 {
+		columns = 1
+	}
+	rows := (len(entries) + columns - 1) / columns
+
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		for col := 0; col < columns; col++ {
+			i := col*rows + row
+			if i >= len(entries) {
+				break
+			}
+			if col > 0 {
+				b.WriteString(strings.Repeat(" ", gap))
+			}
+
+			entry := entries[i]
+			hex := strings.TrimPrefix(entry.Hex, "#")
+			if swatch {
+				b.WriteString(colorSwatch(fmt.Sprintf(" %-*s ", cellWidth-2, entry.Name), hex))
+				continue
+			}
+			// The last cell of a row isn't padded, so lines carry no trailing spaces
+			cell := entry.Name
+			if col < columns-1 && i+rows < len(entries) {
+				cell = fmt.Sprintf("%-*s", cellWidth, entry.Name)
+			}
+			b.WriteString(colorText(cell, hex))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// runListColors prints the CSS, semantic, and custom colors matching the filter
+// terms, in columns or as JSON
+func runListColors(terms []string, order string, swatch bool) error {
+	if err := initColors(); err != nil {
+		return fmt.Errorf("error loading CSS colors: %v", err)
+	}
+	// Custom colors are optional, so a broken config shouldn't hide the CSS list
+	if _, err := loadConfig(); err != nil {
+		warnf("could not load custom colors: %v", err)
+	}
+
+	entries := filterColorEntries(listColorEntries(), terms)
+	if err := sortColorEntries(entries, order); err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON(entries)
+	}
+
+	var css, semantic, custom []ColorEntry
+	for _, entry := range entries {
+		switch {
+		case entry.Custom:
+			custom = append(custom, entry)
+		case entry.Semantic:
+			semantic = append(semantic, entry)
+		default:
+			css = append(css, entry)
+		}
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No colors match %s.\n", strings.Join(terms, ", "))
+		return nil
+	}
+
+	width := terminalWidth()
+	groups := []struct {
+		title   string
+		entries []ColorEntry
+	}{
+		{"Available CSS color names:", css},
+		{"Semantic colors:", semantic},
+		{"Custom colors:", custom},
+	}
+	printed := false
+	for _, group := range groups {
+		if len(group.entries) == 0 {
+			continue
+		}
+		if printed {
+			fmt.Println()
+		}
+		fmt.Println(group.title)
+		fmt.Println(formatColorColumns(group.entries, width, swatch))
+		printed = true
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestColorFamily tests sorting colors into hue families
+func TestColorFamily(t *testing.T) {
+	tests := []struct {
+		hex      string
+		expected string
+	}{
+		{"#ff0000", "red"},
+		{"#dc143c", "red"},
+		{"#ffa500", "orange"},
+		{"#ffff00", "yellow"},
+		{"#008000", "green"},
+		{"#00ffff", "cyan"},
+		{"#000080", "blue"},
+		{"#800080", "pink"},
+		{"#8a2be2", "purple"},
+		{"#808080", "gray"},
+		{"#ffffff", "gray"},
+	}
+
+	for _, tt := range tests {
+		if got := colorFamily(tt.hex); got != tt.expected {
+			t.Errorf("colorFamily(%q) = %q, expected %q", tt.hex, got, tt.expected)
+		}
+	}
+}
+
+// testColorEntries returns a few colors in no particular order
+func testColorEntries() []ColorEntry {
+	return []ColorEntry{
+		{Name: "white", Hex: "#ffffff"},
+		{Name: "navy", Hex: "#000080"},
+		{Name: "red", Hex: "#ff0000"},
+		{Name: "steelblue", Hex: "#4682b4"},
+		{Name: "seagreen", Hex: "#2e8b57"},
+		{Name: "black", Hex: "#000000"},
+	}
+}
+
+// entryNames returns the names of color entries, in order
+func entryNames(entries []ColorEntry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name
+	}
+	return names
+}
+
+// TestFilterColorEntries tests filtering colors by name and hue
+func TestFilterColorEntries(t *testing.T) {
+	tests := []struct {
+		name     string
+		terms    []string
+		expected []string
+	}{
+		{"no terms", nil, []string{"white", "navy", "red", "steelblue", "seagreen", "black"}},
+		{"substring", []string{"sea"}, []string{"seagreen"}},
+		{"hue family", []string{"blue"}, []string{"navy", "steelblue"}},
+		{"grey spelling", []string{"Grey"}, []string{"white", "black"}},
+		{"any term", []string{"red", "sea"}, []string{"red", "seagreen"}},
+		{"no match", []string{"zzz"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := entryNames(filterColorEntries(testColorEntries(), tt.terms))
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("filterColorEntries(%v) = %v, expected %v", tt.terms, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSortColorEntries tests ordering colors by name and by hue
+func TestSortColorEntries(t *testing.T) {
+	tests := []struct {
+		order    string
+		expected []string
+	}{
+		{"name", []string{"black", "navy", "red", "seagreen", "steelblue", "white"}},
+		{"hue", []string{"black", "white", "red", "seagreen", "steelblue", "navy"}},
+	}
+
+	for _, tt := range tests {
+		entries := testColorEntries()
+		if err := sortColorEntries(entries, tt.order); err != nil {
+			t.Fatalf("sortColorEntries(%q) failed: %v", tt.order, err)
+		}
+		if got := entryNames(entries); strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("sortColorEntries(%q) = %v, expected %v", tt.order, got, tt.expected)
+		}
+	}
+
+	if err := sortColorEntries(testColorEntries(), "size"); err == nil {
+		t.Error("Expected error for unknown sort order")
+	}
+}
+
+// TestFormatColorColumns tests laying out names in columns
+func TestFormatColorColumns(t *testing.T) {
+	entries := testColorEntries()
+	sortColorEntries(entries, "name")
+
+	// Cells are 9 wide with a gap of 2, so 30 columns fit two per line, filled top to bottom
+	plain := stripANSI(formatColorColumns(entries, 30, false))
+	expected := "black      seagreen\nnavy       steelblue\nred        white"
+	if plain != expected {
+		t.Errorf("formatColorColumns() =\n%s\nexpected\n%s", plain, expected)
+	}
+
+	// Too narrow for two columns still shows one per line
+	if lines := strings.Split(formatColorColumns(entries, 5, false), "\n"); len(lines) != len(entries) {
+		t.Errorf("Expected %d lines when narrow, got %d", len(entries), len(lines))
+	}
+
+	swatches := formatColorColumns(entries[:1], 80, true)
+	if !strings.Contains(swatches, "\033[48;2;0;0;0m") || !strings.Contains(stripANSI(swatches), " black ") {
+		t.Errorf("Expected a black swatch, got %q", swatches)
+	}
+}
+
+// ansiPattern matches SGR escape sequences
+var ansiPattern = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// stripANSI removes color escape sequences from text
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
@@ -1,13 +1,62 @@
 package main
 
 import (
+	"fmt"
+	"slices"
 	"strings"
+	"unicode"
 
 	"github.com/bh1cqx/set-tab-color/generated"
 )
 
+// cssColors holds the built-in color names in use: the CSS names, unless the
+// color_names setting picks the X11 names or both
 var cssColors = generated.CSSColors
 
+// Values of the color_names setting. When both sets are used, the first wins the
+// names they define differently, such as gray, green, maroon, and purple.
+const (
+	colorNamesCSS    = "css"     // The CSS names (the default)
+	colorNamesX11    = "x11"     // The X11 rgb.txt names, such as DodgerBlue4
+	colorNamesCSSX11 = "css+x11" // Both, with the CSS values for conflicting names
+	colorNamesX11CSS = "x11+css" // Both, with the X11 values for conflicting names
+)
+
+// colorNameSets lists the valid color_names values
+var colorNameSets = []string{colorNamesCSS, colorNamesX11, colorNamesCSSX11, colorNamesX11CSS}
+
+// setColorNames picks the built-in color names from the color_names setting
+func setColorNames(set string) error {
+	switch set {
+	case "", colorNamesCSS:
+		cssColors = generated.CSSColors
+	case colorNamesX11:
+		cssColors = generated.X11Colors
+	case colorNamesCSSX11:
+		cssColors = mergeColorNames(generated.X11Colors, generated.CSSColors)
+	case colorNamesX11CSS:
+		cssColors = mergeColorNames(generated.CSSColors, generated.X11Colors)
+	default:
+		return fmt.Errorf("invalid color_names %q (expected one of: %s)", set, strings.Join(colorNameSets, ", "))
+	}
+	return nil
+}
+
+// mergeColorNames combines two sets of color names, preferring the second
+func mergeColorNames(base, preferred map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(preferred))
+	for name, hex := range base {
+		merged[name] = hex
+	}
+	for name, hex := range preferred {
+		merged[name] = hex
+	}
+	return merged
+}
+
+// customColors holds user-defined color names from the [colors] config table
+var customColors = map[string]string{}
+
 // initColors is no longer needed since cssColors is initialized directly
 func initColors() error {
 	return nil
@@ -20,6 +69,32 @@ func expandHex3(s string) string {
 		strings.Repeat(string(s[2]), 2)
 }
 
+// stripHexAlpha drops the alpha of a #RGBA or #RRGGBBAA color (without the '#'),
+// since terminal colors are opaque
+func stripHexAlpha(s string) (string, bool) {
+	switch {
+	case len(s) == 4 && isHex(s):
+		return expandHex3(s[:3]), true
+	case len(s) == 8 && isHex(s):
+		return s[:6], true
+	}
+	return "", false
+}
+
+// hexAlpha returns the alpha of a color given as #RGBA or #RRGGBBAA as two hex
+// digits, or "" for any other color
+func hexAlpha(input string) string {
+	clean := strings.ToLower(strings.TrimPrefix(input, "#"))
+	hex, ok := stripHexAlpha(clean)
+	if !ok || normalizeColor(input) != hex {
+		return ""
+	}
+	if len(clean) == 4 {
+		return strings.Repeat(clean[3:], 2)
+	}
+	return clean[6:]
+}
+
 // isHex reports whether s consists only of lowercase hex digits
 func isHex(s string) bool {
 	for _, c := range s {
@@ -30,8 +105,11 @@ func isHex(s string) bool {
 	return true
 }
 
-// normalizeColor handles #RGB, #RRGGBB, CSS names, and "default"
+// normalizeColor handles #RGB, #RRGGBB, #RGBA, #RRGGBBAA (dropping the alpha), custom
+// names, semantic names, CSS names, "default", and any of these followed by
+// lighten/darken modifiers
 func normalizeColor(input string) string {
+	defer timePhase("normalization")()
 	clean := strings.ToLower(strings.TrimPrefix(input, "#"))
 	if clean == "default" {
 		return "default"
@@ -42,12 +120,118 @@ func normalizeColor(input string) string {
 	if len(clean) == 6 && isHex(clean) {
 		return clean
 	}
-	if hex, ok := cssColors[clean]; ok {
-		return strings.TrimPrefix(hex, "#")
+	// With the '#', four and eight hex digits are a color with alpha; without it,
+	// a name such as "fade" comes first
+	if strings.HasPrefix(input, "#") {
+		if hex, ok := stripHexAlpha(clean); ok {
+			return hex
+		}
+	}
+	// Custom colors take precedence so users can redefine CSS names
+	if hex, ok := lookupCustomColor(clean); ok {
+		return hex
+	}
+	if hex, ok := lookupSemanticColor(clean); ok {
+		return hex
+	}
+	if hex, ok := lookupCSSColor(clean); ok {
+		return hex
+	}
+	if hex, ok := stripHexAlpha(clean); ok {
+		return hex
+	}
+	if hex, ok := applyColorModifiers(input); ok {
+		return hex
 	}
 	return ""
 }
 
+// colorNameKey folds the ways a color name may be written, so "Light Blue",
+// "light_blue", "light-blue", and "LightBlue" are all "lightblue"
+func colorNameKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '_', '-':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// graySpellings returns a folded name as written and with "grey" and "gray" swapped
+func graySpellings(key string) []string {
+	return []string{key, strings.ReplaceAll(key, "grey", "gray"), strings.ReplaceAll(key, "gray", "grey")}
+}
+
+// lookupCSSColor finds a built-in color however its name is written or gray is spelled
+func lookupCSSColor(name string) (string, bool) {
+	for _, key := range graySpellings(colorNameKey(name)) {
+		if hex, ok := cssColors[key]; ok {
+			return strings.TrimPrefix(hex, "#"), true
+		}
+	}
+	return "", false
+}
+
+// lookupCustomColor finds a [colors] name: as written first, then folded like CSS
+// names. When two custom names fold the same, the first in sorted order wins.
+func lookupCustomColor(name string) (string, bool) {
+	if hex, ok := customColors[name]; ok {
+		return hex, true
+	}
+	keys := graySpellings(colorNameKey(name))
+	var matches []string
+	for custom := range customColors {
+		if slices.Contains(keys, colorNameKey(custom)) {
+			matches = append(matches, custom)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+	slices.Sort(matches)
+	return customColors[matches[0]], true
+}
+
+// isComputedColor reports whether value is picked when the profile is applied
+// ("distinct" for the tab, "auto" for the foreground) rather than a fixed color
+func isComputedColor(key, value string) bool {
+	return (key == "tab" && value == distinctColorName) || (key == "fg" && value == autoColorName)
+}
+
+// setCustomColors replaces the custom color table with the given name → color mapping.
+// Values may be hex colors, semantic names, or CSS color names; they are resolved
+// when registered.
+func setCustomColors(colors map[string]string) error {
+	resolved := make(map[string]string, len(colors))
+	for name, value := range colors {
+		// Resolve against hex and built-in names only, so definitions can't refer to each other
+		clean := strings.ToLower(strings.TrimPrefix(value, "#"))
+		var hex string
+		switch {
+		case len(clean) == 3 && isHex(clean):
+			hex = expandHex3(clean)
+		case len(clean) == 6 && isHex(clean):
+			hex = clean
+		case len(clean) == 4 && isHex(clean), len(clean) == 8 && isHex(clean):
+			hex, _ = stripHexAlpha(clean)
+		default:
+			if semantic, ok := lookupSemanticColor(clean); ok {
+				hex = semantic
+			} else if css, ok := lookupCSSColor(clean); ok {
+				hex = css
+			}
+		}
+		if hex == "" {
+			return fmt.Errorf("invalid value %q for color %q", value, name)
+		}
+		resolved[strings.ToLower(name)] = hex
+	}
+
+	customColors = resolved
+	return nil
+}
+
 // listCSSColorNames returns a list of all available CSS color names
 func listCSSColorNames() ([]string, error) {
 	// Initialize CSS colors if not already done
@@ -62,22 +246,3 @@ func listCSSColorNames() ([]string, error) {
 
 	return names, nil
 }
-
-// listCSSColorNamesFormatted returns a comma-separated string of all available CSS color names
-// with each name colored according to its actual color value
-func listCSSColorNamesFormatted() (string, error) {
-	// Initialize CSS colors if not already done
-	if err := initColors(); err != nil {
-		return "", err
-	}
-
-	coloredNames := make([]string, 0, len(cssColors))
-	for name, hexValue := range cssColors {
-		// Remove the # prefix from hex value for our colorText function
-		hex := strings.TrimPrefix(hexValue, "#")
-		coloredName := colorText(name, hex)
-		coloredNames = append(coloredNames, coloredName)
-	}
-
-	return strings.Join(coloredNames, ", "), nil
-}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -23,6 +24,12 @@ func TestNormalizeColor(t *testing.T) {
 		{"#FF0000", "ff0000"}, // uppercase
 		{"FF0000", "ff0000"},  // uppercase without #
 
+		// Hex colors with alpha, which is dropped
+		{"#ff00", "ffff00"},
+		{"#f808", "ff8800"},
+		{"#ff000080", "ff0000"},
+		{"FF0000CC", "ff0000"},
+
 		// CSS color names (testing a few known ones)
 		{"red", "ff0000"},
 		{"blue", "0000ff"},
@@ -30,13 +37,23 @@ func TestNormalizeColor(t *testing.T) {
 		{"white", "ffffff"},
 		{"black", "000000"},
 
+		// Names however they are written
+		{"Light Blue", "add8e6"},
+		{"light_blue", "add8e6"},
+		{"light-blue", "add8e6"},
+		{"LightBlue", "add8e6"},
+		{"Dark Slate Grey", "2f4f4f"},
+		{"DARK_SLATE_GRAY", "2f4f4f"},
+
 		// Special case
 		{"default", "default"},
 
 		// Invalid colors
 		{"invalid", ""},
 		{"#gg0000", ""},
-		{"#ff00", ""}, // wrong length
+		{"#ff000", ""}, // wrong length
+		{"#ff00000", ""},
+		{"#ff0000gg", ""},
 	}
 
 	for _, test := range tests {
@@ -115,3 +132,115 @@ func TestInitColors(t *testing.T) {
 		t.Errorf("Expected at least 100 colors, got %d", len(cssColors))
 	}
 }
+
+// TestSetCustomColors tests registering user-defined color names
+func TestSetCustomColors(t *testing.T) {
+	defer setCustomColors(nil)
+
+	err := setCustomColors(map[string]string{
+		"corp-orange": "#f26522",
+		"Brand-Blue":  "navy",
+		"short":       "#f80",
+		"blue-grey":   "Light Slate Gray",
+	})
+	if err != nil {
+		t.Fatalf("setCustomColors() failed: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"corp-orange", "f26522"},
+		{"CORP-ORANGE", "f26522"},
+		{"brand-blue", "000080"},
+		{"short", "ff8800"},
+		{"red", "ff0000"}, // CSS names still work
+		{"Corp Orange", "f26522"},
+		{"corp_orange", "f26522"},
+		{"CorpOrange", "f26522"},
+		{"blue-grey", "778899"},
+		{"BlueGray", "778899"},
+	}
+
+	for _, test := range tests {
+		result := normalizeColor(test.input)
+		if result != test.expected {
+			t.Errorf("normalizeColor(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	// Custom names shadow CSS names
+	if err := setCustomColors(map[string]string{"red": "#123456"}); err != nil {
+		t.Fatalf("setCustomColors() failed: %v", err)
+	}
+	if result := normalizeColor("red"); result != "123456" {
+		t.Errorf("Expected custom 'red' to shadow CSS name, got %q", result)
+	}
+	if result := normalizeColor("corp-orange"); result != "" {
+		t.Errorf("Expected previous custom colors to be replaced, got %q", result)
+	}
+
+	// Invalid values are rejected
+	if err := setCustomColors(map[string]string{"bad": "notacolor"}); err == nil {
+		t.Error("Expected setCustomColors() to fail for invalid color value")
+	}
+}
+
+// TestHexAlpha tests reading the alpha of colors given with one
+func TestHexAlpha(t *testing.T) {
+	original := customColors
+	defer func() { customColors = original }()
+	customColors = map[string]string{"fade": "808080"}
+
+	for input, expected := range map[string]string{
+		"#f808":     "88",
+		"#ff000080": "80",
+		"FF0000CC":  "cc",
+		"#ff0000":   "",
+		"red":       "",
+		"fade":      "", // A custom color, not #ffaadd with alpha ee
+		"#fade":     "ee",
+	} {
+		if got := hexAlpha(input); got != expected {
+			t.Errorf("hexAlpha(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+// TestSetColorNames tests choosing the CSS names, the X11 names, or both
+func TestSetColorNames(t *testing.T) {
+	defer setColorNames("")
+
+	tests := []struct {
+		set      string
+		input    string
+		expected string
+	}{
+		{"css", "gray", "808080"},
+		{"css", "DodgerBlue4", ""},
+		{"x11", "DodgerBlue4", "104e8b"},
+		{"x11", "dodger blue 4", "104e8b"},
+		{"x11", "gray", "bebebe"},
+		{"x11", "rebeccapurple", ""},
+		{"css+x11", "gray", "808080"},
+		{"css+x11", "DodgerBlue4", "104e8b"},
+		{"css+x11", "rebeccapurple", "663399"},
+		{"x11+css", "gray", "bebebe"},
+		{"x11+css", "rebeccapurple", "663399"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.set+" "+tt.input, func(t *testing.T) {
+			if err := setColorNames(tt.set); err != nil {
+				t.Fatalf("setColorNames(%q) failed: %v", tt.set, err)
+			}
+			if got := normalizeColor(tt.input); got != tt.expected {
+				t.Errorf("normalizeColor(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	if err := setColorNames("svg"); err == nil || !strings.Contains(err.Error(), "css, x11, css+x11, x11+css") {
+		t.Errorf("Expected an error listing the choices, got %v", err)
+	}
+}
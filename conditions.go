@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// conditionKeys are the conditions a conditional profile value may have, as in
+// tab = { value = "red", unless_terminal = "vscode" }. Each takes a terminal, shell,
+// or mode name, or a pattern like the sub-profile keys ("ssh|mosh", "*sh").
+var conditionKeys = []string{"terminal", "unless_terminal", "shell", "unless_shell", "mode", "unless_mode"}
+
+// valueFor returns the value of a setting for info. A conditional value applies only
+// when all of its conditions hold; a missing setting never applies.
+func (v *ProfileValue) valueFor(info *TerminalShellInfo) (string, bool) {
+	if v == nil {
+		return "", false
+	}
+	for _, key := range conditionKeys {
+		pattern, ok := v.Conditions[key]
+		if !ok {
+			continue
+		}
+		negated := strings.HasPrefix(key, "unless_")
+		if conditionMatches(strings.TrimPrefix(key, "unless_"), pattern, info) == negated {
+			return "", false
+		}
+	}
+	return v.Value, true
+}
+
+// conditionMatches reports whether the terminal chain, shell, or mode of info matches a
+// condition's pattern. A terminal condition matches any terminal in the chain.
+func conditionMatches(kind, pattern string, info *TerminalShellInfo) bool {
+	switch kind {
+	case "terminal":
+		for _, terminal := range info.Terminals {
+			if matchSubProfilePattern(pattern, string(terminal)) {
+				return true
+			}
+		}
+		return false
+	case "shell":
+		return info.Shell != ShellTypeUnknown && matchSubProfilePattern(pattern, string(info.Shell))
+	case "mode":
+		return info.Appearance != AppearanceUnknown && matchSubProfilePattern(pattern, string(info.Appearance))
+	}
+	return false
+}
+
+// checkConditionalValue reports a malformed conditional value: one without a value,
+// with unknown keys, or with a condition that isn't a valid pattern
+func checkConditionalValue(m map[string]interface{}) error {
+	if _, ok := m["value"]; !ok {
+		return fmt.Errorf("conditional value needs a value key")
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "value" {
+			continue
+		}
+		if !containsString(conditionKeys, key) {
+			return fmt.Errorf("unknown condition %q (expected one of: %s)", key, strings.Join(conditionKeys, ", "))
+		}
+		pattern, ok := m[key].(string)
+		if !ok {
+			return fmt.Errorf("condition %s must be a string, got %T", key, m[key])
+		}
+		if err := checkSubProfilePattern(pattern); err != nil {
+			return fmt.Errorf("condition %s: %v", key, err)
+		}
+	}
+	return nil
+}
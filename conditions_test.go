@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestResolveConditionalValues tests values that only apply in some terminals, shells, or modes
+func TestResolveConditionalValues(t *testing.T) {
+	data := map[string]interface{}{
+		"tab": map[string]interface{}{"value": "red", "unless_terminal": "vscode"},
+		"fg":  map[string]interface{}{"value": "white", "shell": "zsh|fish", "unless_mode": "light"},
+		"bg":  "black",
+		"ssh": map[string]interface{}{
+			"badge": map[string]interface{}{"value": "REMOTE", "terminal": "tmux"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		info          TerminalShellInfo
+		expectedTab   string
+		expectedFg    string
+		expectedBadge string
+	}{
+		{"no conditions hold", TerminalShellInfo{Terminals: []TerminalType{TerminalTypeVSCode}, Shell: ShellTypeBash}, "", "", ""},
+		{"unless", TerminalShellInfo{Terminals: []TerminalType{TerminalTypeITerm2}}, "red", "", ""},
+		{"shell pattern", TerminalShellInfo{Shell: ShellTypeFish, Appearance: AppearanceDark}, "red", "white", ""},
+		{"unless mode", TerminalShellInfo{Shell: ShellTypeZsh, Appearance: AppearanceLight}, "red", "", ""},
+		{"sub-profile", TerminalShellInfo{Terminals: []TerminalType{TerminalTypeTmux, TerminalTypeSSH}}, "red", "", "REMOTE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := resolveProfile("dev", testProfile(t, data), &tt.info)
+			if err != nil {
+				t.Fatalf("resolveProfile() failed: %v", err)
+			}
+			if res.Result.Tab != tt.expectedTab || res.Result.Foreground != tt.expectedFg || res.Result.Badge != tt.expectedBadge {
+				t.Errorf("Unexpected result: %+v", res.Result)
+			}
+			if res.Result.Background != "black" {
+				t.Errorf("Expected the plain bg value, got %q", res.Result.Background)
+			}
+		})
+	}
+}
+
+// TestCheckConditionalValue tests that malformed conditional values are reported
+func TestCheckConditionalValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       map[string]interface{}
+		expectError string
+	}{
+		{"valid", map[string]interface{}{"value": "red", "unless_terminal": "vscode|cursor"}, ""},
+		{"missing value", map[string]interface{}{"terminal": "ssh"}, "needs a value"},
+		{"unknown condition", map[string]interface{}{"value": "red", "host": "prod"}, `unknown condition "host"`},
+		{"not a string", map[string]interface{}{"value": "red", "shell": int64(1)}, "must be a string"},
+		{"bad pattern", map[string]interface{}{"value": "red", "terminal": "[ssh"}, "invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConditionalValue(tt.value)
+			if tt.expectError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.expectError) {
+				t.Errorf("Expected error containing %q, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
@@ -3,7 +3,8 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
@@ -13,233 +14,236 @@ var verboseMode bool
 
 // Profile represents a color profile with optional colors and preset
 type Profile struct {
-	Tab        string `toml:"tab,omitempty"`
-	Foreground string `toml:"fg,omitempty"`
-	Background string `toml:"bg,omitempty"`
-	Preset     string `toml:"preset,omitempty"`
+	Tab          string            `toml:"tab,omitempty" json:"tab,omitempty"`
+	Foreground   string            `toml:"fg,omitempty" json:"fg,omitempty"`
+	Background   string            `toml:"bg,omitempty" json:"bg,omitempty"`
+	Preset       string            `toml:"preset,omitempty" json:"preset,omitempty"`
+	Theme        string            `toml:"theme,omitempty" json:"theme,omitempty"`                 // Built-in theme applied after the preset
+	Palette      map[string]string `toml:"palette,omitempty" json:"palette,omitempty"`             // ANSI/UI colors applied on top of the preset
+	Badge        string            `toml:"badge,omitempty" json:"badge,omitempty"`                 // iTerm2 badge text
+	Title        string            `toml:"title,omitempty" json:"title,omitempty"`                 // Tab and window title
+	Attention    string            `toml:"attention,omitempty" json:"attention,omitempty"`         // "true", "false", "bounce", or "fireworks"
+	ITermProfile string            `toml:"iterm_profile,omitempty" json:"iterm_profile,omitempty"` // iTerm2 profile the session switches to before the other settings
+	Transparency string            `toml:"transparency,omitempty" json:"transparency,omitempty"`   // iTerm2 window transparency in percent (0-100), or "default"
+	Blur         string            `toml:"blur,omitempty" json:"blur,omitempty"`                   // "true", "false", a blur radius (0-64), or "default"
+	Priority     int               `toml:"priority,omitempty" json:"priority,omitempty"`           // Breaks ties between matching terminal sub-profiles
 }
 
 // Config represents the TOML configuration file structure with nested profiles
 type Config struct {
-	Profiles map[string]interface{} `toml:"profiles"`
+	ReadOnly       bool                       `toml:"read_only"`       // Marks a shared config that the editing commands must not change
+	DefaultProfile string                     `toml:"default_profile"` // Profile (or comma-separated list) a bare set-tab-color applies
+	ColorNames     string                     `toml:"color_names"`     // Built-in color names: "css", "x11", "css+x11", or "x11+css"
+	Colors         map[string]string          `toml:"colors"`
+	Vars           map[string]string          `toml:"vars"` // Values for {{ vars.NAME }} templates
+	Themes         map[string]ThemeConfig     `toml:"themes"`
+	Contrast       ContrastConfig             `toml:"contrast"`
+	Workspaces     map[string]WorkspaceConfig `toml:"workspaces"`
+	Watch          WatchConfig                `toml:"watch"`
+	Redact         RedactConfig               `toml:"redact"`
+	Detect         DetectConfig               `toml:"detect"`
+	Tmux           TmuxConfig                 `toml:"tmux"`
+	Unsupported    UnsupportedConfig          `toml:"unsupported"`
+	Colorblind     ColorblindConfig           `toml:"colorblind"`
+	ITerm2         ITerm2Config               `toml:"iterm2"`
+	RawProfiles    map[string]interface{}     `toml:"profiles"` // As decoded; Profiles holds the checked tables
+	Profiles       map[string]*ProfileConfig  `toml:"-"`
 }
 
-// getConfigPath returns the configuration file path, checking env var first
+// getConfigPath returns the configuration file path: the last -config file, which
+// the editing commands change, or else the env var or the default location
 func getConfigPath() (string, error) {
+	if len(configPaths) > 0 {
+		return configPaths[len(configPaths)-1], nil
+	}
+
 	// Check environment variable first
 	if configPath := os.Getenv("SET_TAB_COLOR_CONFIG"); configPath != "" {
 		return configPath, nil
 	}
 
-	// Prefer ~/.config/set-tab-color.toml on all platforms (including macOS)
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		configPath := filepath.Join(homeDir, ".config", "set-tab-color.toml")
-		// Check if ~/.config directory exists or the config file exists
-		if _, err := os.Stat(filepath.Dir(configPath)); err == nil {
-			return configPath, nil
-		}
-		if _, err := os.Stat(configPath); err == nil {
-			return configPath, nil
-		}
-	}
-
-	// Fall back to OS-specific config directory (~/Library/Application Support on macOS)
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		return "", fmt.Errorf("could not get config directory: %v", err)
-	}
-
-	return filepath.Join(configDir, "set-tab-color.toml"), nil
+	return findDefaultConfigPath()
 }
 
-// loadConfig loads the TOML configuration file
+// loadConfig loads the TOML configuration file, or merges the -config files
 func loadConfig() (*Config, error) {
-	configPath, err := getConfigPath()
+	defer timePhase("config load")()
+	paths, err := getConfigPaths()
 	if err != nil {
-		return nil, err
+		return nil, withExitCode(exitConfigError, err)
 	}
 
-	// If config file doesn't exist, return empty config
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return &Config{Profiles: make(map[string]interface{})}, nil
+	// Only the default location may be missing; a file named with -config must exist
+	for _, path := range configPaths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, withExitCode(exitConfigError, fmt.Errorf("config file %s given with -config does not exist", path))
+		}
 	}
 
-	// Load config maintaining nested structure
-	var config Config
-	if _, err := toml.DecodeFile(configPath, &config); err != nil {
-		return nil, fmt.Errorf("error parsing config file %s: %v", configPath, err)
+	configPath := paths[0]
+	var config *Config
+	if len(paths) > 1 {
+		configPath = strings.Join(paths, ", ")
+		if config, err = decodeConfigLayers(paths); err != nil {
+			return nil, withExitCode(exitConfigError, fmt.Errorf("error parsing config files %s: %v", configPath, err))
+		}
+	} else if info, err := os.Stat(configPath); err == nil {
+		// Load config maintaining nested structure, reusing an earlier parse if the file is unchanged
+		if config, err = decodeConfigCached(configPath, info); err != nil {
+			return nil, withExitCode(exitConfigError, fmt.Errorf("error parsing config file %s: %v", configPath, err))
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("error reading config file %s: %v", configPath, err))
 	}
 
-	// Initialize profiles map if nil
-	if config.Profiles == nil {
-		config.Profiles = make(map[string]interface{})
+	// If config file doesn't exist, return empty config
+	if config == nil {
+		setColorNames("")
+		customColors = map[string]string{}
+		customThemes = map[string]Profile{}
+		setContrastPair(ContrastConfig{})
+		redactPatterns = nil
+		setDetectRules(DetectConfig{})
+		tmuxChrome = TmuxConfig{}
+		setUnsupportedPolicy(UnsupportedConfig{})
+		setColorblindMode(ColorblindConfig{})
+		setITerm2Config(ITerm2Config{})
+		return &Config{Colors: make(map[string]string), Profiles: make(map[string]*ProfileConfig)}, nil
 	}
 
-	return &config, nil
-}
-
-// extractProfile dynamically extracts a profile from a nested map structure
-func extractProfile(data interface{}) (*Profile, error) {
-	m, ok := data.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("expected map[string]interface{}, got %T", data)
+	// Custom colors may be defined with built-in names, so those are picked first
+	if err := setColorNames(config.ColorNames); err != nil {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("error in %s: %v", configPath, err))
 	}
 
-	// Check if this is a profile (has tab, fg, or bg keys)
-	if !isProfileMap(m) {
-		return nil, fmt.Errorf("not a profile map")
+	// Register custom colors so profiles and flags can reference them
+	if err := setCustomColors(config.Colors); err != nil {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("error in [colors] table of %s: %v", configPath, err))
 	}
-
-	profile := &Profile{}
-
-	if tab, ok := m["tab"]; ok {
-		if tabStr, ok := tab.(string); ok {
-			profile.Tab = tabStr
-		}
+	if err := setCustomThemes(config.Themes); err != nil {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("error in [themes] table of %s: %v", configPath, err))
 	}
-
-	if fg, ok := m["fg"]; ok {
-		if fgStr, ok := fg.(string); ok {
-			profile.Foreground = fgStr
-		}
+	if err := setContrastPair(config.Contrast); err != nil {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("error in [contrast] table of %s: %v", configPath, err))
 	}
-
-	if bg, ok := m["bg"]; ok {
-		if bgStr, ok := bg.(string); ok {
-			profile.Background = bgStr
-		}
+	if err := setDetectRules(config.Detect); err != nil {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("error in [detect] table of %s: %v", configPath, err))
+	}
+	tmuxChrome = config.Tmux
+	if err := setUnsupportedPolicy(config.Unsupported); err != nil {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("error in [unsupported] table of %s: %v", configPath, err))
+	}
+	if err := setColorblindMode(config.Colorblind); err != nil {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("error in [colorblind] table of %s: %v", configPath, err))
+	}
+	if err := setITerm2Config(config.ITerm2); err != nil {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("error in [iterm2] table of %s: %v", configPath, err))
 	}
 
-	if preset, ok := m["preset"]; ok {
-		if presetStr, ok := preset.(string); ok {
-			profile.Preset = presetStr
-		}
+	// Hide sensitive values from verbose output
+	if err := setRedactions(config.Redact, collectSecretProfileNames(config.Profiles)); err != nil {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("error in [redact] table of %s: %v", configPath, err))
 	}
 
-	return profile, nil
+	return config, nil
 }
 
-// isProfileMap checks if a map contains profile-like keys
-func isProfileMap(m map[string]interface{}) bool {
-	for key := range m {
-		if key == "tab" || key == "fg" || key == "bg" || key == "preset" {
-			return true
+// finishDecoding checks a freshly decoded config for unknown keys, expands its
+// templates, and decodes its profiles, returning every problem found
+func finishDecoding(meta toml.MetaData, config *Config) []configError {
+	if config.Colors == nil {
+		config.Colors = make(map[string]string)
+	}
+	errs := unknownConfigKeys(meta)
+	errs = append(errs, expandConfigTemplates(config)...)
+	profiles, profileErrs := parseProfiles(config.RawProfiles)
+	config.Profiles = profiles
+	return append(errs, profileErrs...)
+}
+
+// splitProfileNames splits a profile list such as "base,prod" into the profiles to
+// overlay, in order
+func splitProfileNames(profileName string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(profileName, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("empty profile name in %q", profileName)
 		}
+		names = append(names, name)
 	}
-	return false
+	return names, nil
 }
 
-// getProfileWithTerminalInfo retrieves a profile with optional terminal info override (for testing)
-func getProfileWithTerminalInfo(profileName string, terminalInfo *TerminalShellInfo) (*Profile, error) {
+// resolveProfileNames resolves each profile of a comma-separated list for the terminal info
+func resolveProfileNames(profileName string, terminalInfo *TerminalShellInfo) ([]*ProfileResolution, error) {
 	config, err := loadConfig()
 	if err != nil {
 		return nil, err
 	}
-
-	// Find base profile in nested structure
-	baseData, exists := config.Profiles[profileName]
-	if !exists {
-		return nil, fmt.Errorf("profile %q not found", profileName)
-	}
-
-	// Extract base profile
-	baseProfile, err := extractProfile(baseData)
+	names, err := splitProfileNames(profileName)
 	if err != nil {
-		// Not a valid profile at top level, check if it's a nested structure
-		return nil, fmt.Errorf("profile %q is not a valid profile", profileName)
-	}
-
-	if verboseMode {
-		fmt.Fprintf(os.Stderr, "Using base profile: %q\n", profileName)
-		fmt.Fprintf(os.Stderr, "  Base profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
-			baseProfile.Tab, baseProfile.Foreground, baseProfile.Background, baseProfile.Preset)
+		return nil, err
 	}
 
-	// Start with base profile
-	result := *baseProfile
+	var resolutions []*ProfileResolution
+	for _, name := range names {
+		// Find base profile in nested structure
+		baseData, exists := config.Profiles[name]
+		if !exists {
+			return nil, fmt.Errorf("profile %q not found", name)
+		}
 
-	// Get the nested map for this profile to look for sub-profiles
-	profileMap, ok := baseData.(map[string]interface{})
-	if !ok {
-		// No nested structure, just return base profile
-		if verboseMode {
-			fmt.Fprintf(os.Stderr, "No sub-profiles available for profile %q\n", profileName)
+		// Use provided terminal info (caller must always provide it)
+		resolution, err := resolveProfile(name, baseData, terminalInfo)
+		if err != nil {
+			return nil, err
 		}
-		return &result, nil
+		resolutions = append(resolutions, resolution)
+	}
+	return resolutions, nil
+}
+
+// getProfileWithTerminalInfo retrieves a profile with optional terminal info override (for testing).
+// A comma-separated list of profiles is resolved one by one and overlaid in order.
+func getProfileWithTerminalInfo(profileName string, terminalInfo *TerminalShellInfo) (*Profile, error) {
+	// Resolve every layer before printing anything
+	resolutions, err := resolveProfileNames(profileName, terminalInfo)
+	if err != nil {
+		return nil, err
 	}
 
-	// Use provided terminal info (caller must always provide it)
-	terminalShellInfo := *terminalInfo
+	// Walking the process tree is only worth it when the chain is shown
 	if verboseMode {
-		fmt.Fprintf(os.Stderr, "Terminal detection: %v\n", terminalShellInfo.Terminals)
-		fmt.Fprintf(os.Stderr, "Shell detection: %s\n", terminalShellInfo.Shell)
-		fmt.Fprintf(os.Stderr, "Detection valid: %v", terminalShellInfo.Valid)
-		if !terminalShellInfo.Valid {
-			fmt.Fprintf(os.Stderr, " (shell should come before terminal)")
+		debugf("Terminal detection: %v\n", terminalInfo.Terminals)
+		debugf("Shell detection: %s\n", terminalInfo.Shell)
+		debugf("Detection valid: %v", terminalInfo.Valid)
+		if !terminalInfo.Valid {
+			debugf(" (shell should come before terminal)")
 		}
-		fmt.Fprintf(os.Stderr, "\n")
+		debugf("\n")
 
 		if chain, err := getProcessAncestorChain(); err == nil {
-			fmt.Fprintf(os.Stderr, "Process ancestor chain:\n")
+			debugf("Process ancestor chain:\n")
 			for i, processName := range chain {
-				fmt.Fprintf(os.Stderr, "  %d: %s\n", i, processName)
+				debugf("  %d: %s\n", i, processName)
 			}
 		}
-		fmt.Fprintf(os.Stderr, "\n")
-	}
-
-	// Apply shell-specific overlay first (if it exists)
-	if terminalShellInfo.Shell != ShellTypeUnknown {
-		shellKey := string(terminalShellInfo.Shell)
-		if shellData, exists := profileMap[shellKey]; exists {
-			if shellProfile, err := extractProfile(shellData); err == nil {
-				if verboseMode {
-					fmt.Fprintf(os.Stderr, "Applying shell-specific sub-profile: %s.%s\n", profileName, shellKey)
-					fmt.Fprintf(os.Stderr, "  Shell sub-profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
-						shellProfile.Tab, shellProfile.Foreground, shellProfile.Background, shellProfile.Preset)
-				}
-				result = overlayProfile(result, *shellProfile)
-			}
-		} else if verboseMode {
-			fmt.Fprintf(os.Stderr, "No shell-specific sub-profile found for: %s.%s\n", profileName, shellKey)
-		}
-	}
+		debugf("\n")
 
-	// Apply terminal-specific overlay last (takes priority)
-	// Try terminals in order until we find one with a subprofile
-	var appliedTerminalProfile bool
-	if verboseMode {
-		fmt.Fprintf(os.Stderr, "Checking terminals for sub-profiles: %v\n", terminalShellInfo.Terminals)
-	}
-
-	for _, terminal := range terminalShellInfo.Terminals {
-		terminalKey := string(terminal)
-		if terminalData, exists := profileMap[terminalKey]; exists {
-			if terminalProfile, err := extractProfile(terminalData); err == nil {
-				if verboseMode {
-					fmt.Fprintf(os.Stderr, "Applying terminal-specific sub-profile: %s.%s\n", profileName, terminalKey)
-					fmt.Fprintf(os.Stderr, "  Terminal sub-profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
-						terminalProfile.Tab, terminalProfile.Foreground, terminalProfile.Background, terminalProfile.Preset)
-				}
-				result = overlayProfile(result, *terminalProfile)
-				appliedTerminalProfile = true
-				break // Use the first terminal that has a subprofile
-			}
-		} else if verboseMode {
-			fmt.Fprintf(os.Stderr, "No terminal-specific sub-profile found for: %s.%s\n", profileName, terminalKey)
+		for _, resolution := range resolutions {
+			resolution.printVerbose()
 		}
 	}
 
-	if !appliedTerminalProfile && len(terminalShellInfo.Terminals) > 0 && verboseMode {
-		fmt.Fprintf(os.Stderr, "No terminal sub-profiles found for any terminal in the process chain\n")
+	var result Profile
+	for _, resolution := range resolutions {
+		result = overlayProfile(result, resolution.Result)
 	}
-
-	if verboseMode {
-		fmt.Fprintf(os.Stderr, "Final profile values after overlays: tab=%q, fg=%q, bg=%q, preset=%q\n",
+	if len(resolutions) > 1 {
+		debugf("Combined profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
 			result.Tab, result.Foreground, result.Background, result.Preset)
 	}
-
 	return &result, nil
 }
 
@@ -260,60 +264,154 @@ func overlayProfile(base Profile, overlay Profile) Profile {
 	if overlay.Preset != "" {
 		result.Preset = overlay.Preset
 	}
+	if overlay.Theme != "" {
+		result.Theme = overlay.Theme
+	}
+	if overlay.Badge != "" {
+		result.Badge = overlay.Badge
+	}
+	if overlay.Title != "" {
+		result.Title = overlay.Title
+	}
+	if overlay.Attention != "" {
+		result.Attention = overlay.Attention
+	}
+	if overlay.ITermProfile != "" {
+		result.ITermProfile = overlay.ITermProfile
+	}
+	if overlay.Transparency != "" {
+		result.Transparency = overlay.Transparency
+	}
+	if overlay.Blur != "" {
+		result.Blur = overlay.Blur
+	}
+	result.Palette = overlayPalette(base.Palette, overlay.Palette)
 
 	return result
 }
 
 // applyProfile applies a profile's colors using the existing runSetColor function
 func applyProfile(profile *Profile) error {
-	if verboseMode {
-		fmt.Fprintf(os.Stderr, "\nApplying profile settings:\n")
+	// Tab colors are swapped for colorblind-safe ones before any backend sees them
+	profile.Tab = colorblindSafeTab(profile.Tab)
+
+	// Other sessions can't be reached with escape sequences
+	if usesSessionTargeting() {
+		return applyProfileViaAPI(profile)
+	}
+	if usesTmuxTargeting() {
+		return applyProfileToTmuxTarget(profile)
+	}
+
+	debugf("\nApplying profile settings:\n")
+
+	// The settings reach the terminal in one batch, so there is a single escape
+	// write and at most one it2setcolor run instead of one per setting
+	batch, outermost := startBatch()
+	err := applyProfileSettings(profile)
+	if outermost {
+		if flushErr := batch.flush(); err == nil && flushErr != nil {
+			err = fmt.Errorf("error applying profile: %w", flushErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// The tmux chrome follows the tab color when enabled in the [tmux] table
+	if err := applyTmuxChrome(profile); err != nil {
+		return fmt.Errorf("error styling tmux from profile: %w", err)
+	}
+
+	debugf("Profile application complete.\n")
+
+	return nil
+}
+
+// applyProfileSettings sets a profile's iTerm2 profile, preset, theme, palette, colors, badge,
+// title, window transparency, and attention cue in the order they override each other
+func applyProfileSettings(profile *Profile) error {
+	// Switching the iTerm2 profile replaces every color, so it comes before anything else
+	if profile.ITermProfile != "" {
+		debugf("  Switching iTerm2 profile: %q\n", profile.ITermProfile)
+		if err := runSetITermProfile(profile.ITermProfile); err != nil {
+			return fmt.Errorf("error switching iTerm2 profile from profile: %w", err)
+		}
 	}
 
 	// Apply preset first if specified (so individual colors can override it)
 	if profile.Preset != "" {
-		if verboseMode {
-			fmt.Fprintf(os.Stderr, "  Setting preset: %q\n", profile.Preset)
-		}
+		debugf("  Setting preset: %q\n", profile.Preset)
 		if err := runSetPreset(profile.Preset); err != nil {
-			return fmt.Errorf("error setting preset from profile: %v", err)
+			return fmt.Errorf("error setting preset from profile: %w", err)
 		}
 	}
 
+	// A theme replaces the whole color scheme, so it comes before individual colors
+	if profile.Theme != "" {
+		debugf("  Setting theme: %q\n", profile.Theme)
+		if err := runSetTheme(profile.Theme); err != nil {
+			return fmt.Errorf("error setting theme from profile: %w", err)
+		}
+	}
+
+	// Palette entries come next so they override the matching preset and theme colors
+	if err := applyPalette(profile.Palette); err != nil {
+		return err
+	}
+
 	// Set tab color if specified (overrides preset)
 	if profile.Tab != "" {
-		if verboseMode {
-			fmt.Fprintf(os.Stderr, "  Setting tab color: %q\n", profile.Tab)
-		}
+		debugf("  Setting tab color: %q\n", profile.Tab)
 		if err := runSetColor(TabColor, profile.Tab); err != nil {
-			return fmt.Errorf("error setting tab color from profile: %v", err)
+			return fmt.Errorf("error setting tab color from profile: %w", err)
 		}
 	}
 
 	// Set foreground color if specified (overrides preset)
 	if profile.Foreground != "" {
-		if verboseMode {
-			fmt.Fprintf(os.Stderr, "  Setting foreground color: %q\n", profile.Foreground)
-		}
+		debugf("  Setting foreground color: %q\n", profile.Foreground)
 		if err := runSetColor(ForegroundColor, profile.Foreground); err != nil {
-			return fmt.Errorf("error setting foreground color from profile: %v", err)
+			return fmt.Errorf("error setting foreground color from profile: %w", err)
 		}
 	}
 
 	// Set background color if specified (overrides preset)
 	if profile.Background != "" {
-		if verboseMode {
-			fmt.Fprintf(os.Stderr, "  Setting background color: %q\n", profile.Background)
-		}
+		debugf("  Setting background color: %q\n", profile.Background)
 		if err := runSetColor(BackgroundColor, profile.Background); err != nil {
-			return fmt.Errorf("error setting background color from profile: %v", err)
+			return fmt.Errorf("error setting background color from profile: %w", err)
 		}
 	}
 
-	if verboseMode {
-		fmt.Fprintf(os.Stderr, "Profile application complete.\n")
+	// Badge and title are independent of the colors, so they go last
+	if profile.Badge != "" {
+		debugf("  Setting badge: %q\n", profile.Badge)
+		if err := runSetBadge(profile.Badge); err != nil {
+			return fmt.Errorf("error setting badge from profile: %w", err)
+		}
+	}
+
+	if profile.Title != "" {
+		debugf("  Setting title: %q\n", profile.Title)
+		if err := runSetTitle(profile.Title); err != nil {
+			return fmt.Errorf("error setting title from profile: %w", err)
+		}
+	}
+
+	if profile.Transparency != "" || profile.Blur != "" {
+		debugf("  Setting transparency: %q, blur: %q\n", profile.Transparency, profile.Blur)
+		if err := runSetWindowEffects(profile.Transparency, profile.Blur); err != nil {
+			return fmt.Errorf("error setting transparency from profile: %w", err)
+		}
 	}
 
+	if profile.Attention != "" && profile.Attention != "false" {
+		debugf("  Requesting attention: %q\n", profile.Attention)
+		if err := runRequestAttention(profile.Attention); err != nil {
+			return fmt.Errorf("error requesting attention from profile: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -328,6 +426,7 @@ func listProfileNames() ([]string, error) {
 	for name := range config.Profiles {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 
 	return names, nil
 }
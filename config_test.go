@@ -226,6 +226,66 @@ missing closing bracket
 	}
 }
 
+// TestLoadConfigCustomColors tests the [colors] table of the config file
+func TestLoadConfigCustomColors(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "colors-config.toml")
+
+	configContent := `
+[colors]
+corp-orange = "#f26522"
+night = "midnightblue"
+
+[profiles.brand]
+tab = "corp-orange"
+bg = "night"
+`
+
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+		setCustomColors(nil)
+	}()
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+
+	if len(config.Colors) != 2 {
+		t.Errorf("Expected 2 custom colors, got %d", len(config.Colors))
+	}
+
+	if result := normalizeColor("corp-orange"); result != "f26522" {
+		t.Errorf("Expected corp-orange to resolve to f26522, got %q", result)
+	}
+	if result := normalizeColor("night"); result != "191970" {
+		t.Errorf("Expected night to resolve to 191970, got %q", result)
+	}
+
+	// Invalid custom color values fail the config load
+	invalidContent := `
+[colors]
+broken = "not-a-color"
+`
+	if err := os.WriteFile(configFile, []byte(invalidContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	if _, err := loadConfig(); err == nil || !contains(err.Error(), "[colors]") {
+		t.Errorf("Expected loadConfig() to fail with [colors] error, got: %v", err)
+	}
+}
+
 // TestGetProfile tests retrieving specific profiles
 func TestGetProfile(t *testing.T) {
 	// Create temporary config file
@@ -294,6 +354,69 @@ fg = "white"
 	}
 }
 
+// TestGetProfileComposition tests overlaying a comma-separated list of profiles in order
+func TestGetProfileComposition(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "test-config.toml")
+
+	configContent := `
+[profiles.team]
+tab = "purple"
+fg = "white"
+palette = { red = "#ff5555" }
+
+[profiles.team.vscode]
+fg = "silver"
+
+[profiles.prod]
+tab = "red"
+badge = "PROD"
+
+[profiles.prod.zsh]
+bg = "black"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+
+	info := &TerminalShellInfo{Terminals: []TerminalType{TerminalTypeVSCode}, Shell: ShellTypeZsh, Valid: true}
+	profile, err := getProfileWithTerminalInfo("team, prod", info)
+	if err != nil {
+		t.Fatalf("getProfileWithTerminalInfo() failed: %v", err)
+	}
+	if profile.Tab != "red" || profile.Foreground != "silver" || profile.Background != "black" || profile.Badge != "PROD" {
+		t.Errorf("Profile incorrect: tab=%q, fg=%q, bg=%q, badge=%q", profile.Tab, profile.Foreground, profile.Background, profile.Badge)
+	}
+	if profile.Palette["red"] != "#ff5555" {
+		t.Errorf("Expected the team palette to be kept, got %v", profile.Palette)
+	}
+
+	// Later profiles win
+	profile, err = getProfileWithTerminalInfo("prod,team", info)
+	if err != nil {
+		t.Fatalf("getProfileWithTerminalInfo() failed: %v", err)
+	}
+	if profile.Tab != "purple" || profile.Badge != "PROD" {
+		t.Errorf("Profile incorrect: tab=%q, badge=%q", profile.Tab, profile.Badge)
+	}
+
+	for _, name := range []string{"team,missing", "team,,prod", "team,"} {
+		if _, err := getProfileWithTerminalInfo(name, info); err == nil {
+			t.Errorf("Expected getProfileWithTerminalInfo(%q) to fail", name)
+		}
+	}
+}
+
 // TestApplyProfile tests applying profiles (without actually executing it2setcolor)
 func TestApplyProfile(t *testing.T) {
 	// We can't easily test applyProfile without mocking runSetColor
@@ -356,6 +479,17 @@ func TestOverlayProfile(t *testing.T) {
 	if result3.Tab != "blue" || result3.Foreground != "white" || result3.Background != "black" {
 		t.Errorf("Empty overlay failed: got tab=%q, fg=%q, bg=%q", result3.Tab, result3.Foreground, result3.Background)
 	}
+
+	// Test "default" overlay (resets the target, unlike an unset field)
+	overlay4 := Profile{
+		Foreground: "default",
+		Palette:    map[string]string{"red": "default"},
+	}
+
+	result4 := overlayProfile(Profile{Tab: "blue", Foreground: "white", Palette: map[string]string{"red": "#ff5555"}}, overlay4)
+	if result4.Tab != "blue" || result4.Foreground != "default" || result4.Palette["red"] != "default" {
+		t.Errorf("Default overlay failed: got tab=%q, fg=%q, palette=%v", result4.Tab, result4.Foreground, result4.Palette)
+	}
 }
 
 // TestGetProfileWithSubProfiles tests sub-profile functionality
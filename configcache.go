@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configCacheEnvVar enables the on-disk parsed config cache when set to 1, for
+// shell hooks that run on every prompt
+const configCacheEnvVar = "SET_TAB_COLOR_CACHE"
+
+// configCacheVersion invalidates on-disk caches written with a different Config layout
+const configCacheVersion = 8
+
+// configCacheEntry is a parsed config and the version of the file it came from
+type configCacheEntry struct {
+	Version int
+	Path    string
+	Size    int64
+	ModTime int64 // Unix nanoseconds
+	Config  Config
+}
+
+// configCache holds the last parsed config so repeated loads in one run don't re-read TOML
+var configCache *configCacheEntry
+
+// registerCacheTypes registers the types the TOML decoder puts in the untyped profile
+// tables. It runs on first use rather than in init, so runs without a config file
+// don't pay for it.
+var registerCacheTypes = sync.OnceFunc(func() {
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register([]map[string]interface{}{})
+	gob.Register(time.Time{})
+})
+
+// matches reports whether the entry was parsed from the current version of the file
+func (e *configCacheEntry) matches(path string, info os.FileInfo) bool {
+	return e != nil && e.Version == configCacheVersion && e.Path == path &&
+		e.Size == info.Size() && e.ModTime == info.ModTime().UnixNano()
+}
+
+// decodeConfigCached parses the config file, reusing the in-process cache and, when
+// $SET_TAB_COLOR_CACHE is 1, a parsed copy on disk. Both are keyed by the file's
+// size and modification time.
+func decodeConfigCached(path string, info os.FileInfo) (*Config, error) {
+	if configCache.matches(path, info) {
+		config := configCache.Config
+		return &config, nil
+	}
+
+	diskCache := os.Getenv(configCacheEnvVar) == "1"
+	if diskCache {
+		if entry := readConfigCacheFile(); entry.matches(path, info) {
+			configCache = entry
+			config := entry.Config
+			return &config, nil
+		}
+	}
+
+	var config Config
+	meta, err := toml.DecodeFile(path, &config)
+	if err != nil {
+		return nil, err
+	}
+	if errs := finishDecoding(meta, &config); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	// Only when the file is parsed, so a prompt hook doesn't repeat them every time
+	for _, err := range unknownSubProfileKeys(&config) {
+		warnf("%s: %v", path, err)
+	}
+
+	configCache = &configCacheEntry{
+		Version: configCacheVersion,
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Config:  config,
+	}
+	if diskCache {
+		if err := writeConfigCacheFile(configCache); err != nil {
+			debugf("Could not write config cache: %v\n", err)
+		}
+	}
+	return &config, nil
+}
+
+// getConfigCachePath returns the on-disk parsed config cache
+func getConfigCachePath() (string, error) {
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "config.gob"), nil
+}
+
+// readConfigCacheFile returns the on-disk cache entry, or nil if it is missing or unreadable
+func readConfigCacheFile() *configCacheEntry {
+	cachePath, err := getConfigCachePath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil
+	}
+	var entry configCacheEntry
+	registerCacheTypes()
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// writeConfigCacheFile stores a cache entry on disk, replacing the previous one atomically
+func writeConfigCacheFile(entry *configCacheEntry) error {
+	cachePath, err := getConfigCachePath()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	registerCacheTypes()
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(cachePath), ".config-*.gob")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cachePath)
+}
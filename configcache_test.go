@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestConfigCache tests that an unchanged config file is not parsed again
+func TestConfigCache(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+	defer func() { configCache = nil }()
+
+	stamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeConfig := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if err := os.Chtimes(configFile, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+
+	writeConfig("[profiles.dev]\ntab = \"red\"\n", stamp)
+	if config, err := loadConfig(); err != nil || config.Profiles["dev"] == nil {
+		t.Fatalf("loadConfig() = %v, %v", config, err)
+	}
+
+	// Same size and modification time: the cached parse is reused
+	writeConfig("[profiles.xyz]\ntab = \"red\"\n", stamp)
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	if config.Profiles["dev"] == nil {
+		t.Errorf("Expected the cached config, got profiles %v", config.Profiles)
+	}
+
+	// A new modification time invalidates it
+	writeConfig("[profiles.xyz]\ntab = \"red\"\n", stamp.Add(time.Second))
+	config, err = loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	if config.Profiles["xyz"] == nil || config.Profiles["dev"] != nil {
+		t.Errorf("Expected the changed config, got profiles %v", config.Profiles)
+	}
+}
+
+// TestConfigCacheOnDisk tests reusing a parsed config across runs with $SET_TAB_COLOR_CACHE
+func TestConfigCacheOnDisk(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+
+	for key, value := range map[string]string{
+		"SET_TAB_COLOR_CONFIG": configFile,
+		configCacheEnvVar:      "1",
+		"XDG_CACHE_HOME":       filepath.Join(tempDir, "cache"),
+		"HOME":                 tempDir, // os.UserCacheDir uses ~/Library/Caches on macOS
+	} {
+		original, had := os.LookupEnv(key)
+		os.Setenv(key, value)
+		defer func(key, original string, had bool) {
+			if had {
+				os.Setenv(key, original)
+			} else {
+				os.Unsetenv(key)
+			}
+		}(key, original, had)
+	}
+	defer func() { configCache = nil }()
+
+	stamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	content := "[profiles.dev]\ntab = \"red\"\npriority = 3\n\n[profiles.dev.zsh]\nfg = \"white\"\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	os.Chtimes(configFile, stamp, stamp)
+
+	configCache = nil
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	cachePath, err := getConfigCachePath()
+	if err != nil {
+		t.Fatalf("getConfigCachePath() failed: %v", err)
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("Expected a cache file: %v", err)
+	}
+
+	// A new process starts with an empty in-process cache and reads the file cache
+	configCache = nil
+	profile, err := getProfileWithTerminalInfo("dev", &TerminalShellInfo{Shell: ShellTypeZsh})
+	if err != nil {
+		t.Fatalf("Failed to load profile from the cache: %v", err)
+	}
+	if profile.Tab != "red" || profile.Foreground != "white" {
+		t.Errorf("Unexpected profile from the cache: %+v", profile)
+	}
+	// Decoded profiles must survive the round trip through the file
+	if dev := configCache.Config.Profiles["dev"]; dev == nil || dev.Priority != 3 {
+		t.Errorf("Expected priority 3, got %#v", dev)
+	}
+
+	// The file cache is used even when the in-process one is empty
+	if err := os.WriteFile(configFile, []byte(strings.Replace(content, "dev", "xyz", -1)), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	os.Chtimes(configFile, stamp, stamp)
+	configCache = nil
+	if config, err := loadConfig(); err != nil || config.Profiles["dev"] == nil {
+		t.Errorf("Expected the config from the file cache, got %v, %v", config, err)
+	}
+
+	// A corrupt cache falls back to parsing the file
+	if err := os.WriteFile(cachePath, []byte("garbage"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt cache: %v", err)
+	}
+	configCache = nil
+	if config, err := loadConfig(); err != nil || config.Profiles["xyz"] == nil {
+		t.Errorf("Expected a fresh parse after a corrupt cache, got %v, %v", config, err)
+	}
+}
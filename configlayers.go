@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configPathList collects repeated -config flags
+type configPathList []string
+
+// String returns the paths as given, for flag's usage output
+func (l *configPathList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set adds a -config path
+func (l *configPathList) Set(path string) error {
+	if strings.TrimSpace(path) == "" {
+		return fmt.Errorf("empty config path")
+	}
+	*l = append(*l, path)
+	return nil
+}
+
+// configPaths holds the -config files, merged in order. When empty, the file from
+// $SET_TAB_COLOR_CONFIG or the default location is used.
+var configPaths configPathList
+
+// getConfigPaths returns the config files to load, in the order they are merged
+func getConfigPaths() ([]string, error) {
+	if len(configPaths) > 0 {
+		return configPaths, nil
+	}
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return []string{configPath}, nil
+}
+
+// decodeConfigLayers merges several config files, each one's tables key by key over
+// the ones before it; other values, including arrays such as [[watch.rules]], are
+// replaced. read_only is only taken from the last file, the one editing commands
+// change. Each error names the file that set the offending value.
+func decodeConfigLayers(paths []string) (*Config, error) {
+	merged, layers, err := mergeConfigLayers(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	meta, err := toml.Decode(merged, &config)
+	if err != nil {
+		return nil, err
+	}
+	if errs := finishDecoding(meta, &config); len(errs) > 0 {
+		located := make([]error, len(errs))
+		for i, err := range errs {
+			located[i] = fmt.Errorf("%s: %v", configLayerFor(paths, layers, err.Path), err)
+		}
+		return nil, errors.Join(located...)
+	}
+	for _, err := range unknownSubProfileKeys(&config) {
+		warnf("%s: %v", configLayerFor(paths, layers, err.Path), err)
+	}
+	return &config, nil
+}
+
+// mergeConfigLayers reads config files and merges them in order, returning the
+// result as TOML along with each file's own tables
+func mergeConfigLayers(paths []string) (string, []map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	layers := make([]map[string]interface{}, len(paths))
+	for i, path := range paths {
+		var layer map[string]interface{}
+		if _, err := toml.DecodeFile(path, &layer); err != nil {
+			return "", nil, fmt.Errorf("%s: %v", path, err)
+		}
+		layers[i] = copyConfigTable(layer)
+		if i < len(paths)-1 {
+			delete(layer, "read_only")
+		}
+		mergeConfigTables(merged, layer)
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(merged); err != nil {
+		return "", nil, err
+	}
+	return buf.String(), layers, nil
+}
+
+// copyConfigTable copies a decoded TOML table and its sub-tables, which merging
+// would otherwise change
+func copyConfigTable(table map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(table))
+	for key, value := range table {
+		if sub, ok := value.(map[string]interface{}); ok {
+			value = copyConfigTable(sub)
+		}
+		copied[key] = value
+	}
+	return copied
+}
+
+// configLayerFor returns the file that set a key: the last one defining it, or
+// else the last one defining the closest enclosing table
+func configLayerFor(paths []string, layers []map[string]interface{}, key []string) string {
+	best, bestDepth := paths[len(paths)-1], -1
+	for i, layer := range layers {
+		depth := 0
+		for table := layer; depth < len(key); depth++ {
+			value, ok := table[key[depth]]
+			if !ok {
+				break
+			}
+			if table, ok = value.(map[string]interface{}); !ok {
+				depth++
+				break
+			}
+		}
+		if depth > 0 && depth >= bestDepth {
+			best, bestDepth = paths[i], depth
+		}
+	}
+	return best
+}
+
+// mergeConfigTables overlays one decoded TOML table on another, merging the tables
+// both have
+func mergeConfigTables(base, overlay map[string]interface{}) {
+	for key, value := range overlay {
+		if table, ok := value.(map[string]interface{}); ok {
+			if baseTable, ok := base[key].(map[string]interface{}); ok {
+				mergeConfigTables(baseTable, table)
+				continue
+			}
+		}
+		base[key] = value
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoadConfigLayers tests merging several -config files in order
+func TestLoadConfigLayers(t *testing.T) {
+	tempDir := t.TempDir()
+	team := filepath.Join(tempDir, "team.toml")
+	personal := filepath.Join(tempDir, "personal.toml")
+	if err := os.WriteFile(team, []byte(`read_only = true
+
+[colors]
+corp = "#0055aa"
+
+[watch]
+default = "prod"
+rules = [{ dir = "/srv", profile = "prod" }]
+
+[profiles.prod]
+tab = "red"
+bg = "black"
+`), 0644); err != nil {
+		t.Fatalf("Failed to write team config: %v", err)
+	}
+	if err := os.WriteFile(personal, []byte(`[watch]
+rules = [{ dir = "/home", profile = "me" }]
+
+[profiles.prod]
+tab = "orange"
+
+[profiles.me]
+tab = "corp"
+`), 0644); err != nil {
+		t.Fatalf("Failed to write personal config: %v", err)
+	}
+
+	defer func() { configPaths = nil }()
+	configPaths = configPathList{team, personal}
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	if config.ReadOnly {
+		t.Error("Expected read_only to come only from the last file")
+	}
+	if prod := config.Profiles["prod"]; prod == nil || prod.Tab.Value != "orange" || prod.Background.Value != "black" {
+		t.Errorf("Expected prod merged key by key, got %+v", prod)
+	}
+	if config.Watch.Default != "prod" || len(config.Watch.Rules) != 1 || config.Watch.Rules[0].Profile != "me" {
+		t.Errorf("Expected the watch table merged and its rules replaced, got %+v", config.Watch)
+	}
+	profile, err := getProfileWithTerminalInfo("me", &TerminalShellInfo{})
+	if err != nil || profile.Tab != "corp" {
+		t.Errorf("Expected the team color usable from the personal config, got %+v, %v", profile, err)
+	}
+	if path, err := getConfigPath(); err != nil || path != personal {
+		t.Errorf("getConfigPath() = %q, %v, expected the last file", path, err)
+	}
+
+	// So does a layer with an invalid value
+	if err := os.WriteFile(team, []byte("[profiles.prod]\ntab = \"red\"\npriority = \"high\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write team config: %v", err)
+	}
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), team+": profiles.prod.priority must be an integer") {
+		t.Errorf("Expected the error to name the team config, got %v", err)
+	}
+
+	// A broken layer names its file
+	if err := os.WriteFile(team, []byte("[profiles.prod\n"), 0644); err != nil {
+		t.Fatalf("Failed to write team config: %v", err)
+	}
+	if _, err := loadConfig(); err == nil || exitCode(err) != exitConfigError || !strings.Contains(err.Error(), team+":") {
+		t.Errorf("Expected a config error for a broken layer, got %v", err)
+	}
+
+	// A missing -config file is an error, unlike a missing default config
+	missing := filepath.Join(tempDir, "missing.toml")
+	for _, paths := range []configPathList{{missing}, {personal, missing}} {
+		configPaths = paths
+		if _, err := loadConfig(); err == nil || exitCode(err) != exitConfigError || !strings.Contains(err.Error(), missing) {
+			t.Errorf("Expected a config error naming %s for %v, got %v", missing, paths, err)
+		}
+	}
+}
+
+// TestConfigPathList tests collecting repeated -config flags
+func TestConfigPathList(t *testing.T) {
+	var paths configPathList
+	for _, path := range []string{"team.toml", "me.toml"} {
+		if err := paths.Set(path); err != nil {
+			t.Fatalf("Set(%q) failed: %v", path, err)
+		}
+	}
+	if paths.String() != "team.toml,me.toml" {
+		t.Errorf("String() = %q", paths.String())
+	}
+	if err := paths.Set(" "); err == nil {
+		t.Error("Expected an error for an empty path")
+	}
+}
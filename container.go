@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+)
+
+// Inside a container the process tree ends at the container's PID 1, so the terminal
+// running docker exec or the devcontainer is never an ancestor.
+
+// containerEnvVars are set inside containers: "container" by podman and
+// systemd-nspawn, REMOTE_CONTAINERS by VS Code devcontainers, and
+// KUBERNETES_SERVICE_HOST in every Kubernetes pod
+var containerEnvVars = []string{"container", "REMOTE_CONTAINERS", "KUBERNETES_SERVICE_HOST"}
+
+// containerMarkerFiles are created by container runtimes in the container's root
+var containerMarkerFiles = []string{"/.dockerenv", "/run/.containerenv"}
+
+// isContainer reports whether we run inside a container
+func isContainer() bool {
+	for _, key := range containerEnvVars {
+		if getDetectionEnv(key) != "" {
+			return true
+		}
+	}
+	if getFakeEnvironment() != nil {
+		return false
+	}
+	for _, path := range containerMarkerFiles {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// withContainer appends the container pseudo-terminal to a detected chain when
+// running inside a container
+func withContainer(info TerminalShellInfo) TerminalShellInfo {
+	if isContainer() {
+		info.Terminals = append(info.Terminals, TerminalTypeContainer)
+	}
+	return info
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestDetectContainer tests adding the container layer and falling back to the
+// variables passed into the container when the process tree ends at its boundary
+func TestDetectContainer(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		chain     []string
+		terminals []TerminalType
+	}{
+		{"docker exec with TERM_PROGRAM", map[string]string{"container": "docker", "TERM_PROGRAM": "iTerm.app"},
+			[]string{"set-tab-color", "bash"}, []TerminalType{TerminalTypeITerm2, TerminalTypeContainer}},
+		{"devcontainer", map[string]string{"REMOTE_CONTAINERS": "true", "TERM_PROGRAM": "vscode"},
+			[]string{"set-tab-color", "zsh"}, []TerminalType{TerminalTypeVSCode, TerminalTypeContainer}},
+		{"nothing passed in", map[string]string{"container": "podman"},
+			[]string{"set-tab-color", "bash"}, []TerminalType{TerminalTypeContainer}},
+		{"truncated walk under a non-shell parent", map[string]string{"container": "docker", "TERM_PROGRAM": "vscode"},
+			[]string{"set-tab-color", "make", "bash"}, []TerminalType{TerminalTypeVSCode, TerminalTypeContainer}},
+		{"kubectl exec", map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"},
+			[]string{"set-tab-color", "sh"}, []TerminalType{TerminalTypeContainer}},
+		{"walk inside the container", map[string]string{"container": "docker"},
+			[]string{"set-tab-color", "zsh", "tmux: server"}, []TerminalType{TerminalTypeTmux, TerminalTypeContainer}},
+		{"not a container", map[string]string{"TERM_PROGRAM": "vscode"},
+			[]string{"set-tab-color", "make", "bash"}, []TerminalType{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFakeEnvironment(&FakeEnvironment{Env: tt.env, ProcessChain: tt.chain})
+			defer setFakeEnvironment(nil)
+
+			info := detectTerminalAndShell("")
+			if len(info.Terminals) != len(tt.terminals) {
+				t.Fatalf("Expected terminals %v, got %v", tt.terminals, info.Terminals)
+			}
+			for i := range tt.terminals {
+				if info.Terminals[i] != tt.terminals[i] {
+					t.Errorf("Expected terminals %v, got %v", tt.terminals, info.Terminals)
+					break
+				}
+			}
+		})
+	}
+}
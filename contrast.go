@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// autoColorName is the foreground value that picks a readable color for the background
+const autoColorName = "auto"
+
+// ContrastConfig is the [contrast] table of the config file: the pair of
+// foreground colors that fg = "auto" chooses between
+type ContrastConfig struct {
+	Dark  string `toml:"dark"`  // Used on light backgrounds
+	Light string `toml:"light"` // Used on dark backgrounds
+}
+
+// contrastPair holds the resolved hex colors that fg = "auto" chooses between
+var contrastPair = ContrastConfig{Dark: "000000", Light: "ffffff"}
+
+// setContrastPair replaces the auto foreground pair, keeping black and white for unset colors
+func setContrastPair(pair ContrastConfig) error {
+	resolved := ContrastConfig{Dark: "000000", Light: "ffffff"}
+	for _, field := range []struct {
+		name, value string
+		dest        *string
+	}{
+		{"dark", pair.Dark, &resolved.Dark},
+		{"light", pair.Light, &resolved.Light},
+	} {
+		if field.value == "" {
+			continue
+		}
+		hex := normalizeColor(field.value)
+		if hex == "" || hex == "default" {
+			return fmt.Errorf("invalid value %q for %s", field.value, field.name)
+		}
+		*field.dest = hex
+	}
+
+	contrastPair = resolved
+	return nil
+}
+
+// relativeLuminance returns the WCAG 2 relative luminance of a hex color
+func relativeLuminance(hex string) (float64, error) {
+	r, g, b, err := hexToRGB(hex)
+	if err != nil {
+		return 0, err
+	}
+
+	linear := func(c int) float64 {
+		v := float64(c) / 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b), nil
+}
+
+// contrastRatio returns the WCAG 2 contrast ratio between two hex colors, from 1 to 21
+func contrastRatio(a, b string) (float64, error) {
+	la, err := relativeLuminance(a)
+	if err != nil {
+		return 0, err
+	}
+	lb, err := relativeLuminance(b)
+	if err != nil {
+		return 0, err
+	}
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05), nil
+}
+
+// pickContrastColor returns whichever color of the contrast pair reads better on
+// background. Ties go to the dark color.
+func pickContrastColor(background string) (string, error) {
+	dark, err := contrastRatio(contrastPair.Dark, background)
+	if err != nil {
+		return "", err
+	}
+	light, err := contrastRatio(contrastPair.Light, background)
+	if err != nil {
+		return "", err
+	}
+	if light > dark {
+		return contrastPair.Light, nil
+	}
+	return contrastPair.Dark, nil
+}
+
+// autoForegroundBackground returns the color an "auto" foreground is checked
+// against: the background, then the theme's background, then the tab color
+func autoForegroundBackground(profile *Profile) (string, error) {
+	candidates := []string{profile.Background}
+	if profile.Theme != "" {
+		theme, err := lookupTheme(profile.Theme)
+		if err != nil {
+			return "", err
+		}
+		candidates = append(candidates, theme.Background)
+	}
+	candidates = append(candidates, profile.Tab)
+
+	for _, value := range candidates {
+		// "default" leaves the color to the terminal, so it can't be checked against
+		if value == "" || value == "default" {
+			continue
+		}
+		hex := normalizeColor(value)
+		if hex == "" {
+			return "", withExitCode(exitUnknownColor, fmt.Errorf("unknown color: %s", value))
+		}
+		return hex, nil
+	}
+	return "", fmt.Errorf("fg = %q needs a bg, theme, or tab color to contrast with", autoColorName)
+}
+
+// resolveAutoForeground replaces an "auto" foreground with the contrast pair
+// color that is easier to read on the profile's background
+func resolveAutoForeground(profile *Profile) error {
+	if profile.Foreground != autoColorName {
+		return nil
+	}
+
+	background, err := autoForegroundBackground(profile)
+	if err != nil {
+		return err
+	}
+	profile.Foreground, err = pickContrastColor(background)
+	if err != nil {
+		return err
+	}
+
+	debugf("Picked foreground %s for contrast with %s\n", profile.Foreground, background)
+	return nil
+}
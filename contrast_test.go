@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestContrastRatio tests the WCAG contrast ratio against reference values
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected float64
+	}{
+		{"000000", "ffffff", 21},
+		{"ffffff", "000000", 21},
+		{"777777", "777777", 1},
+		{"767676", "ffffff", 4.54},
+	}
+
+	for _, test := range tests {
+		ratio, err := contrastRatio(test.a, test.b)
+		if err != nil {
+			t.Fatalf("contrastRatio(%q, %q) failed: %v", test.a, test.b, err)
+		}
+		if math.Abs(ratio-test.expected) > 0.01 {
+			t.Errorf("contrastRatio(%q, %q) = %.2f, expected %.2f", test.a, test.b, ratio, test.expected)
+		}
+	}
+}
+
+// TestResolveAutoForeground tests picking the readable foreground for a profile
+func TestResolveAutoForeground(t *testing.T) {
+	defer setContrastPair(ContrastConfig{})
+
+	tests := []struct {
+		name        string
+		profile     Profile
+		pair        ContrastConfig
+		expected    string
+		expectError bool
+	}{
+		{"light bg", Profile{Foreground: "auto", Background: "yellow"}, ContrastConfig{}, "000000", false},
+		{"dark bg", Profile{Foreground: "auto", Background: "navy"}, ContrastConfig{}, "ffffff", false},
+		{"bg before tab", Profile{Foreground: "auto", Background: "navy", Tab: "yellow"}, ContrastConfig{}, "ffffff", false},
+		{"tab only", Profile{Foreground: "auto", Tab: "yellow"}, ContrastConfig{}, "000000", false},
+		{"default bg uses tab", Profile{Foreground: "auto", Background: "default", Tab: "navy"}, ContrastConfig{}, "ffffff", false},
+		{"theme bg", Profile{Foreground: "auto", Theme: "solarized-light", Tab: "navy"}, ContrastConfig{}, "000000", false},
+		{"custom pair", Profile{Foreground: "auto", Background: "navy"}, ContrastConfig{Dark: "#222222", Light: "ivory"}, "fffff0", false},
+		{"fixed fg untouched", Profile{Foreground: "red", Background: "navy"}, ContrastConfig{}, "red", false},
+		{"nothing to contrast with", Profile{Foreground: "auto"}, ContrastConfig{}, "", true},
+		{"unknown bg", Profile{Foreground: "auto", Background: "notacolor"}, ContrastConfig{}, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := setContrastPair(test.pair); err != nil {
+				t.Fatalf("setContrastPair failed: %v", err)
+			}
+			profile := test.profile
+			err := resolveAutoForeground(&profile)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("Expected error, got foreground %q", profile.Foreground)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if profile.Foreground != test.expected {
+				t.Errorf("Foreground = %q, expected %q", profile.Foreground, test.expected)
+			}
+		})
+	}
+}
+
+// TestContrastConfig tests loading the auto foreground pair from the config file
+func TestContrastConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+	defer setContrastPair(ContrastConfig{})
+
+	content := `[contrast]
+dark = "#1d1f21"
+
+[profiles.dev]
+tab = "gold"
+fg = "auto"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	profile, err := getProfileWithTerminalInfo("dev", &TerminalShellInfo{})
+	if err != nil {
+		t.Fatalf("Failed to load profile: %v", err)
+	}
+	if err := resolveAutoForeground(profile); err != nil {
+		t.Fatalf("resolveAutoForeground failed: %v", err)
+	}
+	if profile.Foreground != "1d1f21" {
+		t.Errorf("Foreground = %q, expected the configured dark color", profile.Foreground)
+	}
+	if contrastPair.Light != "ffffff" {
+		t.Errorf("Light = %q, expected white when unset", contrastPair.Light)
+	}
+
+	if err := os.WriteFile(configFile, []byte("[contrast]\nlight = \"notacolor\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := loadConfig(); err == nil || !contains(err.Error(), "[contrast]") {
+		t.Errorf("Expected [contrast] error, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// profileDigest fingerprints every setting a profile applies, so an unchanged profile
+// is recognized even when it sets more than the state file records
+func profileDigest(profile *Profile) string {
+	settings := *profile
+	settings.Tab = colorblindSafeTab(settings.Tab)
+	settings.Priority = 0
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// alreadyApplied reports whether the last apply recorded for this session went to
+// the same tty with the same profile and settings. Prompt hooks rerun set-tab-color
+// on every prompt; skipping the unchanged ones saves writing the same escape
+// sequences and running it2setcolor again. -force and -dry-run always apply.
+func alreadyApplied(profileName string, profile *Profile) bool {
+	if forceApply || dryRunMode || usesSessionTargeting() || usesTmuxTargeting() {
+		return false
+	}
+	tty := ttyName()
+	if tty == "" {
+		return false
+	}
+	state, err := loadSessionState()
+	if err != nil || state == nil || state.Digest == "" {
+		return false
+	}
+	return state.TTY == tty && state.Profile == profileName && state.Digest == profileDigest(profile)
+}
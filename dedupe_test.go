@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+// TestAlreadyApplied tests recognizing a profile the tty already shows
+func TestAlreadyApplied(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("SET_TAB_COLOR_CONFIG", tempDir+"/missing.toml")
+	t.Setenv("XDG_STATE_HOME", tempDir)
+	t.Setenv("XDG_CACHE_HOME", tempDir)
+	t.Setenv("ITERM_SESSION_ID", "w0t0p0:dedupe-test")
+	ttyOutputPath = "/dev/ttys003"
+	defer func() { ttyOutputPath = "" }()
+
+	profile := &Profile{Tab: "red", Badge: "PROD", Palette: map[string]string{"red": "ff0000"}}
+	if alreadyApplied("prod", profile) {
+		t.Fatal("Expected nothing applied before the first run")
+	}
+
+	recordAppliedState("prod", profile)
+	if !alreadyApplied("prod", &Profile{Tab: "red", Badge: "PROD", Palette: map[string]string{"red": "ff0000"}}) {
+		t.Error("Expected the same profile to be recognized")
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		changed *Profile
+	}{
+		{"other profile", "dev", profile},
+		{"changed color", "prod", &Profile{Tab: "orange", Badge: "PROD", Palette: map[string]string{"red": "ff0000"}}},
+		{"changed badge", "prod", &Profile{Tab: "red", Badge: "STAGING", Palette: map[string]string{"red": "ff0000"}}},
+		{"changed palette", "prod", &Profile{Tab: "red", Badge: "PROD", Palette: map[string]string{"red": "cc0000"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if alreadyApplied(tt.profile, tt.changed) {
+				t.Errorf("Expected %s to be applied again", tt.name)
+			}
+		})
+	}
+
+	// Another tty, -force, and -dry-run always apply
+	ttyOutputPath = "/dev/ttys004"
+	if alreadyApplied("prod", profile) {
+		t.Error("Expected another tty to be applied again")
+	}
+	ttyOutputPath = "/dev/ttys003"
+	forceApply = true
+	if alreadyApplied("prod", profile) {
+		t.Error("Expected -force to apply again")
+	}
+	forceApply = false
+	dryRunMode = true
+	if alreadyApplied("prod", profile) {
+		t.Error("Expected -dry-run to apply again")
+	}
+	dryRunMode = false
+}
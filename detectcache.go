@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// detectCacheEnvVar disables the per-tty detection cache when set to 0
+const detectCacheEnvVar = "SET_TAB_COLOR_DETECT_CACHE"
+
+// detectCacheEntry is a process tree detection result for one tty. It is only reused
+// by the same shell on the same tty since the last boot, since ttys are recycled
+// when sessions close.
+type detectCacheEntry struct {
+	TTY       string         `json:"tty"`
+	BootID    string         `json:"boot_id"`
+	ShellPID  int            `json:"shell_pid"`
+	Rules     string         `json:"rules,omitempty"` // [detect] rules the result was made with
+	Terminals []TerminalType `json:"terminals"`
+	Shell     ShellType      `json:"shell"`
+	Valid     bool           `json:"valid"`
+}
+
+// getBootID identifies the current boot, so cache entries don't survive a reboot
+func getBootID() (string, error) {
+	if data, err := os.ReadFile("/proc/sys/kernel/random/boot_id"); err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	bootTime, err := host.BootTime()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d", bootTime), nil
+}
+
+// getDetectCachePath returns the cache file for a tty such as "/dev/ttys003"
+func getDetectCachePath(tty string) (string, error) {
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimPrefix(tty, "/dev/")
+	return filepath.Join(cacheDir, "detect", sanitizeFileName(name)+".json"), nil
+}
+
+// newDetectCacheEntry builds the cache entry for the current shell on a tty
+func newDetectCacheEntry(tty string, info TerminalShellInfo) (*detectCacheEntry, error) {
+	bootID, err := getBootID()
+	if err != nil {
+		return nil, err
+	}
+	return &detectCacheEntry{
+		TTY:       tty,
+		BootID:    bootID,
+		ShellPID:  shellPID(),
+		Rules:     detectRulesKey(),
+		Terminals: info.Terminals,
+		Shell:     info.Shell,
+		Valid:     info.Valid,
+	}, nil
+}
+
+// loadDetectCache returns the cached detection result for a tty if it was saved
+// by the current shell during this boot, with the current [detect] rules
+func loadDetectCache(tty string) (TerminalShellInfo, bool) {
+	cachePath, err := getDetectCachePath(tty)
+	if err != nil {
+		return TerminalShellInfo{}, false
+	}
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return TerminalShellInfo{}, false
+	}
+	var cached detectCacheEntry
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return TerminalShellInfo{}, false
+	}
+
+	current, err := newDetectCacheEntry(tty, TerminalShellInfo{})
+	if err != nil || cached.TTY != current.TTY || cached.BootID != current.BootID || cached.ShellPID != current.ShellPID ||
+		cached.Rules != current.Rules {
+		return TerminalShellInfo{}, false
+	}
+	return TerminalShellInfo{Terminals: cached.Terminals, Shell: cached.Shell, Valid: cached.Valid}, true
+}
+
+// saveDetectCache stores a detection result for a tty, replacing any earlier one
+func saveDetectCache(tty string, info TerminalShellInfo) error {
+	entry, err := newDetectCacheEntry(tty, info)
+	if err != nil {
+		return err
+	}
+	cachePath, err := getDetectCachePath(tty)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return fmt.Errorf("could not create detection cache directory: %v", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmpPath := cachePath + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("could not write detection cache: %v", err)
+	}
+	return os.Rename(tmpPath, cachePath)
+}
+
+// detectCacheTTY returns the tty to cache detection results for, or "" when the
+// cache shouldn't be used
+func detectCacheTTY() string {
+	if getFakeEnvironment() != nil || os.Getenv(detectCacheEnvVar) == "0" {
+		return ""
+	}
+	return ttyName()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestDetectCache tests reusing a detection result for the same shell on the same tty
+func TestDetectCache(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", tempDir)
+
+	if _, ok := loadDetectCache("/dev/ttys003"); ok {
+		t.Error("Expected no cached result before saving")
+	}
+
+	info := TerminalShellInfo{Terminals: []TerminalType{TerminalTypeTmux, TerminalTypeITerm2}, Shell: ShellTypeZsh, Valid: true}
+	if err := saveDetectCache("/dev/ttys003", info); err != nil {
+		t.Fatalf("saveDetectCache() failed: %v", err)
+	}
+
+	cached, ok := loadDetectCache("/dev/ttys003")
+	if !ok {
+		t.Fatal("Expected a cached result")
+	}
+	if len(cached.Terminals) != 2 || cached.Terminals[0] != TerminalTypeTmux || cached.Terminals[1] != TerminalTypeITerm2 ||
+		cached.Shell != ShellTypeZsh || !cached.Valid {
+		t.Errorf("Unexpected cached result: %+v", cached)
+	}
+
+	if _, ok := loadDetectCache("/dev/ttys004"); ok {
+		t.Error("Expected no cached result for another tty")
+	}
+
+	// Entries from another shell or boot are ignored
+	cachePath, err := getDetectCachePath("ttys003")
+	if err != nil {
+		t.Fatalf("getDetectCachePath() failed: %v", err)
+	}
+	for _, content := range []string{
+		`{"tty":"/dev/ttys003","boot_id":"other-boot","shell_pid":` + strconv.Itoa(os.Getppid()) + `,"shell":"zsh"}`,
+		`{"tty":"/dev/ttys003","boot_id":"","shell_pid":1,"shell":"zsh"}`,
+		`not json`,
+	} {
+		if err := os.WriteFile(cachePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write cache: %v", err)
+		}
+		if _, ok := loadDetectCache("/dev/ttys003"); ok {
+			t.Errorf("Expected cache entry %s to be ignored", content)
+		}
+	}
+
+	if filepath.Dir(cachePath) != filepath.Join(tempDir, "set-tab-color", "detect") {
+		t.Errorf("Unexpected cache location %s", cachePath)
+	}
+}
+
+// TestDetectCacheTTY tests when the detection cache is used
+func TestDetectCacheTTY(t *testing.T) {
+	setFakeEnvironment(&FakeEnvironment{Env: map[string]string{}, ProcessChain: []string{"set-tab-color", "zsh"}})
+	if tty := detectCacheTTY(); tty != "" {
+		t.Errorf("Expected no cache with a fake environment, got %q", tty)
+	}
+	setFakeEnvironment(nil)
+
+	original := os.Getenv(detectCacheEnvVar)
+	os.Setenv(detectCacheEnvVar, "0")
+	defer os.Setenv(detectCacheEnvVar, original)
+	if tty := detectCacheTTY(); tty != "" {
+		t.Errorf("Expected no cache when disabled, got %q", tty)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DetectConfig holds the [detect] table: extra process names to recognize while
+// walking the process tree, mapped to the sub-profile key to use for them
+type DetectConfig struct {
+	Terminals map[string]string `toml:"terminals"` // e.g. alacritty = "alacritty", "mosh-server" = "ssh"
+	Shells    map[string]string `toml:"shells"`    // e.g. "nu" = "nu"
+	Shell     string            `toml:"shell"`     // Shell sub-profile to use instead of the detected shell
+}
+
+// detectRule maps a process name to a terminal or shell sub-profile key
+type detectRule struct {
+	process string
+	key     string
+}
+
+// Rules from the [detect] table, checked before the built-in process names, and
+// its shell override
+var (
+	customTerminalRules []detectRule
+	customShellRules    []detectRule
+	configShell         ShellType
+)
+
+// subProfileKeyPattern matches the keys usable for custom terminals and shells
+var subProfileKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// reservedProfileKeys are profile keys that can't name a sub-profile
+var reservedProfileKeys = []string{
+	"tab", "fg", "bg", "preset", "theme", "palette", "badge", "title", "attention", "iterm_profile", "transparency", "blur", "priority", "secret", "sticky", "notify",
+}
+
+// checkSubProfileKey reports a key that can't be used for a custom terminal or shell
+func checkSubProfileKey(key string) error {
+	if !subProfileKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid sub-profile key %q (use lowercase letters, digits, - and _)", key)
+	}
+	if containsString(reservedProfileKeys, key) {
+		return fmt.Errorf("%q is a profile property and can't name a sub-profile", key)
+	}
+	for _, appearance := range knownAppearances {
+		if key == string(appearance) {
+			return fmt.Errorf("%q is reserved for the %s mode sub-profile", key, key)
+		}
+	}
+	if key == defaultSubProfileKey {
+		return fmt.Errorf("%q is reserved for the catch-all sub-profile", key)
+	}
+	if key == rootSubProfileKey {
+		return fmt.Errorf("%q is reserved for the root sub-profile", key)
+	}
+	return nil
+}
+
+// buildDetectRules checks one mapping of the [detect] table and sorts it by process name
+func buildDetectRules(table string, mapping map[string]string) ([]detectRule, error) {
+	var rules []detectRule
+	for process, key := range mapping {
+		if strings.TrimSpace(process) == "" {
+			return nil, fmt.Errorf("empty process name in %s", table)
+		}
+		if err := checkSubProfileKey(key); err != nil {
+			return nil, fmt.Errorf("%s.%q: %v", table, process, err)
+		}
+		rules = append(rules, detectRule{process: process, key: key})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].process < rules[j].process })
+	return rules, nil
+}
+
+// setDetectRules replaces the custom detection rules and the shell override
+func setDetectRules(config DetectConfig) error {
+	terminals, err := buildDetectRules("terminals", config.Terminals)
+	if err != nil {
+		return err
+	}
+	shells, err := buildDetectRules("shells", config.Shells)
+	if err != nil {
+		return err
+	}
+	for _, terminal := range terminals {
+		for _, shell := range shells {
+			if terminal.key == shell.key {
+				return fmt.Errorf("%q is used for both a terminal and a shell", terminal.key)
+			}
+		}
+		for _, shell := range knownShellTypes {
+			if terminal.key == string(shell) {
+				return fmt.Errorf("terminals.%q: %q is a shell", terminal.process, terminal.key)
+			}
+		}
+	}
+	for _, shell := range shells {
+		for _, terminal := range knownTerminalTypes {
+			if shell.key == string(terminal) {
+				return fmt.Errorf("shells.%q: %q is a terminal", shell.process, shell.key)
+			}
+		}
+	}
+
+	shell := ShellType(config.Shell)
+	if config.Shell != "" && !containsShell(knownShellTypes, shell) {
+		custom := false
+		for _, rule := range shells {
+			custom = custom || rule.key == config.Shell
+		}
+		if !custom {
+			return fmt.Errorf("shell: unknown shell type %q (expected one of the shells or a key from [detect.shells])", config.Shell)
+		}
+	}
+
+	customTerminalRules, customShellRules, configShell = terminals, shells, shell
+	return nil
+}
+
+// matchCustomRule returns the key of the first rule matching a process name
+func matchCustomRule(rules []detectRule, name string) (string, bool) {
+	for _, rule := range rules {
+		if matchesTerminalName(name, rule.process, true) {
+			return rule.key, true
+		}
+	}
+	return "", false
+}
+
+// allTerminalTypes returns the built-in terminal types followed by custom ones
+func allTerminalTypes() []TerminalType {
+	terminals := append([]TerminalType{}, knownTerminalTypes...)
+	for _, rule := range customTerminalRules {
+		if !containsTerminal(terminals, TerminalType(rule.key)) {
+			terminals = append(terminals, TerminalType(rule.key))
+		}
+	}
+	return terminals
+}
+
+// allShellTypes returns the built-in shell types followed by custom ones
+func allShellTypes() []ShellType {
+	shells := append([]ShellType{}, knownShellTypes...)
+	for _, rule := range customShellRules {
+		if !containsShell(shells, ShellType(rule.key)) {
+			shells = append(shells, ShellType(rule.key))
+		}
+	}
+	return shells
+}
+
+// knownShellNames returns the valid -shell values
+func knownShellNames() []string {
+	shells := allShellTypes()
+	names := make([]string, len(shells))
+	for i, shell := range shells {
+		names[i] = string(shell)
+	}
+	return names
+}
+
+// containsTerminal reports whether a terminal type is in a list
+func containsTerminal(terminals []TerminalType, terminal TerminalType) bool {
+	for _, t := range terminals {
+		if t == terminal {
+			return true
+		}
+	}
+	return false
+}
+
+// containsShell reports whether a shell type is in a list
+func containsShell(shells []ShellType, shell ShellType) bool {
+	for _, s := range shells {
+		if s == shell {
+			return true
+		}
+	}
+	return false
+}
+
+// detectRulesKey summarizes the custom rules, so cached detection results made with
+// different rules aren't reused
+func detectRulesKey() string {
+	var parts []string
+	for _, rule := range customTerminalRules {
+		parts = append(parts, "t:"+rule.process+"="+rule.key)
+	}
+	for _, rule := range customShellRules {
+		parts = append(parts, "s:"+rule.process+"="+rule.key)
+	}
+	return strings.Join(parts, ",")
+}
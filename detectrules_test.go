@@ -0,0 +1,175 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSetDetectRules tests checking the sub-profile keys of the [detect] table
+func TestSetDetectRules(t *testing.T) {
+	defer setDetectRules(DetectConfig{})
+
+	tests := []struct {
+		name        string
+		config      DetectConfig
+		expectError string
+	}{
+		{"terminals and shells", DetectConfig{
+			Terminals: map[string]string{"alacritty": "alacritty", "mosh-server": "ssh"},
+			Shells:    map[string]string{"nu": "nu"},
+		}, ""},
+		{"uppercase key", DetectConfig{Terminals: map[string]string{"kitty": "Kitty"}}, "invalid sub-profile key"},
+		{"profile property", DetectConfig{Terminals: map[string]string{"kitty": "tab"}}, "is a profile property"},
+		{"appearance", DetectConfig{Shells: map[string]string{"xonsh": "dark"}}, "reserved for the dark mode"},
+		{"default", DetectConfig{Terminals: map[string]string{"kitty": "default"}}, "reserved for the catch-all"},
+		{"terminal named like a shell", DetectConfig{Terminals: map[string]string{"kitty": "zsh"}}, `"zsh" is a shell`},
+		{"shell named like a terminal", DetectConfig{Shells: map[string]string{"xonsh": "tmux"}}, `"tmux" is a terminal`},
+		{"same key for both", DetectConfig{
+			Terminals: map[string]string{"kitty": "kitty"},
+			Shells:    map[string]string{"kitty-shell": "kitty"},
+		}, "both a terminal and a shell"},
+		{"empty process", DetectConfig{Terminals: map[string]string{" ": "kitty"}}, "empty process name"},
+		{"shell override", DetectConfig{Shell: "fish"}, ""},
+		{"custom shell override", DetectConfig{Shells: map[string]string{"nu": "nu"}, Shell: "nu"}, ""},
+		{"unknown shell override", DetectConfig{Shell: "nu"}, `unknown shell type "nu"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setDetectRules(tt.config)
+			switch {
+			case tt.expectError == "" && err != nil:
+				t.Errorf("setDetectRules() failed: %v", err)
+			case tt.expectError != "" && (err == nil || !strings.Contains(err.Error(), tt.expectError)):
+				t.Errorf("setDetectRules() = %v, expected error containing %q", err, tt.expectError)
+			}
+		})
+	}
+}
+
+// TestCustomDetectRules tests detection and resolution with custom process names
+func TestCustomDetectRules(t *testing.T) {
+	err := setDetectRules(DetectConfig{
+		Terminals: map[string]string{"alacritty": "alacritty", "mosh-server": "ssh"},
+		Shells:    map[string]string{"nu": "nu"},
+	})
+	if err != nil {
+		t.Fatalf("setDetectRules() failed: %v", err)
+	}
+	defer setDetectRules(DetectConfig{})
+
+	info := detectTerminalAndShellFromChain([]string{"nu", "mosh-server", "alacritty"}, "")
+	if info.Shell != "nu" || !info.Valid {
+		t.Errorf("Expected a valid nu shell, got %+v", info)
+	}
+	if len(info.Terminals) != 2 || info.Terminals[0] != TerminalTypeSSH || info.Terminals[1] != "alacritty" {
+		t.Errorf("Expected terminals [ssh alacritty], got %v", info.Terminals)
+	}
+
+	if terminal, ok := parseTerminalType("alacritty"); !ok || terminal != "alacritty" {
+		t.Errorf("Expected alacritty to be a valid -terminal value, got %v, %v", terminal, ok)
+	}
+	if !containsString(knownTerminalNames(), "alacritty") {
+		t.Errorf("Expected alacritty in the known terminal names, got %v", knownTerminalNames())
+	}
+
+	// An unsupported emulator's variables no longer settle detection
+	setFakeEnvironment(&FakeEnvironment{
+		Env:          map[string]string{"TERM_PROGRAM": "Alacritty"},
+		ProcessChain: []string{"set-tab-color", "zsh", "alacritty"},
+	})
+	defer setFakeEnvironment(nil)
+	if terminals := detectTerminalAndShell("").Terminals; len(terminals) != 1 || terminals[0] != "alacritty" {
+		t.Errorf("Expected the process walk to find alacritty, got %v", terminals)
+	}
+}
+
+// TestDetectRulesFromConfig tests loading rules from the config and using their sub-profiles
+func TestDetectRulesFromConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+	defer setDetectRules(DetectConfig{})
+
+	content := `[detect.terminals]
+alacritty = "alacritty"
+
+[profiles.dev]
+tab = "blue"
+
+[profiles.dev.alacritty]
+tab = "green"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	setFakeEnvironment(&FakeEnvironment{Env: map[string]string{}, ProcessChain: []string{"set-tab-color", "zsh", "alacritty"}})
+	defer setFakeEnvironment(nil)
+
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	info := detectTerminalAndShell("")
+	profile, err := getProfileWithTerminalInfo("dev", &info)
+	if err != nil {
+		t.Fatalf("getProfileWithTerminalInfo() failed: %v", err)
+	}
+	if profile.Tab != "green" {
+		t.Errorf("Expected the alacritty sub-profile, got tab %q", profile.Tab)
+	}
+
+	if err := os.WriteFile(configFile, []byte("[detect.terminals]\nkitty = \"fg\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "[detect]") {
+		t.Errorf("Expected a [detect] table error, got %v", err)
+	}
+}
+
+// TestShellOverride tests replacing the detected shell with -shell or the [detect] shell
+func TestShellOverride(t *testing.T) {
+	if err := setDetectRules(DetectConfig{Shells: map[string]string{"nu": "nu"}, Shell: "fish"}); err != nil {
+		t.Fatalf("setDetectRules() failed: %v", err)
+	}
+	defer setDetectRules(DetectConfig{})
+	defer func() { shellOverride = "" }()
+
+	setFakeEnvironment(&FakeEnvironment{Env: map[string]string{}, ProcessChain: []string{"set-tab-color", "zsh", "iTerm2"}})
+	defer setFakeEnvironment(nil)
+
+	tests := []struct {
+		name     string
+		override string
+		expected ShellType
+	}{
+		{"config shell", "", ShellTypeFish},
+		{"flag wins over config", "bash", ShellTypeBash},
+		{"custom shell", "nu", "nu"},
+		{"invalid flag falls back to config", "pwsh", ShellTypeFish},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shellOverride = tt.override
+			info := detectTerminalAndShell("")
+			if info.Shell != tt.expected {
+				t.Errorf("Expected shell %q, got %q", tt.expected, info.Shell)
+			}
+			if len(info.Terminals) != 1 || info.Terminals[0] != TerminalTypeITerm2 || !info.Valid {
+				t.Errorf("Expected the terminals to stay detected, got %+v", info)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// distinctColorName is the tab color value that picks a color unlike other sessions'
+const distinctColorName = "distinct"
+
+// distinctCandidates is the number of evenly spaced hues considered for -tab distinct
+const distinctCandidates = 24
+
+// distinctCandidateColors returns saturated colors at evenly spaced hues, as hex
+func distinctCandidateColors() []string {
+	colors := make([]string, distinctCandidates)
+	for i := range colors {
+		r, g, b := hslToRGB(float64(i)*360/distinctCandidates, 0.75, 0.5)
+		colors[i] = fmt.Sprintf("%02x%02x%02x", r, g, b)
+	}
+	return colors
+}
+
+// hslToRGB converts a hue in degrees and saturation/lightness in [0,1] to RGB
+func hslToRGB(h, s, l float64) (uint8, uint8, uint8) {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return uint8(math.Round((r + m) * 255)), uint8(math.Round((g + m) * 255)), uint8(math.Round((b + m) * 255))
+}
+
+// hexToLab converts a 6-digit hex color to CIELAB (D65 white point)
+func hexToLab(hex string) ([3]float64, error) {
+	r, g, b, err := hexToRGB(hex)
+	if err != nil {
+		return [3]float64{}, err
+	}
+
+	linear := func(c int) float64 {
+		v := float64(c) / 255
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	lr, lg, lb := linear(r), linear(g), linear(b)
+
+	x := (0.4124*lr + 0.3576*lg + 0.1805*lb) / 0.95047
+	y := 0.2126*lr + 0.7152*lg + 0.0722*lb
+	z := (0.0193*lr + 0.1192*lg + 0.9505*lb) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}, nil
+}
+
+// labDistance returns the CIE76 color difference between two Lab colors
+func labDistance(a, b [3]float64) float64 {
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2]))
+}
+
+// pickDistinctColor returns the candidate whose nearest used color is farthest away.
+// Ties go to the earlier candidate, so the choice is stable.
+// Colors are compared as seen with the [colorblind] type, if any.
+func pickDistinctColor(used []string) string {
+	var usedLab [][3]float64
+	for _, color := range used {
+		if lab, err := perceivedLab(color, colorblindMode.Type); err == nil {
+			usedLab = append(usedLab, lab)
+		}
+	}
+
+	// With remap, only colorblind-safe colors would survive being applied
+	candidates := distinctCandidateColors()
+	if colorblindMode.Remap {
+		candidates = colorblindSafePalette
+	}
+	best, bestDistance := candidates[0], -1.0
+	for _, candidate := range candidates {
+		lab, _ := perceivedLab(candidate, colorblindMode.Type)
+		nearest := math.Inf(1)
+		for _, u := range usedLab {
+			nearest = math.Min(nearest, labDistance(lab, u))
+		}
+		if nearest > bestDistance {
+			best, bestDistance = candidate, nearest
+		}
+	}
+	return best
+}
+
+// resolveDistinctTab replaces a "distinct" tab color with a color that stands out
+// from the tab colors of the other live sessions in the registry
+func resolveDistinctTab(profile *Profile) error {
+	if profile.Tab != distinctColorName {
+		return nil
+	}
+
+	sessions, err := loadOtherSessions()
+	if err != nil {
+		return fmt.Errorf("could not read session registry: %v", err)
+	}
+
+	var used []string
+	for _, session := range sessions {
+		if session.Tab != "" && session.Tab != "default" {
+			used = append(used, session.Tab)
+		}
+	}
+
+	profile.Tab = pickDistinctColor(used)
+	debugf("Picked distinct tab color %s (%d other sessions in use)\n", profile.Tab, len(used))
+	return nil
+}
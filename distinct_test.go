@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPickDistinctColor tests choosing the candidate farthest from the colors in use
+func TestPickDistinctColor(t *testing.T) {
+	candidates := distinctCandidateColors()
+	if len(candidates) != distinctCandidates {
+		t.Fatalf("Expected %d candidates, got %d", distinctCandidates, len(candidates))
+	}
+
+	if color := pickDistinctColor(nil); color != candidates[0] {
+		t.Errorf("Expected first candidate with no colors in use, got %s", color)
+	}
+
+	// The pick must be farther from every used color than any used color is from the others
+	used := []string{"df2020", "20df20"}
+	color := pickDistinctColor(used)
+	lab, _ := hexToLab(color)
+	for _, u := range used {
+		usedLab, _ := hexToLab(u)
+		if d := labDistance(lab, usedLab); d < 50 {
+			t.Errorf("Picked %s only %.1f away from used color %s", color, d, u)
+		}
+	}
+
+	if again := pickDistinctColor(used); again != color {
+		t.Errorf("Expected a stable pick, got %s then %s", color, again)
+	}
+}
+
+// TestHexToLab tests the sRGB to CIELAB conversion against reference values
+func TestHexToLab(t *testing.T) {
+	tests := []struct {
+		hex      string
+		expected [3]float64
+	}{
+		{"000000", [3]float64{0, 0, 0}},
+		{"ffffff", [3]float64{100, 0, 0}},
+		{"ff0000", [3]float64{53.24, 80.09, 67.20}},
+	}
+
+	for _, test := range tests {
+		lab, err := hexToLab(test.hex)
+		if err != nil {
+			t.Fatalf("hexToLab(%q) failed: %v", test.hex, err)
+		}
+		if labDistance(lab, test.expected) > 0.1 {
+			t.Errorf("hexToLab(%q) = %v, expected %v", test.hex, lab, test.expected)
+		}
+	}
+}
+
+// TestResolveDistinctTab tests that live sessions are avoided and dead ones are pruned
+func TestResolveDistinctTab(t *testing.T) {
+	stateHome := t.TempDir()
+	originalStateHome := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", stateHome)
+	defer os.Setenv("XDG_STATE_HOME", originalStateHome)
+
+	originalSession := os.Getenv("ITERM_SESSION_ID")
+	os.Setenv("ITERM_SESSION_ID", "w0t0p0:SELF")
+	defer os.Setenv("ITERM_SESSION_ID", originalSession)
+
+	sessionsDir := filepath.Join(stateHome, "set-tab-color", "sessions")
+	if err := os.MkdirAll(sessionsDir, 0755); err != nil {
+		t.Fatalf("Failed to create sessions dir: %v", err)
+	}
+
+	write := func(name string, state AppliedState) {
+		data, _ := json.Marshal(state)
+		if err := os.WriteFile(filepath.Join(sessionsDir, name), data, 0644); err != nil {
+			t.Fatalf("Failed to write session state: %v", err)
+		}
+	}
+	candidates := distinctCandidateColors()
+	write("live.json", AppliedState{Tab: candidates[0], ShellPID: os.Getpid()})
+	write("dead.json", AppliedState{Tab: candidates[12], ShellPID: 999999999})
+	write("SELF.json", AppliedState{Tab: candidates[12], ShellPID: os.Getpid()})
+
+	profile := &Profile{Tab: "distinct"}
+	if err := resolveDistinctTab(profile); err != nil {
+		t.Fatalf("resolveDistinctTab() failed: %v", err)
+	}
+
+	// Only the live session's color counts, so the pick moves away from it
+	if expected := pickDistinctColor([]string{candidates[0]}); profile.Tab != expected {
+		t.Errorf("Expected %s, got %s", expected, profile.Tab)
+	}
+	if _, err := os.Stat(filepath.Join(sessionsDir, "dead.json")); !os.IsNotExist(err) {
+		t.Error("Expected the dead session entry to be removed")
+	}
+
+	// Other values are left alone
+	profile = &Profile{Tab: "red"}
+	if err := resolveDistinctTab(profile); err != nil || profile.Tab != "red" {
+		t.Errorf("Expected non-distinct tab to be unchanged, got %q (%v)", profile.Tab, err)
+	}
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// editableProfileKeys lists the keys that can be written by profile set/add
+var editableProfileKeys = []string{"tab", "fg", "bg", "preset"}
+
+// bareKeyPattern matches TOML keys that don't need quoting
+var bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// configEditor edits a TOML config file line by line so that comments and
+// formatting outside the touched keys are preserved
+type configEditor struct {
+	path  string
+	lines []string
+}
+
+// loadConfigEditor reads the config file for editing; a missing file starts empty
+func loadConfigEditor(path string) (*configEditor, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &configEditor{path: path}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSuffix(string(data), "\n")
+	if content == "" {
+		return &configEditor{path: path}, nil
+	}
+	return &configEditor{path: path, lines: strings.Split(content, "\n")}, nil
+}
+
+// editableConfigPath returns the config file the editing commands write to. That
+// is the file in use, or the last -config file, unless it isn't the user's own
+// (see checkConfigEditable); then the edit goes to the last -config file before it
+// that is, or else to the user's default config.
+func editableConfigPath() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	candidates := []string{configPath}
+	for i := len(configPaths) - 2; i >= 0; i-- {
+		candidates = append(candidates, configPaths[i])
+	}
+	if defaultPath, err := findDefaultConfigPath(); err == nil && !slices.Contains(candidates, defaultPath) {
+		candidates = append(candidates, defaultPath)
+	}
+
+	refused := checkConfigEditable(configPath)
+	if refused == nil {
+		return configPath, nil
+	}
+	for _, path := range candidates[1:] {
+		if checkConfigEditable(path) != nil {
+			continue
+		}
+		warnf("%v; editing %s instead", refused, path)
+		if loaded, _ := getConfigPaths(); !slices.Contains(loaded, path) {
+			warnf("%s is not loaded now; add it with -config %s -config %s to use both", path, configPath, path)
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("%v; copy the profiles you want to change into your own config instead", refused)
+}
+
+// checkConfigEditable refuses to edit config files that aren't the user's own:
+// files marked read_only = true, files owned by another user (such as a team or
+// system config selected with SET_TAB_COLOR_CONFIG), and files without write permission
+func checkConfigEditable(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// A broken config shouldn't block replacing it, so only read_only is decoded
+	var marker struct {
+		ReadOnly bool `toml:"read_only"`
+	}
+	toml.DecodeFile(path, &marker)
+	if marker.ReadOnly {
+		return fmt.Errorf("%s is marked read_only", path)
+	}
+
+	if ownedByOtherUser(info) {
+		return fmt.Errorf("%s belongs to another user", path)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("%s is not writable", path)
+		}
+		return err
+	}
+	return f.Close()
+}
+
+// tableHeader returns the table path declared on a line, if it is a [table] header
+func tableHeader(line string) ([]string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "[[") {
+		return nil, false
+	}
+	end := strings.Index(trimmed, "]")
+	if end < 0 {
+		return nil, false
+	}
+	return splitTOMLKey(trimmed[1:end]), true
+}
+
+// lineKey returns the key assigned on a line, if it is a simple key = value line
+func lineKey(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "[") {
+		return "", false
+	}
+	eq := strings.Index(trimmed, "=")
+	if eq <= 0 {
+		return "", false
+	}
+	parts := splitTOMLKey(trimmed[:eq])
+	if len(parts) != 1 {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// samePath reports whether two key paths are equal
+func samePath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPrefixPath reports whether path starts with prefix
+func hasPrefixPath(path, prefix []string) bool {
+	return len(path) >= len(prefix) && samePath(path[:len(prefix)], prefix)
+}
+
+// findTable returns the header index of a table and the index just past its body
+func (e *configEditor) findTable(table []string) (start, end int, ok bool) {
+	start = -1
+	for i, line := range e.lines {
+		header, isHeader := tableHeader(line)
+		if !isHeader {
+			continue
+		}
+		if start >= 0 {
+			return start, i, true
+		}
+		if samePath(header, table) {
+			start = i
+		}
+	}
+	if start < 0 {
+		return 0, 0, false
+	}
+	return start, len(e.lines), true
+}
+
+// hasTable reports whether a table header exists
+func (e *configEditor) hasTable(table []string) bool {
+	_, _, ok := e.findTable(table)
+	return ok
+}
+
+// setKey sets key = value in a table, creating the table if needed. An existing
+// assignment is rewritten in place, keeping its indentation and trailing comment.
+func (e *configEditor) setKey(table []string, key, value string) {
+	start, end, ok := e.findTable(table)
+	if !ok {
+		e.appendTable(table)
+		start, end, _ = e.findTable(table)
+	}
+
+	assignment := formatTOMLKey(key) + " = " + quoteTOMLString(value)
+
+	lastContent := start
+	for i := start + 1; i < end; i++ {
+		line := e.lines[i]
+		if k, isKey := lineKey(line); isKey {
+			if k == key {
+				indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+				e.lines[i] = indent + assignment + trailingComment(line)
+				return
+			}
+			lastContent = i
+		}
+	}
+
+	// Insert after the last assignment so trailing comments and blank lines stay put
+	e.insertLine(lastContent+1, assignment)
+}
+
+// removeKey deletes a key from a table, reporting whether it was present
+func (e *configEditor) removeKey(table []string, key string) bool {
+	start, end, ok := e.findTable(table)
+	if !ok {
+		return false
+	}
+	for i := start + 1; i < end; i++ {
+		if k, isKey := lineKey(e.lines[i]); isKey && k == key {
+			e.lines = append(e.lines[:i], e.lines[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// removeTable deletes a table and all of its sub-tables, reporting whether any were found.
+// Comment lines directly above the following table are kept since they describe it.
+func (e *configEditor) removeTable(table []string) bool {
+	removed := false
+	for {
+		start := -1
+		for i, line := range e.lines {
+			if header, ok := tableHeader(line); ok && hasPrefixPath(header, table) {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			return removed
+		}
+
+		end := len(e.lines)
+		for i := start + 1; i < len(e.lines); i++ {
+			if _, ok := tableHeader(e.lines[i]); ok {
+				end = i
+				break
+			}
+		}
+
+		// Keep the comment block that introduces the next table
+		if end < len(e.lines) {
+			for end > start+1 && strings.HasPrefix(strings.TrimSpace(e.lines[end-1]), "#") {
+				end--
+			}
+		}
+		// Drop blank lines left behind by the removed table
+		for end < len(e.lines) && strings.TrimSpace(e.lines[end]) == "" {
+			end++
+		}
+
+		e.lines = append(e.lines[:start], e.lines[end:]...)
+		removed = true
+	}
+}
+
+// appendTable adds an empty table header at the end of the file
+func (e *configEditor) appendTable(table []string) {
+	if len(e.lines) > 0 && strings.TrimSpace(e.lines[len(e.lines)-1]) != "" {
+		e.lines = append(e.lines, "")
+	}
+	parts := make([]string, len(table))
+	for i, part := range table {
+		parts[i] = formatTOMLKey(part)
+	}
+	e.lines = append(e.lines, "["+strings.Join(parts, ".")+"]")
+}
+
+// insertLine inserts a line at index i
+func (e *configEditor) insertLine(i int, line string) {
+	e.lines = append(e.lines, "")
+	copy(e.lines[i+1:], e.lines[i:])
+	e.lines[i] = line
+}
+
+// content returns the edited file content
+func (e *configEditor) content() string {
+	if len(e.lines) == 0 {
+		return ""
+	}
+	return strings.Join(e.lines, "\n") + "\n"
+}
+
+// save checks that the edited content is still valid TOML and writes it atomically
+func (e *configEditor) save() error {
+	content := e.content()
+
+	var check Config
+	if _, err := toml.Decode(content, &check); err != nil {
+		return fmt.Errorf("edit would produce an invalid config: %v", err)
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(e.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(e.path), 0755); err != nil {
+		return fmt.Errorf("could not create config directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(e.path), ".set-tab-color-*.toml")
+	if err != nil {
+		return fmt.Errorf("could not write config: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write config: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write config: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("could not write config: %v", err)
+	}
+	return os.Rename(tmp.Name(), e.path)
+}
+
+// trailingComment returns the comment suffix of a key = value line (including the
+// whitespace before it), if any
+func trailingComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++ // Skip the escaped character
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			start := i
+			for start > 0 && (line[start-1] == ' ' || line[start-1] == '\t') {
+				start--
+			}
+			return line[start:]
+		}
+	}
+	return ""
+}
+
+// formatTOMLKey quotes a key segment when it isn't a valid bare key
+func formatTOMLKey(key string) string {
+	if bareKeyPattern.MatchString(key) {
+		return key
+	}
+	return quoteTOMLString(key)
+}
+
+// quoteTOMLString returns s as a TOML basic string. Control characters are dropped
+// since config values never need them.
+func quoteTOMLString(s string) string {
+	s = sanitizeEscapeInput(s)
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// parseProfileAssignments parses key=value arguments for profile add/set
+func parseProfileAssignments(args []string) ([][2]string, error) {
+	assignments := make([][2]string, 0, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected key=value, got %q", arg)
+		}
+		key = strings.TrimSpace(key)
+		if !isEditableProfileKey(key) {
+			return nil, fmt.Errorf("unknown profile key %q (expected one of: %s)", key, strings.Join(editableProfileKeys, ", "))
+		}
+		if key != "preset" && normalizeColor(value) == "" && !isComputedColor(key, value) {
+			return nil, withExitCode(exitUnknownColor, fmt.Errorf("unknown color %q for %s", value, key))
+		}
+		assignments = append(assignments, [2]string{key, value})
+	}
+	return assignments, nil
+}
+
+// isEditableProfileKey reports whether key can be written by profile add/set
+func isEditableProfileKey(key string) bool {
+	for _, k := range editableProfileKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// runProfileEdit implements the profile add/set/remove subcommands
+func runProfileEdit(action string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: profile %s <name> [key=value...]", action)
+	}
+
+	// Load custom colors so they're accepted as values
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	configPath, err := editableConfigPath()
+	if err != nil {
+		return err
+	}
+
+	editor, err := loadConfigEditor(configPath)
+	if err != nil {
+		return err
+	}
+
+	name := args[0]
+	table := append([]string{"profiles"}, splitTOMLKey(name)...)
+
+	switch action {
+	case "add", "set":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: profile %s <name> key=value...", action)
+		}
+		assignments, err := parseProfileAssignments(args[1:])
+		if err != nil {
+			return err
+		}
+		// A profile from another config file is overridden key by key in this one
+		exists := editor.hasTable(table) || cfg.Profiles[name] != nil
+		if action == "add" && exists {
+			return fmt.Errorf("profile %q already exists", name)
+		}
+		if action == "set" && !exists {
+			return fmt.Errorf("profile %q not found (use profile add to create it)", name)
+		}
+		for _, assignment := range assignments {
+			editor.setKey(table, assignment[0], assignment[1])
+		}
+	case "remove":
+		if len(args) == 1 {
+			if !editor.removeTable(table) {
+				return fmt.Errorf("profile %q not found", name)
+			}
+			break
+		}
+		for _, key := range args[1:] {
+			if !editor.removeKey(table, key) {
+				return fmt.Errorf("profile %q has no key %q", name, key)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown profile command %q", action)
+	}
+
+	if err := editor.save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated %s\n", configPath)
+	return nil
+}
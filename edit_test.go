@@ -0,0 +1,245 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestConfigEditorPreservesComments tests editing keys and tables without losing comments
+func TestConfigEditorPreservesComments(t *testing.T) {
+	original := `# My config
+
+[profiles.dev]
+tab = "blue"  # team color
+fg = "white"
+
+# Production gets loud colors
+[profiles.prod]
+tab = "red"
+
+[profiles.dev.zsh]
+tab = "cyan"
+`
+
+	configFile := filepath.Join(t.TempDir(), "edit.toml")
+	if err := os.WriteFile(configFile, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	editor, err := loadConfigEditor(configFile)
+	if err != nil {
+		t.Fatalf("loadConfigEditor() failed: %v", err)
+	}
+
+	editor.setKey([]string{"profiles", "dev"}, "tab", "green")
+	editor.setKey([]string{"profiles", "dev"}, "bg", "black")
+	editor.setKey([]string{"profiles", "prod", "iterm2"}, "preset", "Ocean")
+
+	expected := `# My config
+
+[profiles.dev]
+tab = "green"  # team color
+fg = "white"
+bg = "black"
+
+# Production gets loud colors
+[profiles.prod]
+tab = "red"
+
+[profiles.dev.zsh]
+tab = "cyan"
+
+[profiles.prod.iterm2]
+preset = "Ocean"
+`
+	if editor.content() != expected {
+		t.Errorf("Unexpected content after setKey:\n%s\nexpected:\n%s", editor.content(), expected)
+	}
+
+	// Removing a profile drops its sub-profiles but keeps the comment on the next table
+	if !editor.removeTable([]string{"profiles", "dev"}) {
+		t.Fatal("Expected removeTable() to find profiles.dev")
+	}
+
+	expected = `# My config
+
+# Production gets loud colors
+[profiles.prod]
+tab = "red"
+
+[profiles.prod.iterm2]
+preset = "Ocean"
+`
+	if editor.content() != expected {
+		t.Errorf("Unexpected content after removeTable:\n%s\nexpected:\n%s", editor.content(), expected)
+	}
+
+	if !editor.removeKey([]string{"profiles", "prod"}, "tab") {
+		t.Error("Expected removeKey() to find profiles.prod.tab")
+	}
+	if editor.removeKey([]string{"profiles", "prod"}, "tab") {
+		t.Error("Expected removeKey() to report a missing key")
+	}
+}
+
+// TestTrailingComment tests extracting comments from key = value lines
+func TestTrailingComment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`tab = "red"`, ""},
+		{`tab = "red"  # comment`, "  # comment"},
+		{`preset = "Not # a comment"`, ""},
+		{`preset = "quote \" # inside" # outside`, " # outside"},
+		{`preset = 'literal # string'`, ""},
+	}
+
+	for _, test := range tests {
+		result := trailingComment(test.input)
+		if result != test.expected {
+			t.Errorf("trailingComment(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+// TestRunProfileEdit tests the profile add/set/remove commands end to end
+func TestRunProfileEdit(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "profiles.toml")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+
+	noTerminal := &TerminalShellInfo{}
+
+	if err := runProfileEdit("add", []string{"prod", "tab=red", "bg=black"}); err != nil {
+		t.Fatalf("profile add failed: %v", err)
+	}
+	if err := runProfileEdit("add", []string{"prod", "tab=red"}); err == nil {
+		t.Error("Expected profile add to fail for an existing profile")
+	}
+	if err := runProfileEdit("set", []string{"prod", "tab=orange", "preset=Solarized Dark"}); err != nil {
+		t.Fatalf("profile set failed: %v", err)
+	}
+	if err := runProfileEdit("set", []string{"missing", "tab=red"}); err == nil {
+		t.Error("Expected profile set to fail for a missing profile")
+	}
+	if err := runProfileEdit("set", []string{"prod", "tab=notacolor"}); err == nil {
+		t.Error("Expected profile set to reject an unknown color")
+	}
+	if err := runProfileEdit("set", []string{"prod", "font=Menlo"}); err == nil {
+		t.Error("Expected profile set to reject an unknown key")
+	}
+
+	profile, err := getProfileWithTerminalInfo("prod", noTerminal)
+	if err != nil {
+		t.Fatalf("getProfileWithTerminalInfo() failed: %v", err)
+	}
+	if profile.Tab != "orange" || profile.Background != "black" || profile.Preset != "Solarized Dark" {
+		t.Errorf("Profile incorrect after edits: %+v", profile)
+	}
+
+	if err := runProfileEdit("remove", []string{"prod", "bg"}); err != nil {
+		t.Fatalf("profile remove key failed: %v", err)
+	}
+	profile, err = getProfileWithTerminalInfo("prod", noTerminal)
+	if err != nil {
+		t.Fatalf("getProfileWithTerminalInfo() failed: %v", err)
+	}
+	if profile.Background != "" {
+		t.Errorf("Expected bg to be removed, got %q", profile.Background)
+	}
+
+	if err := runProfileEdit("remove", []string{"prod"}); err != nil {
+		t.Fatalf("profile remove failed: %v", err)
+	}
+	if _, err := getProfileWithTerminalInfo("prod", noTerminal); err == nil {
+		t.Error("Expected removed profile to be gone")
+	}
+}
+
+// TestRunProfileEditReadOnly tests that edits to a shared config go to the user's
+// own config instead, and are refused when there is none
+func TestRunProfileEditReadOnly(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "shared.toml")
+	ownConfig := filepath.Join(tempDir, "set-tab-color.toml")
+	t.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+
+	content := "read_only = true\n\n[profiles.prod]\ntab = \"red\"\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	if err := runProfileEdit("set", []string{"prod", "tab=blue"}); err != nil {
+		t.Fatalf("Expected the edit to go to the user's own config, got %v", err)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if string(data) != content {
+		t.Errorf("Expected read-only config to be unchanged, got:\n%s", data)
+	}
+	if data, err := os.ReadFile(ownConfig); err != nil || string(data) != "[profiles.prod]\ntab = \"blue\"\n" {
+		t.Errorf("Expected the override in the user's own config, got %q, %v", data, err)
+	}
+
+	// Without a config of their own to fall back to, the edit is refused
+	if err := os.WriteFile(ownConfig, []byte("read_only = true\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	err = runProfileEdit("set", []string{"prod", "tab=green"})
+	if err == nil || !contains(err.Error(), configFile+" is marked read_only") {
+		t.Errorf("Expected read_only error, got %v", err)
+	}
+
+	// File permissions are ignored for root, so only check them for regular users
+	if os.Getuid() == 0 {
+		return
+	}
+	if err := os.WriteFile(configFile, []byte("[profiles.prod]\ntab = \"red\"\n"), 0444); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	if err := runProfileEdit("set", []string{"prod", "tab=blue"}); err == nil || !contains(err.Error(), "is not writable") {
+		t.Errorf("Expected not writable error, got %v", err)
+	}
+}
+
+// TestEditableConfigPath tests redirecting edits from a read-only -config layer
+func TestEditableConfigPath(t *testing.T) {
+	tempDir := t.TempDir()
+	own := filepath.Join(tempDir, "own.toml")
+	team := filepath.Join(tempDir, "team.toml")
+	if err := os.WriteFile(own, []byte("[profiles.me]\ntab = \"blue\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.WriteFile(team, []byte("read_only = true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", tempDir)
+	defer func() { configPaths = nil }()
+
+	for _, test := range []struct {
+		layers   configPathList
+		expected string
+	}{
+		{configPathList{team, own}, own},
+		{configPathList{own, team}, own},
+		{configPathList{team}, filepath.Join(tempDir, "set-tab-color.toml")},
+	} {
+		configPaths = test.layers
+		if path, err := editableConfigPath(); err != nil || path != test.expected {
+			t.Errorf("editableConfigPath() with %v = %q, %v, expected %q", test.layers, path, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Terminals export variables that their child processes inherit, so the common cases
+// can be detected without walking the process tree. Each layer is looked for from
+// the inside out: tmux, then SSH, then the terminal emulator on this host.
+
+// emulatorEnvVars maps variables set by terminal emulators to the terminal type they
+// indicate; an empty value matches any
+var emulatorEnvVars = []struct {
+	key      string
+	value    string
+	terminal TerminalType
+}{
+	{"ITERM_SESSION_ID", "", TerminalTypeITerm2},
+	{"VSCODE_INJECTION", "", TerminalTypeVSCode},
+	{"TERMINAL_EMULATOR", "JetBrains-JediTerm", TerminalTypeJetBrains},
+	{"GHOSTTY_RESOURCES_DIR", "", TerminalTypeGhostty},
+	{"WARP_IS_LOCAL_SHELL_SESSION", "", TerminalTypeWarp},
+	{"GNOME_TERMINAL_SCREEN", "", TerminalTypeGnome},
+	{"KONSOLE_VERSION", "", TerminalTypeKonsole},
+	{"XTERM_VERSION", "", TerminalTypeXterm},
+	{"KITTY_WINDOW_ID", "", TerminalTypeKitty},
+	{"WEZTERM_PANE", "", TerminalTypeWezTerm},
+}
+
+// termProgramTerminals maps $TERM_PROGRAM values to terminal types
+var termProgramTerminals = map[string]TerminalType{
+	"iTerm.app":    TerminalTypeITerm2,
+	"vscode":       TerminalTypeVSCode,
+	"ghostty":      TerminalTypeGhostty,
+	"WarpTerminal": TerminalTypeWarp,
+	"Hyper":        TerminalTypeHyper,
+	"rio":          TerminalTypeRio,
+	"Tabby":        TerminalTypeTabby,
+	"WezTerm":      TerminalTypeWezTerm,
+}
+
+// currentTTY returns the terminal device of this process; it is replaced in tests.
+// Fixtures don't record the tty, so none is reported while one is active.
+var currentTTY = func() string {
+	if getFakeEnvironment() != nil {
+		return ""
+	}
+	return ttyName()
+}
+
+// detectEmulatorFromEnv returns the terminal emulator indicated by the environment.
+// known is false when no emulator variable is set or they disagree.
+func detectEmulatorFromEnv() (terminal TerminalType, known bool) {
+	// $TERM_PROGRAM is overwritten by the innermost emulator, while other emulators'
+	// variables may be inherited from the one that launched it
+	if program := getDetectionEnv("TERM_PROGRAM"); program != "" && program != "tmux" {
+		if terminal, ok := termProgramTerminals[program]; ok {
+			return terminal, true
+		}
+		return TerminalTypeUnknown, true
+	}
+
+	for _, v := range emulatorEnvVars {
+		if value := getDetectionEnv(v.key); value == "" || (v.value != "" && value != v.value) {
+			continue
+		}
+		if known && terminal != v.terminal {
+			return TerminalTypeUnknown, false
+		}
+		terminal, known = v.terminal, true
+	}
+	return terminal, known
+}
+
+// detectTerminalsFromEnv returns the terminals indicated by environment variables,
+// nearest to the shell first. ok is false when the variables don't settle which
+// terminals are present.
+func detectTerminalsFromEnv() (terminals []TerminalType, ok bool) {
+	if getDetectionEnv("TMUX") != "" || getDetectionEnv("TERM_PROGRAM") == "tmux" {
+		terminals = append(terminals, TerminalTypeTmux)
+	}
+	if sshTTY := getDetectionEnv("SSH_TTY"); sshTTY != "" {
+		// mosh-server keeps the variable of the SSH session that started it, which
+		// names a different tty (inside tmux the pane's tty differs as well)
+		if tty := currentTTY(); len(terminals) == 0 && tty != "" && tty != sshTTY {
+			return nil, false
+		}
+		// The emulator is on the other end of the connection
+		return append(terminals, TerminalTypeSSH), true
+	}
+
+	emulator, known := detectEmulatorFromEnv()
+	if !known {
+		// Under WSL the emulator is on the Windows side, where withWSLHost finds it
+		return terminals, len(terminals) > 0 || isWSL()
+	}
+	if emulator == TerminalTypeUnknown && len(customTerminalRules) > 0 {
+		// A [detect] rule may recognize the emulator's process
+		return nil, false
+	}
+	if emulator != TerminalTypeUnknown {
+		terminals = append(terminals, emulator)
+	}
+	return terminals, true
+}
+
+// getParentProcessName returns the name of the process that started this one
+func getParentProcessName() (string, error) {
+	chain := processChainCache.chain
+	if env := getFakeEnvironment(); env != nil {
+		chain = env.ProcessChain
+	} else if !processChainCache.done {
+		parent, err := process.NewProcess(int32(os.Getppid()))
+		if err != nil {
+			return "", err
+		}
+		return parent.Name()
+	}
+
+	if len(chain) < 2 {
+		return "", fmt.Errorf("no parent process")
+	}
+	return chain[1], nil
+}
+
+// detectTerminalAndShellFromEnv detects terminals from environment variables and the
+// shell from the parent process, without walking the process tree. ok is false when
+// the result is ambiguous and the tree must be walked.
+func detectTerminalAndShellFromEnv() (TerminalShellInfo, bool) {
+	terminals, ok := detectTerminalsFromEnv()
+	if !ok {
+		return TerminalShellInfo{}, false
+	}
+
+	// Run from a script or another program, the shell is further up the tree
+	parent, err := getParentProcessName()
+	if err != nil {
+		return TerminalShellInfo{}, false
+	}
+	shell := matchShell(parent)
+	if shell == ShellTypeUnknown {
+		return TerminalShellInfo{}, false
+	}
+
+	return TerminalShellInfo{Terminals: terminals, Shell: shell, Valid: true}, true
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestDetectTerminalAndShellFromEnv tests detection from terminal environment variables
+func TestDetectTerminalAndShellFromEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		chain     []string
+		override  string
+		expectOK  bool
+		terminals []TerminalType
+		shell     ShellType
+		valid     bool
+	}{
+		{"iTerm2", map[string]string{"TERM_PROGRAM": "iTerm.app", "ITERM_SESSION_ID": "w0t0p0:ABC"},
+			[]string{"set-tab-color", "zsh"}, "", true, []TerminalType{TerminalTypeITerm2}, ShellTypeZsh, true},
+		{"iTerm2 session id only", map[string]string{"ITERM_SESSION_ID": "w0t0p0:ABC"},
+			[]string{"set-tab-color", "bash"}, "", true, []TerminalType{TerminalTypeITerm2}, ShellTypeBash, true},
+		{"tmux inside iTerm2", map[string]string{"TERM_PROGRAM": "tmux", "TMUX": "/tmp/tmux-501/default,1,0", "ITERM_SESSION_ID": "w0t0p0:ABC"},
+			[]string{"set-tab-color", "zsh"}, "", true, []TerminalType{TerminalTypeTmux, TerminalTypeITerm2}, ShellTypeZsh, true},
+		{"tmux over ssh", map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0", "SSH_TTY": "/dev/pts/0", "ITERM_SESSION_ID": "stale"},
+			[]string{"set-tab-color", "fish"}, "", true, []TerminalType{TerminalTypeTmux, TerminalTypeSSH}, ShellTypeFish, true},
+		{"VS Code launched from iTerm2", map[string]string{"TERM_PROGRAM": "vscode", "ITERM_SESSION_ID": "w0t0p0:ABC"},
+			[]string{"set-tab-color", "zsh"}, "", true, []TerminalType{TerminalTypeVSCode}, ShellTypeZsh, true},
+		{"JetBrains IDE", map[string]string{"TERMINAL_EMULATOR": "JetBrains-JediTerm"},
+			[]string{"set-tab-color", "zsh"}, "", true, []TerminalType{TerminalTypeJetBrains}, ShellTypeZsh, true},
+		{"other TERMINAL_EMULATOR", map[string]string{"TERMINAL_EMULATOR": "something-else"},
+			[]string{"set-tab-color", "zsh"}, "", false, nil, "", false},
+		{"Ghostty", map[string]string{"TERM_PROGRAM": "ghostty", "GHOSTTY_RESOURCES_DIR": "/Applications/Ghostty.app/Contents/Resources/ghostty"},
+			[]string{"set-tab-color", "zsh"}, "", true, []TerminalType{TerminalTypeGhostty}, ShellTypeZsh, true},
+		{"Tabby", map[string]string{"TERM_PROGRAM": "Tabby"},
+			[]string{"set-tab-color", "zsh"}, "", true, []TerminalType{TerminalTypeTabby}, ShellTypeZsh, true},
+		{"Konsole", map[string]string{"KONSOLE_VERSION": "230805"},
+			[]string{"set-tab-color", "bash"}, "", true, []TerminalType{TerminalTypeKonsole}, ShellTypeBash, true},
+		{"Konsole started from GNOME Terminal", map[string]string{"KONSOLE_VERSION": "230805", "GNOME_TERMINAL_SCREEN": "/org/gnome/Terminal/screen/1"},
+			[]string{"set-tab-color", "bash"}, "", false, nil, "", false},
+		{"WezTerm", map[string]string{"TERM_PROGRAM": "WezTerm", "WEZTERM_PANE": "3"},
+			[]string{"set-tab-color", "zsh"}, "", true, []TerminalType{TerminalTypeWezTerm}, ShellTypeZsh, true},
+		{"kitty", map[string]string{"TERM": "xterm-kitty", "KITTY_WINDOW_ID": "1"},
+			[]string{"set-tab-color", "fish"}, "", true, []TerminalType{TerminalTypeKitty}, ShellTypeFish, true},
+		{"unsupported emulator", map[string]string{"TERM_PROGRAM": "Apple_Terminal"},
+			[]string{"set-tab-color", "zsh"}, "", true, nil, ShellTypeZsh, true},
+		{"override prepended", map[string]string{"SSH_TTY": "/dev/pts/1"},
+			[]string{"set-tab-color", "zsh"}, "etterminal", true, []TerminalType{TerminalTypeETTerminal, TerminalTypeSSH}, ShellTypeZsh, false},
+		{"no variables", map[string]string{"TERM": "xterm-256color"},
+			[]string{"set-tab-color", "zsh"}, "", false, nil, "", false},
+		{"conflicting emulators", map[string]string{"ITERM_SESSION_ID": "w0t0p0:ABC", "KITTY_WINDOW_ID": "1"},
+			[]string{"set-tab-color", "zsh"}, "", false, nil, "", false},
+		{"parent is not a shell", map[string]string{"TERM_PROGRAM": "iTerm.app"},
+			[]string{"set-tab-color", "make", "zsh"}, "", false, nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFakeEnvironment(&FakeEnvironment{Env: tt.env, ProcessChain: tt.chain})
+			defer setFakeEnvironment(nil)
+
+			info, ok := detectTerminalAndShellFromEnv()
+			info = withTerminalOverride(info, tt.override)
+			if ok != tt.expectOK {
+				t.Fatalf("Expected ok=%v, got %v (%+v)", tt.expectOK, ok, info)
+			}
+			if !ok {
+				return
+			}
+			if len(info.Terminals) != len(tt.terminals) {
+				t.Fatalf("Expected terminals %v, got %v", tt.terminals, info.Terminals)
+			}
+			for i := range tt.terminals {
+				if info.Terminals[i] != tt.terminals[i] {
+					t.Errorf("Expected terminals %v, got %v", tt.terminals, info.Terminals)
+					break
+				}
+			}
+			if info.Shell != tt.shell || info.Valid != tt.valid {
+				t.Errorf("Expected shell=%v valid=%v, got shell=%v valid=%v", tt.shell, tt.valid, info.Shell, info.Valid)
+			}
+		})
+	}
+}
+
+// TestDetectTerminalAndShellFallsBackToWalk tests that ambiguous environments use the process chain
+func TestDetectTerminalAndShellFallsBackToWalk(t *testing.T) {
+	setFakeEnvironment(&FakeEnvironment{
+		Env:          map[string]string{"TERM_PROGRAM": "iTerm.app"},
+		ProcessChain: []string{"set-tab-color", "make", "zsh", "etterminal"},
+	})
+	defer setFakeEnvironment(nil)
+
+	info := detectTerminalAndShell("")
+	if len(info.Terminals) != 1 || info.Terminals[0] != TerminalTypeETTerminal || info.Shell != ShellTypeZsh {
+		t.Errorf("Expected etterminal and zsh from the process chain, got %+v", info)
+	}
+}
+
+// TestDetectMoshFromStaleSSHTTY tests that a mosh session's inherited SSH_TTY doesn't count as SSH
+func TestDetectMoshFromStaleSSHTTY(t *testing.T) {
+	setFakeEnvironment(&FakeEnvironment{
+		Env:          map[string]string{"SSH_TTY": "/dev/pts/1"},
+		ProcessChain: []string{"set-tab-color", "zsh", "mosh-server"},
+	})
+	defer setFakeEnvironment(nil)
+
+	originalTTY := currentTTY
+	defer func() { currentTTY = originalTTY }()
+
+	// Same tty: a plain SSH session
+	currentTTY = func() string { return "/dev/pts/1" }
+	if info, ok := detectTerminalAndShellFromEnv(); !ok || len(info.Terminals) != 1 || info.Terminals[0] != TerminalTypeSSH {
+		t.Errorf("Expected ssh from the environment, got %+v, %v", info, ok)
+	}
+
+	// mosh-server gives the shell a new tty
+	currentTTY = func() string { return "/dev/pts/4" }
+	if _, ok := detectTerminalAndShellFromEnv(); ok {
+		t.Error("Expected a stale SSH_TTY to be ambiguous")
+	}
+	if info := detectTerminalAndShell(""); len(info.Terminals) != 1 || info.Terminals[0] != TerminalTypeMosh {
+		t.Errorf("Expected mosh from the process chain, got %+v", info)
+	}
+}
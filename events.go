@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// An ["events"] request turns its connection into an event stream: after the "ok"
+// reply the watcher writes its recent events as JSON lines, then closes the
+// connection, or with ["events","follow"] keeps writing each new event.
+
+// watchEventHistory is how many recent events a new subscriber is sent first
+const watchEventHistory = 16
+
+// watchEventBuffer is how many events may wait for a slow subscriber before the
+// watcher drops it rather than stall
+const watchEventBuffer = 64
+
+// watchEvent is one line of a watcher's event stream
+type watchEvent struct {
+	Event       string    `json:"event"` // "applied", "matched", or "error"
+	Time        time.Time `json:"time"`
+	Profile     string    `json:"profile,omitempty"` // Empty when colors were set directly
+	Tab         string    `json:"tab,omitempty"`     // Normalized like the state file
+	Foreground  string    `json:"fg,omitempty"`
+	Background  string    `json:"bg,omitempty"`
+	Dir         string    `json:"dir,omitempty"`          // Dir pattern of the matched rule
+	KubeContext string    `json:"kube_context,omitempty"` // kube_context pattern of the matched rule
+	Message     string    `json:"message,omitempty"`      // The error
+}
+
+// eventSubscriber is a connection receiving a watcher's events
+type eventSubscriber struct {
+	events chan watchEvent
+	done   chan struct{} // Closed by the connection when the client goes away
+}
+
+// newEventSubscriber returns a subscriber with room for the recent events and more
+func newEventSubscriber() *eventSubscriber {
+	return &eventSubscriber{events: make(chan watchEvent, watchEventBuffer), done: make(chan struct{})}
+}
+
+// appliedEvent describes a profile, or directly set colors, the watcher applied
+func appliedEvent(name string, profile *Profile) watchEvent {
+	state := newAppliedState(name, profile)
+	return watchEvent{
+		Event:      "applied",
+		Time:       state.AppliedAt,
+		Profile:    redact(name),
+		Tab:        state.Tab,
+		Foreground: state.Foreground,
+		Background: state.Background,
+	}
+}
+
+// matchedEvent describes the [watch] rule that selected a profile
+func matchedEvent(rule WatchRule) watchEvent {
+	return watchEvent{
+		Event:       "matched",
+		Time:        time.Now(),
+		Profile:     redact(rule.Profile),
+		Dir:         redact(rule.Dir),
+		KubeContext: redact(rule.KubeContext),
+	}
+}
+
+// emit records an event and sends it to every subscriber. A subscriber whose
+// connection is gone, or that has fallen too far behind, is dropped.
+func (w *watcher) emit(event watchEvent) {
+	w.recent = append(w.recent, event)
+	if len(w.recent) > watchEventHistory {
+		w.recent = w.recent[1:]
+	}
+
+	kept := w.subscribers[:0]
+	for _, s := range w.subscribers {
+		select {
+		case <-s.done:
+			continue
+		default:
+		}
+		select {
+		case s.events <- event:
+			kept = append(kept, s)
+		default:
+			debugf("Watch: dropping an event subscriber that fell behind\n")
+			close(s.events)
+		}
+	}
+	w.subscribers = kept
+}
+
+// emitError sends an error event, if there was an error
+func (w *watcher) emitError(err error) {
+	if err != nil {
+		w.emit(watchEvent{Event: "error", Time: time.Now(), Message: redact(err.Error())})
+	}
+}
+
+// subscribe sends the recent events to a new subscriber, then keeps it for the
+// events to come if it asked to follow them
+func (w *watcher) subscribe(args []string, s *eventSubscriber) error {
+	if err := checkClientRequest(args); err != nil {
+		return err
+	}
+	for _, event := range w.recent {
+		s.events <- event
+	}
+	if len(args) == 2 {
+		w.subscribers = append(w.subscribers, s)
+	} else {
+		close(s.events)
+	}
+	return nil
+}
+
+// writeWatchEvents writes a subscriber's events to its connection as JSON lines
+func writeWatchEvents(conn io.Writer, s *eventSubscriber) {
+	defer close(s.done)
+	encoder := json.NewEncoder(conn)
+	for event := range s.events {
+		if err := encoder.Encode(event); err != nil {
+			return
+		}
+	}
+}
+
+// streamWatchEvents sends an events request to a watcher and copies the events it
+// writes to out. A followed stream only ends when the watcher goes away.
+func streamWatchEvents(socketPath string, args []string, out io.Writer) error {
+	conn, reader, err := dialWatcher(socketPath, args)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := io.Copy(out, reader); err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		return fmt.Errorf("the watcher closed the event stream")
+	}
+	return nil
+}
+
+// runEvents prints the recent events of a session's watcher, and with --follow
+// keeps printing them as they happen, for status bars that track the session
+func runEvents(args []string) error {
+	request := []string{"events"}
+	var clientArgs []string
+	for _, arg := range args {
+		switch arg {
+		case "--follow", "-follow", "-f":
+			if len(request) == 1 {
+				request = append(request, "follow")
+			}
+		default:
+			clientArgs = append(clientArgs, arg)
+		}
+	}
+	if len(clientArgs) > 0 && !strings.HasPrefix(clientArgs[0], "-") {
+		return fmt.Errorf("usage: events [--follow] [--tty TTY]")
+	}
+	return runClient(append(clientArgs, request...))
+}
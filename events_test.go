@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWatcherEmit tests the event history and dropping subscribers that are gone
+func TestWatcherEmit(t *testing.T) {
+	w := &watcher{}
+	for i := 0; i < watchEventHistory+2; i++ {
+		w.emitError(errors.New("boom"))
+	}
+	w.emitError(nil)
+	if len(w.recent) != watchEventHistory {
+		t.Errorf("Expected %d recent events, got %d", watchEventHistory, len(w.recent))
+	}
+
+	// Without follow the subscriber gets the history and its stream ends
+	s := newEventSubscriber()
+	if err := w.subscribe([]string{"events"}, s); err != nil {
+		t.Fatalf("subscribe() failed: %v", err)
+	}
+	count := 0
+	for event := range s.events {
+		if event.Event != "error" || event.Message != "boom" {
+			t.Errorf("Unexpected event %+v", event)
+		}
+		count++
+	}
+	if count != watchEventHistory || len(w.subscribers) != 0 {
+		t.Errorf("Expected %d events and no subscribers, got %d and %d", watchEventHistory, count, len(w.subscribers))
+	}
+
+	// A follower gets new events until its connection goes away
+	follower := newEventSubscriber()
+	if err := w.subscribe([]string{"events", "follow"}, follower); err != nil {
+		t.Fatalf("subscribe() failed: %v", err)
+	}
+	for range w.recent {
+		<-follower.events
+	}
+	w.emit(matchedEvent(WatchRule{Dir: "/srv/prod", Profile: "prod"}))
+	if event := <-follower.events; event.Event != "matched" || event.Dir != "/srv/prod" || event.Profile != "prod" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	close(follower.done)
+	w.emit(appliedEvent("prod", &Profile{Tab: "red"}))
+	if len(w.subscribers) != 0 {
+		t.Error("Expected the closed subscriber to be dropped")
+	}
+
+	if err := w.subscribe([]string{"events", "all"}, newEventSubscriber()); err == nil {
+		t.Error("Expected error for an unknown events argument")
+	}
+}
+
+// TestWatchEventStream tests following a watcher's events over its socket
+func TestWatchEventStream(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", tempDir)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+		os.Setenv("XDG_STATE_HOME", originalState)
+	}()
+
+	content := `[watch]
+rules = [{ dir = "/srv/prod", profile = "prod" }]
+
+[profiles.prod]
+tab = "red"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	socketPath, err := getWatchSocketPath()
+	if err != nil {
+		t.Fatalf("getWatchSocketPath() failed: %v", err)
+	}
+	listener, err := listenWatchSocket(socketPath)
+	if err != nil {
+		t.Fatalf("listenWatchSocket() failed: %v", err)
+	}
+	defer listener.Close()
+
+	// Serve requests like the watcher's main loop, in dry-run mode
+	requests := make(chan watchRequest)
+	go acceptWatchRequests(listener, requests)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		w := &watcher{terminalType: "iterm2", dir: "/"}
+		for {
+			select {
+			case req := <-requests:
+				dryRunMode = true
+				err := w.serve(req)
+				dryRunMode = false
+				req.reply <- err
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	conn, reader, err := dialWatcher(socketPath, []string{"events", "follow"})
+	if err != nil {
+		t.Fatalf("events request failed: %v", err)
+	}
+	defer conn.Close()
+	events := make(chan watchEvent)
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			var event watchEvent
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Errorf("Invalid event line %q: %v", scanner.Text(), err)
+			}
+			events <- event
+		}
+		close(events)
+	}()
+
+	next := func() watchEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for an event")
+		}
+		return watchEvent{}
+	}
+
+	if err := sendWatchRequest(socketPath, "cwd", "/srv/prod/api"); err != nil {
+		t.Fatalf("cwd request failed: %v", err)
+	}
+	if event := next(); event.Event != "matched" || event.Dir != "/srv/prod" || event.Profile != "prod" {
+		t.Errorf("Expected the matched rule, got %+v", event)
+	}
+	if event := next(); event.Event != "applied" || event.Profile != "prod" || event.Tab != "ff0000" {
+		t.Errorf("Expected the applied profile, got %+v", event)
+	}
+
+	if err := sendWatchRequest(socketPath, "apply", "missing"); err == nil {
+		t.Error("Expected error for a missing profile")
+	}
+	if event := next(); event.Event != "error" || !strings.Contains(event.Message, `profile "missing" not found`) {
+		t.Errorf("Expected the error, got %+v", event)
+	}
+
+	// Without follow, the recent events are printed and the stream ends
+	var buf strings.Builder
+	if err := streamWatchEvents(socketPath, []string{"events"}, &buf); err != nil {
+		t.Fatalf("streamWatchEvents() failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[2], `"event":"error"`) {
+		t.Errorf("Expected the three recent events, got %q", buf.String())
+	}
+}
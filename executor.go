@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// CommandExecutor runs the backend programs: it2setcolor, tmux, and python3 for the
+// iTerm2 Python API. Escape sequences go to escapeWriter instead. Tests replace
+// both, so they can check the exact arguments and bytes without installing programs.
+type CommandExecutor interface {
+	LookPath(file string) (string, error)              // Find a program on $PATH
+	Check(path string) error                           // Report why the program at path can't be run, if it can't
+	Run(path string, args []string) error              // Run with our stdout and stderr
+	Output(path string, args []string) ([]byte, error) // Run and return its stdout
+}
+
+// executor runs backend programs (replaced in tests)
+var executor CommandExecutor = systemExecutor{}
+
+// systemExecutor runs programs with a timeout of backendTimeout
+type systemExecutor struct{}
+
+func (systemExecutor) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+func (systemExecutor) Check(path string) error {
+	return checkExecutable(path)
+}
+
+func (systemExecutor) Run(path string, args []string) error {
+	defer timePhase("backend")()
+	cmd, finish := newBackendCommand(path, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return finish(cmd.Run())
+}
+
+func (systemExecutor) Output(path string, args []string) ([]byte, error) {
+	defer timePhase("backend")()
+	cmd, finish := newBackendCommand(path, args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	return out, finish(err)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingExecutor records the programs it is asked to run instead of running them
+type recordingExecutor struct {
+	installed map[string]bool // Paths that exist and are executable
+	runs      [][]string      // Program path followed by its arguments, per run
+	output    []byte          // Returned by Output
+	err       error           // Returned by Run and Output
+	mu        sync.Mutex      // Guards runs, for commands run by runParallel
+}
+
+// useRecordingExecutor replaces the executor for the rest of the test
+func useRecordingExecutor(t *testing.T) *recordingExecutor {
+	rec := &recordingExecutor{installed: map[string]bool{}}
+	original := executor
+	executor = rec
+	t.Cleanup(func() { executor = original })
+	return rec
+}
+
+func (r *recordingExecutor) LookPath(file string) (string, error) {
+	if r.installed[file] {
+		return "/usr/bin/" + file, nil
+	}
+	return "", fmt.Errorf("%s not found in $PATH", file)
+}
+
+func (r *recordingExecutor) Check(path string) error {
+	if !r.installed[path] {
+		return fmt.Errorf("it2setcolor not found at %s", path)
+	}
+	return nil
+}
+
+func (r *recordingExecutor) Run(path string, args []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, append([]string{path}, args...))
+	return r.err
+}
+
+func (r *recordingExecutor) Output(path string, args []string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, append([]string{path}, args...))
+	return r.output, r.err
+}
+
+// TestRunSetPresetExecutor tests the it2setcolor arguments for a preset
+func TestRunSetPresetExecutor(t *testing.T) {
+	t.Setenv(it2setcolorEnvVar, "/opt/bin/it2setcolor")
+	rec := useRecordingExecutor(t)
+	rec.installed["/opt/bin/it2setcolor"] = true
+	requireIt2setcolor = true
+	defer func() { requireIt2setcolor = false }()
+
+	if err := runSetPreset("Solarized\aDark"); err != nil {
+		t.Fatalf("runSetPreset() failed: %v", err)
+	}
+	if len(rec.runs) != 1 || strings.Join(rec.runs[0], "|") != "/opt/bin/it2setcolor|preset|SolarizedDark" {
+		t.Errorf("Expected a sanitized preset argument, got %v", rec.runs)
+	}
+}
+
+// TestRunSetColorPreferMissing tests that a preferred but missing it2setcolor isn't run
+func TestRunSetColorPreferMissing(t *testing.T) {
+	t.Setenv(it2setcolorEnvVar, "/opt/bin/it2setcolor")
+	rec := useRecordingExecutor(t)
+	var w countingWriter
+	originalWriter := escapeWriter
+	escapeWriter = &w
+	preferIt2setcolor = true
+	defer func() {
+		escapeWriter = originalWriter
+		preferIt2setcolor = false
+	}()
+
+	if err := runSetColor(BackgroundColor, "black"); err != nil {
+		t.Fatalf("runSetColor() failed: %v", err)
+	}
+	if len(rec.runs) != 0 {
+		t.Errorf("Expected nothing to run, got %v", rec.runs)
+	}
+	if string(w.data) != wrapOSC("1337;SetColors=bg=000000") {
+		t.Errorf("Expected the escape sequence instead, got %q", w.data)
+	}
+}
+
+// TestRunTmuxExecutor tests running tmux and reporting it missing
+func TestRunTmuxExecutor(t *testing.T) {
+	rec := useRecordingExecutor(t)
+	if err := runTmux("set-option", "-t", "%3", "status-style", "bg=#ff0000"); err == nil || exitCode(err) != exitBackendUnavailable {
+		t.Errorf("Expected tmux to be reported missing, got %v", err)
+	}
+
+	rec.installed["tmux"] = true
+	if err := runTmux("set-option", "-t", "%3", "status-style", "bg=#ff0000"); err != nil {
+		t.Fatalf("runTmux() failed: %v", err)
+	}
+	if len(rec.runs) != 1 || strings.Join(rec.runs[0], " ") != "/usr/bin/tmux set-option -t %3 status-style bg=#ff0000" {
+		t.Errorf("Unexpected tmux run %v", rec.runs)
+	}
+
+	rec.err = fmt.Errorf("exit status 1")
+	if err := runTmux("rename-window", "-t", "2", "api"); err == nil || !strings.Contains(err.Error(), "tmux rename-window failed") {
+		t.Errorf("Expected a tmux failure, got %v", err)
+	}
+}
+
+// TestQuerySessionsExecutor tests reading sessions from the Python API script's output
+func TestQuerySessionsExecutor(t *testing.T) {
+	rec := useRecordingExecutor(t)
+	rec.installed["python3"] = true
+	rec.output = []byte(`[{"session":"ABC","name":"zsh","profile":"Default","tab":"ff0000","fg":"ffffff","bg":"000000"}]`)
+
+	sessions, err := querySessions()
+	if err != nil {
+		t.Fatalf("querySessions() failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Session != "ABC" || sessions[0].Tab != "ff0000" {
+		t.Errorf("Unexpected sessions %+v", sessions)
+	}
+	if len(rec.runs) != 1 || rec.runs[0][0] != "/usr/bin/python3" || rec.runs[0][1] != "-c" || !strings.Contains(rec.runs[0][3], `"query":true`) {
+		t.Errorf("Unexpected python3 run %v", rec.runs)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes, so scripts can tell why a run failed
+const (
+	exitFailure            = 1 // Any other error
+	exitUsage              = 2 // Invalid flags or flag combinations, as with the flag package
+	exitConfigError        = 3 // The config file can't be read or is invalid
+	exitUnknownColor       = 4 // A color name or value isn't recognized
+	exitBackendUnavailable = 5 // it2setcolor, python3 and the iTerm2 API, or tmux can't be run
+	exitUnsupported        = 6 // With -strict, a requested setting isn't supported by the terminal
+)
+
+// Global -strict flag: fail instead of skipping settings the terminal can't show
+var strictMode bool
+
+// exitCodeError attaches an exit code to an error
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+// withExitCode attaches an exit code to err, keeping its message. nil stays nil.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitCodeError{code: code, err: err}
+}
+
+// exitCode returns the exit code for an error, exitFailure if none was attached
+func exitCode(err error) int {
+	var coded *exitCodeError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return exitFailure
+}
+
+// unsupportedf reports a setting a tmux target can't show: an error with -strict,
+// otherwise a warning unless the [unsupported] action is "skip"
+func unsupportedf(format string, args ...interface{}) error {
+	if strictMode {
+		return withExitCode(exitUnsupported, fmt.Errorf(format, args...))
+	}
+	if unsupportedPolicy.Action == "skip" {
+		debugf(format+"\n", args...)
+		return nil
+	}
+	warnf(format, args...)
+	return nil
+}
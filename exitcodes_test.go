@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExitCode tests the exit codes attached to failures
+func TestExitCode(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "broken.toml")
+	if err := os.WriteFile(configFile, []byte("[profiles.dev\ntab = \"red\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+	_, configErr := loadConfig()
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+		escapeBackend = BackendITerm2
+		strictMode = false
+	}()
+
+	escapeBackend = BackendXterm
+	lenientErr := runSetBadge("PROD")
+	strictMode = true
+	strictErr := runSetBadge("PROD")
+	tmuxErr := applyProfileToTmuxWindow("work:1", &Profile{Tab: "red", Badge: "PROD"})
+	strictMode = false
+
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"plain error", errors.New("boom"), exitFailure},
+		{"config error", configErr, exitConfigError},
+		{"unknown color", runSetColor(TabColor, "not-a-color"), exitUnknownColor},
+		{"wrapped unknown color", applyProfile(&Profile{Foreground: "not-a-color"}), exitUnknownColor},
+		{"wrapped twice", fmt.Errorf("outer: %w", withExitCode(exitBackendUnavailable, errors.New("no tmux"))), exitBackendUnavailable},
+		{"strict unsupported", strictErr, exitUnsupported},
+		{"strict tmux window", tmuxErr, exitUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatal("Expected an error")
+			}
+			if code := exitCode(tt.err); code != tt.expected {
+				t.Errorf("exitCode(%v) = %d, expected %d", tt.err, code, tt.expected)
+			}
+		})
+	}
+
+	if lenientErr != nil {
+		t.Errorf("Expected the badge to be skipped without -strict, got %v", lenientErr)
+	}
+	if withExitCode(exitUsage, nil) != nil {
+		t.Error("Expected withExitCode to keep nil errors nil")
+	}
+	if !strings.Contains(strictErr.Error(), "badge \"PROD\" is not supported") {
+		t.Errorf("Unexpected strict error message: %v", strictErr)
+	}
+}
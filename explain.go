@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// commandLineSource names the -tab, -fg, ... flags as the source of a value
+const commandLineSource = "command line"
+
+// writeExplanation prints the -explain tree: every layer of every profile in the
+// order they are overlaid, followed by the command-line overrides and the table
+// each final value came from. Unlike -verbose, the format is meant to stay stable
+// so explanations can be compared and shared.
+func writeExplanation(w io.Writer, info *TerminalShellInfo, resolutions []*ProfileResolution, overrides Profile) {
+	terminals := make([]string, len(info.Terminals))
+	for i, terminal := range info.Terminals {
+		terminals[i] = string(terminal)
+	}
+	if len(terminals) == 0 {
+		terminals = []string{"none"}
+	}
+	fmt.Fprintf(w, "Terminals: %s\n", strings.Join(terminals, ", "))
+	fmt.Fprintf(w, "Shell: %s\n", info.Shell)
+	if info.Appearance != AppearanceUnknown {
+		fmt.Fprintf(w, "Mode: %s\n", info.Appearance)
+	}
+	if info.Root {
+		fmt.Fprintf(w, "Root: yes\n")
+	}
+
+	sources := map[string]string{}
+	var result Profile
+	for _, r := range resolutions {
+		fmt.Fprintf(w, "\nprofiles.%s\n", redact(r.Name))
+		for i, step := range r.Steps {
+			branch := "|-"
+			if i == len(r.Steps)-1 {
+				branch = "`-"
+			}
+			label := step.Layer
+			if step.Key != "" {
+				label += " " + step.Key
+			}
+			line := fmt.Sprintf("  %s %-18s %s", branch, label, resolutionStatus(step))
+			if step.Values != nil {
+				if values := formatProfileValues(step.Values); values != "" {
+					line += "  " + redact(values)
+				}
+			}
+			fmt.Fprintln(w, strings.TrimRight(line, " "))
+		}
+		for field := range r.Sources {
+			sources[field] = r.sourceName(field)
+		}
+		result = overlayProfile(result, r.Result)
+	}
+
+	if values := formatProfileValues(&overrides); values != "" {
+		fmt.Fprintf(w, "\n%s\n  `- %-18s applied  %s\n", commandLineSource, "flags", redact(values))
+		for field := range profileFieldValues(&overrides) {
+			sources[field] = commandLineSource
+		}
+		result = overlayProfile(result, overrides)
+	}
+
+	writeResultFields(w, &result, func(field string) string { return sources[field] })
+}
+
+// runExplain prints the -explain tree for the -profile list and command-line overrides
+func runExplain(w io.Writer, profileName string, info *TerminalShellInfo, overrides Profile) error {
+	resolutions, err := resolveProfileNames(profileName, info)
+	if err != nil {
+		return err
+	}
+	writeExplanation(w, info, resolutions, overrides)
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestWriteExplanation tests the -explain tree for two profiles and a command-line override
+func TestWriteExplanation(t *testing.T) {
+	info := &TerminalShellInfo{
+		Terminals: []TerminalType{TerminalTypeTmux, TerminalTypeITerm2},
+		Shell:     ShellTypeZsh,
+	}
+	team, err := resolveProfile("team", testProfile(t, map[string]interface{}{
+		"tab":  "blue",
+		"fg":   "white",
+		"tmux": map[string]interface{}{"tab": "green"},
+	}), info)
+	if err != nil {
+		t.Fatalf("resolveProfile() failed: %v", err)
+	}
+	prod, err := resolveProfile("prod", testProfile(t, map[string]interface{}{
+		"bg":  "black",
+		"zsh": map[string]interface{}{"badge": "PROD"},
+	}), info)
+	if err != nil {
+		t.Fatalf("resolveProfile() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	writeExplanation(&buf, info, []*ProfileResolution{team, prod}, Profile{Foreground: "yellow"})
+
+	expected := "Terminals: tmux, iterm2\n" +
+		"Shell: zsh\n" +
+		"\n" +
+		"profiles.team\n" +
+		"  |- base               applied  tab=\"blue\" fg=\"white\"\n" +
+		"  |- shell zsh          not found\n" +
+		"  |- terminal tmux      applied (priority 0)  tab=\"green\"\n" +
+		"  `- terminal iterm2    not found\n" +
+		"\n" +
+		"profiles.prod\n" +
+		"  |- base               applied  bg=\"black\"\n" +
+		"  |- shell zsh          applied  badge=\"PROD\"\n" +
+		"  |- terminal tmux      not found\n" +
+		"  `- terminal iterm2    not found\n" +
+		"\n" +
+		"command line\n" +
+		"  `- flags              applied  fg=\"yellow\"\n" +
+		"\n" +
+		"Result:\n" +
+		"  tab                green                from profiles.team.tmux\n" +
+		"  fg                 yellow               from command line\n" +
+		"  bg                 black                from profiles.prod\n" +
+		"  preset             (unchanged)\n" +
+		"  theme              (unchanged)\n" +
+		"  badge              PROD                 from profiles.prod.zsh\n" +
+		"  title              (unchanged)\n" +
+		"  attention          (unchanged)\n" +
+		"  iterm_profile      (unchanged)\n" +
+		"  transparency       (unchanged)\n" +
+		"  blur               (unchanged)\n"
+	if buf.String() != expected {
+		t.Errorf("Expected explanation:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+// TestWriteExplanationRedacted tests that redacted values stay hidden in the Result section
+func TestWriteExplanationRedacted(t *testing.T) {
+	defer setRedactions(RedactConfig{}, nil)
+	if err := setRedactions(RedactConfig{Patterns: []string{"*.corp.example.com"}}, nil); err != nil {
+		t.Fatalf("setRedactions() failed: %v", err)
+	}
+
+	info := &TerminalShellInfo{Shell: ShellTypeZsh}
+	res, err := resolveProfile("prod", testProfile(t, map[string]interface{}{"badge": "db.corp.example.com"}), info)
+	if err != nil {
+		t.Fatalf("resolveProfile() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	writeExplanation(&buf, info, []*ProfileResolution{res}, Profile{Title: "api.corp.example.com"})
+	if contains(buf.String(), "corp.example.com") {
+		t.Errorf("Expected the hosts to be redacted, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// fakeEnvVar names the environment variable pointing at a detection fixture
+const fakeEnvVar = "SET_TAB_COLOR_FAKE_ENV"
+
+// detectionEnvVars lists the environment variables that influence detection and output
+var detectionEnvVars = []string{
+	"TERM", appearanceEnvVar,
+	"TERM_PROGRAM", "TMUX", "SSH_TTY", "ITERM_SESSION_ID", "VSCODE_INJECTION", "TERMINAL_EMULATOR",
+	"GHOSTTY_RESOURCES_DIR", "WARP_IS_LOCAL_SHELL_SESSION",
+	"GNOME_TERMINAL_SCREEN", "KONSOLE_VERSION", "XTERM_VERSION", "KITTY_WINDOW_ID", "WEZTERM_PANE",
+	"WSL_DISTRO_NAME", "WT_SESSION", "ConEmuPID", "container", "REMOTE_CONTAINERS", "KUBERNETES_SERVICE_HOST", "COLORTERM",
+	"SUDO_USER",
+}
+
+// FakeEnvironment is a fixture replacing the inputs of terminal detection, so a user's
+// environment can be reproduced in bug reports and tests. The format matches the
+// "env" and "process_chain" fields of `detect -json` output.
+type FakeEnvironment struct {
+	Env          map[string]string `json:"env"`           // Replaces detection env vars; missing keys read as empty
+	ProcessChain []string          `json:"process_chain"` // Current process first, nearest ancestor next
+}
+
+var (
+	fakeEnvOnce   sync.Once
+	fakeEnvLoaded *FakeEnvironment
+)
+
+// loadFakeEnvironment reads a fixture file
+func loadFakeEnvironment(path string) (*FakeEnvironment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var env FakeEnvironment
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	if env.Env == nil {
+		env.Env = map[string]string{}
+	}
+	if env.ProcessChain == nil {
+		env.ProcessChain = []string{}
+	}
+	return &env, nil
+}
+
+// getFakeEnvironment returns the active fixture, or nil when detection uses the real
+// environment. An unreadable fixture is reported once and then ignored.
+func getFakeEnvironment() *FakeEnvironment {
+	fakeEnvOnce.Do(func() {
+		path := os.Getenv(fakeEnvVar)
+		if path == "" {
+			return
+		}
+		env, err := loadFakeEnvironment(path)
+		if err != nil {
+			warnf("ignoring %s: %v", fakeEnvVar, err)
+			return
+		}
+		fakeEnvLoaded = env
+	})
+	return fakeEnvLoaded
+}
+
+// getDetectionEnv reads an environment variable used for detection, honoring the fixture
+func getDetectionEnv(key string) string {
+	if env := getFakeEnvironment(); env != nil {
+		return env.Env[key]
+	}
+	return os.Getenv(key)
+}
+
+// setFakeEnvironment replaces the active fixture (used by tests)
+func setFakeEnvironment(env *FakeEnvironment) {
+	fakeEnvOnce.Do(func() {})
+	fakeEnvLoaded = env
+}
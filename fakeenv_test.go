@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFakeEnvironment tests that a fixture replaces process chain and env var inputs
+func TestFakeEnvironment(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "env.json")
+	content := `{
+  "env": {"TERM": "tmux-256color"},
+  "process_chain": ["set-tab-color", "zsh", "tmux: server", "sshd: user@pts/0", "sshd", "iTerm2"]
+}`
+	if err := os.WriteFile(fixture, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	env, err := loadFakeEnvironment(fixture)
+	if err != nil {
+		t.Fatalf("loadFakeEnvironment() failed: %v", err)
+	}
+	setFakeEnvironment(env)
+	defer setFakeEnvironment(nil)
+
+	info := detectTerminalAndShell("")
+	expected := []TerminalType{TerminalTypeTmux, TerminalTypeSSH, TerminalTypeSSH, TerminalTypeITerm2}
+	if len(info.Terminals) != len(expected) {
+		t.Fatalf("Expected terminals %v, got %v", expected, info.Terminals)
+	}
+	for i := range expected {
+		if info.Terminals[i] != expected[i] {
+			t.Errorf("Expected terminals %v, got %v", expected, info.Terminals)
+			break
+		}
+	}
+	if info.Shell != ShellTypeZsh || !info.Valid {
+		t.Errorf("Expected valid zsh detection, got shell=%v valid=%v", info.Shell, info.Valid)
+	}
+
+	if !isTmuxTerm() {
+		t.Error("Expected TERM from the fixture to enable tmux passthrough")
+	}
+	if getDetectionEnv("HOME") != "" {
+		t.Error("Expected env vars missing from the fixture to read as empty")
+	}
+	if !isTerminalInAncestorChain("sshd") {
+		t.Error("Expected sshd to be found in the simulated chain")
+	}
+
+	// Invalid fixtures are rejected
+	if err := os.WriteFile(fixture, []byte("not json"), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	if _, err := loadFakeEnvironment(fixture); err == nil {
+		t.Error("Expected loadFakeEnvironment() to fail for invalid JSON")
+	}
+}
+
+// TestDetectTerminalAndShellFromChain tests detection from explicit process names
+func TestDetectTerminalAndShellFromChain(t *testing.T) {
+	tests := []struct {
+		name      string
+		ancestors []string
+		override  string
+		terminals []TerminalType
+		shell     ShellType
+		valid     bool
+	}{
+		{"shell inside iTerm2", []string{"bash", "login", "iTerm2"}, "", []TerminalType{TerminalTypeITerm2}, ShellTypeBash, true},
+		{"terminal before shell", []string{"tmux: client", "fish"}, "", []TerminalType{TerminalTypeTmux}, ShellTypeFish, false},
+		{"override prepended", []string{"zsh", "Code Helper (Plugin)"}, "ssh", []TerminalType{TerminalTypeSSH, TerminalTypeVSCode}, ShellTypeZsh, false},
+		{"mosh", []string{"zsh", "mosh-server"}, "", []TerminalType{TerminalTypeMosh}, ShellTypeZsh, true},
+		{"JetBrains IDE", []string{"bash", "goland"}, "", []TerminalType{TerminalTypeJetBrains}, ShellTypeBash, true},
+		{"JetBrains Linux launcher", []string{"zsh", "pycharm.sh"}, "", []TerminalType{TerminalTypeJetBrains}, ShellTypeZsh, true},
+		{"Ghostty", []string{"fish", "login", "ghostty"}, "", []TerminalType{TerminalTypeGhostty}, ShellTypeFish, true},
+		{"Warp on Linux", []string{"bash", "warp-terminal"}, "", []TerminalType{TerminalTypeWarp}, ShellTypeBash, true},
+		{"Hyper on Linux", []string{"zsh", "hyper"}, "", []TerminalType{TerminalTypeHyper}, ShellTypeZsh, true},
+		{"kitty", []string{"zsh", "kitty"}, "", []TerminalType{TerminalTypeKitty}, ShellTypeZsh, true},
+		{"WezTerm", []string{"fish", "wezterm-gui"}, "", []TerminalType{TerminalTypeWezTerm}, ShellTypeFish, true},
+		{"GNOME Terminal", []string{"bash", "gnome-terminal-server"}, "", []TerminalType{TerminalTypeGnome}, ShellTypeBash, true},
+		{"truncated GNOME Terminal", []string{"bash", "gnome-terminal-"}, "", []TerminalType{TerminalTypeGnome}, ShellTypeBash, true},
+		{"Konsole", []string{"zsh", "konsole"}, "", []TerminalType{TerminalTypeKonsole}, ShellTypeZsh, true},
+		{"xterm", []string{"sh", "xterm"}, "", []TerminalType{TerminalTypeXterm}, ShellTypeSh, true},
+		{"VS Code on Linux", []string{"bash", "code"}, "", []TerminalType{TerminalTypeVSCode}, ShellTypeBash, true},
+		{"nothing found", []string{"launchd"}, "bogus", nil, ShellTypeUnknown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := detectTerminalAndShellFromChain(tt.ancestors, tt.override)
+			if len(info.Terminals) != len(tt.terminals) {
+				t.Fatalf("Expected terminals %v, got %v", tt.terminals, info.Terminals)
+			}
+			for i := range tt.terminals {
+				if info.Terminals[i] != tt.terminals[i] {
+					t.Errorf("Expected terminals %v, got %v", tt.terminals, info.Terminals)
+					break
+				}
+			}
+			if info.Shell != tt.shell || info.Valid != tt.valid {
+				t.Errorf("Expected shell=%v valid=%v, got shell=%v valid=%v", tt.shell, tt.valid, info.Shell, info.Valid)
+			}
+		})
+	}
+}
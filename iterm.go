@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 )
 
 // ColorTarget represents the type of color to set
@@ -16,7 +17,67 @@ const (
 	BackgroundColor ColorTarget = "bg"
 )
 
-// runSetColor executes it2setcolor with the given color and target
+// Global flags to route color changes through the external it2setcolor binary:
+// -prefer-it2setcolor falls back to escape sequences when it is missing,
+// -require-it2setcolor fails instead
+var (
+	preferIt2setcolor  bool
+	requireIt2setcolor bool
+)
+
+// ITerm2Config is the [iterm2] table of the config file
+type ITerm2Config struct {
+	It2setcolor     string `toml:"it2setcolor"`      // "off", "prefer", or "require", like the flags
+	It2setcolorPath string `toml:"it2setcolor_path"` // Location of it2setcolor; ~ is expanded
+}
+
+// it2setcolorEnvVar overrides the it2setcolor location from the config
+const it2setcolorEnvVar = "SET_TAB_COLOR_IT2SETCOLOR"
+
+// it2setcolorModes lists the valid values of it2setcolor
+var it2setcolorModes = []string{"off", "prefer", "require"}
+
+// iterm2Config holds the [iterm2] table of the loaded config
+var iterm2Config ITerm2Config
+
+// it2setcolorFallbackNoted keeps the missing-binary note to one per run
+var it2setcolorFallbackNoted bool
+
+// setITerm2Config replaces the [iterm2] settings
+func setITerm2Config(config ITerm2Config) error {
+	if config.It2setcolor != "" && !containsString(it2setcolorModes, config.It2setcolor) {
+		return fmt.Errorf("invalid it2setcolor %q (expected one of: %s)", config.It2setcolor, strings.Join(it2setcolorModes, ", "))
+	}
+	iterm2Config = config
+	return nil
+}
+
+// useIt2setcolor reports whether to run it2setcolor rather than emit escape
+// sequences. When it2setcolor is preferred but missing, the built-in sequences
+// (which are what it2setcolor prints) are used instead, noted only with -verbose.
+func useIt2setcolor() bool {
+	if requireIt2setcolor || iterm2Config.It2setcolor == "require" {
+		return true
+	}
+	if !preferIt2setcolor && iterm2Config.It2setcolor != "prefer" {
+		return false
+	}
+
+	it2bin, err := getIt2setcolorPath()
+	if err == nil {
+		err = executor.Check(it2bin)
+	}
+	if err != nil {
+		if !it2setcolorFallbackNoted {
+			infof("it2setcolor is not available (%v); using built-in escape sequences\n", err)
+			it2setcolorFallbackNoted = true
+		}
+		return false
+	}
+	return true
+}
+
+// runSetColor sets the given color target, natively or via it2setcolor
 func runSetColor(target ColorTarget, color string) error {
 	// Initialize CSS colors if not already done
 	if err := initColors(); err != nil {
@@ -26,43 +87,146 @@ func runSetColor(target ColorTarget, color string) error {
 	// Normalize user input
 	normalizedColor := normalizeColor(color)
 	if normalizedColor == "" {
-		return fmt.Errorf("unknown color: %s", color)
+		return withExitCode(exitUnknownColor, fmt.Errorf("unknown color: %s", color))
+	}
+	// Terminal colors are opaque. iTerm2 ignores the alpha of tab colors anyway, but
+	// a translucent foreground or background is likely a mistake.
+	if alpha := hexAlpha(color); alpha != "" && alpha != "ff" && target != TabColor {
+		warnf("the alpha of %s color %s is ignored; terminal colors are opaque", target, color)
+	}
+
+	if escapeBackend == BackendXterm {
+		return emitXtermColor(target, normalizedColor)
+	}
+
+	// it2setcolor only understands "default" for the tab, so other resets are
+	// always sent as escape sequences
+	if (normalizedColor != "default" || target == TabColor) && useIt2setcolor() {
+		return runIt2setcolor(string(target), normalizedColor)
+	}
+	return emitSetColor(target, normalizedColor)
+}
+
+// runSetPreset sets the given iTerm2 color preset, natively or via it2setcolor
+func runSetPreset(presetName string) error {
+	if !backendSupports(FeaturePreset) {
+		return skipUnsupported(fmt.Sprintf("preset %q", presetName))
+	}
+	if useIt2setcolor() {
+		// it2setcolor embeds the name in its own escape sequence, so sanitize it here too
+		return runIt2setcolor("preset", sanitizeEscapeInput(presetName))
+	}
+	return emitSetPreset(presetName)
+}
+
+// runSetITermProfile switches the session to another iTerm2 profile. it2setcolor
+// can't switch profiles, so this is always done with the native escape sequence.
+func runSetITermProfile(profileName string) error {
+	if !backendSupports(FeatureITermProfile) {
+		return skipUnsupported(fmt.Sprintf("iTerm2 profile %q", profileName))
+	}
+	return emitSetProfile(profileName)
+}
+
+// runSetBadge sets the iTerm2 badge text. it2setcolor can't set badges, so this is
+// always done with the native escape sequence.
+func runSetBadge(badge string) error {
+	if !backendSupports(FeatureBadge) {
+		return skipUnsupported(fmt.Sprintf("badge %q", badge))
+	}
+	return emitSetBadge(badge)
+}
+
+// runSetTitle sets the tab and window title with the native escape sequence
+func runSetTitle(title string) error {
+	return emitSetTitle(title)
+}
+
+// runRequestAttention triggers an iTerm2 attention cue with the native escape sequence
+func runRequestAttention(attention string) error {
+	if !backendSupports(FeatureAttention) {
+		return skipUnsupported(fmt.Sprintf("attention %s", attention))
+	}
+	return emitRequestAttention(attention)
+}
+
+// getIt2setcolorPath returns the location of it2setcolor: $SET_TAB_COLOR_IT2SETCOLOR,
+// then it2setcolor_path from the [iterm2] table, then ~/.iterm2/it2setcolor where
+// iTerm2's shell integration installs it, then $PATH (Homebrew, Nix). When none is
+// found, the ~/.iterm2 location is returned for error messages and dry runs.
+func getIt2setcolorPath() (string, error) {
+	if path := os.Getenv(it2setcolorEnvVar); path != "" {
+		return expandHome(path), nil
+	}
+	if path := iterm2Config.It2setcolorPath; path != "" {
+		return expandHome(path), nil
 	}
 
-	// Locate and check existence of custom it2setcolor in ~/.iterm2/
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("could not get home dir: %v", err)
+		return "", fmt.Errorf("could not get home dir: %v", err)
+	}
+	installed := filepath.Join(home, ".iterm2", "it2setcolor")
+	if _, err := os.Stat(installed); err == nil {
+		return installed, nil
+	}
+	if path, err := exec.LookPath("it2setcolor"); err == nil {
+		return path, nil
 	}
-	it2bin := filepath.Join(home, ".iterm2", "it2setcolor")
+	return installed, nil
+}
 
-	if _, err := os.Stat(it2bin); os.IsNotExist(err) {
-		return fmt.Errorf("it2setcolor not found at %s", it2bin)
+// checkExecutable reports why path can't be run as a program, if it can't
+func checkExecutable(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("it2setcolor not found at %s", path)
+	}
+	if err != nil {
+		return err
 	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("it2setcolor at %s is not executable", path)
+	}
+	return nil
+}
 
-	// Execute it2setcolor with the normalized hex
-	cmd := exec.Command(it2bin, string(target), normalizedColor)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// runIt2setcolor executes the external it2setcolor binary with the given arguments,
+// or queues them on the active batch to run together with other settings
+func runIt2setcolor(args ...string) error {
+	if activeBatch != nil {
+		activeBatch.addIt2setcolor(args)
+		return nil
+	}
+	return execIt2setcolor(args)
 }
 
-// runSetPreset executes it2setcolor preset with the given preset name
-func runSetPreset(presetName string) error {
-	// Locate and check existence of custom it2setcolor in ~/.iterm2/
-	home, err := os.UserHomeDir()
+// execIt2setcolor runs it2setcolor once with any number of "target value" pairs
+func execIt2setcolor(args []string) error {
+	it2bin, err := getIt2setcolorPath()
 	if err != nil {
-		return fmt.Errorf("could not get home dir: %v", err)
+		return err
 	}
-	it2bin := filepath.Join(home, ".iterm2", "it2setcolor")
 
-	if _, err := os.Stat(it2bin); os.IsNotExist(err) {
-		return fmt.Errorf("it2setcolor not found at %s", it2bin)
+	if dryRunMode {
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = shellQuote(arg)
+		}
+		_, err := fmt.Fprintf(escapeWriter, "[dry-run] exec: %s %s\n", shellQuote(it2bin), strings.Join(quoted, " "))
+		return err
 	}
 
-	// Execute it2setcolor preset with the preset name
-	cmd := exec.Command(it2bin, "preset", presetName)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	if err := executor.Check(it2bin); err != nil {
+		return withExitCode(exitBackendUnavailable, fmt.Errorf("%v (use -prefer-it2setcolor to fall back to escape sequences)", err))
+	}
+	return executor.Run(it2bin, args)
+}
+
+// shellQuote quotes an argument for display when it contains shell-special characters
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
 }
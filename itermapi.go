@@ -0,0 +1,451 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Global session targeting flags: when set, colors are applied through iTerm2's
+// Python API instead of escape sequences, which only reach the current session
+var (
+	targetSessionID string
+	targetAllTabs   bool
+)
+
+// Values of the -scope flag: which sessions of the iTerm2 window a change reaches
+const (
+	scopeSession = "session" // Only the current session (split pane), as escape sequences do
+	scopeTab     = "tab"     // Every session in the current session's tab
+	scopeWindow  = "window"  // Every session in the current session's window
+)
+
+// targetScopes lists the values -scope accepts
+var targetScopes = []string{scopeSession, scopeTab, scopeWindow}
+
+// Global -scope flag: "tab" or "window" applies to every session there through the
+// Python API; empty for the session alone
+var targetScope string
+
+// useScope widens the change from the current session to its tab or window. Escape
+// sequences only reach the session they are written to, so a wider scope goes
+// through the Python API.
+func useScope(scope string) error {
+	if scope == scopeSession {
+		return nil
+	}
+	if err := useITermAPIForCurrentSession(); err != nil {
+		return withExitCode(exitBackendUnavailable, fmt.Errorf("-scope %s: %v", scope, err))
+	}
+	targetScope = scope
+	return nil
+}
+
+// useITermAPIForCurrentSession points session targeting at the session the command
+// runs in, so -iterm-api changes it through the Python API instead of escape sequences.
+// The iterm2 package reuses $ITERM2_COOKIE when iTerm2 provides it (in scripts it
+// launches); otherwise it asks iTerm2 for one, which may prompt to allow the connection.
+func useITermAPIForCurrentSession() error {
+	if usesSessionTargeting() {
+		return nil
+	}
+	id := os.Getenv("ITERM_SESSION_ID")
+	if id == "" {
+		return withExitCode(exitBackendUnavailable, fmt.Errorf("-iterm-api needs $ITERM_SESSION_ID, which only iTerm2 sets"))
+	}
+	if os.Getenv("ITERM2_COOKIE") == "" {
+		debugf("$ITERM2_COOKIE is not set; iTerm2 may ask to allow the Python API connection\n")
+	}
+	targetSessionID = id
+	return nil
+}
+
+// usesSessionTargeting reports whether colors should go to other sessions
+func usesSessionTargeting() bool {
+	return targetSessionID != "" || targetAllTabs
+}
+
+// normalizeSessionID accepts both $ITERM_SESSION_ID ("w0t1p0:UUID") and bare UUIDs
+func normalizeSessionID(id string) string {
+	if i := strings.LastIndex(id, ":"); i >= 0 {
+		return id[i+1:]
+	}
+	return id
+}
+
+// sessionRequest is passed to the Python API script as JSON
+type sessionRequest struct {
+	Session string            `json:"session,omitempty"`
+	All     bool              `json:"all"`
+	Match   string            `json:"match,omitempty"` // Glob matched against session names
+	Scope   string            `json:"scope,omitempty"` // "tab" or "window" to include the session's neighbors
+	Tab     string            `json:"tab,omitempty"`   // Normalized hex or "default"
+	Fg      string            `json:"fg,omitempty"`
+	Bg      string            `json:"bg,omitempty"`
+	Preset  string            `json:"preset,omitempty"`
+	Palette map[string]string `json:"palette,omitempty"` // Normalized hex by palette key
+	Badge   string            `json:"badge,omitempty"`
+	Title   string            `json:"title,omitempty"`
+	// RequestAttention argument; injected as an escape sequence since the API has no call for it
+	Attention    string `json:"attention,omitempty"`
+	ITermProfile string `json:"iterm_profile,omitempty"` // Name of the iTerm2 profile to switch to first
+	Transparency string `json:"transparency,omitempty"`  // Percent, or "default"
+	Blur         string `json:"blur,omitempty"`          // "true", "false", a radius, or "default"
+	Query        bool   `json:"query,omitempty"`         // Report the matched sessions instead of changing them
+}
+
+// SessionInfo describes an iTerm2 session as reported by the Python API
+type SessionInfo struct {
+	Session string `json:"session"`
+	Name    string `json:"name,omitempty"`
+	Profile string `json:"profile"`       // Name of the session's iTerm2 profile
+	Tab     string `json:"tab,omitempty"` // Hex without '#'; empty when the tab color is off
+	Fg      string `json:"fg"`
+	Bg      string `json:"bg"`
+}
+
+// buildSessionRequest normalizes a profile's colors into a Python API request
+func buildSessionRequest(profile *Profile) (*sessionRequest, error) {
+	// The script sets individual colors, so spell out the theme's colors
+	profile, err := expandTheme(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &sessionRequest{
+		Session: normalizeSessionID(targetSessionID),
+		All:     targetAllTabs,
+		Scope:   targetScope,
+		Preset:  sanitizeEscapeInput(profile.Preset),
+		Badge:   profile.Badge,
+		Title:   sanitizeEscapeInput(profile.Title),
+
+		ITermProfile: profile.ITermProfile,
+		Transparency: profile.Transparency,
+		Blur:         profile.Blur,
+	}
+
+	if profile.Attention != "" && profile.Attention != "false" {
+		mode, ok := attentionModes[profile.Attention]
+		if !ok {
+			return nil, fmt.Errorf("unknown attention value %q (expected true, false, bounce, or fireworks)", profile.Attention)
+		}
+		req.Attention = mode
+	}
+
+	for _, field := range []struct {
+		value string
+		dest  *string
+	}{
+		{profile.Tab, &req.Tab},
+		{profile.Foreground, &req.Fg},
+		{profile.Background, &req.Bg},
+	} {
+		if field.value == "" {
+			continue
+		}
+		normalized := normalizeColor(field.value)
+		if normalized == "" {
+			return nil, withExitCode(exitUnknownColor, fmt.Errorf("unknown color: %s", field.value))
+		}
+		*field.dest = normalized
+	}
+
+	for _, key := range orderedPaletteKeys(profile.Palette) {
+		normalized := normalizeColor(profile.Palette[key])
+		if !isPaletteKey(key) || normalized == "" {
+			return nil, fmt.Errorf("invalid palette entry %s = %q", key, profile.Palette[key])
+		}
+		if req.Palette == nil {
+			req.Palette = map[string]string{}
+		}
+		req.Palette[key] = normalized
+	}
+
+	return req, nil
+}
+
+// applyProfileViaAPI applies a profile to the targeted sessions with iTerm2's Python API.
+// Requires the iterm2 Python package and "Enable Python API" in iTerm2's settings.
+func applyProfileViaAPI(profile *Profile) error {
+	req, err := buildSessionRequest(profile)
+	if err != nil {
+		return err
+	}
+	return runSessionRequest(req)
+}
+
+// runSetWindowEffects sets the current iTerm2 session's transparency and blur. iTerm2
+// has no escape sequence for them, so this goes through the Python API even when
+// everything else is sent as escape sequences.
+func runSetWindowEffects(transparency, blur string) error {
+	description := fmt.Sprintf("transparency %q, blur %q", transparency, blur)
+	if !backendSupports(FeatureTransparency) {
+		return skipUnsupported(description)
+	}
+	id := os.Getenv("ITERM_SESSION_ID")
+	if id == "" {
+		return skipUnsupported(description)
+	}
+	return runSessionRequest(&sessionRequest{Session: normalizeSessionID(id), Transparency: transparency, Blur: blur})
+}
+
+// runSessionRequest runs the Python API script for a request
+func runSessionRequest(req *sessionRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	if dryRunMode {
+		_, err := fmt.Fprintf(escapeWriter, "[dry-run] iTerm2 Python API: %s\n", data)
+		return err
+	}
+
+	debugf("Applying via iTerm2 Python API: %s\n", data)
+
+	python, args, err := itermAPICommand(data)
+	if err != nil {
+		return err
+	}
+	if err := executor.Run(python, args); err != nil {
+		return withExitCode(exitBackendUnavailable,
+			fmt.Errorf("iTerm2 Python API call failed (is the iterm2 package installed and the Python API enabled?): %v", err))
+	}
+	return nil
+}
+
+// itermAPICommand returns the python3 command line running the Python API script for a request
+func itermAPICommand(data []byte) (python string, args []string, err error) {
+	python, err = executor.LookPath("python3")
+	if err != nil {
+		return "", nil, withExitCode(exitBackendUnavailable, fmt.Errorf("python3 is required for the iTerm2 Python API: %v", err))
+	}
+	return python, []string{"-c", itermAPIScript, string(data)}, nil
+}
+
+// querySessions reads the profile and colors of the targeted sessions
+func querySessions() ([]SessionInfo, error) {
+	req := &sessionRequest{Session: normalizeSessionID(targetSessionID), All: targetAllTabs, Scope: targetScope, Query: true}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	python, args, err := itermAPICommand(data)
+	if err != nil {
+		return nil, err
+	}
+	out, err := executor.Output(python, args)
+	if err != nil {
+		return nil, withExitCode(exitBackendUnavailable,
+			fmt.Errorf("iTerm2 Python API call failed (is the iterm2 package installed and the Python API enabled?): %v", err))
+	}
+
+	var sessions []SessionInfo
+	if err := json.Unmarshal(out, &sessions); err != nil {
+		return nil, fmt.Errorf("unexpected response from the iTerm2 Python API: %v", err)
+	}
+	return sessions, nil
+}
+
+// runSessionInfo prints the profile and colors of the current session, or of the
+// sessions chosen with -session or -all-tabs
+func runSessionInfo() error {
+	// Other terminals can still be asked for their colors with escape sequences
+	if !usesSessionTargeting() && os.Getenv("ITERM_SESSION_ID") == "" {
+		return runTerminalColorInfo()
+	}
+	if err := useITermAPIForCurrentSession(); err != nil {
+		return err
+	}
+	sessions, err := querySessions()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printJSON(sessions)
+	}
+	for _, session := range sessions {
+		tab := "off"
+		if session.Tab != "" {
+			tab = colorText("#"+session.Tab, session.Tab)
+		}
+		label := session.Session
+		if session.Name != "" {
+			label += " (" + session.Name + ")"
+		}
+		fmt.Printf("%s\n  profile: %s\n  tab: %s\n  fg: %s\n  bg: %s\n", label, session.Profile, tab,
+			colorText("#"+session.Fg, session.Fg), colorText("#"+session.Bg, session.Bg))
+	}
+	return nil
+}
+
+// itermAPIScript applies a sessionRequest (passed as argv[1]) using the iterm2 package,
+// or with "query" set, prints the matched sessions as a JSON list of SessionInfo
+const itermAPIScript = `
+import fnmatch
+import json
+import sys
+
+import iterm2
+
+REQUEST = json.loads(sys.argv[1])
+
+ANSI = ["black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"]
+PALETTE_SETTERS = {
+    "bold": "set_bold_color",
+    "link": "set_link_color",
+    "selbg": "set_selection_color",
+    "selfg": "set_selected_text_color",
+    "curbg": "set_cursor_color",
+    "curfg": "set_cursor_text_color",
+    "underline": "set_underline_color",
+}
+for index, name in enumerate(ANSI):
+    PALETTE_SETTERS[name] = "set_ansi_%d_color" % index
+    PALETTE_SETTERS["br_" + name] = "set_ansi_%d_color" % (index + 8)
+
+
+def color(value):
+    return iterm2.Color(int(value[0:2], 16), int(value[2:4], 16), int(value[4:6], 16))
+
+
+async def apply(connection, session):
+    profile_name = REQUEST.get("iterm_profile")
+    if profile_name:
+        partials = await iterm2.PartialProfile.async_query(connection, properties=["Guid", "Name"])
+        match = next((p for p in partials if p.name == profile_name), None)
+        if match is None:
+            sys.exit("iTerm2 profile not found: " + profile_name)
+        await session.async_set_profile(await match.async_get_full_profile())
+
+    profile = await session.async_get_profile()
+
+    # "default" restores the value from the session's original profile
+    original = None
+    if "default" in (REQUEST.get("fg"), REQUEST.get("bg"), REQUEST.get("transparency"), REQUEST.get("blur")) or "default" in REQUEST.get("palette", {}).values():
+        partials = await iterm2.PartialProfile.async_query(connection, guids=[profile.original_guid])
+        original = partials[0] if partials else None
+
+    preset_name = REQUEST.get("preset")
+    if preset_name:
+        preset = await iterm2.ColorPreset.async_get(connection, preset_name)
+        if preset is None:
+            sys.exit("color preset not found: " + preset_name)
+        await profile.async_set_color_preset(preset)
+
+    change = iterm2.LocalWriteOnlyProfile()
+    for key, value in REQUEST.get("palette", {}).items():
+        setter = PALETTE_SETTERS[key]
+        if value == "default":
+            if original is not None:
+                getattr(change, setter)(getattr(original, setter[len("set_"):]))
+        else:
+            getattr(change, setter)(color(value))
+
+    tab = REQUEST.get("tab")
+    if tab == "default":
+        change.set_use_tab_color(False)
+    elif tab:
+        change.set_tab_color(color(tab))
+        change.set_use_tab_color(True)
+
+    for key, setter, attr in (
+        ("fg", change.set_foreground_color, "foreground_color"),
+        ("bg", change.set_background_color, "background_color"),
+    ):
+        value = REQUEST.get(key)
+        if value == "default":
+            if original is not None:
+                setter(getattr(original, attr))
+        elif value:
+            setter(color(value))
+
+    if "badge" in REQUEST:
+        change.set_badge_text(REQUEST["badge"])
+
+    transparency = REQUEST.get("transparency")
+    if transparency == "default":
+        if original is not None:
+            change.set_transparency(original.transparency)
+    elif transparency:
+        change.set_transparency(float(transparency) / 100)
+
+    blur = REQUEST.get("blur")
+    if blur == "default":
+        if original is not None:
+            change.set_blur(original.blur)
+            change.set_blur_radius(original.blur_radius)
+    elif blur == "false":
+        change.set_blur(False)
+    elif blur:
+        change.set_blur(True)
+        if blur != "true":
+            change.set_blur_radius(float(blur))
+
+    await session.async_set_profile_properties(change)
+
+    if "title" in REQUEST:
+        await session.async_set_name(REQUEST["title"])
+
+    if "attention" in REQUEST:
+        await session.async_inject(("\x1b]1337;RequestAttention=" + REQUEST["attention"] + "\x07").encode())
+
+
+def hex_color(value):
+    return "%02x%02x%02x" % (round(value.red), round(value.green), round(value.blue))
+
+
+async def describe(session):
+    profile = await session.async_get_profile()
+    return {
+        "session": session.session_id,
+        "name": session.name or "",
+        "profile": profile.name,
+        "tab": hex_color(profile.tab_color) if profile.use_tab_color else "",
+        "fg": hex_color(profile.foreground_color),
+        "bg": hex_color(profile.background_color),
+    }
+
+
+def contains_target(sessions):
+    return any(session.session_id == REQUEST.get("session") for session in sessions)
+
+
+def in_scope(window, tab, session):
+    scope = REQUEST.get("scope")
+    if scope == "window":
+        return contains_target(s for t in window.tabs for s in t.sessions)
+    if scope == "tab":
+        return contains_target(tab.sessions)
+    return session.session_id == REQUEST.get("session")
+
+
+async def main(connection):
+    app = await iterm2.async_get_app(connection)
+    matched = 0
+    described = []
+    for window in app.terminal_windows:
+        for tab in window.tabs:
+            for session in tab.sessions:
+                if (
+                    REQUEST["all"]
+                    or in_scope(window, tab, session)
+                    or ("match" in REQUEST and fnmatch.fnmatchcase(session.name or "", REQUEST["match"]))
+                ):
+                    if REQUEST.get("query"):
+                        described.append(await describe(session))
+                    else:
+                        await apply(connection, session)
+                    matched += 1
+    if matched == 0:
+        sys.exit("no iTerm2 session matches " + REQUEST.get("session", REQUEST.get("match", "")))
+    if REQUEST.get("query"):
+        print(json.dumps(described))
+
+
+iterm2.run_until_complete(main)
+`
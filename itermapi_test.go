@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestNormalizeSessionID tests accepting both $ITERM_SESSION_ID and bare UUIDs
+func TestNormalizeSessionID(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"w0t1p0:4F2C9A1E-0B6D", "4F2C9A1E-0B6D"},
+		{"4F2C9A1E-0B6D", "4F2C9A1E-0B6D"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if result := normalizeSessionID(test.input); result != test.expected {
+			t.Errorf("normalizeSessionID(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+// TestApplyProfileViaAPIDryRun tests the request built for session targeting
+func TestApplyProfileViaAPIDryRun(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	targetSessionID = "w0t0p0:ABC"
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+		targetSessionID = ""
+	}()
+
+	if err := applyProfile(&Profile{Tab: "red", Background: "default", Preset: "Solarized\aDark"}); err != nil {
+		t.Fatalf("applyProfile() failed: %v", err)
+	}
+
+	expected := `[dry-run] iTerm2 Python API: {"session":"ABC","all":false,"tab":"ff0000","bg":"default","preset":"SolarizedDark"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	if err := applyProfile(&Profile{Tab: "notacolor"}); err == nil || !contains(err.Error(), "unknown color") {
+		t.Errorf("Expected unknown color error, got %v", err)
+	}
+}
+
+// TestUseITermAPIForCurrentSession tests targeting the current session with -iterm-api
+func TestUseITermAPIForCurrentSession(t *testing.T) {
+	original, had := os.LookupEnv("ITERM_SESSION_ID")
+	defer func() {
+		targetSessionID = ""
+		if had {
+			os.Setenv("ITERM_SESSION_ID", original)
+		} else {
+			os.Unsetenv("ITERM_SESSION_ID")
+		}
+	}()
+
+	os.Unsetenv("ITERM_SESSION_ID")
+	if err := useITermAPIForCurrentSession(); err == nil || exitCode(err) != exitBackendUnavailable {
+		t.Errorf("Expected an error outside iTerm2, got %v", err)
+	}
+
+	os.Setenv("ITERM_SESSION_ID", "w0t1p0:ABC")
+	if err := useITermAPIForCurrentSession(); err != nil {
+		t.Fatalf("useITermAPIForCurrentSession() failed: %v", err)
+	}
+	if targetSessionID != "w0t1p0:ABC" {
+		t.Errorf("Expected the current session to be targeted, got %q", targetSessionID)
+	}
+
+	// An explicit -session wins
+	targetSessionID = "DEF"
+	if err := useITermAPIForCurrentSession(); err != nil || targetSessionID != "DEF" {
+		t.Errorf("Expected -session to be kept, got %q (%v)", targetSessionID, err)
+	}
+}
+
+// TestSessionRestoreProfile tests reverting to the colors a session reported
+func TestSessionRestoreProfile(t *testing.T) {
+	profile := sessionRestoreProfile(SessionInfo{Profile: "Default", Fg: "c7c7c7", Bg: "000000"})
+	if profile.Tab != "default" || profile.Foreground != "c7c7c7" || profile.Background != "000000" {
+		t.Errorf("Unexpected restore profile: %+v", profile)
+	}
+	profile = sessionRestoreProfile(SessionInfo{Tab: "ff0000", Fg: "ffffff", Bg: "000000"})
+	if profile.Tab != "ff0000" {
+		t.Errorf("Expected the tab color to be kept, got %q", profile.Tab)
+	}
+}
+
+// TestRunSetWindowEffects tests setting transparency and blur through the Python API
+func TestRunSetWindowEffects(t *testing.T) {
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+		escapeBackend = BackendITerm2
+	}()
+	t.Setenv("ITERM_SESSION_ID", "w0t0p0:ABC")
+
+	if err := applyProfile(&Profile{Tab: "red", Transparency: "0", Blur: "false"}); err != nil {
+		t.Fatalf("applyProfile() failed: %v", err)
+	}
+	if !contains(buf.String(), `[dry-run] iTerm2 Python API: {"session":"ABC","all":false,"transparency":"0","blur":"false"}`) {
+		t.Errorf("Expected a Python API request for the current session, got %q", buf.String())
+	}
+
+	// Other terminals skip it like any unsupported setting
+	buf.Reset()
+	escapeBackend = BackendXterm
+	if err := runSetWindowEffects("30", ""); err != nil || !contains(buf.String(), "[dry-run] skip transparency") {
+		t.Errorf("Expected transparency skipped, got %v, %q", err, buf.String())
+	}
+}
+
+// TestUseScope tests widening a change to the current session's tab or window
+func TestUseScope(t *testing.T) {
+	defer func() {
+		targetSessionID = ""
+		targetScope = ""
+	}()
+
+	t.Setenv("ITERM_SESSION_ID", "w0t0p1:ABC")
+	if err := useScope(scopeSession); err != nil || usesSessionTargeting() {
+		t.Errorf("Expected session scope to keep escape sequences, got %v", err)
+	}
+	if err := useScope(scopeTab); err != nil || targetSessionID != "w0t0p1:ABC" || targetScope != scopeTab {
+		t.Fatalf("Expected tab scope through the Python API, got %v (session %q, scope %q)", err, targetSessionID, targetScope)
+	}
+	req, err := buildSessionRequest(&Profile{Background: "black"})
+	if err != nil || req.Session != "ABC" || req.Scope != scopeTab {
+		t.Errorf("Expected a tab-scoped request, got %+v, %v", req, err)
+	}
+
+	targetSessionID, targetScope = "", ""
+	t.Setenv("ITERM_SESSION_ID", "")
+	if err := useScope(scopeWindow); err == nil || exitCode(err) != exitBackendUnavailable {
+		t.Errorf("Expected an error outside iTerm2, got %v", err)
+	}
+}
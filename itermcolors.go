@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// plistNode is a generic XML element of a property list
+type plistNode struct {
+	XMLName  xml.Name
+	Text     string      `xml:",chardata"`
+	Children []plistNode `xml:",any"`
+}
+
+// itermColorKeys maps .itermcolors entries to theme keys ("fg", "bg", or a palette key)
+var itermColorKeys = map[string]string{
+	"Foreground Color":    "fg",
+	"Background Color":    "bg",
+	"Cursor Color":        "curbg",
+	"Cursor Text Color":   "curfg",
+	"Bold Color":          "bold",
+	"Link Color":          "link",
+	"Selection Color":     "selbg",
+	"Selected Text Color": "selfg",
+	"Underline Color":     "underline",
+}
+
+func init() {
+	for i, key := range paletteKeys[:16] {
+		itermColorKeys[fmt.Sprintf("Ansi %d Color", i)] = key
+	}
+}
+
+// plistDictEntries returns the key/value pairs of a <dict> node
+func plistDictEntries(dict plistNode) (map[string]plistNode, error) {
+	entries := map[string]plistNode{}
+	for i := 0; i+1 < len(dict.Children); i += 2 {
+		key := dict.Children[i]
+		if key.XMLName.Local != "key" {
+			return nil, fmt.Errorf("expected <key> in <dict>, got <%s>", key.XMLName.Local)
+		}
+		entries[strings.TrimSpace(key.Text)] = dict.Children[i+1]
+	}
+	return entries, nil
+}
+
+// plistColorToHex converts an .itermcolors color dict (components in 0-1) to hex.
+// Colors are taken as-is regardless of their color space.
+func plistColorToHex(dict plistNode) (string, error) {
+	entries, err := plistDictEntries(dict)
+	if err != nil {
+		return "", err
+	}
+
+	var hex strings.Builder
+	for _, component := range []string{"Red Component", "Green Component", "Blue Component"} {
+		node, ok := entries[component]
+		if !ok {
+			return "", fmt.Errorf("missing %s", component)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(node.Text), 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s %q", component, node.Text)
+		}
+		fmt.Fprintf(&hex, "%02x", int(math.Round(math.Max(0, math.Min(1, value))*255)))
+	}
+	return hex.String(), nil
+}
+
+// parseItermColors reads the colors of an iTerm2 .itermcolors file as a theme
+func parseItermColors(data []byte) (ThemeConfig, error) {
+	var root plistNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return ThemeConfig{}, fmt.Errorf("invalid .itermcolors file: %v", err)
+	}
+	if root.XMLName.Local != "plist" || len(root.Children) != 1 || root.Children[0].XMLName.Local != "dict" {
+		return ThemeConfig{}, fmt.Errorf("invalid .itermcolors file: expected a <plist> with one <dict>")
+	}
+
+	entries, err := plistDictEntries(root.Children[0])
+	if err != nil {
+		return ThemeConfig{}, fmt.Errorf("invalid .itermcolors file: %v", err)
+	}
+
+	theme := ThemeConfig{Palette: map[string]string{}}
+	for name, node := range entries {
+		key, known := itermColorKeys[name]
+		if !known {
+			continue // Tab, badge, and cursor guide colors have no theme equivalent
+		}
+		hex, err := plistColorToHex(node)
+		if err != nil {
+			return ThemeConfig{}, fmt.Errorf("invalid %s: %v", name, err)
+		}
+		switch key {
+		case "fg":
+			theme.Foreground = hex
+		case "bg":
+			theme.Background = hex
+		default:
+			theme.Palette[key] = hex
+		}
+	}
+
+	if theme.Foreground == "" && theme.Background == "" && len(theme.Palette) == 0 {
+		return ThemeConfig{}, fmt.Errorf("no colors found in .itermcolors file")
+	}
+	return theme, nil
+}
+
+// runThemeImport implements "theme import <file> [name]", storing the colors of an
+// .itermcolors file or a base16/base24 YAML scheme in the [themes] table of the config
+func runThemeImport(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: theme import <file.itermcolors|scheme.yaml> [name]")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	// The name defaults to the scheme's own name, then to the file name
+	name := strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+	var theme ThemeConfig
+	switch strings.ToLower(filepath.Ext(args[0])) {
+	case ".yaml", ".yml":
+		var schemeName string
+		theme, schemeName, err = parseBase16(data)
+		if schemeName != "" {
+			name = schemeName
+		}
+	default:
+		theme, err = parseItermColors(data)
+	}
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		name = args[1]
+	}
+	name = themeKey(name)
+	if name == "" {
+		return fmt.Errorf("theme name must not be empty")
+	}
+
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	configPath, err := editableConfigPath()
+	if err != nil {
+		return err
+	}
+
+	editor, err := loadConfigEditor(configPath)
+	if err != nil {
+		return err
+	}
+
+	table := []string{"themes", name}
+	if editor.hasTable(table) {
+		return fmt.Errorf("theme %q already exists", name)
+	}
+	if theme.Foreground != "" {
+		editor.setKey(table, "fg", "#"+theme.Foreground)
+	}
+	if theme.Background != "" {
+		editor.setKey(table, "bg", "#"+theme.Background)
+	}
+	if !editor.hasTable(table) {
+		editor.appendTable(table)
+	}
+	for _, key := range orderedPaletteKeys(theme.Palette) {
+		editor.setKey(append(table, "palette"), key, "#"+theme.Palette[key])
+	}
+
+	if err := editor.save(); err != nil {
+		return err
+	}
+	fmt.Printf("Imported theme %q into %s\n", name, configPath)
+	return nil
+}
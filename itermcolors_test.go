@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testItermColors is a trimmed .itermcolors file
+const testItermColors = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Ansi 1 Color</key>
+	<dict>
+		<key>Alpha Component</key>
+		<real>1</real>
+		<key>Blue Component</key>
+		<real>0.3333333432674408</real>
+		<key>Color Space</key>
+		<string>sRGB</string>
+		<key>Green Component</key>
+		<real>0.3333333432674408</real>
+		<key>Red Component</key>
+		<real>1</real>
+	</dict>
+	<key>Background Color</key>
+	<dict>
+		<key>Blue Component</key>
+		<real>0.21176470816135406</real>
+		<key>Green Component</key>
+		<real>0.16470588743686676</real>
+		<key>Red Component</key>
+		<real>0.15686275064945221</real>
+	</dict>
+	<key>Foreground Color</key>
+	<dict>
+		<key>Blue Component</key>
+		<real>0.94901961088180542</real>
+		<key>Green Component</key>
+		<real>0.97254902124404907</real>
+		<key>Red Component</key>
+		<real>0.97254902124404907</real>
+	</dict>
+	<key>Selection Color</key>
+	<dict>
+		<key>Blue Component</key>
+		<real>0.35294118523597717</real>
+		<key>Green Component</key>
+		<real>0.27843138575553894</real>
+		<key>Red Component</key>
+		<real>0.26666668057441711</real>
+	</dict>
+	<key>Tab Color</key>
+	<dict>
+		<key>Blue Component</key>
+		<real>0</real>
+		<key>Green Component</key>
+		<real>0</real>
+		<key>Red Component</key>
+		<real>1</real>
+	</dict>
+</dict>
+</plist>
+`
+
+// TestParseItermColors tests extracting theme colors from an .itermcolors plist
+func TestParseItermColors(t *testing.T) {
+	theme, err := parseItermColors([]byte(testItermColors))
+	if err != nil {
+		t.Fatalf("parseItermColors() failed: %v", err)
+	}
+
+	if theme.Foreground != "f8f8f2" || theme.Background != "282a36" {
+		t.Errorf("Unexpected fg/bg: %q/%q", theme.Foreground, theme.Background)
+	}
+	expected := map[string]string{"red": "ff5555", "selbg": "44475a"}
+	if len(theme.Palette) != len(expected) {
+		t.Fatalf("Expected palette %v, got %v", expected, theme.Palette)
+	}
+	for key, value := range expected {
+		if theme.Palette[key] != value {
+			t.Errorf("palette.%s = %q, expected %q", key, theme.Palette[key], value)
+		}
+	}
+
+	for _, bad := range []string{"not xml", "<plist><array/></plist>", "<plist><dict></dict></plist>"} {
+		if _, err := parseItermColors([]byte(bad)); err == nil {
+			t.Errorf("Expected parseItermColors(%q) to fail", bad)
+		}
+	}
+}
+
+// TestRunThemeImport tests storing an imported theme in the config and using it
+func TestRunThemeImport(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+	themeFile := filepath.Join(tempDir, "My Dracula.itermcolors")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+	defer setCustomThemes(nil)
+
+	if err := os.WriteFile(configFile, []byte("[profiles.dev]\ntheme = \"my-dracula\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	if err := os.WriteFile(themeFile, []byte(testItermColors), 0644); err != nil {
+		t.Fatalf("Failed to create theme file: %v", err)
+	}
+
+	if err := runThemeImport([]string{themeFile}); err != nil {
+		t.Fatalf("runThemeImport() failed: %v", err)
+	}
+	if err := runThemeImport([]string{themeFile}); err == nil || !contains(err.Error(), "already exists") {
+		t.Errorf("Expected second import to fail, got %v", err)
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	expected := `[profiles.dev]
+theme = "my-dracula"
+
+[themes.my-dracula]
+fg = "#f8f8f2"
+bg = "#282a36"
+
+[themes.my-dracula.palette]
+red = "#ff5555"
+selbg = "#44475a"
+`
+	if string(data) != expected {
+		t.Errorf("Expected config:\n%s\ngot:\n%s", expected, data)
+	}
+
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	theme, err := lookupTheme("My Dracula")
+	if err != nil {
+		t.Fatalf("lookupTheme() failed: %v", err)
+	}
+	if theme.Background != "282a36" || theme.Palette["red"] != "ff5555" {
+		t.Errorf("Unexpected imported theme: %+v", theme)
+	}
+	if issues, err := validateConfigFile(configFile); err != nil || len(issues) != 0 {
+		t.Errorf("Expected imported config to validate, got %v (%v)", issues, err)
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JournalEntry records one setting applied to a terminal
+type JournalEntry struct {
+	Time    time.Time `json:"time"`
+	TTY     string    `json:"tty,omitempty"`
+	Session string    `json:"session,omitempty"` // tmux pane or terminal session, which can outlive the tty
+	Profile string    `json:"profile,omitempty"` // Empty when colors were set directly
+	Target  string    `json:"target"`            // tab, fg, bg, preset, theme, badge, title, a palette key, or reset
+	Color   string    `json:"color"`             // Normalized hex (without '#') for colors, otherwise the value as given
+}
+
+// resetJournalTarget marks a reset, which discards everything recorded before it
+const resetJournalTarget = "reset"
+
+// maxJournalSize is the journal size at which old entries are dropped
+const maxJournalSize = 256 * 1024
+
+// getJournalPath returns the path of the journal of applied settings
+func getJournalPath() (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "journal.jsonl"), nil
+}
+
+// journalSessionKey identifies the session an entry belongs to: the tmux pane,
+// which survives detaching and reattaching, or else the terminal session
+func journalSessionKey() string {
+	if pane := os.Getenv("TMUX_PANE"); pane != "" && os.Getenv("TMUX") != "" {
+		return "tmux-" + pane
+	}
+	return getSessionKey()
+}
+
+// newJournalEntries lists the settings of a profile as journal entries, in the
+// order they are applied
+func newJournalEntries(tty, session, profileName string, profile *Profile) []JournalEntry {
+	now := time.Now()
+	var entries []JournalEntry
+	add := func(target, value string) {
+		if value != "" {
+			entries = append(entries, JournalEntry{Time: now, TTY: tty, Session: session, Profile: profileName, Target: target, Color: value})
+		}
+	}
+
+	add("iterm_profile", profile.ITermProfile)
+	add("preset", profile.Preset)
+	add("theme", profile.Theme)
+	for _, key := range orderedPaletteKeys(profile.Palette) {
+		add(key, normalizeColor(profile.Palette[key]))
+	}
+	add("tab", normalizeColor(profile.Tab))
+	add("fg", normalizeColor(profile.Foreground))
+	add("bg", normalizeColor(profile.Background))
+	add("badge", profile.Badge)
+	add("title", profile.Title)
+	add("transparency", profile.Transparency)
+	add("blur", profile.Blur)
+	return entries
+}
+
+// appendJournal adds entries to the journal, dropping the older half of the
+// entries once it grows past maxJournalSize
+func appendJournal(entries []JournalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	journalPath, err := getJournalPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(journalPath), 0755); err != nil {
+		return fmt.Errorf("could not create state directory: %v", err)
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(journalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write journal: %v", err)
+	}
+	_, err = f.WriteString(b.String())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write journal: %v", err)
+	}
+
+	if info, err := os.Stat(journalPath); err == nil && info.Size() > maxJournalSize {
+		return trimJournal(journalPath)
+	}
+	return nil
+}
+
+// trimJournal keeps the newer half of the journal's lines
+func trimJournal(journalPath string) error {
+	data, err := os.ReadFile(journalPath)
+	if err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	kept := strings.Join(lines[len(lines)/2:], "")
+	if !strings.HasSuffix(kept, "\n") {
+		kept += "\n"
+	}
+
+	// Write via rename so a concurrent reader never sees a partial journal
+	tmpPath := journalPath + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(kept), 0644); err != nil {
+		return fmt.Errorf("could not write journal: %v", err)
+	}
+	return os.Rename(tmpPath, journalPath)
+}
+
+// recordJournal journals a profile applied to the current tty, warning instead of failing
+func recordJournal(profileName string, profile *Profile) {
+	if dryRunMode {
+		return
+	}
+	if err := appendJournal(newJournalEntries(ttyName(), journalSessionKey(), profileName, profile)); err != nil {
+		debugf("Could not record journal: %v\n", err)
+	}
+}
+
+// recordJournalReset journals a reset of the current tty
+func recordJournalReset() {
+	if dryRunMode {
+		return
+	}
+	entry := JournalEntry{Time: time.Now(), TTY: ttyName(), Session: journalSessionKey(), Target: resetJournalTarget, Color: "default"}
+	if err := appendJournal([]JournalEntry{entry}); err != nil {
+		debugf("Could not record journal: %v\n", err)
+	}
+}
+
+// loadJournal reads the journal, oldest entry first. Lines that can't be parsed,
+// such as one cut short by a crash, are skipped.
+func loadJournal() ([]JournalEntry, error) {
+	journalPath, err := getJournalPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(journalPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// filterJournal keeps the entries recorded for a tty
+func filterJournal(entries []JournalEntry, tty string) []JournalEntry {
+	var matched []JournalEntry
+	for _, entry := range entries {
+		if entry.TTY == tty {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// filterJournalSession keeps the entries recorded for a session
+func filterJournalSession(entries []JournalEntry, session string) []JournalEntry {
+	var matched []JournalEntry
+	for _, entry := range entries {
+		if entry.Session == session {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// journalProfile replays entries into the profile that restores their end state,
+// along with the profile name of the last entry. ok is false if nothing was recorded.
+func journalProfile(entries []JournalEntry) (profile *Profile, profileName string, ok bool) {
+	if len(entries) == 0 {
+		return nil, "", false
+	}
+
+	result := Profile{}
+	for _, entry := range entries {
+		switch entry.Target {
+		case resetJournalTarget:
+			result = resetProfile
+		case "tab":
+			result.Tab = entry.Color
+		case "fg":
+			result.Foreground = entry.Color
+		case "bg":
+			result.Background = entry.Color
+		case "iterm_profile":
+			result.ITermProfile = entry.Color
+		case "preset":
+			result.Preset = entry.Color
+		case "theme":
+			result.Theme = entry.Color
+		case "badge":
+			result.Badge = entry.Color
+		case "title":
+			result.Title = entry.Color
+		case "transparency":
+			result.Transparency = entry.Color
+		case "blur":
+			result.Blur = entry.Color
+		default:
+			if isPaletteKey(entry.Target) {
+				result.Palette = overlayPalette(result.Palette, map[string]string{entry.Target: entry.Color})
+			}
+		}
+		profileName = entry.Profile
+	}
+	return &result, profileName, true
+}
+
+// formatJournalEntry renders an entry as a line of history output
+func formatJournalEntry(entry JournalEntry, showTTY bool) string {
+	value := entry.Color
+	if isColorJournalTarget(entry.Target) && value != "default" {
+		value = colorText("#"+value, value)
+	}
+	fields := []string{entry.Time.Local().Format("2006-01-02 15:04:05")}
+	if showTTY {
+		tty := entry.TTY
+		if tty == "" {
+			tty = "-"
+		}
+		fields = append(fields, tty)
+	}
+	profile := entry.Profile
+	if profile == "" {
+		profile = "-"
+	}
+	fields = append(fields, profile, entry.Target, value)
+	return strings.Join(fields, "  ")
+}
+
+// isColorJournalTarget reports whether an entry's value is a hex color
+func isColorJournalTarget(target string) bool {
+	return target == "tab" || target == "fg" || target == "bg" || isPaletteKey(target)
+}
+
+// runHistory prints the journal of the current tty, or of every tty with --all
+func runHistory(args []string) error {
+	const usage = "usage: history [--all] [-n N]"
+	all := false
+	limit := 20
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--all" || args[i] == "-all":
+			all = true
+		case args[i] == "-n" || args[i] == "--n":
+			if i+1 >= len(args) {
+				return fmt.Errorf(usage)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid count %q (expected a number, 0 for all)", args[i+1])
+			}
+			limit = n
+			i++
+		default:
+			return fmt.Errorf("unexpected argument %q (%s)", args[i], usage)
+		}
+	}
+
+	entries, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	tty := ttyName()
+	if !all {
+		entries = filterJournal(entries, tty)
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if jsonOutput {
+		if entries == nil {
+			entries = []JournalEntry{}
+		}
+		return printJSON(entries)
+	}
+	if len(entries) == 0 {
+		if all || tty == "" {
+			fmt.Println("Nothing has been applied yet.")
+		} else {
+			fmt.Printf("Nothing has been applied to %s yet.\n", tty)
+		}
+		return nil
+	}
+	for _, entry := range entries {
+		fmt.Println(formatJournalEntry(entry, all))
+	}
+	return nil
+}
+
+// normalizeTTYPath turns "ttys003" or "pts/3" into a device path
+func normalizeTTYPath(tty string) string {
+	if strings.HasPrefix(tty, "/") {
+		return tty
+	}
+	return "/dev/" + tty
+}
+
+// runReapply applies the end state of a tty's journal again. It is meant for tmux
+// attach hooks and shell reconnection hooks: --tty names the terminal to restore
+// when stdout isn't it, and a tty without history falls back to the journal of
+// the tmux pane or terminal session. Having nothing to restore is only a warning.
+func runReapply(args []string) error {
+	const usage = "usage: reapply [--tty TTY]"
+	tty := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--tty" || args[i] == "-tty":
+			if i+1 >= len(args) {
+				return fmt.Errorf(usage)
+			}
+			tty = normalizeTTYPath(args[i+1])
+			i++
+		case strings.HasPrefix(args[i], "--tty="):
+			tty = normalizeTTYPath(strings.TrimPrefix(args[i], "--tty="))
+		default:
+			return fmt.Errorf("unexpected argument %q (%s)", args[i], usage)
+		}
+	}
+
+	entries, err := loadJournal()
+	if err != nil {
+		return err
+	}
+
+	var recorded []JournalEntry
+	label := tty
+	if tty != "" {
+		recorded = filterJournal(entries, tty)
+	} else {
+		label = ttyName()
+		if label != "" {
+			recorded = filterJournal(entries, label)
+		}
+		if len(recorded) == 0 {
+			label = journalSessionKey()
+			recorded = filterJournalSession(entries, label)
+		}
+	}
+	profile, name, ok := journalProfile(recorded)
+	if !ok {
+		warnf("nothing has been applied to %s yet", label)
+		return nil
+	}
+
+	// Write to the named terminal instead of stdout, as from a tmux run-shell hook
+	if tty != "" && !dryRunMode {
+		f, err := os.OpenFile(tty, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("could not open %s: %v", tty, err)
+		}
+		defer f.Close()
+		originalWriter := escapeWriter
+		escapeWriter = f
+		defer func() { escapeWriter = originalWriter }()
+	}
+
+	// The config still decides how tmux is styled and what to do with unsupported settings
+	if _, err := loadConfig(); err != nil {
+		warnf("could not load config: %v", err)
+	}
+	useEscapeBackendFor(detectTerminals(""))
+
+	if name == "" {
+		name = "colors set directly"
+	}
+	infof("Reapplying %s to %s\n", name, label)
+	return applyProfile(profile)
+}
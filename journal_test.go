@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestJournalProfile tests replaying journal entries into the resulting profile
+func TestJournalProfile(t *testing.T) {
+	entries := newJournalEntries("/dev/ttys001", "pid-1", "prod", &Profile{Tab: "red", Preset: "Ocean", Palette: map[string]string{"red": "#f00"}})
+	entries = append(entries, newJournalEntries("/dev/ttys001", "pid-1", "", &Profile{Background: "navy", Title: "db"})...)
+
+	profile, name, ok := journalProfile(entries)
+	if !ok {
+		t.Fatal("Expected journalProfile() to find entries")
+	}
+	if name != "" {
+		t.Errorf("Expected the name of the last entry, got %q", name)
+	}
+	if profile.Tab != "ff0000" || profile.Background != "000080" || profile.Preset != "Ocean" || profile.Title != "db" {
+		t.Errorf("Unexpected profile: %+v", profile)
+	}
+	if profile.Palette["red"] != "ff0000" {
+		t.Errorf("Expected palette entry, got %v", profile.Palette)
+	}
+
+	// A reset discards what came before it
+	entries = append(entries, JournalEntry{TTY: "/dev/ttys001", Target: resetJournalTarget, Color: "default"})
+	entries = append(entries, newJournalEntries("/dev/ttys001", "pid-1", "dev", &Profile{Tab: "blue"})...)
+	profile, name, _ = journalProfile(entries)
+	if name != "dev" || profile.Tab != "0000ff" || profile.Background != "default" || profile.Preset != "" || profile.Palette != nil {
+		t.Errorf("Unexpected profile after reset: %q %+v", name, profile)
+	}
+
+	if _, _, ok := journalProfile(nil); ok {
+		t.Error("Expected journalProfile() to report an empty journal")
+	}
+}
+
+// TestAppendJournal tests writing, reading, filtering, and trimming the journal
+func TestAppendJournal(t *testing.T) {
+	tempDir := t.TempDir()
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", tempDir)
+	defer os.Setenv("XDG_STATE_HOME", originalState)
+
+	if err := appendJournal(newJournalEntries("/dev/ttys001", "pid-1", "prod", &Profile{Tab: "red", Foreground: "white"})); err != nil {
+		t.Fatalf("appendJournal() failed: %v", err)
+	}
+	if err := appendJournal(newJournalEntries("/dev/ttys002", "tmux-%3", "dev", &Profile{Tab: "blue"})); err != nil {
+		t.Fatalf("appendJournal() failed: %v", err)
+	}
+
+	entries, err := loadJournal()
+	if err != nil {
+		t.Fatalf("loadJournal() failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+	mine := filterJournal(entries, "/dev/ttys001")
+	if len(mine) != 2 || mine[0].Target != "tab" || mine[0].Color != "ff0000" || mine[0].Profile != "prod" {
+		t.Errorf("Unexpected entries for ttys001: %+v", mine)
+	}
+
+	// A truncated line is skipped rather than failing the whole journal
+	journalPath := filepath.Join(tempDir, "set-tab-color", "journal.jsonl")
+	f, err := os.OpenFile(journalPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	f.WriteString(`{"time":"2026-`)
+	f.Close()
+	if entries, err := loadJournal(); err != nil || len(entries) != 3 {
+		t.Errorf("Expected truncated line to be skipped, got %d entries, err %v", len(entries), err)
+	}
+
+	if err := trimJournal(journalPath); err != nil {
+		t.Fatalf("trimJournal() failed: %v", err)
+	}
+	if entries, _ := loadJournal(); len(entries) != 1 || entries[0].TTY != "/dev/ttys002" {
+		t.Errorf("Expected only the newer half after trimming, got %+v", entries)
+	}
+}
+
+// TestRunReapplyTTY tests restoring a tty's history to the terminal named with --tty
+func TestRunReapplyTTY(t *testing.T) {
+	tempDir := t.TempDir()
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", tempDir)
+	os.Setenv("SET_TAB_COLOR_CONFIG", filepath.Join(tempDir, "missing.toml"))
+	defer func() {
+		os.Setenv("XDG_STATE_HOME", originalState)
+		os.Unsetenv("SET_TAB_COLOR_CONFIG")
+	}()
+
+	// A regular file stands in for the pane's terminal
+	tty := filepath.Join(tempDir, "pts-3")
+	if err := os.WriteFile(tty, nil, 0644); err != nil {
+		t.Fatalf("Failed to create tty stand-in: %v", err)
+	}
+
+	setLogLevel(LogError)
+	defer setLogLevel(LogWarn)
+	if err := runReapply([]string{"--tty", tty}); err != nil {
+		t.Errorf("Expected nothing to reapply to be only a warning, got %v", err)
+	}
+
+	if err := appendJournal(newJournalEntries(tty, "tmux-%3", "prod", &Profile{Background: "navy"})); err != nil {
+		t.Fatalf("appendJournal() failed: %v", err)
+	}
+	if err := runReapply([]string{"--tty=" + tty}); err != nil {
+		t.Fatalf("runReapply() failed: %v", err)
+	}
+	if data, _ := os.ReadFile(tty); !contains(string(data), "000080") {
+		t.Errorf("Expected the background to be written to the tty, got %q", data)
+	}
+
+	if err := runReapply([]string{"extra"}); err == nil {
+		t.Error("Expected runReapply() to reject unknown arguments")
+	}
+}
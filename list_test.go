@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -60,6 +62,14 @@ bg = "black"
 			t.Errorf("Expected profile %q not found in results", expected)
 		}
 	}
+
+	// Profiles are listed in a stable, sorted order
+	for i := 1; i < len(profiles); i++ {
+		if profiles[i-1] > profiles[i] {
+			t.Errorf("Expected sorted profiles, got %v", profiles)
+			break
+		}
+	}
 }
 
 // TestListProfileNamesEmpty tests listing when no profiles exist
@@ -117,3 +127,79 @@ func TestListCSSColorNames(t *testing.T) {
 		}
 	}
 }
+
+// TestListProfileDetails tests the -list-profiles -long view
+func TestListProfileDetails(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	configContent := `
+[profiles.prod]
+tab = "red"
+fg = "white"
+
+[profiles.prod.iterm2]
+tab = "darkred"
+
+[profiles.prod.zsh]
+badge = "PROD"
+
+[profiles.staging]
+tab = "orange"
+
+[profiles.base]
+bg = "black"
+
+[watch]
+default = "staging"
+
+[[watch.rules]]
+dir = "~/src/prod/*"
+profile = "base,prod"
+
+[[workspaces.ops.layouts]]
+tmux_window = "ops:db"
+profile = "prod"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+	setFakeEnvironment(&FakeEnvironment{Env: map[string]string{}, ProcessChain: []string{"set-tab-color", "zsh", "iTerm2"}})
+	defer setFakeEnvironment(nil)
+
+	details, err := listProfileDetails("")
+	if err != nil {
+		t.Fatalf("listProfileDetails() failed: %v", err)
+	}
+	if len(details) != 3 {
+		t.Fatalf("Expected 3 profiles, got %d", len(details))
+	}
+
+	prod := details[1]
+	if prod.Name != "prod" {
+		t.Fatalf("Expected profiles sorted by name, got %q second", prod.Name)
+	}
+	if prod.Values == nil || prod.Values.Tab != "darkred" || prod.Values.Badge != "PROD" || prod.Values.Foreground != "white" {
+		t.Errorf("Expected prod resolved for iTerm2 and zsh, got %+v", prod.Values)
+	}
+	if strings.Join(prod.SubProfiles, ",") != "iterm2,zsh" {
+		t.Errorf("Expected sub-profiles iterm2, zsh, got %v", prod.SubProfiles)
+	}
+	expectedRefs := []string{`watch rule 1 (dir "~/src/prod/*")`, `workspace "ops": tmux window ops:db`}
+	if strings.Join(prod.UsedBy, "|") != strings.Join(expectedRefs, "|") {
+		t.Errorf("Expected references %v, got %v", expectedRefs, prod.UsedBy)
+	}
+	if staging := details[2]; len(staging.UsedBy) != 1 || staging.UsedBy[0] != "watch default" {
+		t.Errorf("Expected staging used by the watch default, got %v", staging.UsedBy)
+	}
+
+	var buf bytes.Buffer
+	printProfileDetails(&buf, details)
+	for _, expected := range []string{"prod\n", `values: tab="darkred" fg="white" badge="PROD"`, "sub-profiles: iterm2, zsh", "used by: watch default"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+}
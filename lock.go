@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Global -force flag: replace a sticky profile applied earlier in this session
+var forceApply bool
+
+// isStickyProfile reports whether a profile (or any profile of a comma-separated
+// list) is marked with sticky = true
+func isStickyProfile(profileName string) bool {
+	if profileName == "" {
+		return false
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return false
+	}
+	for _, name := range strings.Split(profileName, ",") {
+		if profile := config.Profiles[strings.TrimSpace(name)]; profile != nil && profile.Sticky {
+			return true
+		}
+	}
+	return false
+}
+
+// checkProfileLock returns an error when a sticky profile other than profileName
+// holds this session, so a prompt hook or the watcher can't silently replace a
+// danger color. An empty profileName stands for colors set directly. -force and
+// reset lift the lock.
+func checkProfileLock(profileName string) error {
+	if forceApply || usesTmuxTargeting() {
+		return nil
+	}
+	state, err := loadSessionState()
+	if err != nil || state == nil {
+		return nil
+	}
+	return profileLockError(state.Profile, state.Locked, profileName)
+}
+
+// profileLockError returns the error for applying profileName while a profile
+// holds the session, or nil if the profile isn't locked or is the same one
+func profileLockError(applied string, locked bool, profileName string) error {
+	if !locked || applied == profileName {
+		return nil
+	}
+	return fmt.Errorf("sticky profile %q is applied in this session; use -force or 'set-tab-color reset' to replace it", redact(applied))
+}
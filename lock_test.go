@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestProfileLock tests that a sticky profile holds the session until -force or reset
+func TestProfileLock(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+	if err := os.WriteFile(configFile, []byte(`[profiles.prod]
+tab = "red"
+sticky = true
+
+[profiles.dev]
+tab = "green"
+`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	t.Setenv("XDG_STATE_HOME", tempDir)
+	t.Setenv("XDG_CACHE_HOME", tempDir)
+	t.Setenv("ITERM_SESSION_ID", "w0t0p0:lock-test")
+
+	recordAppliedState("dev", &Profile{Tab: "green"})
+	if err := checkProfileLock("prod"); err != nil {
+		t.Errorf("Expected no lock after a plain profile, got %v", err)
+	}
+
+	recordAppliedState("prod", &Profile{Tab: "red"})
+	if state, err := loadSessionState(); err != nil || state == nil || !state.Locked {
+		t.Fatalf("Expected the session locked by prod, got %+v, %v", state, err)
+	}
+	if err := checkProfileLock("prod"); err != nil {
+		t.Errorf("Expected the sticky profile itself to apply again, got %v", err)
+	}
+	for _, name := range []string{"dev", ""} {
+		if err := checkProfileLock(name); err == nil || !strings.Contains(err.Error(), `"prod"`) {
+			t.Errorf("checkProfileLock(%q) = %v, expected the lock to name prod", name, err)
+		}
+	}
+
+	// Animations and the picker set colors directly, so they are held off too
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	err := runTabAnimation([]string{"ff0000", "0000ff"}, time.Second)
+	escapeWriter = originalWriter
+	if err == nil || buf.Len() != 0 {
+		t.Errorf("Expected the lock to stop the animation before any frame, got %v, %q", err, buf.String())
+	}
+
+	forceApply = true
+	err = checkProfileLock("dev")
+	forceApply = false
+	if err != nil {
+		t.Errorf("Expected -force to override the lock, got %v", err)
+	}
+
+	if err := clearAppliedState(); err != nil {
+		t.Fatalf("clearAppliedState() failed: %v", err)
+	}
+	if err := checkProfileLock("dev"); err != nil {
+		t.Errorf("Expected reset to lift the lock, got %v", err)
+	}
+}
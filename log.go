@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// LogLevel orders diagnostics by importance
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota // Every step of detection and resolution (-verbose)
+	LogInfo                  // Decisions worth knowing about, such as a fallback
+	LogWarn                  // Problems that don't stop the run (the default)
+	LogError                 // Only errors, which are always printed (-quiet)
+)
+
+// logLevelNames maps -log-level values to levels
+var logLevelNames = map[string]LogLevel{
+	"debug": LogDebug,
+	"info":  LogInfo,
+	"warn":  LogWarn,
+	"error": LogError,
+}
+
+// logFormats lists the valid -log-format values
+var logFormats = []string{"text", "json"}
+
+// Environment variables providing the log level and format when no flag sets them
+const (
+	logLevelEnvVar  = "SET_TAB_COLOR_LOG_LEVEL"
+	logFormatEnvVar = "SET_TAB_COLOR_LOG_FORMAT"
+)
+
+var (
+	logLevel  = LogWarn
+	logJSON   bool
+	logWriter io.Writer = os.Stderr // Replaced in tests
+)
+
+// logEntry is one line of -log-format json output
+type logEntry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"msg"`
+}
+
+// String returns the -log-level name of a level
+func (level LogLevel) String() string {
+	for name, l := range logLevelNames {
+		if l == level {
+			return name
+		}
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(level))
+}
+
+// parseLogLevel parses a -log-level value
+func parseLogLevel(value string) (LogLevel, error) {
+	level, ok := logLevelNames[strings.ToLower(value)]
+	if !ok {
+		return 0, fmt.Errorf("invalid log level %q (expected one of: debug, info, warn, error)", value)
+	}
+	return level, nil
+}
+
+// configureLogging sets the log level and format. An explicit level wins over
+// -verbose and -quiet, which win over $SET_TAB_COLOR_LOG_LEVEL; the format flag
+// likewise wins over $SET_TAB_COLOR_LOG_FORMAT.
+func configureLogging(level, format string, verbose, quiet bool) error {
+	switch {
+	case level != "":
+	case verbose:
+		level = "debug"
+	case quiet:
+		level = "error"
+	default:
+		level = os.Getenv(logLevelEnvVar)
+	}
+	if level == "" {
+		level = "warn"
+	}
+	parsed, err := parseLogLevel(level)
+	if err != nil {
+		return err
+	}
+
+	if format == "" {
+		format = os.Getenv(logFormatEnvVar)
+	}
+	if format == "" {
+		format = "text"
+	}
+	if !containsString(logFormats, format) {
+		return fmt.Errorf("invalid log format %q (expected one of: %s)", format, strings.Join(logFormats, ", "))
+	}
+
+	setLogLevel(parsed)
+	logJSON = format == "json"
+	return nil
+}
+
+// setLogLevel changes the log level. verboseMode follows it, since callers use it
+// to skip work whose debug output wouldn't be shown.
+func setLogLevel(level LogLevel) {
+	logLevel = level
+	verboseMode = level <= LogDebug
+}
+
+// logEnabled reports whether messages of a level are shown
+func logEnabled(level LogLevel) bool {
+	return level >= logLevel
+}
+
+// logf writes a redacted message of a level to the log. In text format, debug and
+// info messages are written as given, so indented and multi-line output keeps its
+// layout; warnings and errors get a prefix. In JSON format each message is a line.
+func logf(level LogLevel, format string, args ...interface{}) {
+	if !logEnabled(level) {
+		return
+	}
+	message := redact(fmt.Sprintf(format, args...))
+
+	if logJSON {
+		message = strings.TrimSpace(message)
+		if message == "" {
+			return
+		}
+		data, err := json.Marshal(logEntry{Time: time.Now(), Level: level.String(), Message: message})
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(logWriter, "%s\n", data)
+		return
+	}
+
+	switch level {
+	case LogWarn:
+		fmt.Fprintf(logWriter, "Warning: %s\n", message)
+	case LogError:
+		fmt.Fprintf(logWriter, "Error: %s\n", message)
+	default:
+		fmt.Fprint(logWriter, message)
+	}
+}
+
+// debugf writes diagnostic output shown with -verbose or -log-level debug
+func debugf(format string, args ...interface{}) {
+	logf(LogDebug, format, args...)
+}
+
+// infof notes a decision such as a fallback, shown with -log-level info
+func infof(format string, args ...interface{}) {
+	logf(LogInfo, format, args...)
+}
+
+// warnf prints a warning unless -quiet is set
+func warnf(format string, args ...interface{}) {
+	logf(LogWarn, format, args...)
+}
+
+// errorf logs an error that doesn't end the run, as in a resident watcher
+func errorf(format string, args ...interface{}) {
+	logf(LogError, format, args...)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// captureLog redirects the log to a buffer at a level until the returned function is called
+func captureLog(level LogLevel, jsonFormat bool) (*bytes.Buffer, func()) {
+	var buf bytes.Buffer
+	originalWriter := logWriter
+	logWriter = &buf
+	setLogLevel(level)
+	logJSON = jsonFormat
+	return &buf, func() {
+		logWriter = originalWriter
+		setLogLevel(LogWarn)
+		logJSON = false
+	}
+}
+
+// TestLogLevels tests that messages below the log level are dropped
+func TestLogLevels(t *testing.T) {
+	buf, restore := captureLog(LogInfo, false)
+	defer restore()
+
+	debugf("detail\n")
+	infof("  Using the xterm escape sequences\n")
+	warnf("profile %q has no colors", "empty")
+	errorf("watch: %s", "gone")
+
+	expected := "  Using the xterm escape sequences\n" +
+		"Warning: profile \"empty\" has no colors\n" +
+		"Error: watch: gone\n"
+	if buf.String() != expected {
+		t.Errorf("Expected log:\n%s\ngot:\n%s", expected, buf.String())
+	}
+	if verboseMode {
+		t.Error("Expected verboseMode off at info level")
+	}
+}
+
+// TestLogJSON tests JSON log lines
+func TestLogJSON(t *testing.T) {
+	buf, restore := captureLog(LogDebug, true)
+	defer restore()
+
+	debugf("\nApplying profile settings:\n")
+	debugf("\n")
+	warnf("could not load config")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines (blank messages dropped), got %q", lines)
+	}
+	var entry logEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Invalid JSON log line %q: %v", lines[0], err)
+	}
+	if entry.Level != "debug" || entry.Message != "Applying profile settings:" || entry.Time.IsZero() {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil || entry.Level != "warn" {
+		t.Errorf("Expected a warn entry, got %+v (%v)", entry, err)
+	}
+}
+
+// TestConfigureLogging tests the precedence of flags and environment variables
+func TestConfigureLogging(t *testing.T) {
+	defer func() {
+		setLogLevel(LogWarn)
+		logJSON = false
+	}()
+
+	tests := []struct {
+		name     string
+		level    string
+		format   string
+		verbose  bool
+		quiet    bool
+		envLevel string
+		expected LogLevel
+		json     bool
+		wantErr  bool
+	}{
+		{name: "default", expected: LogWarn},
+		{name: "verbose", verbose: true, expected: LogDebug},
+		{name: "quiet", quiet: true, expected: LogError},
+		{name: "level wins over verbose", level: "info", verbose: true, expected: LogInfo},
+		{name: "environment", envLevel: "debug", expected: LogDebug},
+		{name: "quiet wins over environment", envLevel: "debug", quiet: true, expected: LogError},
+		{name: "json", format: "json", expected: LogWarn, json: true},
+		{name: "bad level", level: "loud", wantErr: true},
+		{name: "bad format", format: "xml", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(logLevelEnvVar, test.envLevel)
+			t.Setenv(logFormatEnvVar, "")
+			err := configureLogging(test.level, test.format, test.verbose, test.quiet)
+			if test.wantErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("configureLogging() failed: %v", err)
+			}
+			if logLevel != test.expected || logJSON != test.json {
+				t.Errorf("Expected level %s and json %v, got %s and %v", test.expected, test.json, logLevel, logJSON)
+			}
+			if verboseMode != (test.expected == LogDebug) {
+				t.Errorf("Expected verboseMode to follow the level")
+			}
+		})
+	}
+}
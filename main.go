@@ -4,56 +4,249 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"time"
 )
 
 func main() {
 	// Define command-line flags
 	var (
-		tabColor        = flag.String("tab", "", "Set tab color")
-		foregroundColor = flag.String("fg", "", "Set foreground color")
+		tabColor        = flag.String("tab", "", "Set tab color (\"distinct\" picks one unlike other sessions' tabs)")
+		foregroundColor = flag.String("fg", "", "Set foreground color (\"auto\" picks black or white for contrast with -bg or -tab)")
 		backgroundColor = flag.String("bg", "", "Set background color")
 		presetName      = flag.String("preset", "", "Set iTerm2 color preset")
+		themeName       = flag.String("theme", "", "Apply a built-in theme (solarized-dark, solarized-light, dracula, nord, gruvbox)")
+		badgeText       = flag.String("badge", "", "Set iTerm2 badge text")
+		titleText       = flag.String("title", "", "Set tab and window title")
+		lightenAmount   = flag.Float64("lighten", 0, "Lighten -tab/-fg/-bg by this many percentage points of HSL lightness")
+		darkenAmount    = flag.Float64("darken", 0, "Darken -tab/-fg/-bg by this many percentage points of HSL lightness")
+		cycleColors     = flag.String("cycle", "", "Animate the tab color through these comma-separated colors, ending on the last")
+		pulseTab        = flag.Bool("pulse", false, "Animate the -tab color between it and a darker shade")
+		animDuration    = flag.Duration("duration", 5*time.Second, "How long -cycle or -pulse animate")
+		preview         = flag.Duration("preview", 0, "Apply the colors for this long (e.g. 5s), then revert to the previous ones")
 		profileName     = flag.String("profile", "", "Use predefined profile from config file")
-		terminalType    = flag.String("terminal", "", "Override terminal type for subprofile selection (iterm2, vscode, ssh, tmux, etterminal)")
+		explain         = flag.Bool("explain", false, "Print which profile layer or flag each -profile value came from before applying")
+		terminalType    = flag.String("terminal", "", "Override terminal type for subprofile selection (e.g. iterm2, vscode, ghostty, ssh, tmux; 'help' lists all)")
+		shellName       = flag.String("shell", "", "Override shell type for subprofile selection (e.g. zsh, bash, fish)")
+		appearanceMode  = flag.String("mode", "", "Select the dark or light sub-profile (dark, light, auto; default from $SET_TAB_COLOR_MODE or the macOS appearance)")
 		listProfiles    = flag.Bool("list-profiles", false, "List all available profiles")
-		listColors      = flag.Bool("list-colors", false, "List all available CSS color names")
+		longListing     = flag.Bool("long", false, "With -list-profiles, show each profile's values, sub-profiles, and the rules using it")
+		listColors      = flag.Bool("list-colors", false, "List all available CSS color names, or those matching the names or hues given as arguments")
+		colorSort       = flag.String("sort", "name", "Order -list-colors by name or hue")
+		swatch          = flag.Bool("swatch", false, "Show -list-colors names on their color instead of in it")
 		verbose         = flag.Bool("verbose", false, "Enable verbose output for debugging")
+		dryRun          = flag.Bool("dry-run", false, "Print the escape sequences or it2setcolor commands instead of running them")
+		jsonFlag        = flag.Bool("json", false, "Print -list-profiles, -list-colors, and detect output as JSON")
+		sessionID       = flag.String("session", "", "Apply to the iTerm2 session with this ID (e.g. from $ITERM_SESSION_ID) via the Python API")
+		allTabs         = flag.Bool("all-tabs", false, "Apply to every iTerm2 session via the Python API")
+		itermAPI        = flag.Bool("iterm-api", false, "Apply to the current iTerm2 session via the Python API instead of escape sequences")
+		scope           = flag.String("scope", scopeSession, "Apply to the current iTerm2 session (split pane) only, or to every session in its tab or window (session, tab, window)")
+		tmuxWindow      = flag.String("tmux-window", "", "Apply to this tmux window (e.g. 2 or work:logs) with tmux options")
+		tmuxPane        = flag.String("tmux-pane", "", "Apply to this tmux pane (e.g. %3) with tmux options")
+		readStdin       = flag.Bool("stdin", false, "Read commands such as \"tab red\" or \"profile prod\" from stdin, one per line")
+		interactive     = flag.Bool("i", false, "Pick a tab, background, foreground color, or theme interactively, previewing as you move")
+		quiet           = flag.Bool("quiet", false, "Suppress warnings")
+		logLevelFlag    = flag.String("log-level", "", "Show diagnostics at this level and above: debug, info, warn, error (default warn, or $SET_TAB_COLOR_LOG_LEVEL)")
+		logFormatFlag   = flag.String("log-format", "", "Write diagnostics as text or json lines (default text, or $SET_TAB_COLOR_LOG_FORMAT)")
+		strict          = flag.Bool("strict", false, "Fail instead of skipping settings the terminal doesn't support")
+		preferIt2set    = flag.Bool("prefer-it2setcolor", false, "Use iTerm2's it2setcolor instead of built-in escape sequences when it is installed")
+		requireIt2set   = flag.Bool("require-it2setcolor", false, "Always use iTerm2's it2setcolor, failing if it is missing")
+		timeout         = flag.Duration("timeout", defaultBackendTimeout, "Give up on it2setcolor, tmux, the Python API, or a blocked terminal after this long (0 waits indefinitely)")
+		async           = flag.Bool("async", false, "Apply in a background process and return immediately, e.g. from a prompt hook")
+		ttyPath         = flag.String("tty", "", "Write escape sequences to this terminal (e.g. /dev/ttys003) instead of stdout or /dev/tty")
+		force           = flag.Bool("force", false, "Replace a sticky profile applied earlier in this session, and resend settings this tty already shows")
+		timing          = flag.Bool("timing", false, "Report the time spent loading the config, detecting, normalizing colors, and in the backend on stderr")
 	)
+	flag.Var(&configPaths, "config", "Use this config file instead of the default; repeat to merge files in order, later ones winning")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] <command>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nCommands:\n")
+		fmt.Fprintf(os.Stderr, "  detect                              Show detected terminals and shell (and the -profile it resolves to)\n")
+		fmt.Fprintf(os.Stderr, "  show-profile <name>                 Show how a profile resolves here, layer by layer\n")
+		fmt.Fprintf(os.Stderr, "  caps                                Show what the detected terminal can display\n")
+		fmt.Fprintf(os.Stderr, "  preview <color>...                  Show swatches of colors, or of every color of -profile\n")
+		fmt.Fprintf(os.Stderr, "  reset                               Restore tab, foreground, and background to defaults\n")
+		fmt.Fprintf(os.Stderr, "  status [--format FORMAT]            Print the last applied profile (text, sketchybar, polybar, waybar)\n")
+		fmt.Fprintf(os.Stderr, "  history [--all] [-n N]              Show the settings applied to this tty (or every tty)\n")
+		fmt.Fprintf(os.Stderr, "  reapply [--tty TTY]                 Apply the last recorded settings of this tty again\n")
+		fmt.Fprintf(os.Stderr, "  session-info                        Show the iTerm2 profile and colors of this session (fg/bg only outside iTerm2)\n")
+		fmt.Fprintf(os.Stderr, "  config validate                     Check the config file for errors\n")
+		fmt.Fprintf(os.Stderr, "  config audit [--threshold N]        Warn about profiles whose colors look alike\n")
+		fmt.Fprintf(os.Stderr, "  config export                       Print the config as a bundle for another host\n")
+		fmt.Fprintf(os.Stderr, "  config import [file]                Replace the config with a bundle from a file or stdin\n")
+		fmt.Fprintf(os.Stderr, "  profile add <name> key=value...     Create a profile (keys: tab, fg, bg, preset)\n")
+		fmt.Fprintf(os.Stderr, "  profile set <name> key=value...     Change values of an existing profile\n")
+		fmt.Fprintf(os.Stderr, "  profile remove <name> [key...]      Remove a profile, or only the given keys\n")
+		fmt.Fprintf(os.Stderr, "  theme import <file> [name]          Store an .itermcolors file or base16 scheme as a theme\n")
+		fmt.Fprintf(os.Stderr, "  workspace apply <name>              Apply the profiles of every layout in a workspace\n")
+		fmt.Fprintf(os.Stderr, "  watch                               Stay resident and reapply the [watch] profile on changes\n")
+		fmt.Fprintf(os.Stderr, "  watch cwd <dir>                     Report a directory change to this session's watcher\n")
+		fmt.Fprintf(os.Stderr, "  rules test [--dir DIR]              Show which [watch] rule selects a profile, and why (also --kube-context)\n")
+		fmt.Fprintf(os.Stderr, "  client [--tty TTY] <request>        Send apply, set, reset, or ping to a session's watcher\n")
+		fmt.Fprintf(os.Stderr, "  events [--follow] [--tty TTY]       Print a watcher's applied, matched, and error events as JSON lines\n")
+		fmt.Fprintf(os.Stderr, "  ssh [ssh args...]                   Apply -profile and pass it to the remote end of ssh\n")
+		fmt.Fprintf(os.Stderr, "  remote                              Reapply the profile passed in by the ssh command\n")
+		fmt.Fprintf(os.Stderr, "  help <terminal>                     Show setup steps and limitations for a terminal (%s)\n", strings.Join(knownTerminalNames(), ", "))
 		fmt.Fprintf(os.Stderr, "\nOptions:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nColor formats supported:\n")
 		fmt.Fprintf(os.Stderr, "  - Hex colors: #f80, #ff8800\n")
 		fmt.Fprintf(os.Stderr, "  - CSS color names: red, blue, lightblue, etc.\n")
+		fmt.Fprintf(os.Stderr, "  - Custom color names from the [colors] config table\n")
 		fmt.Fprintf(os.Stderr, "  - default: restore default color\n")
 		fmt.Fprintf(os.Stderr, "\nConfiguration:\n")
-		fmt.Fprintf(os.Stderr, "  Config file: ~/.config/set-tab-color.toml (or $SET_TAB_COLOR_CONFIG)\n")
+		fmt.Fprintf(os.Stderr, "  Config file: ~/.config/set-tab-color.toml (or $SET_TAB_COLOR_CONFIG, or -config)\n")
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s -tab red\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -fg white -bg black\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -tab #ff8800 -fg lightblue\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -preset 'Solarized Dark'\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -preset 'Ocean' -tab red\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -theme dracula -tab red\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -tab 'red@dark(20%%)' -fg '#ff8800 lighten 10%%'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -tab red -badge PROD -title 'prod db'\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -cycle red,orange -duration 5s\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -profile myprofile\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -profile myprofile -terminal iterm2\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -profile myprofile -mode dark\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -profile myprofile -dry-run\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -bg navy -preview 5s\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -session \"$ITERM_SESSION_ID\" -tab red\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -i\n", os.Args[0])
 	}
 
 	flag.Parse()
+	timingMode = *timing
+	if timingMode {
+		// Everything before flag parsing, including package initialization
+		addPhaseTime("startup", time.Since(processStart))
+		defer writeTimings(os.Stderr)
+	}
 
-	// Set global verbose mode
-	verboseMode = *verbose
+	// -verbose and -quiet are shorthands for the debug and error log levels
+	if err := configureLogging(*logLevelFlag, *logFormatFlag, *verbose, *quiet); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Run '%s -h' for usage.\n", os.Args[0])
+		os.Exit(exitUsage)
+	}
+	preferIt2setcolor = *preferIt2set
+	requireIt2setcolor = *requireIt2set
+	dryRunMode = *dryRun
+	jsonOutput = *jsonFlag
+	targetSessionID = *sessionID
+	targetAllTabs = *allTabs
+	targetTmuxWindow = *tmuxWindow
+	strictMode = *strict
+	forceApply = *force
+	targetTmuxPane = *tmuxPane
+	appearanceOverride = *appearanceMode
+	shellOverride = *shellName
+	previewDuration = *preview
+	backendTimeout = *timeout
+	claimAsyncFixture()
+
+	// Report conflicting or ignored flags precisely instead of dumping usage
+	setFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	// Arguments after -list-colors filter the list rather than naming a command
+	subcommand := ""
+	if flag.NArg() > 0 && !*listColors {
+		subcommand = flag.Arg(0)
+	}
+	if *terminalType != "" || *shellName != "" {
+		// Terminals and shells added in the [detect] table are valid -terminal values; errors
+		// are reported when the config is loaded again below
+		loadConfig()
+	}
+	// Without anything to apply, the config's default_profile is applied as if given with -profile
+	if usesDefaultProfile(setFlags, subcommand) {
+		if config, err := loadConfig(); err == nil && config.DefaultProfile != "" {
+			*profileName = config.DefaultProfile
+			setFlags["profile"] = true
+		}
+	}
+	warnings, err := checkFlagCombination(setFlags, *terminalType, subcommand)
+	for _, warning := range warnings {
+		warnf("%s", warning)
+	}
+	if err == nil {
+		if _, modeErr := parseAppearanceMode(*appearanceMode); modeErr != nil {
+			err = fmt.Errorf("%v for -mode", modeErr)
+		} else if !containsString(targetScopes, *scope) {
+			err = fmt.Errorf("invalid value %q for -scope (expected one of: %s)", *scope, strings.Join(targetScopes, ", "))
+		} else if !containsString(colorSortOrders, *colorSort) {
+			err = fmt.Errorf("invalid value %q for -sort (expected one of: %s)", *colorSort, strings.Join(colorSortOrders, ", "))
+		} else if _, ok := parseShellType(*shellName); *shellName != "" && !ok {
+			err = fmt.Errorf("unknown shell type %q for -shell (expected one of: %s)", *shellName, strings.Join(knownShellNames(), ", "))
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Run '%s -h' for usage.\n", os.Args[0])
+		os.Exit(exitUsage)
+	}
+	if *itermAPI {
+		if err := useITermAPIForCurrentSession(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	}
+	if err := useScope(*scope); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+
+	// Hand the work to a background process; it runs this same command line
+	if *async && !dryRunMode && os.Getenv(asyncChildEnvVar) == "" && !*listProfiles && !*listColors &&
+		(subcommand == "" || containsString(subcommandFlags[subcommand], "async")) {
+		if err := startAsync(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+
+	// Escape sequences go to the terminal even when stdout is piped or redirected
+	if err := useTerminalOutput(*ttyPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+
+	// Handle subcommands
+	if subcommand != "" {
+		opts := commandOptions{ProfileName: *profileName, TerminalType: *terminalType}
+		if err := runSubcommand(flag.Args(), opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
 
 	// Handle listing operations
+	if *listProfiles && *longListing {
+		if err := runListProfileDetails(*terminalType); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading profiles: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+
 	if *listProfiles {
 		profiles, err := listProfileNames()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading profiles: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
-		if len(profiles) == 0 {
+		if jsonOutput {
+			if err := printJSON(profiles); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		} else if len(profiles) == 0 {
 			fmt.Println("No profiles found.")
 		} else {
 			fmt.Println("Available profiles:")
@@ -65,81 +258,504 @@ func main() {
 	}
 
 	if *listColors {
-		coloredOutput, err := listCSSColorNamesFormatted()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading CSS colors: %v\n", err)
-			os.Exit(1)
+		if err := runListColors(flag.Args(), *colorSort, *swatch); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
 		}
+		return
+	}
 
-		fmt.Println("Available CSS color names:")
-		fmt.Println(coloredOutput)
+	// Apply a batch of commands with a single detection
+	if *readStdin {
+		if err := runBatch(os.Stdin, *terminalType); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
 		return
 	}
 
-	// Validate terminal type if specified without profile
-	if *terminalType != "" && *profileName == "" {
-		fmt.Fprintf(os.Stderr, "Error: -terminal option can only be used with -profile\n\n")
-		flag.Usage()
-		os.Exit(1)
+	// Browse colors and themes with a live preview
+	if *interactive {
+		if err := runPicker(*terminalType); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		return
 	}
 
 	// Handle profile-based configuration
 	if *profileName != "" {
-		// Cannot mix profile with individual colors or preset
-		if *tabColor != "" || *foregroundColor != "" || *backgroundColor != "" || *presetName != "" {
-			fmt.Fprintf(os.Stderr, "Error: Cannot use -profile with individual color options or -preset\n\n")
-			flag.Usage()
-			os.Exit(1)
+		// Detection rules come from the config
+		if _, err := loadConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading profile: %v\n", err)
+			os.Exit(exitCode(err))
 		}
-
 		terminalInfo := detectTerminalAndShell(*terminalType)
+		useEscapeBackendFor(terminalInfo)
+		if usesTmuxTargeting() {
+			// Resolve as if run in the target, like workspace layouts
+			terminalInfo.Terminals = withTerminalOverride(TerminalShellInfo{Terminals: []TerminalType{TerminalTypeTmux}}, *terminalType).Terminals
+		}
 		profile, err := getProfileWithTerminalInfo(*profileName, &terminalInfo)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading profile: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
+		}
+		// Colors given on the command line override the profile, like they override -preset
+		overrides := Profile{Tab: *tabColor, Foreground: *foregroundColor, Background: *backgroundColor,
+			Preset: *presetName, Theme: *themeName, Badge: *badgeText, Title: *titleText}
+		overridden := overlayProfile(*profile, overrides)
+		profile = &overridden
+		if *explain {
+			if err := runExplain(os.Stdout, *profileName, &terminalInfo, overrides); err != nil {
+				fmt.Fprintf(os.Stderr, "Error explaining profile: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		}
+		if err := resolveDistinctTab(profile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error picking distinct color: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		if err := resolveAutoForeground(profile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error picking foreground color: %v\n", err)
+			os.Exit(exitCode(err))
 		}
 
+		if err := checkProfileLock(*profileName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying profile: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		// Prompt hooks rerun the same profile; don't resend what the tty already shows
+		if previewDuration == 0 && alreadyApplied(*profileName, profile) {
+			debugf("Profile %s is already applied to this tty; skipping\n", redact(*profileName))
+			return
+		}
+
+		var original *Profile
+		if previewDuration > 0 {
+			original = previewOriginal()
+		}
 		if err := applyProfile(profile); err != nil {
 			fmt.Fprintf(os.Stderr, "Error applying profile: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
+		}
+		if original != nil {
+			if err := endPreview(original, profile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+			return
+		}
+
+		// Remember what was applied for status bars; another tmux window's colors aren't ours
+		if !usesTmuxTargeting() {
+			recordAppliedState(*profileName, profile)
 		}
 		return
 	}
 
 	// Check if at least one color option or preset was provided
-	if *tabColor == "" && *foregroundColor == "" && *backgroundColor == "" && *presetName == "" {
-		fmt.Fprintf(os.Stderr, "Error: At least one color option, preset, or profile must be specified\n\n")
+	if *tabColor == "" && *foregroundColor == "" && *backgroundColor == "" && *presetName == "" &&
+		*themeName == "" && *badgeText == "" && *titleText == "" && *cycleColors == "" {
+		fmt.Fprintf(os.Stderr, "Error: At least one color option, preset, or profile must be specified (or default_profile set in the config)\n\n")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+
+	// Load custom color names from config so they can be used on the command line
+	if _, err := loadConfig(); err != nil {
+		warnf("could not load custom colors: %v", err)
+	}
+	if err := checkProfileLock(""); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+	useEscapeBackendFor(detectTerminals(""))
+
+	// Pick the distinct tab color before anything is applied or recorded
+	if *tabColor == distinctColorName {
+		profile := &Profile{Tab: *tabColor}
+		if err := resolveDistinctTab(profile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error picking distinct color: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		*tabColor = profile.Tab
+	}
+
+	// -lighten/-darken become modifiers on each color given on the command line
+	if *lightenAmount != 0 || *darkenAmount != 0 {
+		for _, amount := range []float64{*lightenAmount, *darkenAmount} {
+			if amount < 0 || amount > 100 {
+				fmt.Fprintf(os.Stderr, "Error: -lighten and -darken take a percentage between 0 and 100, got %g\n", amount)
+				os.Exit(exitUsage)
+			}
+		}
+		*tabColor = withLightnessFlags(*tabColor, *lightenAmount, *darkenAmount)
+		*foregroundColor = withLightnessFlags(*foregroundColor, *lightenAmount, *darkenAmount)
+		*backgroundColor = withLightnessFlags(*backgroundColor, *lightenAmount, *darkenAmount)
+	}
+
+	// Swap the tab color for a colorblind-safe one when the [colorblind] table asks for it
+	*tabColor = colorblindSafeTab(*tabColor)
+
+	// Pick the foreground once the colors it must contrast with are known
+	if *foregroundColor == autoColorName {
+		profile := &Profile{Tab: *tabColor, Foreground: *foregroundColor, Background: *backgroundColor, Theme: *themeName}
+		if err := resolveAutoForeground(profile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error picking foreground color: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		*foregroundColor = profile.Foreground
+	}
+
+	// Resolve the animation colors before anything is applied, so bad colors fail early
+	var animation []string
+	if *cycleColors != "" || *pulseTab {
+		var err error
+		if *cycleColors != "" {
+			animation, err = parseCycleColors(*cycleColors)
+		} else {
+			animation, err = pulseColors(*tabColor)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	}
+
+	// Capture what a preview reverts to before anything changes
+	var original *Profile
+	if previewDuration > 0 {
+		original = previewOriginal()
+	}
+
+	// Other sessions are updated in a single Python API call, and tmux targets with tmux options
+	if usesSessionTargeting() || usesTmuxTargeting() {
+		profile := &Profile{Tab: *tabColor, Foreground: *foregroundColor, Background: *backgroundColor, Preset: *presetName, Theme: *themeName, Badge: *badgeText, Title: *titleText}
+		if err := applyProfile(profile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying colors: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		if original != nil {
+			if err := endPreview(original, profile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		}
+		return
 	}
 
+	// Colors given directly are skipped the same way when they are unchanged
+	applied := &Profile{Tab: *tabColor, Foreground: *foregroundColor, Background: *backgroundColor, Preset: *presetName, Theme: *themeName, Badge: *badgeText, Title: *titleText}
+	if animation == nil && original == nil && alreadyApplied("", applied) {
+		debugf("These colors are already applied to this tty; skipping\n")
+		return
+	}
+
+	// The settings reach the terminal in one batch; nothing is sent if one of them fails
+	batch, _ := startBatch()
+
 	// Apply preset first if specified (so individual colors can override it)
 	if *presetName != "" {
 		if err := runSetPreset(*presetName); err != nil {
 			fmt.Fprintf(os.Stderr, "Error setting preset: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
+		}
+	}
+
+	if *themeName != "" {
+		if err := runSetTheme(*themeName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting theme: %v\n", err)
+			os.Exit(exitCode(err))
 		}
 	}
 
-	// Set colors based on provided arguments (these override preset settings)
-	if *tabColor != "" {
+	// Set colors based on provided arguments (these override preset and theme settings).
+	// An animated tab is set last instead.
+	if *tabColor != "" && animation == nil {
 		if err := runSetColor(TabColor, *tabColor); err != nil {
 			fmt.Fprintf(os.Stderr, "Error setting tab color: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 	}
 
 	if *foregroundColor != "" {
 		if err := runSetColor(ForegroundColor, *foregroundColor); err != nil {
 			fmt.Fprintf(os.Stderr, "Error setting foreground color: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 	}
 
 	if *backgroundColor != "" {
 		if err := runSetColor(BackgroundColor, *backgroundColor); err != nil {
 			fmt.Fprintf(os.Stderr, "Error setting background color: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitCode(err))
+		}
+	}
+
+	if *badgeText != "" {
+		if err := runSetBadge(*badgeText); err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting badge: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	}
+
+	if *titleText != "" {
+		if err := runSetTitle(*titleText); err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting title: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	}
+
+	if err := batch.flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error applying colors: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+
+	if animation != nil {
+		if err := runTabAnimation(animation, *animDuration); err != nil {
+			fmt.Fprintf(os.Stderr, "Error animating tab color: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		*tabColor = animation[len(animation)-1]
+	}
+
+	if err := applyTmuxChrome(&Profile{Tab: *tabColor}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error styling tmux: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+
+	// A preview reverts instead of being remembered
+	if original != nil {
+		if err := endPreview(original, applied); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+
+	// Remember what was applied for status bars
+	applied.Tab = *tabColor
+	recordAppliedState("", applied)
+}
+
+// colorFlags are the flags that set colors, the badge, or the title directly
+var colorFlags = []string{"tab", "fg", "bg", "preset", "theme", "badge", "title"}
+
+// globalFlags are accepted in every mode
+var globalFlags = []string{"verbose", "dry-run", "prefer-it2setcolor", "require-it2setcolor", "quiet", "strict", "timeout", "tty", "log-level", "log-format", "config", "force", "timing"}
+
+// subcommandFlags lists the option flags each subcommand reads besides the global ones
+var subcommandFlags = map[string][]string{
+	"detect":       {"profile", "terminal", "shell", "mode", "json"},
+	"show-profile": {"terminal", "shell", "mode", "json"},
+	"caps":         {"terminal", "json"},
+	"preview":      {"profile", "terminal", "shell", "mode", "json"},
+	"status":       {"json"},
+	"history":      {"json"},
+	"reapply":      {"async"},
+	"reset":        {"session", "all-tabs", "iterm-api", "scope", "tmux-window", "tmux-pane", "async"},
+	"session-info": {"session", "all-tabs", "iterm-api", "scope", "json"},
+	"config":       {"json"},
+	"profile":      {},
+	"theme":        {},
+	"workspace":    {"mode"},
+	"watch":        {"terminal", "shell", "mode"},
+	"rules":        {"json"},
+	"client":       {},
+	"events":       {},
+	"ssh":          {"profile", "terminal", "shell", "mode"},
+	"remote":       {"async"},
+	"help":         {},
+}
+
+// usesDefaultProfile reports whether nothing to apply, list, or run was given, so
+// the config's default_profile is applied
+func usesDefaultProfile(set map[string]bool, subcommand string) bool {
+	if subcommand != "" {
+		return false
+	}
+	actions := append([]string{"profile", "list-profiles", "list-colors", "stdin", "i", "cycle", "pulse", "lighten", "darken"}, colorFlags...)
+	return len(setFlagList(set, actions)) == 0
+}
+
+// checkFlagCombination validates which flags were set together. Combinations that
+// can't be honored are errors naming the flags involved; flags that would simply be
+// ignored produce warnings.
+func checkFlagCombination(set map[string]bool, terminalType, subcommand string) ([]string, error) {
+	var warnings []string
+
+	if terminalType != "" {
+		if _, ok := parseTerminalType(terminalType); !ok {
+			return nil, fmt.Errorf("unknown terminal type %q for -terminal (expected one of: %s)",
+				terminalType, strings.Join(knownTerminalNames(), ", "))
+		}
+	}
+
+	if set["async"] && set["dry-run"] {
+		warnings = append(warnings, "-async is ignored with -dry-run")
+	}
+
+	if subcommand != "" {
+		accepted, known := subcommandFlags[subcommand]
+		if !known {
+			return nil, nil // runSubcommand reports unknown commands
+		}
+		for _, name := range sortedFlagNames(set) {
+			if !containsString(globalFlags, name) && !containsString(accepted, name) {
+				warnings = append(warnings, fmt.Sprintf("-%s is ignored by the %s command", name, subcommand))
+			}
+		}
+		return warnings, nil
+	}
+
+	setColors := setFlagList(set, colorFlags)
+
+	if !set["list-colors"] {
+		for _, name := range setFlagList(set, []string{"sort", "swatch"}) {
+			warnings = append(warnings, fmt.Sprintf("-%s has no effect without -list-colors", name))
+		}
+	}
+
+	if set["list-profiles"] && set["list-colors"] {
+		return nil, fmt.Errorf("-list-profiles and -list-colors cannot be used together")
+	}
+	if set["long"] && !set["list-profiles"] {
+		warnings = append(warnings, "-long has no effect without -list-profiles")
+	}
+	for _, listing := range []string{"list-profiles", "list-colors"} {
+		if !set[listing] {
+			continue
+		}
+		ignored := []string{"profile", "terminal", "shell", "mode", "session", "all-tabs", "iterm-api", "scope", "tmux-window", "tmux-pane", "cycle", "pulse", "stdin", "i", "preview", "async"}
+		if listing == "list-profiles" && set["long"] {
+			// The detail view resolves profiles for a terminal, shell, and mode
+			ignored = []string{"profile", "session", "all-tabs", "iterm-api", "scope", "tmux-window", "tmux-pane", "cycle", "pulse", "stdin", "i", "preview", "async"}
+		}
+		for _, name := range append(setColors, setFlagList(set, ignored)...) {
+			warnings = append(warnings, fmt.Sprintf("-%s is ignored with -%s", name, listing))
+		}
+		return warnings, nil
+	}
+
+	if set["i"] {
+		var conflicting []string
+		for _, name := range append(setFlagList(set, []string{"profile", "stdin"}), setColors...) {
+			conflicting = append(conflicting, "-"+name)
+		}
+		for _, name := range setFlagList(set, []string{"cycle", "pulse", "lighten", "darken", "dry-run", "preview", "iterm-api", "scope", "async"}) {
+			conflicting = append(conflicting, "-"+name)
+		}
+		if len(conflicting) > 0 {
+			return nil, fmt.Errorf("-i cannot be combined with %s", strings.Join(conflicting, ", "))
+		}
+	}
+
+	if set["stdin"] {
+		var conflicting []string
+		for _, name := range append(setFlagList(set, []string{"profile"}), setColors...) {
+			conflicting = append(conflicting, "-"+name)
+		}
+		for _, name := range setFlagList(set, []string{"cycle", "pulse", "lighten", "darken", "preview", "async"}) {
+			conflicting = append(conflicting, "-"+name)
+		}
+		if len(conflicting) > 0 {
+			return nil, fmt.Errorf("-stdin cannot be combined with %s; put the commands on stdin", strings.Join(conflicting, ", "))
+		}
+	}
+
+	if set["session"] && set["all-tabs"] {
+		return nil, fmt.Errorf("-session and -all-tabs cannot be used together")
+	}
+	if set["scope"] && set["all-tabs"] {
+		return nil, fmt.Errorf("-scope and -all-tabs cannot be used together")
+	}
+	if set["session"] || set["all-tabs"] || set["iterm-api"] {
+		for _, name := range setFlagList(set, []string{"prefer-it2setcolor", "require-it2setcolor"}) {
+			warnings = append(warnings, fmt.Sprintf("-%s is ignored with -session/-all-tabs/-iterm-api", name))
+		}
+	}
+	if set["tmux-window"] || set["tmux-pane"] {
+		switch {
+		case set["tmux-window"] && set["tmux-pane"]:
+			return nil, fmt.Errorf("-tmux-window and -tmux-pane cannot be used together")
+		case set["session"] || set["all-tabs"] || set["iterm-api"] || set["scope"]:
+			return nil, fmt.Errorf("-tmux-window and -tmux-pane cannot be combined with -session/-all-tabs/-iterm-api/-scope")
+		}
+		for _, name := range setFlagList(set, []string{"prefer-it2setcolor", "require-it2setcolor"}) {
+			warnings = append(warnings, fmt.Sprintf("-%s is ignored with -tmux-window/-tmux-pane", name))
+		}
+	}
+
+	if set["cycle"] || set["pulse"] {
+		switch {
+		case set["cycle"] && set["pulse"]:
+			return nil, fmt.Errorf("-cycle and -pulse cannot be used together")
+		case set["cycle"] && set["tab"]:
+			return nil, fmt.Errorf("-cycle sets the tab color, so it cannot be combined with -tab")
+		case set["pulse"] && !set["tab"]:
+			return nil, fmt.Errorf("-pulse needs a -tab color to pulse")
+		case set["profile"]:
+			return nil, fmt.Errorf("-cycle and -pulse cannot be combined with -profile")
+		case set["session"] || set["all-tabs"] || set["iterm-api"] || set["scope"]:
+			return nil, fmt.Errorf("-cycle and -pulse cannot be combined with -session/-all-tabs/-iterm-api/-scope")
+		case set["tmux-window"] || set["tmux-pane"]:
+			return nil, fmt.Errorf("-cycle and -pulse cannot be combined with -tmux-window/-tmux-pane")
+		}
+	} else if set["duration"] {
+		warnings = append(warnings, "-duration has no effect without -cycle or -pulse")
+	}
+	for _, name := range setFlagList(set, []string{"lighten", "darken"}) {
+		if set["profile"] {
+			return nil, fmt.Errorf("-%s cannot be combined with -profile; use a modifier like \"red@dark(20%%)\" in the profile", name)
+		}
+		if !set["tab"] && !set["fg"] && !set["bg"] {
+			warnings = append(warnings, fmt.Sprintf("-%s has no effect without -tab, -fg, or -bg", name))
+		}
+	}
+	if set["explain"] && !set["profile"] {
+		warnings = append(warnings, "-explain has no effect without -profile")
+	}
+	for _, name := range setFlagList(set, []string{"terminal", "shell", "mode"}) {
+		if !set["profile"] && !set["stdin"] && !(set["i"] && name == "terminal") {
+			warnings = append(warnings, fmt.Sprintf("-%s has no effect without -profile", name))
+		}
+	}
+	if set["json"] {
+		warnings = append(warnings, "-json only affects -list-profiles, -list-colors, and query commands")
+	}
+
+	return warnings, nil
+}
+
+// setFlagList returns the names from candidates that were set, in candidate order
+func setFlagList(set map[string]bool, candidates []string) []string {
+	var names []string
+	for _, name := range candidates {
+		if set[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// sortedFlagNames returns the names of the set flags in sorted order
+func sortedFlagNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
 		}
 	}
+	return false
 }
@@ -54,42 +54,298 @@ func TestMainFlagParsing(t *testing.T) {
 	}
 }
 
-// TestMainErrorMessages tests error message generation
+// TestMainErrorMessages tests error message generation for conflicting flags
 func TestMainErrorMessages(t *testing.T) {
 	tests := []struct {
 		name        string
-		profileName string
-		tabColor    string
+		flags       []string
+		terminal    string
+		subcommand  string
 		expectError string
 	}{
 		{
-			name:        "profile with individual colors",
-			profileName: "test",
-			tabColor:    "red",
-			expectError: "Cannot use -profile with individual color options",
+			name:        "both listings",
+			flags:       []string{"list-profiles", "list-colors"},
+			expectError: "-list-profiles and -list-colors cannot be used together",
 		},
 		{
-			name:        "non-existent profile",
-			profileName: "nonexistent",
-			expectError: "profile \"nonexistent\" not found",
+			name:        "unknown terminal",
+			flags:       []string{"profile", "terminal"},
+			terminal:    "alacritty",
+			expectError: `unknown terminal type "alacritty" for -terminal`,
+		},
+		{
+			name:        "session with all tabs",
+			flags:       []string{"session", "all-tabs", "tab"},
+			expectError: "-session and -all-tabs cannot be used together",
+		},
+		{
+			name:        "scope with all tabs",
+			flags:       []string{"scope", "all-tabs", "bg"},
+			expectError: "-scope and -all-tabs cannot be used together",
+		},
+		{
+			name:        "pulse with scope",
+			flags:       []string{"pulse", "tab", "scope"},
+			expectError: "cannot be combined with -session/-all-tabs/-iterm-api/-scope",
+		},
+		{
+			name:        "cycle with tab",
+			flags:       []string{"cycle", "tab"},
+			expectError: "-cycle sets the tab color",
+		},
+		{
+			name:        "pulse without tab",
+			flags:       []string{"pulse", "fg"},
+			expectError: "-pulse needs a -tab color",
+		},
+		{
+			name:        "cycle with session",
+			flags:       []string{"cycle", "session"},
+			expectError: "cannot be combined with -session/-all-tabs",
+		},
+		{
+			name:        "tmux window with pane",
+			flags:       []string{"tmux-window", "tmux-pane", "tab"},
+			expectError: "-tmux-window and -tmux-pane cannot be used together",
+		},
+		{
+			name:        "tmux pane with session",
+			flags:       []string{"tmux-pane", "session", "bg"},
+			expectError: "cannot be combined with -session/-all-tabs",
+		},
+		{
+			name:        "pulse with tmux window",
+			flags:       []string{"pulse", "tab", "tmux-window"},
+			expectError: "cannot be combined with -tmux-window/-tmux-pane",
+		},
+		{
+			name:        "interactive with colors",
+			flags:       []string{"i", "tab", "dry-run"},
+			expectError: "-i cannot be combined with -tab, -dry-run",
+		},
+		{
+			name:        "stdin with preview",
+			flags:       []string{"stdin", "preview"},
+			expectError: "-stdin cannot be combined with -preview",
+		},
+		{
+			name:        "stdin with async",
+			flags:       []string{"stdin", "async"},
+			expectError: "-stdin cannot be combined with -async",
+		},
+		{
+			name:        "stdin with colors",
+			flags:       []string{"stdin", "profile", "tab"},
+			expectError: "-stdin cannot be combined with -profile, -tab",
+		},
+		{
+			name:        "darken with profile",
+			flags:       []string{"profile", "darken"},
+			expectError: "-darken cannot be combined with -profile",
+		},
+		{
+			name:        "unknown terminal with subcommand",
+			flags:       []string{"terminal"},
+			terminal:    "bogus",
+			subcommand:  "detect",
+			expectError: `unknown terminal type "bogus"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set := map[string]bool{}
+			for _, name := range test.flags {
+				set[name] = true
+			}
+
+			_, err := checkFlagCombination(set, test.terminal, test.subcommand)
+			if err == nil {
+				t.Fatalf("Expected error containing %q, got none", test.expectError)
+			}
+			if !strings.Contains(err.Error(), test.expectError) {
+				t.Errorf("Expected error to contain %q, got %q", test.expectError, err.Error())
+			}
+		})
+	}
+}
+
+// TestCheckFlagCombinationWarnings tests combinations that are allowed with a warning
+func TestCheckFlagCombinationWarnings(t *testing.T) {
+	tests := []struct {
+		name       string
+		flags      []string
+		terminal   string
+		subcommand string
+		expected   []string
+	}{
+		{
+			name:     "plain colors",
+			flags:    []string{"tab", "fg", "bg", "preset", "verbose", "dry-run"},
+			expected: nil,
+		},
+		{
+			name:     "profile with individual colors",
+			flags:    []string{"profile", "tab", "preset", "badge"},
+			expected: nil,
+		},
+		{
+			name:     "profile with terminal",
+			flags:    []string{"profile", "terminal"},
+			terminal: "iterm2",
+			expected: nil,
+		},
+		{
+			name:     "shell without profile",
+			flags:    []string{"tab", "shell"},
+			expected: []string{"-shell has no effect without -profile"},
+		},
+		{
+			name:     "terminal without profile",
+			flags:    []string{"tab", "terminal"},
+			terminal: "ssh",
+			expected: []string{"-terminal has no effect without -profile"},
+		},
+		{
+			name:     "interactive with terminal",
+			flags:    []string{"i", "terminal"},
+			terminal: "vscode",
+			expected: nil,
+		},
+		{
+			name:     "long listing with terminal",
+			flags:    []string{"list-profiles", "long", "terminal", "tab"},
+			terminal: "vscode",
+			expected: []string{"-tab is ignored with -list-profiles"},
+		},
+		{
+			name:     "long without list-profiles",
+			flags:    []string{"tab", "long"},
+			expected: []string{"-long has no effect without -list-profiles"},
+		},
+		{
+			name:     "sort without list-colors",
+			flags:    []string{"tab", "sort", "swatch"},
+			expected: []string{"-sort has no effect without -list-colors", "-swatch has no effect without -list-colors"},
+		},
+		{
+			name:     "json while applying colors",
+			flags:    []string{"tab", "json"},
+			expected: []string{"-json only affects -list-profiles, -list-colors, and query commands"},
+		},
+		{
+			name:     "listing with colors",
+			flags:    []string{"list-colors", "json", "tab", "profile"},
+			expected: []string{"-tab is ignored with -list-colors", "-profile is ignored with -list-colors"},
+		},
+		{
+			name:       "subcommand with supported flags",
+			flags:      []string{"profile", "terminal", "json", "verbose"},
+			terminal:   "tmux",
+			subcommand: "detect",
+			expected:   nil,
+		},
+		{
+			name:       "subcommand with ignored flags",
+			flags:      []string{"tab", "profile", "json"},
+			subcommand: "status",
+			expected:   []string{"-profile is ignored by the status command", "-tab is ignored by the status command"},
+		},
+		{
+			name:     "session ignores prefer-it2setcolor",
+			flags:    []string{"session", "tab", "prefer-it2setcolor"},
+			expected: []string{"-prefer-it2setcolor is ignored with -session/-all-tabs/-iterm-api"},
+		},
+		{
+			name:     "lighten without colors",
+			flags:    []string{"lighten", "badge"},
+			expected: []string{"-lighten has no effect without -tab, -fg, or -bg"},
+		},
+		{
+			name:     "duration without animation",
+			flags:    []string{"duration", "tab"},
+			expected: []string{"-duration has no effect without -cycle or -pulse"},
+		},
+		{
+			name:     "darken with tab",
+			flags:    []string{"darken", "tab"},
+			expected: nil,
+		},
+		{
+			name:       "reset with all tabs",
+			flags:      []string{"all-tabs"},
+			subcommand: "reset",
+			expected:   nil,
+		},
+		{
+			name:     "async with dry-run",
+			flags:    []string{"tab", "async", "dry-run"},
+			expected: []string{"-async is ignored with -dry-run"},
+		},
+		{
+			name:       "status ignores async",
+			flags:      []string{"async", "timeout"},
+			subcommand: "status",
+			expected:   []string{"-async is ignored by the status command"},
+		},
+		{
+			name:       "unknown subcommand",
+			flags:      []string{"tab"},
+			subcommand: "frobnicate",
+			expected:   nil,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			// Test the validation logic without actually calling main()
-			hasProfile := test.profileName != ""
-			hasIndividualColors := test.tabColor != ""
-
-			if hasProfile && hasIndividualColors {
-				expectedMsg := "Cannot use -profile with individual color options"
-				if !strings.Contains(test.expectError, expectedMsg) {
-					t.Errorf("Expected error to contain %q", expectedMsg)
+			set := map[string]bool{}
+			for _, name := range test.flags {
+				set[name] = true
+			}
+
+			warnings, err := checkFlagCombination(set, test.terminal, test.subcommand)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(warnings) != len(test.expected) {
+				t.Fatalf("Expected warnings %q, got %q", test.expected, warnings)
+			}
+			for i := range warnings {
+				if warnings[i] != test.expected[i] {
+					t.Errorf("Warning %d = %q, expected %q", i, warnings[i], test.expected[i])
 				}
 			}
+		})
+	}
+}
+
+// TestUsesDefaultProfile tests when a run falls back to the config's default_profile
+func TestUsesDefaultProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		flags      []string
+		subcommand string
+		expected   bool
+	}{
+		{"bare run", nil, "", true},
+		{"options only", []string{"verbose", "dry-run", "terminal", "explain", "async"}, "", true},
+		{"profile", []string{"profile"}, "", false},
+		{"color", []string{"tab"}, "", false},
+		{"listing", []string{"list-profiles"}, "", false},
+		{"interactive", []string{"i"}, "", false},
+		{"animation", []string{"cycle"}, "", false},
+		{"subcommand", nil, "status", false},
+	}
 
-			if hasProfile && !hasIndividualColors {
-				// This would test getProfile, which is already tested in config_test.go
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set := map[string]bool{}
+			for _, name := range test.flags {
+				set[name] = true
+			}
+			if got := usesDefaultProfile(set, test.subcommand); got != test.expected {
+				t.Errorf("usesDefaultProfile(%v, %q) = %v, expected %v", test.flags, test.subcommand, got, test.expected)
 			}
 		})
 	}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// colorModifiers maps modifier names to the sign of their lightness change
+var colorModifiers = map[string]float64{
+	"lighten": 1,
+	"light":   1,
+	"darken":  -1,
+	"dark":    -1,
+}
+
+// applyColorModifiers resolves a color with lightness modifiers, in either the
+// "red@dark(20%)" or the "#ff8800 lighten 10%" form. Modifiers can be chained and
+// are applied left to right in HSL space; amounts are percentage points of lightness.
+func applyColorModifiers(input string) (string, bool) {
+	base, steps, ok := parseColorModifiers(input)
+	if !ok {
+		return "", false
+	}
+
+	hex := normalizeColor(base)
+	if hex == "" || hex == "default" {
+		return "", false
+	}
+
+	r, g, b, err := hexToRGB(hex)
+	if err != nil {
+		return "", false
+	}
+	h, s, l := rgbToHSL(r, g, b)
+	for _, step := range steps {
+		l = math.Max(0, math.Min(1, l+step/100))
+	}
+	r8, g8, b8 := hslToRGB(h, s, l)
+	return fmt.Sprintf("%02x%02x%02x", r8, g8, b8), true
+}
+
+// parseColorModifiers splits a color into its base and signed lightness steps
+func parseColorModifiers(input string) (string, []float64, bool) {
+	var steps []float64
+
+	// Function form: base@op(N%)@op(N%)...
+	if parts := strings.Split(strings.TrimSpace(input), "@"); len(parts) > 1 {
+		for _, part := range parts[1:] {
+			open := strings.Index(part, "(")
+			if open < 0 || !strings.HasSuffix(part, ")") {
+				return "", nil, false
+			}
+			step, ok := modifierStep(part[:open], part[open+1:len(part)-1])
+			if !ok {
+				return "", nil, false
+			}
+			steps = append(steps, step)
+		}
+		return strings.TrimSpace(parts[0]), steps, true
+	}
+
+	// Word form: base op N% op N%... Modifiers are taken from the end, so the base
+	// may be a name with spaces such as "Light Blue"
+	fields := strings.Fields(input)
+	n := len(fields)
+	for n >= 3 {
+		step, ok := modifierStep(fields[n-2], fields[n-1])
+		if !ok {
+			break
+		}
+		steps = append([]float64{step}, steps...)
+		n -= 2
+	}
+	if len(steps) == 0 {
+		return "", nil, false
+	}
+	return strings.Join(fields[:n], " "), steps, true
+}
+
+// modifierStep returns the signed lightness change for a modifier and amount ("20%" or "20")
+func modifierStep(name, amount string) (float64, bool) {
+	sign, ok := colorModifiers[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(amount), "%"), 64)
+	if err != nil || value < 0 || value > 100 {
+		return 0, false
+	}
+	return sign * value, true
+}
+
+// rgbToHSL converts RGB components to a hue in degrees and saturation/lightness in [0,1]
+func rgbToHSL(r, g, b int) (h, s, l float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	max := math.Max(rf, math.Max(gf, bf))
+	min := math.Min(rf, math.Min(gf, bf))
+	l = (max + min) / 2
+	if max == min {
+		return 0, 0, l
+	}
+
+	d := max - min
+	if l > 0.5 {
+		s = d / (2 - max - min)
+	} else {
+		s = d / (max + min)
+	}
+
+	switch max {
+	case rf:
+		h = math.Mod((gf-bf)/d+6, 6)
+	case gf:
+		h = (bf-rf)/d + 2
+	default:
+		h = (rf-gf)/d + 4
+	}
+	return h * 60, s, l
+}
+
+// withLightnessFlags appends -lighten/-darken to a command-line color.
+// An "auto" foreground is left alone so it is picked against the adjusted colors.
+func withLightnessFlags(color string, lighten, darken float64) string {
+	if color == "" || color == "default" || color == autoColorName {
+		return color
+	}
+	if lighten != 0 {
+		color += fmt.Sprintf(" lighten %g%%", lighten)
+	}
+	if darken != 0 {
+		color += fmt.Sprintf(" darken %g%%", darken)
+	}
+	return color
+}
@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+// TestApplyColorModifiers tests lightness modifiers in both the function and word forms
+func TestApplyColorModifiers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"red@dark(20%)", "990000"},
+		{"red@darken(20)", "990000"},
+		{"Red @ Dark(20%)", "990000"},
+		{"#ff8800 lighten 10%", "ffa033"},
+		{"red lighten 10% darken 10%", "ff0000"},
+		{"red@dark(20%)@lighten(20%)", "ff0000"},
+		{"white lighten 30%", "ffffff"}, // Clamped at full lightness
+		{"black darken 10%", "000000"},  // Clamped at zero lightness
+		{"navy@light(100)", "ffffff"},
+		{"Light Blue darken 10%", "86c5da"}, // Names with spaces take modifiers too
+		{"light slate gray lighten 10% darken 5%", "8695a4"},
+		{"light blue darken 10% lighten", ""},
+		{"red@dark(20%", ""},
+		{"red@dim(20%)", ""},
+		{"red@dark(120%)", ""},
+		{"red darken", ""},
+		{"red darken -5%", ""},
+		{"notacolor@dark(20%)", ""},
+		{"default@dark(20%)", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			if result := normalizeColor(test.input); result != test.expected {
+				t.Errorf("normalizeColor(%q) = %q, expected %q", test.input, result, test.expected)
+			}
+		})
+	}
+}
+
+// TestRGBToHSLRoundTrip tests that converting to HSL and back preserves the color
+func TestRGBToHSLRoundTrip(t *testing.T) {
+	for _, hex := range []string{"ff0000", "00ff00", "0000ff", "ff8800", "808080", "123456", "fafafa"} {
+		r, g, b, err := hexToRGB(hex)
+		if err != nil {
+			t.Fatalf("hexToRGB(%q) failed: %v", hex, err)
+		}
+		h, s, l := rgbToHSL(r, g, b)
+		r2, g2, b2 := hslToRGB(h, s, l)
+		if int(r2) != r || int(g2) != g || int(b2) != b {
+			t.Errorf("%s round-tripped to %02x%02x%02x", hex, r2, g2, b2)
+		}
+	}
+}
+
+// TestWithLightnessFlags tests turning -lighten/-darken into modifiers
+func TestWithLightnessFlags(t *testing.T) {
+	tests := []struct {
+		color           string
+		lighten, darken float64
+		expected        string
+	}{
+		{"red", 0, 20, "red darken 20%"},
+		{"#ff8800", 10, 0, "#ff8800 lighten 10%"},
+		{"red", 5, 2.5, "red lighten 5% darken 2.5%"},
+		{"Light Blue", 0, 10, "Light Blue darken 10%"},
+		{"", 0, 20, ""},
+		{"default", 10, 0, "default"},
+		{"auto", 10, 0, "auto"},
+	}
+
+	for _, test := range tests {
+		if result := withLightnessFlags(test.color, test.lighten, test.darken); result != test.expected {
+			t.Errorf("withLightnessFlags(%q, %g, %g) = %q, expected %q",
+				test.color, test.lighten, test.darken, result, test.expected)
+		}
+	}
+
+	// -tab 'Light Blue' -darken 10
+	if result := normalizeColor(withLightnessFlags("Light Blue", 0, 10)); result != "86c5da" {
+		t.Errorf("Expected Light Blue darkened by 10%% to be 86c5da, got %q", result)
+	}
+}
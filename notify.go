@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Notification methods for the notify profile key
+const (
+	notifyDesktop = "desktop" // osascript on macOS, notify-send elsewhere; the bell without either
+	notifyBell    = "bell"    // The terminal bell
+)
+
+// profileNotifyMethod returns how a profile (or the first of a comma-separated list
+// that asks for it) is announced when applied, or "" for no notification
+func profileNotifyMethod(profileName string) string {
+	if profileName == "" {
+		return ""
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return ""
+	}
+	for _, name := range strings.Split(profileName, ",") {
+		if profile := config.Profiles[strings.TrimSpace(name)]; profile != nil && profile.Notify != "" {
+			return profile.Notify
+		}
+	}
+	return ""
+}
+
+// notifyProfileApplied announces a profile with notify set, unless it was already
+// the session's profile, so prompt hooks that reapply it don't notify every time
+func notifyProfileApplied(profileName string, previous *AppliedState) {
+	method := profileNotifyMethod(profileName)
+	if method == "" || (previous != nil && previous.Profile == profileName) {
+		return
+	}
+	if err := sendNotification(method, fmt.Sprintf("Applied profile %s", redact(profileName))); err != nil {
+		warnf("could not send notification: %v", err)
+	}
+}
+
+// sendNotification shows a desktop notification or rings the terminal bell
+func sendNotification(method, message string) error {
+	if method == notifyDesktop {
+		if path, args, ok := desktopNotifier(message); ok {
+			if dryRunMode {
+				quoted := make([]string, len(args))
+				for i, arg := range args {
+					quoted[i] = shellQuote(arg)
+				}
+				return writeTerminalOutput(fmt.Sprintf("[dry-run] exec: %s %s\n", shellQuote(path), strings.Join(quoted, " ")))
+			}
+			return executor.Run(path, args)
+		}
+		debugf("No desktop notifier found; ringing the bell instead\n")
+	}
+	return writeSequence("bell", "\a")
+}
+
+// desktopNotifier returns the program and arguments that show message as a desktop
+// notification, if one is installed
+func desktopNotifier(message string) (string, []string, bool) {
+	if runtime.GOOS == "darwin" {
+		path, err := executor.LookPath("osascript")
+		if err != nil {
+			return "", nil, false
+		}
+		script := fmt.Sprintf("display notification %s with title \"set-tab-color\"", appleScriptString(message))
+		return path, []string{"-e", script}, true
+	}
+	path, err := executor.LookPath("notify-send")
+	if err != nil {
+		return "", nil, false
+	}
+	return path, []string{"--urgency=critical", "set-tab-color", message}, true
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	s = sanitizeEscapeInput(s)
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
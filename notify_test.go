@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestNotifyProfileApplied tests announcing profiles with notify set
+func TestNotifyProfileApplied(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+	if err := os.WriteFile(configFile, []byte(`[profiles.prod]
+tab = "red"
+notify = true
+
+[profiles.alert]
+tab = "orange"
+notify = "bell"
+
+[profiles.dev]
+tab = "green"
+`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	t.Setenv("XDG_CACHE_HOME", tempDir)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+	rec := useRecordingExecutor(t)
+	rec.installed["notify-send"] = true
+	rec.installed["osascript"] = true
+
+	notifyProfileApplied("dev", nil)
+	notifyProfileApplied("prod", &AppliedState{Profile: "prod"})
+	if len(rec.runs) != 0 || buf.Len() != 0 {
+		t.Fatalf("Expected no notification for dev or a reapplied prod, got %v, %q", rec.runs, buf.String())
+	}
+
+	notifyProfileApplied("prod", &AppliedState{Profile: "dev"})
+	if len(rec.runs) != 1 || !strings.Contains(strings.Join(rec.runs[0], " "), "Applied profile prod") {
+		t.Errorf("Expected a desktop notification naming prod, got %v", rec.runs)
+	}
+
+	notifyProfileApplied("alert", nil)
+	if len(rec.runs) != 1 || buf.String() != "\a" {
+		t.Errorf("Expected only the bell for alert, got %v, %q", rec.runs, buf.String())
+	}
+
+	// Without a notifier, the bell rings instead
+	buf.Reset()
+	rec.installed = map[string]bool{}
+	notifyProfileApplied("prod", nil)
+	if buf.String() != "\a" {
+		t.Errorf("Expected the bell as a fallback, got %q", buf.String())
+	}
+}
+
+// TestAppleScriptString tests quoting notification text for osascript
+func TestAppleScriptString(t *testing.T) {
+	if got := appleScriptString("say \"hi\" \\ now\a"); got != `"say \"hi\" \\ now"` {
+		t.Errorf("appleScriptString() = %s", got)
+	}
+}
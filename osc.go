@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// escapeWriter receives natively emitted escape sequences (replaced in tests)
+var escapeWriter io.Writer = os.Stdout
+
+// Global dry-run flag: print what would be emitted or executed instead of doing it
+var dryRunMode bool
+
+// escapeForDisplay renders control characters in an escape sequence readably,
+// e.g. ESC as \e and BEL as \a
+func escapeForDisplay(seq string) string {
+	var b strings.Builder
+	for _, r := range seq {
+		switch {
+		case r == '\033':
+			b.WriteString(`\e`)
+		case r == '\a':
+			b.WriteString(`\a`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// writeSequence emits an escape sequence, or describes it in dry-run mode
+func writeSequence(description, seq string) error {
+	if dryRunMode {
+		return writeTerminalOutput(fmt.Sprintf("[dry-run] %s: %s\n", description, escapeForDisplay(seq)))
+	}
+	return writeTerminalOutput(seq)
+}
+
+// sanitizeEscapeInput strips control characters (C0, DEL, and C1) from user-supplied text
+// that ends up inside an escape sequence, so values from untrusted sources such as repo
+// names can't terminate the sequence early or inject sequences of their own
+func sanitizeEscapeInput(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// isTmuxTerm reports whether TERM indicates we're inside tmux or screen,
+// which require OSC sequences to be wrapped in a DCS passthrough
+func isTmuxTerm() bool {
+	term := getDetectionEnv("TERM")
+	return strings.HasPrefix(term, "screen") || strings.HasPrefix(term, "tmux")
+}
+
+// wrapOSC wraps an OSC payload with the introducer and terminator,
+// using tmux passthrough when needed (mirrors it2setcolor's print_osc/print_st)
+func wrapOSC(payload string) string {
+	if isTmuxTerm() {
+		return "\033Ptmux;\033\033]" + payload + "\a\033\\"
+	}
+	return "\033]" + payload + "\a"
+}
+
+// buildSetColorSequence returns the escape sequence for a normalized color
+// (6-digit hex without '#', or "default")
+func buildSetColorSequence(target ColorTarget, normalizedColor string) (string, error) {
+	if normalizedColor == "default" && target != TabColor {
+		// SetColors has no "default", so use the standard xterm reset sequences
+		reset, ok := resetSequences[target]
+		if !ok {
+			return "", fmt.Errorf("%s cannot be reset to \"default\"", target)
+		}
+		return wrapOSC(reset), nil
+	}
+
+	if target != TabColor {
+		// fg/bg use iTerm2's proprietary SetColors sequence
+		return wrapOSC(fmt.Sprintf("1337;SetColors=%s=%s", target, normalizedColor)), nil
+	}
+
+	// Tab color uses the per-channel OSC 6 sequence
+	if normalizedColor == "default" {
+		return wrapOSC("6;1;bg;*;default"), nil
+	}
+
+	r, g, b, err := hexToRGB(normalizedColor)
+	if err != nil {
+		return "", err
+	}
+	return wrapOSC(fmt.Sprintf("6;1;bg;red;brightness;%d", r)) +
+		wrapOSC(fmt.Sprintf("6;1;bg;green;brightness;%d", g)) +
+		wrapOSC(fmt.Sprintf("6;1;bg;blue;brightness;%d", b)), nil
+}
+
+// buildSetPresetSequence returns the escape sequence selecting an iTerm2 color preset
+func buildSetPresetSequence(presetName string) string {
+	return wrapOSC("1337;SetColors=preset=" + sanitizeEscapeInput(presetName))
+}
+
+// buildSetProfileSequence returns the escape sequence switching the session to another iTerm2 profile
+func buildSetProfileSequence(profileName string) string {
+	return wrapOSC("1337;SetProfile=" + sanitizeEscapeInput(profileName))
+}
+
+// buildSetBadgeSequence returns the escape sequence setting the iTerm2 badge text
+func buildSetBadgeSequence(badge string) string {
+	return wrapOSC("1337;SetBadgeFormat=" + base64.StdEncoding.EncodeToString([]byte(badge)))
+}
+
+// buildSetTitleSequence returns the escape sequence setting the tab and window title.
+// OSC 0 is understood by nearly every terminal. Inside tmux or screen the sequence
+// isn't passed through: OSC 2 is handled by the multiplexer itself and sets the pane
+// title, which tmux can forward to the outer terminal with set-titles.
+func buildSetTitleSequence(title string) string {
+	if isTmuxTerm() {
+		return "\033]2;" + sanitizeEscapeInput(title) + "\a"
+	}
+	return wrapOSC("0;" + sanitizeEscapeInput(title))
+}
+
+// attentionModes maps the profile attention values to iTerm2 RequestAttention arguments
+var attentionModes = map[string]string{
+	"true":      "yes",  // Bounce the dock icon until the app is activated
+	"bounce":    "once", // Bounce the dock icon once
+	"fireworks": "fireworks",
+}
+
+// buildRequestAttentionSequence returns the escape sequence for an attention cue
+func buildRequestAttentionSequence(attention string) (string, error) {
+	mode, ok := attentionModes[attention]
+	if !ok {
+		return "", fmt.Errorf("unknown attention value %q (expected true, false, bounce, or fireworks)", attention)
+	}
+	return wrapOSC("1337;RequestAttention=" + mode), nil
+}
+
+// emitSetColor writes the escape sequence for a normalized color to the terminal
+func emitSetColor(target ColorTarget, normalizedColor string) error {
+	seq, err := buildSetColorSequence(target, normalizedColor)
+	if err != nil {
+		return err
+	}
+	return writeSequence(fmt.Sprintf("%s %s", target, normalizedColor), seq)
+}
+
+// emitSetPreset writes the escape sequence for an iTerm2 color preset to the terminal
+func emitSetPreset(presetName string) error {
+	return writeSequence(fmt.Sprintf("preset %q", presetName), buildSetPresetSequence(presetName))
+}
+
+// emitSetProfile writes the escape sequence for an iTerm2 profile switch to the terminal
+func emitSetProfile(profileName string) error {
+	return writeSequence(fmt.Sprintf("iTerm2 profile %q", profileName), buildSetProfileSequence(profileName))
+}
+
+// emitSetBadge writes the escape sequence for the iTerm2 badge to the terminal
+func emitSetBadge(badge string) error {
+	return writeSequence(fmt.Sprintf("badge %q", badge), buildSetBadgeSequence(badge))
+}
+
+// emitSetTitle writes the escape sequence for the tab and window title to the terminal
+func emitSetTitle(title string) error {
+	return writeSequence(fmt.Sprintf("title %q", title), buildSetTitleSequence(title))
+}
+
+// emitRequestAttention writes the escape sequence for an attention cue to the terminal
+func emitRequestAttention(attention string) error {
+	seq, err := buildRequestAttentionSequence(attention)
+	if err != nil {
+		return err
+	}
+	return writeSequence(fmt.Sprintf("attention %s", attention), seq)
+}
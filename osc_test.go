@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestBuildSetColorSequence tests the native escape sequences for each color target
+func TestBuildSetColorSequence(t *testing.T) {
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	tests := []struct {
+		name     string
+		target   ColorTarget
+		color    string
+		expected string
+	}{
+		{
+			name:     "tab color",
+			target:   TabColor,
+			color:    "ff8800",
+			expected: "\033]6;1;bg;red;brightness;255\a\033]6;1;bg;green;brightness;136\a\033]6;1;bg;blue;brightness;0\a",
+		},
+		{
+			name:     "tab default",
+			target:   TabColor,
+			color:    "default",
+			expected: "\033]6;1;bg;*;default\a",
+		},
+		{
+			name:     "foreground color",
+			target:   ForegroundColor,
+			color:    "ffffff",
+			expected: "\033]1337;SetColors=fg=ffffff\a",
+		},
+		{
+			name:     "background default",
+			target:   BackgroundColor,
+			color:    "default",
+			expected: "\033]111\a",
+		},
+		{
+			name:     "foreground default",
+			target:   ForegroundColor,
+			color:    "default",
+			expected: "\033]110\a",
+		},
+		{
+			name:     "palette color",
+			target:   "br_red",
+			color:    "ff5555",
+			expected: "\033]1337;SetColors=br_red=ff5555\a",
+		},
+		{
+			name:     "palette ansi default",
+			target:   "br_white",
+			color:    "default",
+			expected: "\033]104;15\a",
+		},
+		{
+			name:     "cursor default",
+			target:   "curbg",
+			color:    "default",
+			expected: "\033]112\a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			seq, err := buildSetColorSequence(test.target, test.color)
+			if err != nil {
+				t.Fatalf("buildSetColorSequence() failed: %v", err)
+			}
+			if seq != test.expected {
+				t.Errorf("buildSetColorSequence(%q, %q) = %q, expected %q", test.target, test.color, seq, test.expected)
+			}
+		})
+	}
+}
+
+// TestWrapOSCTmux tests that sequences are wrapped in DCS passthrough inside tmux
+func TestWrapOSCTmux(t *testing.T) {
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "tmux-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	expected := "\033Ptmux;\033\033]1337;SetColors=preset=Ocean\a\033\\"
+	if seq := buildSetPresetSequence("Ocean"); seq != expected {
+		t.Errorf("buildSetPresetSequence() = %q, expected %q", seq, expected)
+	}
+}
+
+// TestRunSetColorNative tests that runSetColor emits sequences without it2setcolor
+func TestRunSetColorNative(t *testing.T) {
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	// Point HOME somewhere without it2setcolor to prove it isn't needed
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", t.TempDir())
+	defer os.Setenv("HOME", originalHome)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+
+	if err := runSetColor(ForegroundColor, "red"); err != nil {
+		t.Fatalf("runSetColor() failed: %v", err)
+	}
+	if err := runSetPreset("Solarized Dark"); err != nil {
+		t.Fatalf("runSetPreset() failed: %v", err)
+	}
+
+	expected := "\033]1337;SetColors=fg=ff0000\a\033]1337;SetColors=preset=Solarized Dark\a"
+	if buf.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, buf.String())
+	}
+}
+
+// TestRunSetColorAlpha tests that alpha is dropped, with a warning except for the tab
+func TestRunSetColorAlpha(t *testing.T) {
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+	logs, restore := captureLog(LogWarn, false)
+	defer restore()
+
+	if err := runSetColor(TabColor, "#ff000080"); err != nil {
+		t.Fatalf("runSetColor() failed: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected the tab color's alpha to be dropped quietly, got %q", logs.String())
+	}
+
+	if err := runSetColor(BackgroundColor, "#0008"); err != nil {
+		t.Fatalf("runSetColor() failed: %v", err)
+	}
+	if !strings.Contains(logs.String(), "alpha of bg color #0008 is ignored") {
+		t.Errorf("Expected a warning about the background's alpha, got %q", logs.String())
+	}
+
+	// An opaque alpha says nothing
+	logs.Reset()
+	if err := runSetColor(ForegroundColor, "#ffffffff"); err != nil {
+		t.Fatalf("runSetColor() failed: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected no warning for an opaque alpha, got %q", logs.String())
+	}
+
+	if !strings.Contains(buf.String(), "red;brightness;255") || !strings.Contains(buf.String(), "bg=000000") || !strings.Contains(buf.String(), "fg=ffffff") {
+		t.Errorf("Expected the colors without alpha, got %q", buf.String())
+	}
+}
+
+// TestSanitizeEscapeInput tests stripping of control characters from untrusted input
+func TestSanitizeEscapeInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Solarized Dark", "Solarized Dark"},
+		{"repo\a\033]0;pwned\a", "repo]0;pwned"},
+		{"line\nbreak\ttab", "linebreaktab"},
+		{"del\x7fc1\u009b31m", "delc131m"},
+		{"ünïcödé ✓", "ünïcödé ✓"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		result := sanitizeEscapeInput(test.input)
+		if result != test.expected {
+			t.Errorf("sanitizeEscapeInput(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	// Preset names can't break out of the SetColors sequence
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	expected := "\033]1337;SetColors=preset=Evil]0;title\a"
+	if seq := buildSetPresetSequence("Evil\a\033]0;title"); seq != expected {
+		t.Errorf("buildSetPresetSequence() = %q, expected %q", seq, expected)
+	}
+}
+
+// TestDryRun tests that dry-run mode describes sequences and commands instead of running them
+func TestDryRun(t *testing.T) {
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", "/home/tester")
+	defer os.Setenv("HOME", originalHome)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+	}()
+
+	if err := runSetColor(TabColor, "default"); err != nil {
+		t.Fatalf("runSetColor() failed: %v", err)
+	}
+	if err := runSetPreset("Solarized Dark"); err != nil {
+		t.Fatalf("runSetPreset() failed: %v", err)
+	}
+
+	// The it2setcolor binary doesn't need to exist in dry-run mode when it is required
+	requireIt2setcolor = true
+	defer func() { requireIt2setcolor = false }()
+	if err := runSetColor(BackgroundColor, "black"); err != nil {
+		t.Fatalf("runSetColor() with it2setcolor failed: %v", err)
+	}
+
+	expected := "[dry-run] tab default: \\e]6;1;bg;*;default\\a\n" +
+		"[dry-run] preset \"Solarized Dark\": \\e]1337;SetColors=preset=Solarized Dark\\a\n" +
+		"[dry-run] exec: /home/tester/.iterm2/it2setcolor bg 000000\n"
+	if buf.String() != expected {
+		t.Errorf("Expected dry-run output:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+// TestEscapeForDisplay tests rendering of control characters
+func TestEscapeForDisplay(t *testing.T) {
+	input := "\033Ptmux;\033\033]1337;x\a\033\\\t"
+	expected := `\ePtmux;\e\e]1337;x\a\e\\\x09`
+	if result := escapeForDisplay(input); result != expected {
+		t.Errorf("escapeForDisplay(%q) = %q, expected %q", input, result, expected)
+	}
+}
+
+// TestBuildBadgeAndTitleSequences tests the badge and title escape sequences
+func TestBuildBadgeAndTitleSequences(t *testing.T) {
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	// Badge text is base64-encoded, so control characters can't break out of it
+	if seq := buildSetBadgeSequence("PROD\a"); seq != "\033]1337;SetBadgeFormat=UFJPRAc=\a" {
+		t.Errorf("buildSetBadgeSequence() = %q", seq)
+	}
+	if seq := buildSetTitleSequence("prod\033]db"); seq != "\033]0;prod]db\a" {
+		t.Errorf("buildSetTitleSequence() = %q", seq)
+	}
+
+	// Inside tmux the title goes to the multiplexer as OSC 2, without passthrough
+	os.Setenv("TERM", "tmux-256color")
+	if seq := buildSetTitleSequence("prod"); seq != "\033]2;prod\a" {
+		t.Errorf("buildSetTitleSequence() in tmux = %q", seq)
+	}
+	if seq := buildSetBadgeSequence("PROD"); seq != "\033Ptmux;\033\033]1337;SetBadgeFormat=UFJPRA==\a\033\\" {
+		t.Errorf("buildSetBadgeSequence() in tmux = %q", seq)
+	}
+}
+
+// TestApplyProfileBadgeAndTitle tests that profile badge and title are applied after the colors
+func TestApplyProfileBadgeAndTitle(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+
+	base := testProfile(t, map[string]interface{}{"bg": "black", "badge": "PROD", "title": "prod"}).profile(nil)
+	profile := overlayProfile(*base, Profile{Title: "prod (ssh)"})
+
+	if err := applyProfile(&profile); err != nil {
+		t.Fatalf("applyProfile() failed: %v", err)
+	}
+
+	expected := "\033]1337;SetColors=bg=000000\a\033]1337;SetBadgeFormat=UFJPRA==\a\033]0;prod (ssh)\a"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+// TestApplyProfileITermProfile tests that the iTerm2 profile is switched before the colors it would replace
+func TestApplyProfileITermProfile(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+
+	profile := testProfile(t, map[string]interface{}{"iterm_profile": "Production\a", "tab": "red"}).profile(nil)
+	if err := applyProfile(profile); err != nil {
+		t.Fatalf("applyProfile() failed: %v", err)
+	}
+
+	expected := "\033]1337;SetProfile=Production\a" +
+		"\033]6;1;bg;red;brightness;255\a\033]6;1;bg;green;brightness;0\a\033]6;1;bg;blue;brightness;0\a"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+// TestDefaultConformance tests that "default" is translated for every target on every backend
+func TestDefaultConformance(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	originalTerm := os.Getenv("TERM")
+	defer os.Setenv("TERM", originalTerm)
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", "/home/test")
+	defer os.Setenv("HOME", originalHome)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+
+	tests := []struct {
+		target  ColorTarget
+		native  string // Unwrapped OSC command
+		it2exec bool   // Whether it2setcolor handles the reset itself
+	}{
+		{TabColor, "6;1;bg;*;default", true},
+		{ForegroundColor, "110", false},
+		{BackgroundColor, "111", false},
+		{"black", "104;0", false},
+		{"br_white", "104;15", false},
+		{"curbg", "112", false},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.target), func(t *testing.T) {
+			// Native escape sequences, plain and inside tmux
+			os.Setenv("TERM", "xterm-256color")
+			buf.Reset()
+			if err := runSetColor(test.target, "default"); err != nil {
+				t.Fatalf("runSetColor() failed: %v", err)
+			}
+			if expected := "\033]" + test.native + "\a"; buf.String() != expected {
+				t.Errorf("native: expected %q, got %q", expected, buf.String())
+			}
+
+			os.Setenv("TERM", "tmux-256color")
+			buf.Reset()
+			if err := runSetColor(test.target, "default"); err != nil {
+				t.Fatalf("runSetColor() failed: %v", err)
+			}
+			if expected := "\033Ptmux;\033\033]" + test.native + "\a\033\\"; buf.String() != expected {
+				t.Errorf("tmux: expected %q, got %q", expected, buf.String())
+			}
+
+			// it2setcolor only resets the tab; everything else falls back to escape sequences
+			os.Setenv("TERM", "xterm-256color")
+			requireIt2setcolor, dryRunMode = true, true
+			buf.Reset()
+			err := runSetColor(test.target, "default")
+			requireIt2setcolor, dryRunMode = false, false
+			if err != nil {
+				t.Fatalf("runSetColor() failed: %v", err)
+			}
+			if test.it2exec {
+				if expected := "[dry-run] exec: /home/test/.iterm2/it2setcolor " + string(test.target) + " default\n"; buf.String() != expected {
+					t.Errorf("it2setcolor: expected %q, got %q", expected, buf.String())
+				}
+			} else if !contains(buf.String(), "\\e]"+test.native+"\\a") {
+				t.Errorf("it2setcolor: expected escape sequence %q, got %q", test.native, buf.String())
+			}
+		})
+	}
+
+	// Targets without a reset sequence are rejected rather than silently ignored
+	if err := runSetColor("bold", "default"); err == nil || !contains(err.Error(), "cannot be reset") {
+		t.Errorf("Expected reset error for bold, got %v", err)
+	}
+
+	// The Python API passes "default" through for the script to restore from the original profile
+	req, err := buildSessionRequest(&Profile{Tab: "default", Foreground: "default", Palette: map[string]string{"red": "default"}})
+	if err != nil {
+		t.Fatalf("buildSessionRequest() failed: %v", err)
+	}
+	if req.Tab != "default" || req.Fg != "default" || req.Palette["red"] != "default" {
+		t.Errorf("Expected defaults to be passed through, got %+v", req)
+	}
+}
+
+// TestRequestAttention tests the attention cue sequences and that false overrides an inherited cue
+func TestRequestAttention(t *testing.T) {
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	for attention, expected := range map[string]string{
+		"true":      "\033]1337;RequestAttention=yes\a",
+		"bounce":    "\033]1337;RequestAttention=once\a",
+		"fireworks": "\033]1337;RequestAttention=fireworks\a",
+	} {
+		seq, err := buildRequestAttentionSequence(attention)
+		if err != nil {
+			t.Fatalf("buildRequestAttentionSequence(%q) failed: %v", attention, err)
+		}
+		if seq != expected {
+			t.Errorf("buildRequestAttentionSequence(%q) = %q, expected %q", attention, seq, expected)
+		}
+	}
+	if _, err := buildRequestAttentionSequence("loudly"); err == nil {
+		t.Error("Expected error for unknown attention value")
+	}
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+
+	res, err := resolveProfile("alert", testProfile(t, map[string]interface{}{
+		"badge":     "ALERT",
+		"attention": true,
+		"ssh":       map[string]interface{}{"attention": false},
+	}), &TerminalShellInfo{Terminals: []TerminalType{TerminalTypeSSH}})
+	if err != nil {
+		t.Fatalf("resolveProfile() failed: %v", err)
+	}
+	if err := applyProfile(&res.Result); err != nil {
+		t.Fatalf("applyProfile() failed: %v", err)
+	}
+	if contains(buf.String(), "RequestAttention") {
+		t.Errorf("Expected attention = false in a sub-profile to suppress the cue, got %q", buf.String())
+	}
+}
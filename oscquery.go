@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// oscQueryTimeout is how long to wait for the terminal to answer a color query.
+// The query ends with a device attributes request, which nearly every terminal
+// answers at once, so the full wait only happens on terminals that answer nothing.
+const oscQueryTimeout = time.Second
+
+// terminalReader sends a query to the terminal and returns its reply; tests replace it
+var terminalReader = readTerminalReply
+
+// buildColorQuery asks for the foreground (OSC 10) and background (OSC 11), then
+// for the primary device attributes (DA1). Terminals answer in order, so the DA1
+// reply marks the end of whatever color replies are coming.
+func buildColorQuery() string {
+	return "\033]10;?\a\033]11;?\a\033[c"
+}
+
+// queryTerminalColors asks the terminal for its current foreground and background
+// colors, as hex without '#'. Terminals that don't support the queries answer only
+// the DA1 request, which is reported as an error.
+func queryTerminalColors() (fg, bg string, err error) {
+	timeout := oscQueryTimeout
+	if backendTimeout > 0 && backendTimeout < timeout {
+		timeout = backendTimeout
+	}
+	reply, err := terminalReader(buildColorQuery(), timeout)
+	if err != nil {
+		return "", "", err
+	}
+	colors := parseOSCColorReplies(reply)
+	fg, bg = colors[10], colors[11]
+	if fg == "" && bg == "" {
+		return "", "", withExitCode(exitUnsupported, fmt.Errorf("the terminal did not answer the OSC 10/11 color queries"))
+	}
+	return fg, bg, nil
+}
+
+// isDeviceAttributesReply reports whether data holds a complete DA1 reply
+// ("ESC [ ? ... c"), the end of the color query's answers
+func isDeviceAttributesReply(data []byte) bool {
+	start := bytes.Index(data, []byte("\033[?"))
+	return start >= 0 && bytes.IndexByte(data[start:], 'c') >= 0
+}
+
+// parseOSCColorReplies extracts the colors from OSC color replies such as
+// "ESC ] 11 ; rgb:1e1e/1e1e/1e1e BEL", keyed by OSC number. Replies may end with
+// BEL or ST, and anything between them (such as the DA1 reply) is skipped.
+func parseOSCColorReplies(data []byte) map[int]string {
+	colors := map[int]string{}
+	for {
+		start := bytes.Index(data, []byte("\033]"))
+		if start < 0 {
+			return colors
+		}
+		data = data[start+2:]
+		end := bytes.IndexAny(data, "\a\033")
+		if end < 0 {
+			return colors
+		}
+		body := string(data[:end])
+		data = data[end:]
+
+		number, spec, ok := strings.Cut(body, ";")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			continue
+		}
+		if hex, ok := parseXColorSpec(spec); ok {
+			colors[n] = hex
+		}
+	}
+}
+
+// parseXColorSpec converts an X11 color specification as terminals report it to
+// hex: "rgb:R/G/B" (or "rgba:R/G/B/A") with 1 to 4 hex digits per component,
+// scaled to 8 bits, or "#rrggbb"
+func parseXColorSpec(spec string) (string, bool) {
+	if strings.HasPrefix(spec, "#") {
+		hex := normalizeColor(spec)
+		return hex, hex != "" && hex != "default"
+	}
+
+	kind, value, ok := strings.Cut(spec, ":")
+	if !ok {
+		return "", false
+	}
+	components := strings.Split(value, "/")
+	switch {
+	case kind == "rgb" && len(components) == 3:
+	case kind == "rgba" && len(components) == 4:
+		components = components[:3]
+	default:
+		return "", false
+	}
+
+	var hex strings.Builder
+	for _, component := range components {
+		if len(component) < 1 || len(component) > 4 {
+			return "", false
+		}
+		v, err := strconv.ParseUint(component, 16, 16)
+		if err != nil {
+			return "", false
+		}
+		// Scale from the component's own range, so "f", "ff", and "ffff" are all full
+		maxValue := uint64(1)<<(4*len(component)) - 1
+		fmt.Fprintf(&hex, "%02x", (v*255+maxValue/2)/maxValue)
+	}
+	return hex.String(), true
+}
+
+// runTerminalColorInfo is session-info outside iTerm2: it shows the foreground and
+// background the terminal reports. The tab color and profile can't be queried.
+func runTerminalColorInfo() error {
+	fg, bg, err := queryTerminalColors()
+	if err != nil {
+		return err
+	}
+	info := SessionInfo{Session: ttyName(), Fg: fg, Bg: bg}
+	if jsonOutput {
+		return printJSON([]SessionInfo{info})
+	}
+	label := info.Session
+	if label == "" {
+		label = controllingTerminal
+	}
+	fmt.Printf("%s\n  fg: %s\n  bg: %s\n", label, reportedColor(fg), reportedColor(bg))
+	return nil
+}
+
+// reportedColor shows a queried color, or "unknown" when the terminal didn't report it
+func reportedColor(hex string) string {
+	if hex == "" {
+		return "unknown"
+	}
+	return colorText("#"+hex, hex)
+}
@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// readTerminalReply would read the terminal's answer to a query; raw terminal
+// reads aren't implemented here
+func readTerminalReply(query string, timeout time.Duration) ([]byte, error) {
+	return nil, withExitCode(exitUnsupported, fmt.Errorf("querying the terminal is not supported on %s", runtime.GOOS))
+}
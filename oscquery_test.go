@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// useTerminalReply makes terminal queries answer with *reply for the rest of the test
+func useTerminalReply(t *testing.T, reply *string) {
+	original := terminalReader
+	terminalReader = func(query string, timeout time.Duration) ([]byte, error) {
+		return []byte(*reply), nil
+	}
+	t.Cleanup(func() { terminalReader = original })
+}
+
+// TestParseXColorSpec tests converting the color formats terminals report
+func TestParseXColorSpec(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+		ok       bool
+	}{
+		{"rgb:ffff/ffff/ffff", "ffffff", true},
+		{"rgb:1e1e/1e1e/2e2e", "1e1e2e", true},
+		{"rgb:ff/80/00", "ff8000", true},
+		{"rgb:f/8/0", "ff8800", true},
+		{"rgb:fff/800/000", "ff8000", true},
+		{"rgb:8000/8000/8000", "808080", true},
+		{"rgba:0000/0000/ffff/ffff", "0000ff", true},
+		{"#282a36", "282a36", true},
+		{"rgb:ffff/ffff", "", false},
+		{"rgb:fffff/0/0", "", false},
+		{"rgb:zz/00/00", "", false},
+		{"cmyk:0/0/0/0", "", false},
+		{"?", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, ok := parseXColorSpec(tt.spec)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("parseXColorSpec(%q) = %q, %v, expected %q, %v", tt.spec, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+// TestParseOSCColorReplies tests extracting colors from a terminal's answer
+func TestParseOSCColorReplies(t *testing.T) {
+	reply := "\033]10;rgb:c5c5/c8c8/c6c6\033\\\033]11;rgb:1d1d/1f1f/2121\a\033[?64;1;2;6;22c"
+	expected := map[int]string{10: "c5c8c6", 11: "1d1f21"}
+	if got := parseOSCColorReplies([]byte(reply)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("parseOSCColorReplies() = %v, expected %v", got, expected)
+	}
+
+	// A cut-off reply and garbage are ignored
+	if got := parseOSCColorReplies([]byte("\033]11;nonsense\a\033]10;rgb:ff")); len(got) != 0 {
+		t.Errorf("Expected no colors, got %v", got)
+	}
+}
+
+// TestQueryTerminalColors tests querying the foreground and background
+func TestQueryTerminalColors(t *testing.T) {
+	reply := "\033]10;rgb:ffff/ffff/ffff\a\033]11;rgb:0000/0000/0000\a\033[?62;c"
+	useTerminalReply(t, &reply)
+
+	if fg, bg, err := queryTerminalColors(); err != nil || fg != "ffffff" || bg != "000000" {
+		t.Errorf("queryTerminalColors() = %q, %q, %v", fg, bg, err)
+	}
+
+	// Terminals without the queries only answer DA1
+	reply = "\033[?1;2c"
+	if _, _, err := queryTerminalColors(); err == nil || exitCode(err) != exitUnsupported {
+		t.Errorf("Expected an unsupported error, got %v", err)
+	}
+}
+
+// TestIsDeviceAttributesReply tests recognizing the end of the query's answers
+func TestIsDeviceAttributesReply(t *testing.T) {
+	for reply, expected := range map[string]bool{
+		"\033]11;rgb:0/0/0\a\033[?62;22c": true,
+		"\033[?62;22":                     false,
+		"\033]11;rgb:0/0/0\a":             false,
+		"":                                false,
+	} {
+		if got := isDeviceAttributesReply([]byte(reply)); got != expected {
+			t.Errorf("isDeviceAttributesReply(%q) = %v, expected %v", reply, got, expected)
+		}
+	}
+}
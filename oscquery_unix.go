@@ -0,0 +1,63 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// readTerminalReply writes a query to the terminal and reads the answer until a
+// DA1 reply arrives or the timeout passes. The terminal is put in raw mode for the
+// duration, so the reply isn't echoed or held back until a newline, and each read
+// gives up after a tenth of a second so the deadline is checked even when nothing
+// comes. The descriptor stays blocking; Go's poller would wait past the deadline.
+func readTerminalReply(query string, timeout time.Duration) ([]byte, error) {
+	path := ttyOutputPath
+	if path == "" {
+		path = controllingTerminal
+	}
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, withExitCode(exitBackendUnavailable, fmt.Errorf("could not open %s to query the terminal: %v", path, err))
+	}
+	defer unix.Close(fd)
+
+	original, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, withExitCode(exitBackendUnavailable, fmt.Errorf("%s is not a terminal: %v", path, err))
+	}
+	raw := *original
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	raw.Cc[unix.VMIN] = 0
+	raw.Cc[unix.VTIME] = 1
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, fmt.Errorf("could not put %s in raw mode: %v", path, err)
+	}
+	defer unix.IoctlSetTermios(fd, ioctlSetTermios, original)
+
+	if _, err := unix.Write(fd, []byte(query)); err != nil {
+		return nil, fmt.Errorf("could not write to %s: %v", path, err)
+	}
+
+	var reply []byte
+	buf := make([]byte, 256)
+	deadline := time.Now().Add(timeout)
+	for !isDeviceAttributesReply(reply) {
+		if time.Now().After(deadline) {
+			debugf("No complete reply from the terminal within %s: %q\n", timeout, reply)
+			break
+		}
+		n, err := unix.Read(fd, buf)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read from %s: %v", path, err)
+		}
+		reply = append(reply, buf[:n]...)
+	}
+	return reply, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Global flag selecting machine-readable JSON output for query commands
+var jsonOutput bool
+
+// ColorEntry describes a named color in JSON output
+type ColorEntry struct {
+	Name     string `json:"name"`
+	Hex      string `json:"hex"`
+	Custom   bool   `json:"custom,omitempty"`
+	Semantic bool   `json:"semantic,omitempty"`
+}
+
+// DetectionResult describes terminal/shell detection in JSON output
+type DetectionResult struct {
+	Terminals    []TerminalType    `json:"terminals"`
+	Shell        ShellType         `json:"shell"`
+	Valid        bool              `json:"valid"`
+	Appearance   Appearance        `json:"appearance,omitempty"`
+	Root         bool              `json:"root"`
+	Env          map[string]string `json:"env"`
+	ProcessChain []string          `json:"process_chain"`
+	Simulated    string            `json:"simulated,omitempty"` // Fixture path when SET_TAB_COLOR_FAKE_ENV is active
+	ProfileName  string            `json:"profile_name,omitempty"`
+	Profile      *Profile          `json:"profile,omitempty"`
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// listColorEntries returns all CSS and custom colors sorted by name
+func listColorEntries() []ColorEntry {
+	entries := make([]ColorEntry, 0, len(cssColors)+len(semanticColors)+len(customColors))
+	for name, hex := range cssColors {
+		entries = append(entries, ColorEntry{Name: name, Hex: hex})
+	}
+	for name, hex := range semanticColors {
+		entries = append(entries, ColorEntry{Name: name, Hex: "#" + hex, Semantic: true})
+	}
+	for name, hex := range customColors {
+		entries = append(entries, ColorEntry{Name: name, Hex: "#" + hex, Custom: true})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return !entries[i].Custom
+	})
+	return entries
+}
+
+// buildDetectionResult collects detection details, resolving profileName if given.
+// Process names are redacted since they may reveal host names.
+func buildDetectionResult(terminalOverride, profileName string) (*DetectionResult, error) {
+	info := detectTerminalAndShell(terminalOverride)
+
+	result := &DetectionResult{
+		Terminals:    info.Terminals,
+		Shell:        info.Shell,
+		Valid:        info.Valid,
+		Appearance:   info.Appearance,
+		Root:         info.Root,
+		Env:          map[string]string{},
+		ProcessChain: []string{},
+	}
+	for _, key := range detectionEnvVars {
+		result.Env[key] = redact(getDetectionEnv(key))
+	}
+	if getFakeEnvironment() != nil {
+		result.Simulated = os.Getenv(fakeEnvVar)
+	}
+	if result.Terminals == nil {
+		result.Terminals = []TerminalType{}
+	}
+
+	if chain, err := getProcessAncestorChain(); err == nil {
+		for _, name := range chain {
+			result.ProcessChain = append(result.ProcessChain, redact(name))
+		}
+	}
+
+	if profileName != "" {
+		profile, err := getProfileWithTerminalInfo(profileName, &info)
+		if err != nil {
+			return nil, err
+		}
+		result.ProfileName = redact(profileName)
+		result.Profile = profile
+	}
+
+	return result, nil
+}
+
+// runDetect prints terminal/shell detection results, as text or JSON
+func runDetect(terminalOverride, profileName string) error {
+	// Config provides redaction patterns and custom colors
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+
+	result, err := buildDetectionResult(terminalOverride, profileName)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printJSON(result)
+	}
+
+	terminals := make([]string, len(result.Terminals))
+	for i, terminal := range result.Terminals {
+		terminals[i] = string(terminal)
+	}
+	if len(terminals) == 0 {
+		terminals = []string{"none"}
+	}
+
+	fmt.Printf("Terminals: %s\n", strings.Join(terminals, ", "))
+	fmt.Printf("Shell: %s\n", result.Shell)
+	fmt.Printf("Valid: %v\n", result.Valid)
+	if result.Appearance != AppearanceUnknown {
+		fmt.Printf("Mode: %s\n", result.Appearance)
+	}
+	if result.Root {
+		fmt.Printf("Root: yes\n")
+	}
+	if result.Simulated != "" {
+		fmt.Printf("Simulated from: %s\n", result.Simulated)
+	}
+	fmt.Printf("Environment:\n")
+	for _, key := range detectionEnvVars {
+		fmt.Printf("  %s=%s\n", key, result.Env[key])
+	}
+	fmt.Printf("Process chain:\n")
+	for i, name := range result.ProcessChain {
+		fmt.Printf("  %d: %s\n", i, name)
+	}
+
+	if result.Profile != nil {
+		fmt.Printf("Resolved profile %q: tab=%q, fg=%q, bg=%q, preset=%q\n", result.ProfileName,
+			result.Profile.Tab, result.Profile.Foreground, result.Profile.Background, result.Profile.Preset)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// TestListColorEntries tests the JSON color listing including custom colors
+func TestListColorEntries(t *testing.T) {
+	if err := setCustomColors(map[string]string{"corp-orange": "#f26522"}); err != nil {
+		t.Fatalf("setCustomColors() failed: %v", err)
+	}
+	defer setCustomColors(nil)
+
+	entries := listColorEntries()
+	if expected := len(cssColors) + len(semanticColors) + 1; len(entries) != expected {
+		t.Errorf("Expected %d entries, got %d", expected, len(entries))
+	}
+
+	if !sort.SliceIsSorted(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name }) {
+		t.Error("Expected entries to be sorted by name")
+	}
+
+	found := false
+	for _, entry := range entries {
+		if entry.Name == "corp-orange" {
+			found = true
+			if entry.Hex != "#f26522" || !entry.Custom {
+				t.Errorf("Unexpected custom entry: %+v", entry)
+			}
+		}
+		if entry.Name == "red" && (entry.Hex != "#ff0000" || entry.Custom) {
+			t.Errorf("Unexpected CSS entry: %+v", entry)
+		}
+	}
+	if !found {
+		t.Error("Expected custom color in entries")
+	}
+
+	data, err := json.Marshal(entries[:1])
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	if string(data) != `[{"name":"aliceblue","hex":"#f0f8ff"}]` {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+}
+
+// TestBuildDetectionResult tests detection output with a resolved profile
+func TestBuildDetectionResult(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "detect.toml")
+	configContent := `
+[profiles.dev]
+tab = "blue"
+
+[profiles.dev.iterm2]
+tab = "purple"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+
+	result, err := buildDetectionResult("iterm2", "dev")
+	if err != nil {
+		t.Fatalf("buildDetectionResult() failed: %v", err)
+	}
+
+	if len(result.Terminals) == 0 || result.Terminals[0] != TerminalTypeITerm2 {
+		t.Errorf("Expected terminal override first, got %v", result.Terminals)
+	}
+	if len(result.ProcessChain) == 0 {
+		t.Error("Expected a non-empty process chain")
+	}
+	if result.Profile == nil || result.Profile.Tab != "purple" {
+		t.Errorf("Expected resolved iterm2 sub-profile, got %+v", result.Profile)
+	}
+
+	if _, err := buildDetectionResult("", "missing"); err == nil {
+		t.Error("Expected error for a missing profile")
+	}
+}
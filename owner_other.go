@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// ownedByOtherUser reports whether a file belongs to a user other than the current one.
+// File ownership isn't available here, so only the read-only checks apply.
+func ownedByOtherUser(info os.FileInfo) bool {
+	return false
+}
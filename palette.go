@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// paletteKeys lists the iTerm2 SetColors keys a profile's palette may set, in the
+// order they are applied
+var paletteKeys = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"br_black", "br_red", "br_green", "br_yellow", "br_blue", "br_magenta", "br_cyan", "br_white",
+	"bold", "link", "selbg", "selfg", "curbg", "curfg", "underline",
+}
+
+// resetSequences maps the color targets that can be restored to the terminal's
+// configured color to the OSC command that does it (the tab uses OSC 6 instead)
+var resetSequences = map[ColorTarget]string{
+	ForegroundColor: "110",
+	BackgroundColor: "111",
+	"curbg":         "112",
+}
+
+func init() {
+	// OSC 104 resets a single ANSI color by index; the first 16 palette keys are ANSI 0-15
+	for i, key := range paletteKeys[:16] {
+		resetSequences[ColorTarget(key)] = fmt.Sprintf("104;%d", i)
+	}
+}
+
+// canResetTarget reports whether "default" is supported for a color target
+func canResetTarget(target ColorTarget) bool {
+	if target == TabColor {
+		return true
+	}
+	_, ok := resetSequences[target]
+	return ok
+}
+
+// isPaletteKey checks if a key is a known palette entry
+func isPaletteKey(key string) bool {
+	for _, k := range paletteKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// overlayPalette merges palette entries key by key, with overlay entries winning
+func overlayPalette(base, overlay map[string]string) map[string]string {
+	if len(overlay) == 0 {
+		return base
+	}
+
+	result := make(map[string]string, len(base)+len(overlay))
+	for key, value := range base {
+		result[key] = value
+	}
+	for key, value := range overlay {
+		result[key] = value
+	}
+	return result
+}
+
+// orderedPaletteKeys returns the keys set in a palette in application order.
+// Unknown keys sort after the known ones so the order is always deterministic.
+func orderedPaletteKeys(palette map[string]string) []string {
+	var keys, unknown []string
+	for _, key := range paletteKeys {
+		if _, ok := palette[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	for key := range palette {
+		if !isPaletteKey(key) {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return append(keys, unknown...)
+}
+
+// applyPalette sets every palette entry in application order
+func applyPalette(palette map[string]string) error {
+	for _, key := range orderedPaletteKeys(palette) {
+		if !isPaletteKey(key) {
+			return fmt.Errorf("unknown palette key %q", key)
+		}
+		debugf("  Setting palette color %s: %q\n", key, palette[key])
+		if err := runSetColor(ColorTarget(key), palette[key]); err != nil {
+			return fmt.Errorf("error setting palette color %s: %w", key, err)
+		}
+	}
+	return nil
+}
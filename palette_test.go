@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPaletteOverlay tests that palette entries merge key by key across sub-profiles
+func TestPaletteOverlay(t *testing.T) {
+	data := map[string]interface{}{
+		"preset": "Solarized Dark",
+		"palette": map[string]interface{}{
+			"red":  "#dc322f",
+			"blue": "#268bd2",
+		},
+		"iterm2": map[string]interface{}{
+			"palette": map[string]interface{}{
+				"blue": "#0000ff",
+				"bold": "white",
+			},
+		},
+	}
+
+	res, err := resolveProfile("dev", testProfile(t, data), &TerminalShellInfo{Terminals: []TerminalType{TerminalTypeITerm2}})
+	if err != nil {
+		t.Fatalf("resolveProfile() failed: %v", err)
+	}
+
+	expected := map[string]string{"red": "#dc322f", "blue": "#0000ff", "bold": "white"}
+	if len(res.Result.Palette) != len(expected) {
+		t.Fatalf("Expected palette %v, got %v", expected, res.Result.Palette)
+	}
+	for key, value := range expected {
+		if res.Result.Palette[key] != value {
+			t.Errorf("palette.%s = %q, expected %q", key, res.Result.Palette[key], value)
+		}
+	}
+	if res.Result.Preset != "Solarized Dark" {
+		t.Errorf("Expected preset to be kept, got %q", res.Result.Preset)
+	}
+	if source := res.sourceName("palette.red"); source != "profiles.dev" {
+		t.Errorf("Expected palette.red from profiles.dev, got %q", source)
+	}
+	if source := res.sourceName("palette.blue"); source != "profiles.dev.iterm2" {
+		t.Errorf("Expected palette.blue from profiles.dev.iterm2, got %q", source)
+	}
+
+	// The base palette must not be modified by the overlay
+	if base := res.Steps[0].Values.Palette; len(base) != 2 || base["blue"] != "#268bd2" {
+		t.Errorf("Base palette was modified: %v", base)
+	}
+}
+
+// TestApplyProfilePaletteOrder tests that the preset is applied first, then the
+// palette in a fixed order, then the explicit tab/fg/bg colors
+func TestApplyProfilePaletteOrder(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+
+	profile := &Profile{
+		Preset:     "Solarized Dark",
+		Foreground: "white",
+		Palette: map[string]string{
+			"selbg":  "navy",
+			"br_red": "#ff5555",
+			"red":    "#dc322f",
+		},
+	}
+
+	// Map iteration order is random, so repeat to catch ordering differences
+	for i := 0; i < 10; i++ {
+		buf.Reset()
+		if err := applyProfile(profile); err != nil {
+			t.Fatalf("applyProfile() failed: %v", err)
+		}
+
+		expected := "\033]1337;SetColors=preset=Solarized Dark\a" +
+			"\033]1337;SetColors=red=dc322f\a" +
+			"\033]1337;SetColors=br_red=ff5555\a" +
+			"\033]1337;SetColors=selbg=000080\a" +
+			"\033]1337;SetColors=fg=ffffff\a"
+		if buf.String() != expected {
+			t.Fatalf("Expected %q, got %q", expected, buf.String())
+		}
+	}
+
+	if err := applyProfile(&Profile{Palette: map[string]string{"purple": "red"}}); err == nil || !contains(err.Error(), "unknown palette key") {
+		t.Errorf("Expected unknown palette key error, got %v", err)
+	}
+}
+
+// TestValidatePalette tests palette validation in config files
+func TestValidatePalette(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "palette-config.toml")
+
+	configContent := `[profiles.dev]
+preset = "Solarized Dark"
+
+[profiles.dev.palette]
+red = "#dc322f"
+purple = "#800080"
+blue = "default"
+bold = "default"
+green = "grassy"
+`
+
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer setCustomColors(nil)
+
+	issues, err := validateConfigFile(configFile)
+	if err != nil {
+		t.Fatalf("validateConfigFile() failed: %v", err)
+	}
+
+	expected := []ValidationIssue{
+		{Line: 6, Message: "unknown palette key profiles.dev.palette.purple (known keys: black, red, green, yellow, blue, magenta, cyan, white, br_black, br_red, br_green, br_yellow, br_blue, br_magenta, br_cyan, br_white, bold, link, selbg, selfg, curbg, curfg, underline)"},
+		{Line: 8, Message: `profiles.dev.palette.bold cannot be "default"`},
+		{Line: 9, Message: `unknown color "grassy" for profiles.dev.palette.green`},
+	}
+
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %v", len(expected), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue != expected[i] {
+			t.Errorf("Issue %d = %+v, expected %+v", i, issue, expected[i])
+		}
+	}
+}
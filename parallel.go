@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// parallelTasks counts the runParallel groups in flight. Phases aren't timed inside
+// them, since the phase stack assumes one goroutine; the group is timed as a whole.
+var parallelTasks atomic.Int32
+
+// runParallel runs independent backend invocations, such as the layouts of a
+// workspace that target different windows, at the same time and returns all of their errors joined. In dry-run mode
+// they run in order, so the printed commands stay stable.
+func runParallel(tasks []func() error) error {
+	if dryRunMode || len(tasks) < 2 {
+		var errs []error
+		for _, task := range tasks {
+			errs = append(errs, task())
+		}
+		return errors.Join(errs...)
+	}
+
+	defer timePhase("backend")()
+	parallelTasks.Add(1)
+	defer parallelTasks.Add(-1)
+
+	var group errgroup.Group
+	errs := make([]error, len(tasks))
+	for i, task := range tasks {
+		group.Go(func() error {
+			errs[i] = task()
+			return nil
+		})
+	}
+	group.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRunParallel tests that tasks run at the same time and all their errors are reported
+func TestRunParallel(t *testing.T) {
+	// Each task waits for the other, so they only finish if both run at once
+	first, second := make(chan struct{}), make(chan struct{})
+	wait := func(ready, other chan struct{}, err error) func() error {
+		return func() error {
+			close(ready)
+			select {
+			case <-other:
+				return err
+			case <-time.After(5 * time.Second):
+				return errors.New("tasks did not run concurrently")
+			}
+		}
+	}
+
+	err := runParallel([]func() error{
+		wait(first, second, errors.New("first failed")),
+		wait(second, first, errors.New("second failed")),
+	})
+	if err == nil || !strings.Contains(err.Error(), "first failed") || !strings.Contains(err.Error(), "second failed") {
+		t.Errorf("Expected both errors, got %v", err)
+	}
+
+	if err := runParallel(nil); err != nil {
+		t.Errorf("Expected no error without tasks, got %v", err)
+	}
+}
+
+// TestRunParallelDryRun tests that dry-run mode runs tasks in order
+func TestRunParallelDryRun(t *testing.T) {
+	dryRunMode = true
+	defer func() { dryRunMode = false }()
+
+	var order []int
+	var tasks []func() error
+	for i := 0; i < 5; i++ {
+		tasks = append(tasks, func() error { order = append(order, i); return nil })
+	}
+	if err := runParallel(tasks); err != nil {
+		t.Fatalf("runParallel() failed: %v", err)
+	}
+	if !sort.IntsAreSorted(order) || len(order) != 5 {
+		t.Errorf("Expected tasks in order, got %v", order)
+	}
+}
+
+// TestApplyProfileToTmuxWindowBatched tests that every tmux option is set, in order,
+// by a single tmux invocation
+func TestApplyProfileToTmuxWindowBatched(t *testing.T) {
+	rec := useRecordingExecutor(t)
+	rec.installed["tmux"] = true
+
+	profile := &Profile{Tab: "red", Foreground: "white", Background: "black", Title: "build;"}
+	if err := applyProfileToTmuxWindow("@1", profile); err != nil {
+		t.Fatalf("applyProfileToTmuxWindow() failed: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"set-option -w -t @1 window-style fg=#ffffff,bg=#000000",
+		"set-option -w -t @1 window-active-style fg=#ffffff,bg=#000000",
+		"set-option -w -t @1 window-status-style bg=#ff0000",
+		"set-option -w -t @1 window-status-current-style bg=#ff0000",
+		`rename-window -t @1 build\;`,
+	}, " ; ")
+	if len(rec.runs) != 1 || strings.Join(rec.runs[0][1:], " ") != expected {
+		t.Errorf("Expected one tmux invocation %q, got %v", expected, rec.runs)
+	}
+
+	rec.runs = nil
+	rec.err = errors.New("no server running")
+	if err := applyProfileToTmuxWindow("@1", profile); err == nil || !strings.Contains(err.Error(), "no server running") {
+		t.Errorf("Expected the tmux failure, got %v", err)
+	}
+	if err := runTmuxCommands(nil); err != nil {
+		t.Errorf("Expected no error without commands, got %v", err)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// pickerTargets are the settings the picker previews, switched with the Tab key
+var pickerTargets = []string{"tab", "bg", "fg", "theme"}
+
+// pickerHeight is how many entries the picker shows at once
+const pickerHeight = 15
+
+// pickerItem is one entry of the picker: a named color, or a theme when Hex is empty
+type pickerItem struct {
+	Name string
+	Hex  string
+}
+
+// picker holds the state of the interactive picker
+type picker struct {
+	colors []pickerItem
+	themes []pickerItem
+	target int    // Index into pickerTargets
+	filter string // Typed text narrowing the entries by substring
+	cursor int    // Index into the filtered entries
+}
+
+// newPicker builds a picker over the CSS, custom, and theme names
+func newPicker() *picker {
+	p := &picker{}
+	for _, entry := range listColorEntries() {
+		if entry.Custom || customColors[entry.Name] == "" {
+			p.colors = append(p.colors, pickerItem{Name: entry.Name, Hex: entry.Hex})
+		}
+	}
+	for _, name := range listThemeNames() {
+		p.themes = append(p.themes, pickerItem{Name: name})
+	}
+	return p
+}
+
+// targetName returns the setting being previewed
+func (p *picker) targetName() string {
+	return pickerTargets[p.target]
+}
+
+// items returns the entries for the current target that match the filter
+func (p *picker) items() []pickerItem {
+	all := p.colors
+	if p.targetName() == "theme" {
+		all = p.themes
+	}
+	if p.filter == "" {
+		return all
+	}
+	var matched []pickerItem
+	for _, item := range all {
+		if strings.Contains(item.Name, p.filter) {
+			matched = append(matched, item)
+		}
+	}
+	return matched
+}
+
+// selected returns the entry under the cursor, if any
+func (p *picker) selected() (pickerItem, bool) {
+	items := p.items()
+	if len(items) == 0 {
+		return pickerItem{}, false
+	}
+	return items[p.cursor], true
+}
+
+// selectedProfile returns the profile previewing the entry under the cursor
+func (p *picker) selectedProfile() (*Profile, bool) {
+	item, ok := p.selected()
+	if !ok {
+		return nil, false
+	}
+	switch p.targetName() {
+	case "bg":
+		return &Profile{Background: item.Name}, true
+	case "fg":
+		return &Profile{Foreground: item.Name}, true
+	case "theme":
+		return &Profile{Theme: item.Name}, true
+	}
+	return &Profile{Tab: item.Name}, true
+}
+
+// Keys the picker understands besides typed text, named so they can't be typed
+const (
+	keyUp        = "<up>"
+	keyDown      = "<down>"
+	keyPageUp    = "<pgup>"
+	keyPageDown  = "<pgdn>"
+	keyTab       = "<tab>"
+	keyEnter     = "<enter>"
+	keyBackspace = "<backspace>"
+	keyCancel    = "<cancel>"
+)
+
+// parsePickerKey decodes the bytes of one read from the terminal: a special key,
+// or text when typed faster than it is read
+func parsePickerKey(b []byte) string {
+	switch string(b) {
+	case "\033[A", "\033OA":
+		return keyUp
+	case "\033[B", "\033OB":
+		return keyDown
+	case "\033[5~":
+		return keyPageUp
+	case "\033[6~":
+		return keyPageDown
+	case "\t":
+		return keyTab
+	case "\r", "\n":
+		return keyEnter
+	case "\x7f", "\b":
+		return keyBackspace
+	case "\033", "\x03", "\x04":
+		return keyCancel
+	}
+	for _, c := range b {
+		if c < ' ' || c >= 0x7f {
+			return ""
+		}
+	}
+	return string(b)
+}
+
+// handleKey updates the picker for a key press. It reports whether the previewed
+// entry changed, and whether the picker is done (with the entry chosen or not).
+func (p *picker) handleKey(key string) (changed, done, chosen bool) {
+	before, _ := p.selected()
+	beforeTarget := p.target
+
+	switch key {
+	case keyUp:
+		p.moveCursor(-1)
+	case keyDown:
+		p.moveCursor(1)
+	case keyPageUp:
+		p.moveCursor(-pickerHeight)
+	case keyPageDown:
+		p.moveCursor(pickerHeight)
+	case keyTab:
+		p.target = (p.target + 1) % len(pickerTargets)
+		p.filter, p.cursor = "", 0
+	case keyBackspace:
+		if p.filter != "" {
+			p.filter = p.filter[:len(p.filter)-1]
+			p.cursor = 0
+		}
+	case keyEnter:
+		_, ok := p.selected()
+		return false, true, ok
+	case keyCancel:
+		return false, true, false
+	case "":
+	default:
+		p.filter += strings.ToLower(key)
+		p.cursor = 0
+	}
+
+	after, ok := p.selected()
+	return ok && (after != before || p.target != beforeTarget), false, false
+}
+
+// moveCursor moves the cursor by delta entries, stopping at either end
+func (p *picker) moveCursor(delta int) {
+	p.cursor += delta
+	if last := len(p.items()) - 1; p.cursor > last {
+		p.cursor = last
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+// render draws the picker: a header, a window of entries around the cursor, and help
+func (p *picker) render(w io.Writer) {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	for i, name := range pickerTargets {
+		if i == p.target {
+			fmt.Fprintf(&b, "[%s] ", name)
+		} else {
+			fmt.Fprintf(&b, " %s  ", name)
+		}
+	}
+	fmt.Fprintf(&b, "\nFilter: %s\n\n", p.filter)
+
+	items := p.items()
+	start := p.cursor - pickerHeight/2
+	if start > len(items)-pickerHeight {
+		start = len(items) - pickerHeight
+	}
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < len(items) && i < start+pickerHeight; i++ {
+		marker := "  "
+		if i == p.cursor {
+			marker = "> "
+		}
+		item := items[i]
+		if item.Hex == "" {
+			fmt.Fprintf(&b, "%s%s\n", marker, item.Name)
+		} else {
+			fmt.Fprintf(&b, "%s%s %-22s %s\n", marker, colorSwatch("    ", item.Hex), item.Name, item.Hex)
+		}
+	}
+	if len(items) == 0 {
+		b.WriteString("  (no matches)\n")
+	}
+
+	b.WriteString("\n↑/↓ move  Tab switch target  type to filter  Enter choose  Esc cancel\n")
+	io.WriteString(w, b.String())
+}
+
+// runStty runs stty on the terminal, returning its output
+func runStty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s failed: %v", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// runPicker shows the interactive picker, previewing each entry as the cursor
+// moves. On Enter it keeps the choice and offers to save it to a profile; on
+// Esc it restores the colors this session had before.
+func runPicker(terminalType string) error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	// Previews change the colors, and the choice is recorded as set directly
+	if err := checkProfileLock(""); err != nil {
+		return err
+	}
+	useEscapeBackendFor(detectTerminals(terminalType))
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("the picker needs a terminal: %v", err)
+	}
+	defer tty.Close()
+
+	saved, err := runStty(tty, "-g")
+	if err != nil {
+		return err
+	}
+	// Read key by key; Ctrl-C arrives as a key so the terminal is always restored
+	if _, err := runStty(tty, "-icanon", "-echo", "-isig", "min", "1"); err != nil {
+		return err
+	}
+	original := previewOriginal()
+
+	p := newPicker()
+	fmt.Fprint(tty, "\033[?1049h\033[?25l")
+	profile, chosen, err := p.loop(tty, original)
+	fmt.Fprint(tty, "\033[?25h\033[?1049l")
+	if _, sttyErr := runStty(tty, saved); sttyErr != nil && err == nil {
+		err = sttyErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if !chosen {
+		return applyProfile(original)
+	}
+	recordAppliedState("", profile)
+
+	item, _ := p.selected()
+	fmt.Fprintf(tty, "Chose %s %s\n", p.targetName(), item.Name)
+	fmt.Fprint(tty, "Save to profile (leave empty to skip): ")
+	name, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("error reading profile name: %v", err)
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		return nil
+	}
+	return savePickerChoice(name, p.targetName(), item.Name)
+}
+
+// loop reads keys until the picker is done, previewing each newly selected entry.
+// Switching targets first restores the original colors, so only one is previewed.
+func (p *picker) loop(tty *os.File, original *Profile) (*Profile, bool, error) {
+	// Preview the first entry right away
+	profile, _ := p.selectedProfile()
+	if err := applyProfile(profile); err != nil {
+		return nil, false, err
+	}
+
+	buf := make([]byte, 16)
+	for {
+		p.render(tty)
+		n, err := tty.Read(buf)
+		if err != nil {
+			return nil, false, fmt.Errorf("error reading key: %v", err)
+		}
+
+		target := p.target
+		changed, done, chosen := p.handleKey(parsePickerKey(buf[:n]))
+		if done {
+			profile, _ := p.selectedProfile()
+			return profile, chosen, nil
+		}
+		if p.target != target {
+			if err := applyProfile(original); err != nil {
+				return nil, false, err
+			}
+		}
+		if changed {
+			profile, _ := p.selectedProfile()
+			if err := applyProfile(profile); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+}
+
+// savePickerChoice writes the chosen entry to a profile, creating it if needed
+func savePickerChoice(profileName, target, value string) error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	configPath, err := editableConfigPath()
+	if err != nil {
+		return err
+	}
+
+	editor, err := loadConfigEditor(configPath)
+	if err != nil {
+		return err
+	}
+	editor.setKey(append([]string{"profiles"}, splitTOMLKey(profileName)...), target, value)
+	if err := editor.save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated %s\n", configPath)
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParsePickerKey tests decoding key presses
+func TestParsePickerKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"\033[A", keyUp},
+		{"\033OB", keyDown},
+		{"\033[6~", keyPageDown},
+		{"\t", keyTab},
+		{"\r", keyEnter},
+		{"\x7f", keyBackspace},
+		{"\033", keyCancel},
+		{"\x03", keyCancel},
+		{"b", "b"},
+		{"tom", "tom"},
+		{"\033[C", ""},
+	}
+
+	for _, tt := range tests {
+		if got := parsePickerKey([]byte(tt.input)); got != tt.expected {
+			t.Errorf("parsePickerKey(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+// newTestPicker returns a picker over a few fixed colors and themes
+func newTestPicker() *picker {
+	return &picker{
+		colors: []pickerItem{{"blue", "#0000ff"}, {"lightblue", "#add8e6"}, {"red", "#ff0000"}},
+		themes: []pickerItem{{Name: "dracula"}, {Name: "nord"}},
+	}
+}
+
+// TestPickerHandleKey tests moving, filtering, and switching targets
+func TestPickerHandleKey(t *testing.T) {
+	p := newTestPicker()
+
+	if changed, done, _ := p.handleKey(keyUp); changed || done {
+		t.Errorf("Moving up at the top should change nothing")
+	}
+	if changed, _, _ := p.handleKey(keyDown); !changed {
+		t.Errorf("Moving down should change the preview")
+	}
+	if item, _ := p.selected(); item.Name != "lightblue" {
+		t.Errorf("Expected lightblue selected, got %q", item.Name)
+	}
+
+	// Filtering narrows the entries and starts at the first match
+	p.handleKey("r")
+	p.handleKey("e")
+	if item, _ := p.selected(); item.Name != "red" {
+		t.Errorf("Expected red selected after filtering, got %q", item.Name)
+	}
+	p.handleKey(keyBackspace)
+	if items := p.items(); len(items) != 1 {
+		t.Errorf("Expected 1 entry matching %q, got %d", p.filter, len(items))
+	}
+
+	// Switching targets clears the filter
+	for i := 0; i < 3; i++ {
+		p.handleKey(keyTab)
+	}
+	if p.targetName() != "theme" || p.filter != "" {
+		t.Errorf("Expected theme target with no filter, got %q with %q", p.targetName(), p.filter)
+	}
+	profile, _ := p.selectedProfile()
+	if profile.Theme != "dracula" {
+		t.Errorf("Expected dracula theme previewed, got %+v", profile)
+	}
+
+	// No match can't be chosen
+	p.handleKey("x")
+	if _, done, chosen := p.handleKey(keyEnter); !done || chosen {
+		t.Errorf("Enter without a match should end without a choice")
+	}
+	p.handleKey(keyBackspace)
+	if _, done, chosen := p.handleKey(keyEnter); !done || !chosen {
+		t.Errorf("Enter should choose the selected entry")
+	}
+	if _, done, chosen := p.handleKey(keyCancel); !done || chosen {
+		t.Errorf("Esc should end without a choice")
+	}
+}
+
+// TestPickerSelectedProfile tests the profile previewed for each target
+func TestPickerSelectedProfile(t *testing.T) {
+	p := newTestPicker()
+	expected := []Profile{{Tab: "blue"}, {Background: "blue"}, {Foreground: "blue"}}
+	for _, want := range expected {
+		profile, ok := p.selectedProfile()
+		if !ok || !reflect.DeepEqual(*profile, want) {
+			t.Errorf("Target %s: got %+v, expected %+v", p.targetName(), profile, want)
+		}
+		p.handleKey(keyTab)
+	}
+}
+
+// TestPickerRender tests drawing the picker
+func TestPickerRender(t *testing.T) {
+	p := newTestPicker()
+	p.handleKey(keyDown)
+
+	var buf bytes.Buffer
+	p.render(&buf)
+	output := buf.String()
+
+	for _, expected := range []string{"[tab]", "> ", "lightblue", "#add8e6", "Enter choose"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%q", expected, output)
+		}
+	}
+
+	p.handleKey("z")
+	buf.Reset()
+	p.render(&buf)
+	if !strings.Contains(buf.String(), "(no matches)") {
+		t.Errorf("Expected no matches, got:\n%q", buf.String())
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// Global -preview flag: how long colors stay before they are reverted. Zero keeps them.
+var previewDuration time.Duration
+
+// previewOriginal returns the profile restoring the colors after a preview: this
+// session's recorded state, or the defaults when nothing was recorded. A single
+// iTerm2 session is asked for its current colors through the Python API instead.
+// Other targets have no recorded state, so they return to the defaults.
+func previewOriginal() *Profile {
+	if targetSessionID != "" && !targetAllTabs && !dryRunMode {
+		sessions, err := querySessions()
+		if err == nil && len(sessions) == 1 {
+			return sessionRestoreProfile(sessions[0])
+		}
+		debugf("Could not read the session's colors, reverting to defaults: %v\n", err)
+	}
+	if usesSessionTargeting() || usesTmuxTargeting() {
+		return restoreProfile(nil)
+	}
+	state, err := loadSessionState()
+	if err != nil {
+		debugf("Could not read session state: %v\n", err)
+	}
+	original := restoreProfile(state)
+
+	// Colors set outside set-tab-color aren't recorded; ask the terminal for them
+	if !dryRunMode && (state == nil || state.Foreground == "" || state.Background == "") {
+		fg, bg, err := queryTerminalColors()
+		if err != nil {
+			debugf("Could not query the terminal's colors, reverting to defaults: %v\n", err)
+		}
+		if fg != "" && (state == nil || state.Foreground == "") {
+			original.Foreground = fg
+		}
+		if bg != "" && (state == nil || state.Background == "") {
+			original.Background = bg
+		}
+	}
+	return original
+}
+
+// endPreview waits out the preview and restores the original colors. Ctrl-C ends
+// the preview early but still reverts, so an interrupted preview leaves nothing behind.
+// What the previewed profile set without a recorded previous value is reset: the
+// palette and cursor when it had a theme or palette, and the badge and title.
+func endPreview(original, previewed *Profile) error {
+	if !dryRunMode {
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		defer signal.Stop(interrupted)
+
+		timer := time.NewTimer(previewDuration)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-interrupted:
+		}
+	}
+
+	debugf("Preview over, reverting to tab=%q fg=%q bg=%q\n", original.Tab, original.Foreground, original.Background)
+	batch, outermost := startBatch()
+	var err error
+	// Reset first, so a recorded preset is put back over the default palette
+	if !usesSessionTargeting() && !usesTmuxTargeting() {
+		err = resetTerminalExtras(previewed.Theme != "" || len(previewed.Palette) > 0, previewed.Badge != "", previewed.Title != "")
+	}
+	if err == nil {
+		err = applyProfile(original)
+	}
+	if outermost {
+		if flushErr := batch.flush(); err == nil {
+			err = flushErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error reverting preview: %w", err)
+	}
+	return nil
+}
+
+// sessionRestoreProfile returns the profile that brings a session back to the
+// colors it reported
+func sessionRestoreProfile(session SessionInfo) *Profile {
+	profile := &Profile{Tab: session.Tab, Foreground: session.Fg, Background: session.Bg}
+	if profile.Tab == "" {
+		profile.Tab = "default"
+	}
+	return profile
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPreviewOriginal tests choosing the colors a preview reverts to
+func TestPreviewOriginal(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", t.TempDir())
+	defer os.Setenv("XDG_STATE_HOME", originalState)
+
+	// A terminal that doesn't answer color queries only replies to DA1
+	reply := "\033[?62;c"
+	useTerminalReply(t, &reply)
+
+	recordAppliedState("prod", &Profile{Tab: "red", Background: "navy"})
+	expected := Profile{Tab: "ff0000", Foreground: "default", Background: "000080"}
+	if profile := previewOriginal(); !reflect.DeepEqual(*profile, expected) {
+		t.Errorf("previewOriginal() = %+v, expected %+v", profile, expected)
+	}
+
+	// Colors the state doesn't record come from the terminal when it answers
+	reply = "\033]10;rgb:eeee/eeee/eeee\033\\\033]11;rgb:1e1e/1e1e/1e1e\a\033[?62;c"
+	expected.Foreground = "eeeeee"
+	if profile := previewOriginal(); !reflect.DeepEqual(*profile, expected) {
+		t.Errorf("previewOriginal() = %+v, expected %+v", profile, expected)
+	}
+
+	// A tmux window has no recorded state of its own
+	targetTmuxWindow = "2"
+	defer func() { targetTmuxWindow = "" }()
+	if profile := previewOriginal(); !reflect.DeepEqual(*profile, resetProfile) {
+		t.Errorf("Expected defaults for a tmux target, got %+v", profile)
+	}
+}
+
+// TestEndPreview tests reverting after a preview; dry runs don't wait
+func TestEndPreview(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	previewDuration = time.Hour
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+		previewDuration = 0
+	}()
+
+	start := time.Now()
+	if err := endPreview(&Profile{Tab: "ff0000", Foreground: "default", Background: "default"}, &Profile{Tab: "blue"}); err != nil {
+		t.Fatalf("endPreview() failed: %v", err)
+	}
+	if time.Since(start) > time.Minute {
+		t.Errorf("Expected a dry run not to wait")
+	}
+	output := buf.String()
+	if !strings.Contains(output, "6;1;bg;red;brightness;255") {
+		t.Errorf("Expected the tab color to be reverted to red, got %q", output)
+	}
+	if strings.Contains(output, "palette") || strings.Contains(output, "title") {
+		t.Errorf("Expected only the colors to be reverted after previewing a tab color, got %q", output)
+	}
+
+	// A theme, badge, and title have nothing recorded to go back to, so they are reset
+	buf.Reset()
+	if err := endPreview(&Profile{Tab: "ff0000", Foreground: "default", Background: "default"}, &Profile{Theme: "dracula", Badge: "PROD", Title: "T"}); err != nil {
+		t.Fatalf("endPreview() failed: %v", err)
+	}
+	output = buf.String()
+	for _, expected := range []string{"palette and cursor reset", `badge ""`, `title ""`} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in the revert, got %q", expected, output)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import "os"
+
+// rootSubProfileKey names the sub-profile applied when running as root or through sudo
+const rootSubProfileKey = "root"
+
+// detectRoot reports whether set-tab-color runs as root or was started through sudo
+// ($SUDO_USER is set), which selects the "root" sub-profile. A fixture decides from its
+// $SUDO_USER alone so it gives the same result for any user, except the one -async
+// hands to its background process, which runs as the same user as its parent.
+func detectRoot() bool {
+	if getDetectionEnv("SUDO_USER") != "" {
+		return true
+	}
+	asyncFixture := os.Getenv(asyncChildEnvVar) != "" && os.Getenv(asyncChildEnvVar) == os.Getenv(fakeEnvVar)
+	if getFakeEnvironment() != nil && !asyncFixture {
+		return false
+	}
+	return os.Geteuid() == 0
+}
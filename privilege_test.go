@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestDetectRoot tests root detection from $SUDO_USER and the effective UID
+func TestDetectRoot(t *testing.T) {
+	defer setFakeEnvironment(nil)
+
+	setFakeEnvironment(&FakeEnvironment{Env: map[string]string{"SUDO_USER": "alice"}})
+	if !detectRoot() {
+		t.Error("Expected a fixture with $SUDO_USER to run as root")
+	}
+
+	// Fixtures ignore the UID of the user replaying them
+	setFakeEnvironment(&FakeEnvironment{Env: map[string]string{}})
+	if detectRoot() {
+		t.Error("Expected a fixture without $SUDO_USER not to run as root")
+	}
+
+	setFakeEnvironment(nil)
+	t.Setenv("SUDO_USER", "")
+	if detectRoot() != (os.Geteuid() == 0) {
+		t.Errorf("Expected detectRoot() to follow the effective UID %d", os.Geteuid())
+	}
+}
+
+// TestResolveProfileRoot tests that the root sub-profile is applied over every other layer
+func TestResolveProfileRoot(t *testing.T) {
+	data := map[string]interface{}{
+		"tab":    "blue",
+		"iterm2": map[string]interface{}{"tab": "green", "fg": "white", "priority": int64(10)},
+		"root":   map[string]interface{}{"tab": "red"},
+	}
+	info := &TerminalShellInfo{Terminals: []TerminalType{TerminalTypeITerm2}, Shell: ShellTypeZsh}
+
+	res, err := resolveProfile("prod", testProfile(t, data), info)
+	if err != nil {
+		t.Fatalf("resolveProfile() failed: %v", err)
+	}
+	if res.Result.Tab != "green" {
+		t.Errorf("Expected the terminal tab color without root, got %q", res.Result.Tab)
+	}
+
+	info.Root = true
+	res, err = resolveProfile("prod", testProfile(t, data), info)
+	if err != nil {
+		t.Fatalf("resolveProfile() failed: %v", err)
+	}
+	if res.Result.Tab != "red" || res.Result.Foreground != "white" {
+		t.Errorf("Unexpected result as root: %+v", res.Result)
+	}
+	if last := res.Steps[len(res.Steps)-1]; last.Layer != "root" || !last.Applied {
+		t.Errorf("Expected the root layer last, got %+v", last)
+	}
+	if res.sourceName("tab") != "profiles.prod.root" {
+		t.Errorf("Unexpected tab source: %q", res.sourceName("tab"))
+	}
+}
@@ -0,0 +1,558 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProfileValue is a profile setting as written in the config: a plain value, or a
+// conditional one such as { value = "red", unless_terminal = "vscode" }
+type ProfileValue struct {
+	Value      string
+	Conditions map[string]string // Condition key → name or pattern; empty for a plain value
+}
+
+// ProfileConfig is a profile table decoded from the config: its own settings and the
+// sub-profile tables nested in it, by key ("zsh", "iterm2", "ssh|mosh", ...)
+type ProfileConfig struct {
+	Tab          *ProfileValue
+	Foreground   *ProfileValue
+	Background   *ProfileValue
+	Preset       *ProfileValue
+	Theme        *ProfileValue
+	Badge        *ProfileValue
+	Title        *ProfileValue
+	Attention    *ProfileValue // "true", "false", or a named cue
+	ITermProfile *ProfileValue
+	Transparency *ProfileValue
+	Blur         *ProfileValue
+	Palette      map[string]string
+	HasPalette   bool // A palette table is present, even if empty
+	Priority     int
+	Secret       bool
+	Sticky       bool   // Once applied, only -force or reset replaces it in the session
+	Notify       string // "desktop" or "bell" to announce the profile when applied, or ""
+	SubProfiles  map[string]*ProfileConfig
+}
+
+// configError is a problem with a config value, with the keys leading to it so
+// config validate can report its line
+type configError struct {
+	Path    []string
+	Message string
+}
+
+func (e configError) Error() string {
+	return e.Message
+}
+
+// profileValueKeys are the settings a profile table may have, besides palette,
+// priority, secret, sticky, and notify
+var profileValueKeys = []string{"tab", "fg", "bg", "preset", "theme", "badge", "title", "attention", "iterm_profile", "transparency", "blur"}
+
+// profileKeyAliases are names people reach for instead of the short profile keys
+var profileKeyAliases = map[string]string{
+	"foreground": "fg",
+	"background": "bg",
+	"color":      "tab",
+	"tab_color":  "tab",
+	"name":       "title",
+}
+
+// value returns the setting stored under a profile key
+func (c *ProfileConfig) value(key string) *ProfileValue {
+	switch key {
+	case "tab":
+		return c.Tab
+	case "fg":
+		return c.Foreground
+	case "bg":
+		return c.Background
+	case "preset":
+		return c.Preset
+	case "theme":
+		return c.Theme
+	case "badge":
+		return c.Badge
+	case "title":
+		return c.Title
+	case "attention":
+		return c.Attention
+	case "iterm_profile":
+		return c.ITermProfile
+	case "transparency":
+		return c.Transparency
+	case "blur":
+		return c.Blur
+	}
+	return nil
+}
+
+// setValue stores the setting for a profile key
+func (c *ProfileConfig) setValue(key string, value *ProfileValue) {
+	switch key {
+	case "tab":
+		c.Tab = value
+	case "fg":
+		c.Foreground = value
+	case "bg":
+		c.Background = value
+	case "preset":
+		c.Preset = value
+	case "theme":
+		c.Theme = value
+	case "badge":
+		c.Badge = value
+	case "title":
+		c.Title = value
+	case "attention":
+		c.Attention = value
+	case "iterm_profile":
+		c.ITermProfile = value
+	case "transparency":
+		c.Transparency = value
+	case "blur":
+		c.Blur = value
+	}
+}
+
+// hasValues reports whether the table sets anything to apply, as opposed to only
+// holding sub-profiles
+func (c *ProfileConfig) hasValues() bool {
+	if c == nil {
+		return false
+	}
+	for _, key := range profileValueKeys {
+		if c.value(key) != nil {
+			return true
+		}
+	}
+	return c.HasPalette
+}
+
+// profile returns the settings of the table for info, resolving conditional values
+func (c *ProfileConfig) profile(info *TerminalShellInfo) *Profile {
+	if info == nil {
+		info = &TerminalShellInfo{}
+	}
+	str := func(key string) string {
+		if value, ok := c.value(key).valueFor(info); ok {
+			return value
+		}
+		return ""
+	}
+	return &Profile{
+		Tab:          str("tab"),
+		Foreground:   str("fg"),
+		Background:   str("bg"),
+		Preset:       str("preset"),
+		Theme:        str("theme"),
+		Badge:        str("badge"),
+		Title:        str("title"),
+		Attention:    str("attention"),
+		ITermProfile: str("iterm_profile"),
+		Transparency: str("transparency"),
+		Blur:         str("blur"),
+		Palette:      c.Palette,
+		Priority:     c.Priority,
+	}
+}
+
+// subProfile returns the settings of a sub-profile table for info, or nil when the
+// table doesn't exist or has nothing to apply
+func (c *ProfileConfig) subProfile(key string, info *TerminalShellInfo) *Profile {
+	sub := c.SubProfiles[key]
+	if !sub.hasValues() {
+		return nil
+	}
+	return sub.profile(info)
+}
+
+// sortedSubProfileKeys returns the keys of the sub-profile tables in sorted order
+func (c *ProfileConfig) sortedSubProfileKeys() []string {
+	return sortedMapKeys(c.SubProfiles)
+}
+
+// parseProfiles decodes the [profiles] tables, reporting every problem found
+func parseProfiles(raw map[string]interface{}) (map[string]*ProfileConfig, []configError) {
+	profiles := make(map[string]*ProfileConfig, len(raw))
+	var errs []configError
+	for _, name := range sortedMapKeys(raw) {
+		m, ok := raw[name].(map[string]interface{})
+		if !ok {
+			errs = append(errs, configError{Path: []string{"profiles", name}, Message: fmt.Sprintf("profile %q must be a table", name)})
+			continue
+		}
+		profile, profileErrs := parseProfileConfig([]string{"profiles", name}, m)
+		profiles[name] = profile
+		errs = append(errs, profileErrs...)
+	}
+	return profiles, errs
+}
+
+// parseProfileConfig decodes a profile or sub-profile table. Keys that aren't settings
+// must hold sub-profile tables; anything else is reported with the keys leading to it.
+func parseProfileConfig(path []string, m map[string]interface{}) (*ProfileConfig, []configError) {
+	profile := &ProfileConfig{}
+	var errs []configError
+	prefix := strings.Join(path, ".")
+	addError := func(key string, format string, args ...interface{}) {
+		errs = append(errs, configError{Path: append(append([]string{}, path...), key), Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, key := range sortedMapKeys(m) {
+		raw := m[key]
+		switch {
+		case containsString(profileValueKeys, key):
+			value, err := parseProfileValue(prefix+"."+key, key, raw)
+			if err != nil {
+				addError(key, "%v", err)
+				continue
+			}
+			profile.setValue(key, value)
+
+		case key == "palette":
+			palette, ok := raw.(map[string]interface{})
+			if !ok {
+				addError(key, "%s.palette must be a table, got %T", prefix, raw)
+				continue
+			}
+			profile.HasPalette = true
+			profile.Palette = map[string]string{}
+			for _, paletteKey := range sortedMapKeys(palette) {
+				value, ok := palette[paletteKey].(string)
+				switch {
+				case !isPaletteKey(paletteKey):
+					errs = append(errs, configError{
+						Path:    append(append([]string{}, path...), "palette", paletteKey),
+						Message: fmt.Sprintf("unknown palette key %s.palette.%s (known keys: %s)", prefix, paletteKey, strings.Join(paletteKeys, ", ")),
+					})
+				case !ok:
+					errs = append(errs, configError{
+						Path:    append(append([]string{}, path...), "palette", paletteKey),
+						Message: fmt.Sprintf("%s.palette.%s must be a string, got %T", prefix, paletteKey, palette[paletteKey]),
+					})
+				default:
+					profile.Palette[paletteKey] = value
+				}
+			}
+
+		case key == "priority":
+			priority, ok := raw.(int64)
+			if !ok {
+				addError(key, "%s.priority must be an integer, got %T", prefix, raw)
+				continue
+			}
+			profile.Priority = int(priority)
+
+		case key == "secret":
+			secret, ok := raw.(bool)
+			if !ok {
+				addError(key, "%s.secret must be true or false, got %T", prefix, raw)
+				continue
+			}
+			profile.Secret = secret
+
+		case key == "sticky":
+			sticky, ok := raw.(bool)
+			if !ok {
+				addError(key, "%s.sticky must be true or false, got %T", prefix, raw)
+				continue
+			}
+			profile.Sticky = sticky
+
+		case key == "notify":
+			switch v := raw.(type) {
+			case bool:
+				if v {
+					profile.Notify = notifyDesktop
+				}
+			case string:
+				if v != notifyDesktop && v != notifyBell {
+					addError(key, "%s.notify must be true, false, \"desktop\", or \"bell\", got %v", prefix, v)
+					continue
+				}
+				profile.Notify = v
+			default:
+				addError(key, "%s.notify must be true, false, \"desktop\", or \"bell\", got %T", prefix, raw)
+			}
+
+		default:
+			sub, ok := raw.(map[string]interface{})
+			if !ok {
+				message := fmt.Sprintf("unknown key %q in %s", key, prefix)
+				if suggestion := suggestProfileKey(key); suggestion != "" {
+					message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				addError(key, "%s", message)
+				continue
+			}
+			subProfile, subErrs := parseProfileConfig(append(append([]string{}, path...), key), sub)
+			if profile.SubProfiles == nil {
+				profile.SubProfiles = map[string]*ProfileConfig{}
+			}
+			profile.SubProfiles[key] = subProfile
+			errs = append(errs, subErrs...)
+		}
+	}
+	return profile, errs
+}
+
+// parseProfileValue decodes the setting at name, plain or conditional. attention
+// also takes true or false, transparency a percentage, and blur true, false, or a radius.
+func parseProfileValue(name, key string, raw interface{}) (*ProfileValue, error) {
+	value := &ProfileValue{}
+	if conditional, ok := raw.(map[string]interface{}); ok {
+		if err := checkConditionalValue(conditional); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		for _, condition := range conditionKeys {
+			if pattern, ok := conditional[condition].(string); ok {
+				if value.Conditions == nil {
+					value.Conditions = map[string]string{}
+				}
+				value.Conditions[condition] = pattern
+			}
+		}
+		raw = conditional["value"]
+	}
+
+	switch key {
+	case "transparency":
+		return parseWindowEffect(value, name, raw, 100, "a number from 0 to 100 or \"default\"")
+	case "blur":
+		if v, ok := raw.(bool); ok {
+			value.Value = fmt.Sprint(v)
+			return value, nil
+		}
+		return parseWindowEffect(value, name, raw, 64, "true, false, a radius from 0 to 64, or \"default\"")
+	}
+
+	switch v := raw.(type) {
+	case string:
+		if key == "attention" && v != "bounce" && v != "fireworks" {
+			return nil, fmt.Errorf("%s must be true, false, \"bounce\", or \"fireworks\", got %v", name, raw)
+		}
+		value.Value = v
+	case bool:
+		if key != "attention" {
+			return nil, fmt.Errorf("%s must be a string, got %T", name, raw)
+		}
+		value.Value = fmt.Sprint(v)
+	default:
+		if key == "attention" {
+			return nil, fmt.Errorf("%s must be true, false, \"bounce\", or \"fireworks\", got %v", name, raw)
+		}
+		return nil, fmt.Errorf("%s must be a string, got %T", name, raw)
+	}
+	return value, nil
+}
+
+// parseWindowEffect decodes a transparency or blur amount from 0 to max, or "default"
+func parseWindowEffect(value *ProfileValue, name string, raw interface{}, max float64, expected string) (*ProfileValue, error) {
+	var amount float64
+	switch v := raw.(type) {
+	case string:
+		if v != "default" {
+			return nil, fmt.Errorf("%s must be %s, got %q", name, expected, v)
+		}
+		value.Value = v
+		return value, nil
+	case int64:
+		amount = float64(v)
+	case float64:
+		amount = v
+	default:
+		return nil, fmt.Errorf("%s must be %s, got %T", name, expected, raw)
+	}
+	if amount < 0 || amount > max {
+		return nil, fmt.Errorf("%s must be %s, got %v", name, expected, raw)
+	}
+	value.Value = strconv.FormatFloat(amount, 'f', -1, 64)
+	return value, nil
+}
+
+// suggestProfileKey returns the profile key closest to a misspelled one, if any is close
+func suggestProfileKey(key string) string {
+	candidates := map[string]string{"palette": "palette", "priority": "priority", "secret": "secret", "sticky": "sticky", "notify": "notify"}
+	for _, known := range profileValueKeys {
+		candidates[known] = known
+	}
+	for alias, known := range profileKeyAliases {
+		candidates[alias] = known
+	}
+
+	if name := closestName(key, sortedMapKeys(candidates)); name != "" {
+		return candidates[name]
+	}
+	return ""
+}
+
+// closestName returns the candidate closest to a misspelled name, if any is within
+// two edits
+func closestName(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if distance := editDistance(strings.ToLower(name), candidate); distance < bestDistance && distance < len(candidate) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// unknownSubProfileKeys reports sub-profile tables that no terminal, shell, or mode
+// selects, usually misspellings such as [profiles.dev.itrem2]. Terminals and shells
+// from the config's [detect] table count as known. Every name in a combination key
+// and every plain name in a pattern key is checked, as are the shell tables nested
+// in a terminal's.
+func unknownSubProfileKeys(config *Config) []configError {
+	terminals, shells := subProfileNames(config.Detect)
+	topLevel := append(append([]string{}, terminals...), shells...)
+	for _, appearance := range knownAppearances {
+		topLevel = append(topLevel, string(appearance))
+	}
+	topLevel = append(topLevel, defaultSubProfileKey, rootSubProfileKey)
+
+	var errs []configError
+	for _, name := range sortedMapKeys(config.Profiles) {
+		profile := config.Profiles[name]
+		prefix := "profiles." + name
+		for _, key := range profile.sortedSubProfileKeys() {
+			path := []string{"profiles", name, key}
+			switch {
+			case strings.Contains(key, "+"):
+				terminal, shell, _ := strings.Cut(key, "+")
+				if !containsString(terminals, terminal) {
+					errs = append(errs, unknownSubProfileName(path, "terminal", terminal, key, terminals))
+				} else if !containsString(shells, shell) {
+					errs = append(errs, unknownSubProfileName(path, "shell", shell, key, shells))
+				}
+			case isSubProfilePattern(key):
+				for _, alternative := range strings.Split(key, "|") {
+					alternative = strings.TrimSpace(alternative)
+					if alternative != "" && !strings.ContainsAny(alternative, "*?[") && !containsString(topLevel, alternative) {
+						errs = append(errs, unknownSubProfileName(path, "terminal or shell", alternative, key, topLevel))
+					}
+				}
+			case !containsString(topLevel, key):
+				errs = append(errs, unknownSubProfileError(path, prefix, topLevel))
+				continue
+			}
+
+			// Only a terminal's table can hold tables of its own, one per shell
+			nestedNames := shells
+			if !containsString(terminals, key) {
+				nestedNames = nil
+			}
+			for _, nestedKey := range profile.SubProfiles[key].sortedSubProfileKeys() {
+				if !containsString(nestedNames, nestedKey) {
+					errs = append(errs, unknownSubProfileError(append(append([]string{}, path...), nestedKey), prefix+"."+key, nestedNames))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// subProfileNames returns the terminal and shell names usable in sub-profile keys,
+// built-in and from a [detect] table
+func subProfileNames(detect DetectConfig) (terminals, shells []string) {
+	for _, terminal := range knownTerminalTypes {
+		terminals = append(terminals, string(terminal))
+	}
+	for _, key := range sortedMapKeys(detect.Terminals) {
+		terminals = append(terminals, detect.Terminals[key])
+	}
+	for _, shell := range knownShellTypes {
+		shells = append(shells, string(shell))
+	}
+	for _, key := range sortedMapKeys(detect.Shells) {
+		shells = append(shells, detect.Shells[key])
+	}
+	return terminals, shells
+}
+
+// unknownSubProfileError reports a sub-profile table at path that nothing selects
+func unknownSubProfileError(path []string, prefix string, candidates []string) configError {
+	key := path[len(path)-1]
+	message := fmt.Sprintf("unknown sub-profile key %q in %s", key, prefix)
+	if suggestion := closestName(key, candidates); suggestion != "" {
+		message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+	}
+	return configError{Path: path, Message: message}
+}
+
+// unknownSubProfileName reports a name in a combination or pattern key that nothing matches
+func unknownSubProfileName(path []string, kind, name, key string, candidates []string) configError {
+	message := fmt.Sprintf("unknown %s %q in sub-profile key %q of %s", kind, name, key, strings.Join(path[:2], "."))
+	if suggestion := closestName(name, candidates); suggestion != "" {
+		message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+	}
+	return configError{Path: path, Message: message}
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// unknownConfigKeys reports keys outside [profiles] that no part of the config reads,
+// usually misspellings. Keys inside an unknown table aren't reported separately, and
+// profiles are checked by parseProfiles.
+func unknownConfigKeys(meta toml.MetaData) []configError {
+	var errs []configError
+	reported := map[string]bool{}
+	for _, key := range meta.Undecoded() {
+		if key[0] == "profiles" || hasReportedPrefix(reported, key) {
+			continue
+		}
+		reported[strings.Join(key, ".")] = true
+		parent, name := strings.Join(key[:len(key)-1], "."), key[len(key)-1]
+		message := fmt.Sprintf("unknown top-level key %q", name)
+		if parent != "" {
+			message = fmt.Sprintf("unknown key %q in %s", name, parent)
+		}
+		errs = append(errs, configError{Path: append([]string{}, key...), Message: message})
+	}
+	return errs
+}
+
+// hasReportedPrefix reports whether a table enclosing key was already reported
+func hasReportedPrefix(reported map[string]bool, key toml.Key) bool {
+	for i := 1; i < len(key); i++ {
+		if reported[strings.Join(key[:i], ".")] {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedMapKeys returns the keys of a map in sorted order
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+// testProfile decodes a profile table written as it comes from the TOML decoder
+func testProfile(t *testing.T, data map[string]interface{}) *ProfileConfig {
+	t.Helper()
+	profile, errs := parseProfileConfig([]string{"profiles", "test"}, data)
+	if len(errs) > 0 {
+		t.Fatalf("parseProfileConfig() failed: %v", errs)
+	}
+	return profile
+}
+
+// decodeTestConfig decodes a config as loadConfig does
+func decodeTestConfig(t *testing.T, content string) *Config {
+	t.Helper()
+	var config Config
+	meta, err := toml.Decode(content, &config)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if errs := finishDecoding(meta, &config); len(errs) > 0 {
+		t.Fatalf("Failed to decode config: %v", errs)
+	}
+	return &config
+}
+
+// TestParseProfileConfig tests decoding a profile table into its settings and sub-profiles
+func TestParseProfileConfig(t *testing.T) {
+	config := decodeTestConfig(t, `
+[profiles.dev]
+tab = "blue"
+attention = true
+priority = 2
+secret = true
+sticky = true
+transparency = 20
+blur = 12.5
+fg = { value = "white", unless_terminal = "vscode" }
+
+[profiles.dev.palette]
+curbg = "yellow"
+
+[profiles.dev.iterm2.zsh]
+badge = "DEV"
+`)
+
+	dev := config.Profiles["dev"]
+	if dev == nil {
+		t.Fatal("Expected profile dev")
+	}
+	if dev.Tab.Value != "blue" || dev.Attention.Value != "true" || dev.Priority != 2 || !dev.Secret || !dev.Sticky {
+		t.Errorf("Unexpected settings: %+v", dev)
+	}
+	if dev.Transparency.Value != "20" || dev.Blur.Value != "12.5" {
+		t.Errorf("Unexpected window effects: %+v, %+v", dev.Transparency, dev.Blur)
+	}
+	if dev.Foreground.Value != "white" || dev.Foreground.Conditions["unless_terminal"] != "vscode" {
+		t.Errorf("Unexpected conditional value: %+v", dev.Foreground)
+	}
+	if !dev.HasPalette || dev.Palette["curbg"] != "yellow" {
+		t.Errorf("Unexpected palette: %v", dev.Palette)
+	}
+
+	iterm2 := dev.SubProfiles["iterm2"]
+	if iterm2.hasValues() || iterm2.SubProfiles["zsh"].Badge.Value != "DEV" {
+		t.Errorf("Expected iterm2 to only hold the zsh table, got %+v", iterm2)
+	}
+	if p := dev.profile(&TerminalShellInfo{Terminals: []TerminalType{TerminalTypeVSCode}}); p.Foreground != "" || p.Tab != "blue" {
+		t.Errorf("Expected the condition to drop fg in vscode, got %+v", p)
+	}
+}
+
+// TestParseProfileConfigErrors tests the problems reported while decoding profiles
+func TestParseProfileConfigErrors(t *testing.T) {
+	var config Config
+	if _, err := toml.Decode(`
+[profiles.dev]
+tab = 3
+foregound = "white"
+colour = "red"
+attention = "loud"
+priority = "high"
+secret = "yes"
+sticky = 1
+notify = "loud"
+transparency = 150
+blur = "soft"
+
+[profiles.dev.palette]
+curbg = 1
+glow = "red"
+
+[profiles.dev.zsh]
+bakground = "black"
+
+[profiles]
+broken = "red"
+`, &config); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	_, errs := parseProfiles(config.RawProfiles)
+	expected := []struct {
+		path    string
+		message string
+	}{
+		{"profiles.broken", `profile "broken" must be a table`},
+		{"profiles.dev.attention", `profiles.dev.attention must be true, false, "bounce", or "fireworks", got loud`},
+		{"profiles.dev.blur", `profiles.dev.blur must be true, false, a radius from 0 to 64, or "default", got "soft"`},
+		{"profiles.dev.colour", `unknown key "colour" in profiles.dev (did you mean "tab"?)`},
+		{"profiles.dev.foregound", `unknown key "foregound" in profiles.dev (did you mean "fg"?)`},
+		{"profiles.dev.notify", `profiles.dev.notify must be true, false, "desktop", or "bell", got loud`},
+		{"profiles.dev.palette.curbg", `profiles.dev.palette.curbg must be a string, got int64`},
+		{"profiles.dev.palette.glow", `unknown palette key profiles.dev.palette.glow (known keys: ` + strings.Join(paletteKeys, ", ") + `)`},
+		{"profiles.dev.priority", `profiles.dev.priority must be an integer, got string`},
+		{"profiles.dev.secret", `profiles.dev.secret must be true or false, got string`},
+		{"profiles.dev.sticky", `profiles.dev.sticky must be true or false, got int64`},
+		{"profiles.dev.tab", `profiles.dev.tab must be a string, got int64`},
+		{"profiles.dev.transparency", `profiles.dev.transparency must be a number from 0 to 100 or "default", got 150`},
+		{"profiles.dev.zsh.bakground", `unknown key "bakground" in profiles.dev.zsh (did you mean "bg"?)`},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(errs), errs)
+	}
+	for i, err := range errs {
+		if path := strings.Join(err.Path, "."); path != expected[i].path || err.Message != expected[i].message {
+			t.Errorf("Error %d = %s: %q, expected %s: %q", i, path, err.Message, expected[i].path, expected[i].message)
+		}
+	}
+}
+
+// TestSuggestProfileKey tests suggestions for misspelled profile keys
+func TestSuggestProfileKey(t *testing.T) {
+	for key, expected := range map[string]string{
+		"foreground": "fg",
+		"Background": "bg",
+		"prority":    "priority",
+		"pallete":    "palette",
+		"titel":      "title",
+		"zsh":        "",
+		"ssh":        "",
+	} {
+		if got := suggestProfileKey(key); got != expected {
+			t.Errorf("suggestProfileKey(%q) = %q, expected %q", key, got, expected)
+		}
+	}
+}
+
+// TestUnknownConfigKeys tests reporting keys outside [profiles] that nothing reads
+func TestUnknownConfigKeys(t *testing.T) {
+	var config Config
+	meta, err := toml.Decode(`
+default_profle = "dev"
+
+[iterm2]
+bogus = true
+
+[colours]
+brand = "red"
+
+[profiles.dev]
+tab = "red"
+`, &config)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	var got []string
+	for _, err := range unknownConfigKeys(meta) {
+		got = append(got, err.Message)
+	}
+	expected := []string{
+		`unknown top-level key "default_profle"`,
+		`unknown key "bogus" in iterm2`,
+		`unknown top-level key "colours"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unknownConfigKeys() = %q, expected %q", got, expected)
+	}
+}
+
+// TestUnknownSubProfileKeys tests reporting sub-profile tables no terminal, shell, or mode selects
+func TestUnknownSubProfileKeys(t *testing.T) {
+	config := decodeTestConfig(t, `
+[detect.terminals]
+alacritty = "alacritty"
+
+[profiles.dev]
+tab = "blue"
+
+[profiles.dev.itrem2]
+tab = "red"
+
+[profiles.dev.alacritty]
+tab = "green"
+
+[profiles.dev.dark]
+bg = "black"
+
+[profiles.dev."ssh|mosj"]
+tab = "yellow"
+
+[profiles.dev."*sh"]
+fg = "white"
+
+[profiles.dev."iterm2+zhs"]
+badge = "DEV"
+
+[profiles.dev.iterm2.fish]
+badge = "FISH"
+
+[profiles.dev.iterm2.vscode]
+badge = "CODE"
+
+[profiles.dev.zsh.iterm2]
+badge = "ZSH"
+`)
+
+	var got []string
+	for _, err := range unknownSubProfileKeys(config) {
+		got = append(got, strings.Join(err.Path, ".")+": "+err.Message)
+	}
+	expected := []string{
+		`profiles.dev.iterm2.vscode: unknown sub-profile key "vscode" in profiles.dev.iterm2`,
+		`profiles.dev.iterm2+zhs: unknown shell "zhs" in sub-profile key "iterm2+zhs" of profiles.dev (did you mean "zsh"?)`,
+		`profiles.dev.itrem2: unknown sub-profile key "itrem2" in profiles.dev (did you mean "iterm2"?)`,
+		`profiles.dev.ssh|mosj: unknown terminal or shell "mosj" in sub-profile key "ssh|mosj" of profiles.dev (did you mean "mosh"?)`,
+		`profiles.dev.zsh.iterm2: unknown sub-profile key "iterm2" in profiles.dev.zsh`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unknownSubProfileKeys() =\n%s\nexpected\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ProfileDetail describes a profile for -list-profiles -long
+type ProfileDetail struct {
+	Name        string   `json:"name"`
+	Values      *Profile `json:"values,omitempty"` // Resolved for the detected terminal and shell
+	SubProfiles []string `json:"sub_profiles"`     // Keys of the profile's sub-profile tables
+	UsedBy      []string `json:"used_by"`          // Watch rules and workspace layouts naming the profile
+	Error       string   `json:"error,omitempty"`  // Why the profile couldn't be resolved
+}
+
+// subProfileKeys returns the sorted keys of a profile's sub-profile tables,
+// including shell tables nested in terminal ones ("iterm2.zsh")
+func subProfileKeys(profile *ProfileConfig) []string {
+	keys := []string{}
+	if profile == nil {
+		return keys
+	}
+	for key, sub := range profile.SubProfiles {
+		if sub.hasValues() {
+			keys = append(keys, key)
+		}
+		for nestedKey, nested := range sub.SubProfiles {
+			if nested.hasValues() {
+				keys = append(keys, key+"."+nestedKey)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// namesProfile reports whether a profile reference, possibly a comma-separated
+// list, includes the named profile
+func namesProfile(reference, name string) bool {
+	names, err := splitProfileNames(reference)
+	if err != nil {
+		return false
+	}
+	return containsString(names, name)
+}
+
+// profileReferences returns every watch rule and workspace layout naming a profile
+func profileReferences(config *Config, name string) []string {
+	refs := []string{}
+	for i, rule := range config.Watch.Rules {
+		if !namesProfile(rule.Profile, name) {
+			continue
+		}
+		var conditions []string
+		if rule.Dir != "" {
+			conditions = append(conditions, fmt.Sprintf("dir %q", rule.Dir))
+		}
+		if rule.KubeContext != "" {
+			conditions = append(conditions, fmt.Sprintf("kube_context %q", rule.KubeContext))
+		}
+		refs = append(refs, fmt.Sprintf("watch rule %d (%s)", i+1, strings.Join(conditions, ", ")))
+	}
+	if config.Watch.Default != "" && namesProfile(config.Watch.Default, name) {
+		refs = append(refs, "watch default")
+	}
+
+	workspaces := make([]string, 0, len(config.Workspaces))
+	for workspace := range config.Workspaces {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Strings(workspaces)
+	for _, workspace := range workspaces {
+		for _, layout := range config.Workspaces[workspace].Layouts {
+			if namesProfile(layout.Profile, name) {
+				refs = append(refs, fmt.Sprintf("workspace %q: %s", workspace, layout.describe()))
+			}
+		}
+	}
+	return refs
+}
+
+// listProfileDetails describes every profile, resolving each for the detected
+// (or overridden) terminal and shell
+func listProfileDetails(terminalOverride string) ([]ProfileDetail, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	names, err := listProfileNames()
+	if err != nil {
+		return nil, err
+	}
+
+	info := detectTerminalAndShell(terminalOverride)
+	details := make([]ProfileDetail, 0, len(names))
+	for _, name := range names {
+		data := config.Profiles[name]
+		detail := ProfileDetail{
+			Name:        name,
+			SubProfiles: subProfileKeys(data),
+			UsedBy:      profileReferences(config, name),
+		}
+		// A broken profile is reported rather than hiding the others
+		if res, err := resolveProfile(name, data, &info); err != nil {
+			detail.Error = err.Error()
+		} else {
+			detail.Values = &res.Result
+		}
+		details = append(details, detail)
+	}
+	return details, nil
+}
+
+// printProfileDetails writes the -list-profiles -long view
+func printProfileDetails(w io.Writer, details []ProfileDetail) {
+	for i, detail := range details {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n", detail.Name)
+		if detail.Error != "" {
+			fmt.Fprintf(w, "  error: %s\n", detail.Error)
+		} else if values := formatProfileValues(detail.Values); values != "" {
+			fmt.Fprintf(w, "  values: %s\n", values)
+		}
+		if len(detail.SubProfiles) > 0 {
+			fmt.Fprintf(w, "  sub-profiles: %s\n", strings.Join(detail.SubProfiles, ", "))
+		}
+		for _, ref := range detail.UsedBy {
+			fmt.Fprintf(w, "  used by: %s\n", ref)
+		}
+	}
+}
+
+// runListProfileDetails prints every profile with its values, sub-profiles, and users
+func runListProfileDetails(terminalOverride string) error {
+	details, err := listProfileDetails(terminalOverride)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON(details)
+	}
+	if len(details) == 0 {
+		fmt.Println("No profiles found.")
+		return nil
+	}
+	printProfileDetails(os.Stdout, details)
+	return nil
+}
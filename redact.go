@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// redactedPlaceholder replaces sensitive values in diagnostic output
+const redactedPlaceholder = "[REDACTED]"
+
+// RedactConfig lists values that must not appear in verbose/diagnostic output
+type RedactConfig struct {
+	Patterns []string `toml:"patterns"` // Glob patterns such as "*.corp.example.com"
+}
+
+// redactPattern is a compiled pattern or literal value. A literal only matches
+// where it stands alone, so a secret profile named "dev" leaves /dev/ttys003 and
+// "devbox" alone.
+type redactPattern struct {
+	re      *regexp.Regexp
+	literal bool
+}
+
+// redactPatterns holds the compiled patterns currently in effect
+var redactPatterns []redactPattern
+
+// continuesName reports whether a character next to a literal makes it part of a
+// longer name or path
+func continuesName(r rune) bool {
+	return r == '_' || r == '-' || r == '/' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// replace replaces the pattern's matches in s with the placeholder
+func (p redactPattern) replace(s string) string {
+	if !p.literal {
+		return p.re.ReplaceAllString(s, redactedPlaceholder)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range p.re.FindAllStringIndex(s, -1) {
+		before, _ := utf8.DecodeLastRuneInString(s[:m[0]])
+		after, _ := utf8.DecodeRuneInString(s[m[1]:])
+		if continuesName(before) || continuesName(after) {
+			continue
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(redactedPlaceholder)
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// globToRegexp converts a glob pattern to a case-insensitive regular expression.
+// Wildcards never match whitespace or quotes, so a pattern can't swallow the
+// surrounding log line.
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?i)")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(`[^\s"']*`)
+		case '?':
+			b.WriteString(`[^\s"']`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return regexp.Compile(b.String())
+}
+
+// compileRedactPattern compiles a configured glob pattern, refusing one that
+// would hide everything
+func compileRedactPattern(glob string) (*regexp.Regexp, error) {
+	if strings.Trim(glob, "*?") == "" {
+		return nil, fmt.Errorf("redact pattern %q would hide everything", glob)
+	}
+	re, err := globToRegexp(glob)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redact pattern %q: %v", glob, err)
+	}
+	return re, nil
+}
+
+// setRedactions replaces the active redaction set with the configured glob patterns
+// plus the given literal values (e.g. names of profiles marked secret)
+func setRedactions(cfg RedactConfig, literals []string) error {
+	patterns := make([]redactPattern, 0, len(cfg.Patterns)+len(literals))
+	for _, glob := range cfg.Patterns {
+		re, err := compileRedactPattern(glob)
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns, redactPattern{re: re})
+	}
+	for _, literal := range literals {
+		if literal == "" {
+			continue
+		}
+		patterns = append(patterns, redactPattern{re: regexp.MustCompile("(?i)" + regexp.QuoteMeta(literal)), literal: true})
+	}
+
+	redactPatterns = patterns
+	return nil
+}
+
+// redact replaces every sensitive value in s with a placeholder
+func redact(s string) string {
+	for _, pattern := range redactPatterns {
+		s = pattern.replace(s)
+	}
+	return s
+}
+
+// collectSecretProfileNames returns the names of profiles marked with secret = true
+func collectSecretProfileNames(profiles map[string]*ProfileConfig) []string {
+	var names []string
+	for name, profile := range profiles {
+		if profile.Secret {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// redactProfile returns a copy of a profile with every value redacted
+func redactProfile(p Profile) Profile {
+	for _, value := range []*string{
+		&p.Tab, &p.Foreground, &p.Background, &p.Preset, &p.Theme, &p.Badge, &p.Title,
+		&p.Attention, &p.ITermProfile, &p.Transparency, &p.Blur,
+	} {
+		*value = redact(*value)
+	}
+	if p.Palette != nil {
+		palette := make(map[string]string, len(p.Palette))
+		for key, value := range p.Palette {
+			palette[key] = redact(value)
+		}
+		p.Palette = palette
+	}
+	return p
+}
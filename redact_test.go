@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRedact tests hiding configured patterns and literals from diagnostic output
+func TestRedact(t *testing.T) {
+	defer setRedactions(RedactConfig{}, nil)
+
+	err := setRedactions(RedactConfig{
+		Patterns: []string{"*.corp.example.com", "db-??"},
+	}, []string{"payments-prod", "dev"})
+	if err != nil {
+		t.Fatalf("setRedactions() failed: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"  2: ssh build01.corp.example.com", "  2: ssh [REDACTED]"},
+		{"Using base profile: \"payments-prod\"", "Using base profile: \"[REDACTED]\""},
+		{"Host DB-42 reached", "Host [REDACTED] reached"},
+		{"nothing sensitive here", "nothing sensitive here"},
+		// Literals only match whole names, not parts of paths or longer names
+		{"tty /dev/ttys003, host devbox, profile dev-eu", "tty /dev/ttys003, host devbox, profile dev-eu"},
+		{"profiles.dev.tab: dev,payments-prod (DEV)", "profiles.[REDACTED].tab: [REDACTED],[REDACTED] ([REDACTED])"},
+		{"dev dev", "[REDACTED] [REDACTED]"},
+	}
+
+	for _, test := range tests {
+		result := redact(test.input)
+		if result != test.expected {
+			t.Errorf("redact(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	// Patterns consisting only of wildcards are rejected
+	if err := setRedactions(RedactConfig{Patterns: []string{"*"}}, nil); err == nil {
+		t.Error("Expected setRedactions() to reject a match-everything pattern")
+	}
+}
+
+// TestLoadConfigRedactions tests that [redact] patterns and secret profiles are registered
+func TestLoadConfigRedactions(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "redact-config.toml")
+
+	configContent := `
+[redact]
+patterns = ["*.internal"]
+
+[profiles.acme-billing]
+secret = true
+tab = "red"
+
+[profiles.dev]
+tab = "blue"
+`
+
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+		setRedactions(RedactConfig{}, nil)
+	}()
+
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+
+	input := "profile acme-billing on bastion.internal, profile dev"
+	expected := "profile [REDACTED] on [REDACTED], profile dev"
+	if result := redact(input); result != expected {
+		t.Errorf("redact(%q) = %q, expected %q", input, result, expected)
+	}
+
+	// Secret profiles still resolve normally
+	profile, err := getProfileWithTerminalInfo("acme-billing", &TerminalShellInfo{})
+	if err != nil {
+		t.Fatalf("getProfileWithTerminalInfo() failed: %v", err)
+	}
+	if profile.Tab != "red" {
+		t.Errorf("Expected tab 'red', got %q", profile.Tab)
+	}
+}
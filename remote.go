@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// remoteEnvVar carries the resolved profile to the remote end of ssh. Most sshd
+// configurations accept LC_* variables (AcceptEnv LANG LC_*), so no server change
+// is needed there.
+const remoteEnvVar = "LC_SET_TAB_COLOR"
+
+// remoteProfile is the payload of remoteEnvVar: a profile with its colors resolved
+// to hex, so the remote host needs neither the profile nor its custom colors
+type remoteProfile struct {
+	Name string `json:"name,omitempty"`
+	Profile
+}
+
+// encodeRemoteProfile resolves a profile's colors and encodes it for remoteEnvVar.
+// The attention cue is dropped since the remote side reapplies the colors often.
+func encodeRemoteProfile(name string, profile *Profile) (string, error) {
+	payload := remoteProfile{Name: name, Profile: *profile}
+	payload.Attention = ""
+	payload.Priority = 0
+
+	for _, color := range []*string{&payload.Tab, &payload.Foreground, &payload.Background} {
+		if *color == "" {
+			continue
+		}
+		hex := normalizeColor(*color)
+		if hex == "" {
+			return "", withExitCode(exitUnknownColor, fmt.Errorf("unknown color %q", *color))
+		}
+		*color = hex
+	}
+	if len(payload.Palette) > 0 {
+		palette := make(map[string]string, len(payload.Palette))
+		for key, value := range payload.Palette {
+			hex := normalizeColor(value)
+			if hex == "" {
+				return "", withExitCode(exitUnknownColor, fmt.Errorf("unknown color %q for palette key %s", value, key))
+			}
+			palette[key] = hex
+		}
+		payload.Palette = palette
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeRemoteProfile reads the profile name and profile from a remoteEnvVar value
+func decodeRemoteProfile(value string) (string, *Profile, error) {
+	var payload remoteProfile
+	if err := json.Unmarshal([]byte(value), &payload); err != nil {
+		return "", nil, fmt.Errorf("invalid %s: %v", remoteEnvVar, err)
+	}
+	return payload.Name, &payload.Profile, nil
+}
+
+// runSSH applies a profile as resolved for ssh, then runs ssh with the profile in
+// remoteEnvVar so "set-tab-color remote" on the other end can reapply it
+func runSSH(args []string, opts commandOptions) error {
+	if opts.ProfileName == "" || len(args) == 0 {
+		return fmt.Errorf("usage: -profile <name> ssh [ssh options] <destination> [command]")
+	}
+
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	info := detectTerminalAndShell(opts.TerminalType)
+	useEscapeBackendFor(info)
+
+	// The tab shows the remote session, so resolve the ssh sub-profile unless -terminal says otherwise
+	resolveInfo := info
+	if opts.TerminalType == "" {
+		resolveInfo = withTerminalOverride(info, string(TerminalTypeSSH))
+	}
+	profile, err := getProfileWithTerminalInfo(opts.ProfileName, &resolveInfo)
+	if err != nil {
+		return err
+	}
+	if err := resolveDistinctTab(profile); err != nil {
+		return err
+	}
+	if err := resolveAutoForeground(profile); err != nil {
+		return err
+	}
+
+	value, err := encodeRemoteProfile(opts.ProfileName, profile)
+	if err != nil {
+		return err
+	}
+	if err := checkProfileLock(opts.ProfileName); err != nil {
+		return err
+	}
+
+	if err := applyProfile(profile); err != nil {
+		return err
+	}
+	recordAppliedState(opts.ProfileName, profile)
+
+	sshArgs := append([]string{"-o", "SendEnv=" + remoteEnvVar}, args...)
+	if dryRunMode {
+		quoted := make([]string, len(sshArgs))
+		for i, arg := range sshArgs {
+			quoted[i] = shellQuote(arg)
+		}
+		_, err := fmt.Fprintf(escapeWriter, "[dry-run] exec: %s=%s ssh %s\n", remoteEnvVar, shellQuote(value), strings.Join(quoted, " "))
+		return err
+	}
+
+	ssh, err := exec.LookPath("ssh")
+	if err != nil {
+		return fmt.Errorf("ssh is required: %v", err)
+	}
+	cmd := exec.Command(ssh, sshArgs...)
+	cmd.Env = append(os.Environ(), remoteEnvVar+"="+value)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		// ssh has already reported what went wrong, so only its status is passed on
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return withExitCode(exitErr.ExitCode(), fmt.Errorf("ssh exited with status %d", exitErr.ExitCode()))
+		}
+		return fmt.Errorf("could not run ssh: %v", err)
+	}
+	return nil
+}
+
+// runRemote applies the profile passed in by "set-tab-color ssh". It does nothing
+// when the variable isn't set, so it can run unconditionally from shell startup
+// files, prompt hooks, and tmux hooks.
+func runRemote(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: remote")
+	}
+
+	value := os.Getenv(remoteEnvVar)
+	if value == "" {
+		debugf("%s is not set; nothing to apply\n", remoteEnvVar)
+		return nil
+	}
+	name, profile, err := decodeRemoteProfile(value)
+	if err != nil {
+		return err
+	}
+
+	// The remote config may still style tmux or set the [unsupported] policy
+	if _, err := loadConfig(); err != nil {
+		warnf("could not load config: %v", err)
+	}
+	useEscapeBackendFor(detectTerminals(""))
+
+	if err := checkProfileLock(name); err != nil {
+		return err
+	}
+	if err := applyProfile(profile); err != nil {
+		return err
+	}
+	recordAppliedState(name, profile)
+	return nil
+}
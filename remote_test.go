@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestEncodeRemoteProfile tests resolving colors and round-tripping the payload
+func TestEncodeRemoteProfile(t *testing.T) {
+	if err := setCustomColors(map[string]string{"corp": "#f26522"}); err != nil {
+		t.Fatalf("setCustomColors() failed: %v", err)
+	}
+	defer setCustomColors(nil)
+
+	profile := &Profile{Tab: "corp", Foreground: "white", Background: "default", Title: "prod db",
+		Attention: "bounce", Palette: map[string]string{"red": "env:prod"}}
+	value, err := encodeRemoteProfile("prod", profile)
+	if err != nil {
+		t.Fatalf("encodeRemoteProfile() failed: %v", err)
+	}
+
+	name, decoded, err := decodeRemoteProfile(value)
+	if err != nil {
+		t.Fatalf("decodeRemoteProfile() failed: %v", err)
+	}
+	if name != "prod" {
+		t.Errorf("Expected name prod, got %q", name)
+	}
+	if decoded.Tab != "f26522" || decoded.Foreground != "ffffff" || decoded.Background != "default" || decoded.Title != "prod db" {
+		t.Errorf("Unexpected decoded profile: %+v", decoded)
+	}
+	if decoded.Attention != "" {
+		t.Errorf("Expected attention to be dropped, got %q", decoded.Attention)
+	}
+	if decoded.Palette["red"] != "d32f2f" {
+		t.Errorf("Expected palette color to be resolved, got %q", decoded.Palette["red"])
+	}
+
+	if _, err := encodeRemoteProfile("bad", &Profile{Tab: "notacolor"}); err == nil || exitCode(err) != exitUnknownColor {
+		t.Errorf("Expected unknown color error, got %v", err)
+	}
+	if _, _, err := decodeRemoteProfile("not json"); err == nil {
+		t.Error("Expected decodeRemoteProfile() to reject invalid input")
+	}
+}
+
+// TestRunSSHDryRun tests the ssh command line and environment that would be used
+func TestRunSSHDryRun(t *testing.T) {
+	configFile := t.TempDir() + "/config.toml"
+	content := "[profiles.prod]\ntab = \"red\"\n\n[profiles.prod.ssh]\ntab = \"orange\"\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+
+	if err := runSSH([]string{"host"}, commandOptions{}); err == nil {
+		t.Error("Expected runSSH() to require -profile")
+	}
+	if err := runSSH([]string{"-p", "2222", "host"}, commandOptions{ProfileName: "prod"}); err != nil {
+		t.Fatalf("runSSH() failed: %v", err)
+	}
+
+	output := buf.String()
+	expected := `[dry-run] exec: LC_SET_TAB_COLOR='{"name":"prod","tab":"ffa500"}' ssh -o SendEnv=LC_SET_TAB_COLOR -p 2222 host`
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected output to contain %q, got %q", expected, output)
+	}
+}
+
+// TestRunRemoteUnset tests that remote does nothing without the variable
+func TestRunRemoteUnset(t *testing.T) {
+	original, had := os.LookupEnv(remoteEnvVar)
+	os.Unsetenv(remoteEnvVar)
+	defer func() {
+		if had {
+			os.Setenv(remoteEnvVar, original)
+		}
+	}()
+
+	if err := runRemote(nil); err != nil {
+		t.Errorf("runRemote() failed: %v", err)
+	}
+	if err := runRemote([]string{"extra"}); err == nil {
+		t.Error("Expected runRemote() to reject arguments")
+	}
+}
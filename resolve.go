@@ -0,0 +1,491 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// defaultSubProfileKey names the sub-profile applied when no shell or terminal
+// sub-profile matches
+const defaultSubProfileKey = "default"
+
+// ResolutionStep records one layer considered while resolving a profile
+type ResolutionStep struct {
+	Layer    string   `json:"layer"`         // "base", "appearance", "shell", "terminal", "combination", "default", or "root"
+	Key      string   `json:"key,omitempty"` // Sub-profile key, e.g. "zsh", "ssh|mosh", or "iterm2.zsh"; empty for the base profile
+	Found    bool     `json:"found"`
+	Applied  bool     `json:"applied"`
+	Priority int      `json:"priority,omitempty"`
+	Values   *Profile `json:"values,omitempty"`
+}
+
+// ProfileResolution is the full trace of resolving a profile for a terminal/shell
+type ProfileResolution struct {
+	Name       string            `json:"name"`
+	Terminals  []TerminalType    `json:"terminals"`
+	Shell      ShellType         `json:"shell"`
+	Appearance Appearance        `json:"appearance,omitempty"`
+	Root       bool              `json:"root,omitempty"`
+	Steps      []ResolutionStep  `json:"steps"`
+	Sources    map[string]string `json:"sources"` // Field → sub-profile key that supplied it ("" for base)
+	Result     Profile           `json:"result"`
+}
+
+// resolveProfile overlays the appearance, shell, and terminal sub-profiles of a profile
+// on its base values and records every step. The "dark" or "light" sub-profile is applied
+// first, then the shell sub-profile, then the matching terminal sub-profile with the
+// highest priority (ties go to the terminal found first in the chain). A shell or
+// terminal without a table of its own uses the first pattern key matching it, and
+// the "default" sub-profile is applied when neither matched anything. Sub-profiles
+// for a terminal and shell together ("iterm2.zsh" or "iterm2+zsh") come next, and
+// the "root" sub-profile, when running as root, is applied over everything.
+func resolveProfile(profileName string, config *ProfileConfig, info *TerminalShellInfo) (*ProfileResolution, error) {
+	if !config.hasValues() {
+		return nil, fmt.Errorf("profile %q is not a valid profile", profileName)
+	}
+	baseProfile := config.profile(info)
+
+	res := &ProfileResolution{
+		Name:       profileName,
+		Terminals:  info.Terminals,
+		Shell:      info.Shell,
+		Appearance: info.Appearance,
+		Root:       info.Root,
+		Sources:    map[string]string{},
+	}
+	if res.Terminals == nil {
+		res.Terminals = []TerminalType{}
+	}
+
+	apply := func(key string, p *Profile) {
+		for field, value := range map[string]string{
+			"tab": p.Tab, "fg": p.Foreground, "bg": p.Background, "preset": p.Preset, "theme": p.Theme,
+			"badge": p.Badge, "title": p.Title, "attention": p.Attention, "iterm_profile": p.ITermProfile, "transparency": p.Transparency, "blur": p.Blur,
+		} {
+			if value != "" {
+				res.Sources[field] = key
+			}
+		}
+		for paletteKey := range p.Palette {
+			res.Sources["palette."+paletteKey] = key
+		}
+		res.Result = overlayProfile(res.Result, *p)
+	}
+
+	res.Steps = append(res.Steps, ResolutionStep{Layer: "base", Found: true, Applied: true, Values: baseProfile})
+	apply("", baseProfile)
+
+	// Light/dark mode is the broadest condition, so it comes before the shell and terminal
+	if info.Appearance != AppearanceUnknown {
+		appearanceKey := string(info.Appearance)
+		step := ResolutionStep{Layer: "appearance", Key: appearanceKey}
+		if appearanceProfile := config.subProfile(appearanceKey, info); appearanceProfile != nil {
+			step.Found, step.Applied, step.Values = true, true, appearanceProfile
+			apply(appearanceKey, appearanceProfile)
+		}
+		res.Steps = append(res.Steps, step)
+	}
+
+	// Apply shell-specific overlay next (if it exists)
+	if info.Shell != ShellTypeUnknown {
+		shellKey, shellProfile := findSubProfile(config, string(info.Shell), info)
+		step := ResolutionStep{Layer: "shell", Key: shellKey}
+		if shellProfile != nil {
+			step.Found, step.Applied, step.Values = true, true, shellProfile
+			apply(shellKey, shellProfile)
+		}
+		res.Steps = append(res.Steps, step)
+	}
+
+	// Apply terminal-specific overlay last (takes priority)
+	selected := -1
+	for _, terminal := range info.Terminals {
+		terminalKey, terminalProfile := findSubProfile(config, string(terminal), info)
+		step := ResolutionStep{Layer: "terminal", Key: terminalKey}
+		if terminalProfile != nil {
+			step.Found, step.Values, step.Priority = true, terminalProfile, terminalProfile.Priority
+			if selected < 0 || terminalProfile.Priority > res.Steps[selected].Priority {
+				selected = len(res.Steps)
+			}
+		}
+		res.Steps = append(res.Steps, step)
+	}
+
+	if selected >= 0 {
+		res.Steps[selected].Applied = true
+		apply(res.Steps[selected].Key, res.Steps[selected].Values)
+	}
+
+	// Terminal+shell combinations are the most specific, so they come after both.
+	// Only the combinations that exist are recorded, and like terminal sub-profiles
+	// the one with the highest priority is applied.
+	combination := -1
+	if info.Shell != ShellTypeUnknown {
+		for _, terminal := range info.Terminals {
+			key, combinationProfile := findCombinationSubProfile(config, terminal, info)
+			if combinationProfile == nil {
+				continue
+			}
+			if combination < 0 || combinationProfile.Priority > res.Steps[combination].Priority {
+				combination = len(res.Steps)
+			}
+			res.Steps = append(res.Steps, ResolutionStep{
+				Layer: "combination", Key: key, Found: true, Priority: combinationProfile.Priority, Values: combinationProfile,
+			})
+		}
+	}
+	if combination >= 0 {
+		res.Steps[combination].Applied = true
+		apply(res.Steps[combination].Key, res.Steps[combination].Values)
+	}
+
+	// The catch-all layer stands in for a shell or terminal sub-profile, so it is
+	// only applied when none matched
+	if defaultProfile := config.subProfile(defaultSubProfileKey, info); defaultProfile != nil {
+		step := ResolutionStep{Layer: "default", Key: defaultSubProfileKey, Found: true, Values: defaultProfile}
+		if !res.appliedShell() && selected < 0 && combination < 0 {
+			step.Applied = true
+			apply(defaultSubProfileKey, defaultProfile)
+		}
+		res.Steps = append(res.Steps, step)
+	}
+
+	// Root shells are where a warning matters most, so nothing overrides this layer
+	if info.Root {
+		step := ResolutionStep{Layer: "root", Key: rootSubProfileKey}
+		if rootProfile := config.subProfile(rootSubProfileKey, info); rootProfile != nil {
+			step.Found, step.Applied, step.Values = true, true, rootProfile
+			apply(rootSubProfileKey, rootProfile)
+		}
+		res.Steps = append(res.Steps, step)
+	}
+
+	return res, nil
+}
+
+// findCombinationSubProfile returns the sub-profile for a terminal and info's shell together:
+// the shell table nested in the terminal's ("iterm2.zsh"), or else the combined key
+// ("iterm2+zsh"). The returned key names the table that was found.
+func findCombinationSubProfile(config *ProfileConfig, terminal TerminalType, info *TerminalShellInfo) (string, *Profile) {
+	shell := info.Shell
+	if terminalConfig := config.SubProfiles[string(terminal)]; terminalConfig != nil {
+		if p := terminalConfig.subProfile(string(shell), info); p != nil {
+			return string(terminal) + "." + string(shell), p
+		}
+	}
+	key := string(terminal) + "+" + string(shell)
+	if p := config.subProfile(key, info); p != nil {
+		return key, p
+	}
+	return "", nil
+}
+
+// isSubProfilePattern reports whether a sub-profile key lists alternatives or uses
+// wildcards, as in "ssh|mosh" or "*sh"
+func isSubProfilePattern(key string) bool {
+	return strings.ContainsAny(key, "|*?[")
+}
+
+// matchSubProfilePattern reports whether a shell or terminal name matches one of the
+// |-separated glob patterns of a sub-profile key
+func matchSubProfilePattern(key, name string) bool {
+	for _, pattern := range strings.Split(key, "|") {
+		if matched, _ := path.Match(strings.TrimSpace(pattern), name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSubProfilePattern reports a malformed glob in a pattern sub-profile key
+func checkSubProfilePattern(key string) error {
+	for _, pattern := range strings.Split(key, "|") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return fmt.Errorf("sub-profile key %q has an empty alternative", key)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("sub-profile key %q has an invalid pattern %q", key, pattern)
+		}
+	}
+	return nil
+}
+
+// findSubProfile returns the sub-profile for a shell or terminal: the table named
+// after it, or else the first matching pattern key. Keys that only list names
+// ("ssh|mosh") are tried before wildcard keys ("*sh"), each in sorted order.
+// The returned key is the name itself when nothing matches.
+func findSubProfile(config *ProfileConfig, name string, info *TerminalShellInfo) (string, *Profile) {
+	if p := config.subProfile(name, info); p != nil {
+		return name, p
+	}
+
+	var patterns []string
+	for key := range config.SubProfiles {
+		if isSubProfilePattern(key) {
+			patterns = append(patterns, key)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		wildcardI, wildcardJ := strings.ContainsAny(patterns[i], "*?["), strings.ContainsAny(patterns[j], "*?[")
+		if wildcardI != wildcardJ {
+			return !wildcardI
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, key := range patterns {
+		if !matchSubProfilePattern(key, name) {
+			continue
+		}
+		if p := config.subProfile(key, info); p != nil {
+			return key, p
+		}
+	}
+	return name, nil
+}
+
+// sourceName returns the config table that supplied a field of the result
+func (r *ProfileResolution) sourceName(field string) string {
+	key, ok := r.Sources[field]
+	if !ok {
+		return ""
+	}
+	if key == "" {
+		return "profiles." + r.Name
+	}
+	return "profiles." + r.Name + "." + key
+}
+
+// printVerbose writes the resolution trace in the -verbose log format
+func (r *ProfileResolution) printVerbose() {
+	for _, step := range r.Steps {
+		switch {
+		case step.Layer == "base":
+			debugf("Using base profile: %q\n", r.Name)
+			debugf("  Base profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
+				step.Values.Tab, step.Values.Foreground, step.Values.Background, step.Values.Preset)
+		case step.Layer == "appearance" && step.Found:
+			debugf("Applying %s mode sub-profile: %s.%s\n", step.Key, r.Name, step.Key)
+			debugf("  Mode sub-profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
+				step.Values.Tab, step.Values.Foreground, step.Values.Background, step.Values.Preset)
+		case step.Layer == "appearance":
+			debugf("No %s mode sub-profile found for: %s.%s\n", step.Key, r.Name, step.Key)
+		case step.Layer == "shell" && step.Found:
+			debugf("Applying shell-specific sub-profile: %s.%s\n", r.Name, step.Key)
+			debugf("  Shell sub-profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
+				step.Values.Tab, step.Values.Foreground, step.Values.Background, step.Values.Preset)
+		case step.Layer == "shell":
+			debugf("No shell-specific sub-profile found for: %s.%s\n", r.Name, step.Key)
+		case step.Layer == "terminal" && step.Applied:
+			debugf("Applying terminal-specific sub-profile: %s.%s (priority %d)\n", r.Name, step.Key, step.Priority)
+			debugf("  Terminal sub-profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
+				step.Values.Tab, step.Values.Foreground, step.Values.Background, step.Values.Preset)
+		case step.Layer == "terminal" && step.Found:
+			debugf("Skipping terminal-specific sub-profile: %s.%s (priority %d)\n", r.Name, step.Key, step.Priority)
+		case step.Layer == "terminal":
+			debugf("No terminal-specific sub-profile found for: %s.%s\n", r.Name, step.Key)
+		case step.Layer == "combination" && step.Applied:
+			debugf("Applying terminal+shell sub-profile: %s.%s (priority %d)\n", r.Name, step.Key, step.Priority)
+			debugf("  Combination sub-profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
+				step.Values.Tab, step.Values.Foreground, step.Values.Background, step.Values.Preset)
+		case step.Layer == "combination":
+			debugf("Skipping terminal+shell sub-profile: %s.%s (priority %d)\n", r.Name, step.Key, step.Priority)
+		case step.Layer == "root" && step.Found:
+			debugf("Applying root sub-profile: %s.%s\n", r.Name, step.Key)
+			debugf("  Root sub-profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
+				step.Values.Tab, step.Values.Foreground, step.Values.Background, step.Values.Preset)
+		case step.Layer == "root":
+			debugf("No root sub-profile found for: %s.%s\n", r.Name, step.Key)
+		case step.Layer == "default" && step.Applied:
+			debugf("Applying default sub-profile: %s.%s\n", r.Name, step.Key)
+			debugf("  Default sub-profile values: tab=%q, fg=%q, bg=%q, preset=%q\n",
+				step.Values.Tab, step.Values.Foreground, step.Values.Background, step.Values.Preset)
+		case step.Layer == "default":
+			debugf("Skipping default sub-profile: %s.%s (a shell or terminal sub-profile matched)\n", r.Name, step.Key)
+		}
+	}
+
+	if len(r.Terminals) > 0 && !r.appliedTerminal() {
+		debugf("No terminal sub-profiles found for any terminal in the process chain\n")
+	}
+
+	debugf("Final profile values after overlays: tab=%q, fg=%q, bg=%q, preset=%q\n",
+		r.Result.Tab, r.Result.Foreground, r.Result.Background, r.Result.Preset)
+}
+
+// appliedShell reports whether a shell sub-profile was applied
+func (r *ProfileResolution) appliedShell() bool {
+	for _, step := range r.Steps {
+		if step.Layer == "shell" && step.Applied {
+			return true
+		}
+	}
+	return false
+}
+
+// appliedTerminal reports whether a terminal sub-profile was applied
+func (r *ProfileResolution) appliedTerminal() bool {
+	for _, step := range r.Steps {
+		if step.Layer == "terminal" && step.Applied {
+			return true
+		}
+	}
+	return false
+}
+
+// redacted returns a copy of the resolution with the profile name and every value
+// redacted, for output that may be shared
+func (r *ProfileResolution) redacted() *ProfileResolution {
+	copied := *r
+	copied.Name = redact(r.Name)
+	copied.Steps = make([]ResolutionStep, len(r.Steps))
+	for i, step := range r.Steps {
+		if step.Values != nil {
+			values := redactProfile(*step.Values)
+			step.Values = &values
+		}
+		copied.Steps[i] = step
+	}
+	copied.Sources = make(map[string]string, len(r.Sources))
+	for field, key := range r.Sources {
+		copied.Sources[field] = redact(key)
+	}
+	copied.Result = redactProfile(r.Result)
+	return &copied
+}
+
+// printResolution writes a human-readable resolution report
+func printResolution(w io.Writer, r *ProfileResolution) {
+	terminals := make([]string, len(r.Terminals))
+	for i, terminal := range r.Terminals {
+		terminals[i] = string(terminal)
+	}
+	if len(terminals) == 0 {
+		terminals = []string{"none"}
+	}
+
+	fmt.Fprintf(w, "Profile: %s\n", redact(r.Name))
+	fmt.Fprintf(w, "Terminals: %s\n", strings.Join(terminals, ", "))
+	fmt.Fprintf(w, "Shell: %s\n", r.Shell)
+	if r.Appearance != AppearanceUnknown {
+		fmt.Fprintf(w, "Mode: %s\n", r.Appearance)
+	}
+	if r.Root {
+		fmt.Fprintf(w, "Root: yes\n")
+	}
+
+	fmt.Fprintf(w, "\nLayers:\n")
+	for _, step := range r.Steps {
+		label := step.Layer
+		if step.Key != "" {
+			label += " " + step.Key
+		}
+
+		fmt.Fprintf(w, "  %-20s %s\n", label, resolutionStatus(step))
+		if step.Values != nil {
+			fmt.Fprintf(w, "  %-20s %s\n", "", redact(formatProfileValues(step.Values)))
+		}
+	}
+
+	writeResultFields(w, &r.Result, r.sourceName)
+}
+
+// resolutionStatus describes whether a layer was found and applied, the same way
+// for show-profile and -explain
+func resolutionStatus(step ResolutionStep) string {
+	switch {
+	case step.Applied && (step.Layer == "terminal" || step.Layer == "combination"):
+		return fmt.Sprintf("applied (priority %d)", step.Priority)
+	case step.Applied:
+		return "applied"
+	case step.Found && step.Layer == "default":
+		return "skipped (a shell or terminal sub-profile matched)"
+	case step.Found:
+		return fmt.Sprintf("skipped (priority %d is not the highest)", step.Priority)
+	default:
+		return "not found"
+	}
+}
+
+// profileFieldNames lists the scalar profile fields in the order they are reported
+var profileFieldNames = []string{"tab", "fg", "bg", "preset", "theme", "badge", "title", "attention", "iterm_profile", "transparency", "blur"}
+
+// profileFieldValues maps the non-empty scalar fields of a profile by name
+func profileFieldValues(p *Profile) map[string]string {
+	values := map[string]string{}
+	for field, value := range map[string]string{
+		"tab": p.Tab, "fg": p.Foreground, "bg": p.Background, "preset": p.Preset, "theme": p.Theme,
+		"badge": p.Badge, "title": p.Title, "attention": p.Attention, "iterm_profile": p.ITermProfile, "transparency": p.Transparency, "blur": p.Blur,
+	} {
+		if value != "" {
+			values[field] = value
+		}
+	}
+	return values
+}
+
+// resultFieldWidth fits every field name of the Result section, palette keys included
+const resultFieldWidth = len("palette.br_magenta")
+
+// writeResultFields prints the Result section of show-profile and -explain: each
+// field's redacted value and the table that supplied it
+func writeResultFields(w io.Writer, result *Profile, source func(field string) string) {
+	fmt.Fprintf(w, "\nResult:\n")
+	values := profileFieldValues(result)
+	for _, field := range profileFieldNames {
+		if values[field] == "" {
+			fmt.Fprintf(w, "  %-*s (unchanged)\n", resultFieldWidth, field)
+			continue
+		}
+		fmt.Fprintf(w, "  %-*s %-20s from %s\n", resultFieldWidth, field, redact(values[field]), redact(source(field)))
+	}
+	for _, key := range orderedPaletteKeys(result.Palette) {
+		field := "palette." + key
+		fmt.Fprintf(w, "  %-*s %-20s from %s\n", resultFieldWidth, field, redact(result.Palette[key]), redact(source(field)))
+	}
+}
+
+// formatProfileValues renders the non-empty values of a profile
+func formatProfileValues(p *Profile) string {
+	var parts []string
+	values := profileFieldValues(p)
+	for _, field := range profileFieldNames {
+		if value := values[field]; value != "" {
+			parts = append(parts, fmt.Sprintf("%s=%q", field, value))
+		}
+	}
+	for _, key := range orderedPaletteKeys(p.Palette) {
+		parts = append(parts, fmt.Sprintf("palette.%s=%q", key, p.Palette[key]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// runShowProfile prints how a profile resolves for the detected (or overridden) environment
+func runShowProfile(profileName, terminalOverride string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	data, exists := config.Profiles[profileName]
+	if !exists {
+		return fmt.Errorf("profile %q not found", profileName)
+	}
+
+	info := detectTerminalAndShell(terminalOverride)
+	res, err := resolveProfile(profileName, data, &info)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printJSON(res.redacted())
+	}
+
+	printResolution(os.Stdout, res)
+	return nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestResolveProfileTrace tests the recorded layers and field sources of a resolution
+func TestResolveProfileTrace(t *testing.T) {
+	data := map[string]interface{}{
+		"tab": "blue",
+		"fg":  "white",
+		"zsh": map[string]interface{}{"fg": "yellow"},
+		"tmux": map[string]interface{}{
+			"tab": "green",
+		},
+		"ssh": map[string]interface{}{
+			"tab":      "orange",
+			"priority": int64(5),
+		},
+	}
+
+	res, err := resolveProfile("dev", testProfile(t, data), &TerminalShellInfo{
+		Terminals: []TerminalType{TerminalTypeTmux, TerminalTypeITerm2, TerminalTypeSSH},
+		Shell:     ShellTypeZsh,
+	})
+	if err != nil {
+		t.Fatalf("resolveProfile() failed: %v", err)
+	}
+
+	expectedSteps := []struct {
+		layer, key     string
+		found, applied bool
+	}{
+		{"base", "", true, true},
+		{"shell", "zsh", true, true},
+		{"terminal", "tmux", true, false},
+		{"terminal", "iterm2", false, false},
+		{"terminal", "ssh", true, true},
+	}
+
+	if len(res.Steps) != len(expectedSteps) {
+		t.Fatalf("Expected %d steps, got %d: %+v", len(expectedSteps), len(res.Steps), res.Steps)
+	}
+	for i, expected := range expectedSteps {
+		step := res.Steps[i]
+		if step.Layer != expected.layer || step.Key != expected.key || step.Found != expected.found || step.Applied != expected.applied {
+			t.Errorf("Step %d = %+v, expected %+v", i, step, expected)
+		}
+	}
+
+	if res.Result.Tab != "orange" || res.Result.Foreground != "yellow" {
+		t.Errorf("Unexpected result: %+v", res.Result)
+	}
+	if res.sourceName("tab") != "profiles.dev.ssh" || res.sourceName("fg") != "profiles.dev.zsh" || res.sourceName("bg") != "" {
+		t.Errorf("Unexpected sources: %v", res.Sources)
+	}
+
+	if _, err := resolveProfile("broken", testProfile(t, map[string]interface{}{"zsh": map[string]interface{}{}}), &TerminalShellInfo{}); err == nil {
+		t.Error("Expected error for a profile without values")
+	}
+}
+
+// TestResolveProfilePatternsAndDefault tests pattern sub-profile keys and the catch-all layer
+func TestResolveProfilePatternsAndDefault(t *testing.T) {
+	data := map[string]interface{}{
+		"tab":      "blue",
+		"ssh|mosh": map[string]interface{}{"tab": "orange"},
+		"*sh":      map[string]interface{}{"fg": "yellow"},
+		"bash":     map[string]interface{}{"fg": "white"},
+		"default":  map[string]interface{}{"tab": "gray", "bg": "black"},
+	}
+
+	tests := []struct {
+		name         string
+		info         TerminalShellInfo
+		expectedTab  string
+		expectedFg   string
+		expectedBg   string
+		expectedKeys []string // Keys of the applied sub-profiles
+	}{
+		{"pattern terminal", TerminalShellInfo{Terminals: []TerminalType{TerminalTypeMosh}}, "orange", "", "", []string{"ssh|mosh"}},
+		{"exact key wins over pattern", TerminalShellInfo{Shell: ShellTypeBash}, "blue", "white", "", []string{"bash"}},
+		{"glob shell", TerminalShellInfo{Shell: ShellTypeZsh}, "blue", "yellow", "", []string{"*sh"}},
+		{"default", TerminalShellInfo{Terminals: []TerminalType{TerminalTypeITerm2}}, "gray", "", "black", []string{"default"}},
+		{"nothing detected", TerminalShellInfo{}, "gray", "", "black", []string{"default"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := resolveProfile("dev", testProfile(t, data), &tt.info)
+			if err != nil {
+				t.Fatalf("resolveProfile() failed: %v", err)
+			}
+			if res.Result.Tab != tt.expectedTab || res.Result.Foreground != tt.expectedFg || res.Result.Background != tt.expectedBg {
+				t.Errorf("Unexpected result: %+v", res.Result)
+			}
+			var applied []string
+			for _, step := range res.Steps {
+				if step.Applied && step.Layer != "base" {
+					applied = append(applied, step.Key)
+				}
+			}
+			if strings.Join(applied, ",") != strings.Join(tt.expectedKeys, ",") {
+				t.Errorf("Expected applied sub-profiles %v, got %v", tt.expectedKeys, applied)
+			}
+		})
+	}
+
+	res, _ := resolveProfile("dev", testProfile(t, data), &TerminalShellInfo{Terminals: []TerminalType{TerminalTypeSSH}})
+	if last := res.Steps[len(res.Steps)-1]; last.Layer != "default" || !last.Found || last.Applied {
+		t.Errorf("Expected a skipped default step, got %+v", last)
+	}
+	if res.sourceName("tab") != "profiles.dev.ssh|mosh" {
+		t.Errorf("Unexpected tab source: %q", res.sourceName("tab"))
+	}
+}
+
+// TestResolveProfileCombinations tests nested and combined terminal+shell sub-profiles
+func TestResolveProfileCombinations(t *testing.T) {
+	data := map[string]interface{}{
+		"tab": "blue",
+		"iterm2": map[string]interface{}{
+			"tab": "green",
+			"zsh": map[string]interface{}{"tab": "purple"},
+		},
+		"zsh":       map[string]interface{}{"fg": "yellow"},
+		"tmux+bash": map[string]interface{}{"bg": "black"},
+		"ssh+zsh":   map[string]interface{}{"tab": "orange", "priority": int64(5)},
+		"default":   map[string]interface{}{"tab": "gray"},
+	}
+
+	tests := []struct {
+		name        string
+		info        TerminalShellInfo
+		expectedTab string
+		expectedFg  string
+		expectedBg  string
+		expectedKey string // Key of the applied combination, if any
+	}{
+		{"nested", TerminalShellInfo{Terminals: []TerminalType{TerminalTypeITerm2}, Shell: ShellTypeZsh}, "purple", "yellow", "", "iterm2.zsh"},
+		{"other shell", TerminalShellInfo{Terminals: []TerminalType{TerminalTypeITerm2}, Shell: ShellTypeBash}, "green", "", "", ""},
+		{"combined key", TerminalShellInfo{Terminals: []TerminalType{TerminalTypeTmux}, Shell: ShellTypeBash}, "blue", "", "black", "tmux+bash"},
+		{"priority", TerminalShellInfo{Terminals: []TerminalType{TerminalTypeITerm2, TerminalTypeSSH}, Shell: ShellTypeZsh}, "orange", "yellow", "", "ssh+zsh"},
+		{"unknown shell", TerminalShellInfo{Terminals: []TerminalType{TerminalTypeSSH}}, "gray", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := resolveProfile("dev", testProfile(t, data), &tt.info)
+			if err != nil {
+				t.Fatalf("resolveProfile() failed: %v", err)
+			}
+			if res.Result.Tab != tt.expectedTab || res.Result.Foreground != tt.expectedFg || res.Result.Background != tt.expectedBg {
+				t.Errorf("Unexpected result: %+v", res.Result)
+			}
+			applied := ""
+			for _, step := range res.Steps {
+				if step.Layer == "combination" && step.Applied {
+					applied = step.Key
+				}
+			}
+			if applied != tt.expectedKey {
+				t.Errorf("Expected combination %q, got %q", tt.expectedKey, applied)
+			}
+		})
+	}
+
+	res, _ := resolveProfile("dev", testProfile(t, data), &TerminalShellInfo{Terminals: []TerminalType{TerminalTypeITerm2}, Shell: ShellTypeZsh})
+	if res.sourceName("tab") != "profiles.dev.iterm2.zsh" {
+		t.Errorf("Unexpected tab source: %q", res.sourceName("tab"))
+	}
+}
+
+// TestPrintResolution tests the human-readable show-profile report
+func TestPrintResolution(t *testing.T) {
+	data := map[string]interface{}{
+		"tab":    "blue",
+		"iterm2": map[string]interface{}{"bg": "black"},
+	}
+
+	res, err := resolveProfile("dev", testProfile(t, data), &TerminalShellInfo{
+		Terminals: []TerminalType{TerminalTypeITerm2},
+		Shell:     ShellTypeBash,
+	})
+	if err != nil {
+		t.Fatalf("resolveProfile() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	printResolution(&buf, res)
+	output := buf.String()
+
+	for _, expected := range []string{
+		"Profile: dev",
+		"Terminals: iterm2",
+		"shell bash           not found",
+		"terminal iterm2      applied (priority 0)",
+		"from profiles.dev.iterm2",
+		"preset             (unchanged)",
+	} {
+		if !contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+// TestPrintResolutionRedacted tests that show-profile hides redacted values in the
+// layers, the result, and the JSON output
+func TestPrintResolutionRedacted(t *testing.T) {
+	defer setRedactions(RedactConfig{}, nil)
+	if err := setRedactions(RedactConfig{Patterns: []string{"*.corp.example.com"}}, []string{"acme-prod"}); err != nil {
+		t.Fatalf("setRedactions() failed: %v", err)
+	}
+
+	res, err := resolveProfile("acme-prod", testProfile(t, map[string]interface{}{
+		"badge":  "acme-prod on db.corp.example.com",
+		"iterm2": map[string]interface{}{"title": "db.corp.example.com"},
+	}), &TerminalShellInfo{Terminals: []TerminalType{TerminalTypeITerm2}, Shell: ShellTypeBash})
+	if err != nil {
+		t.Fatalf("resolveProfile() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	printResolution(&buf, res)
+	data, err := json.Marshal(res.redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, output := range []string{buf.String(), string(data)} {
+		if contains(output, "acme-prod") || contains(output, "corp.example.com") {
+			t.Errorf("Expected the profile name and hosts to be redacted, got:\n%s", output)
+		}
+	}
+	if res.Result.Badge != "acme-prod on db.corp.example.com" {
+		t.Errorf("Expected the resolution itself to be left alone, got badge %q", res.Result.Badge)
+	}
+}
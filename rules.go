@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RuleTestResult reports which [watch] rule selects a profile for a directory and
+// kubectl context, and why the other matching rules lost
+type RuleTestResult struct {
+	Dir         string           `json:"dir"`
+	KubeContext string           `json:"kube_context"`
+	Rules       []RuleTestStatus `json:"rules"`
+	Profile     string           `json:"profile"`        // Empty when nothing applies
+	Rule        int              `json:"rule,omitempty"` // 1-based index of the selected rule; 0 for the default
+}
+
+// RuleTestStatus is one rule's outcome
+type RuleTestStatus struct {
+	Dir         string `json:"dir,omitempty"`
+	KubeContext string `json:"kube_context,omitempty"`
+	Profile     string `json:"profile"`
+	Priority    int    `json:"priority"`
+	Matched     bool   `json:"matched"`
+	Selected    bool   `json:"selected"`
+	Status      string `json:"status"`
+}
+
+// testWatchRules explains the selection of a profile for a directory and kubectl
+// context. Names and patterns are redacted, as the result may be shared.
+func testWatchRules(config WatchConfig, dir, kubeContext string) *RuleTestResult {
+	matches := rankWatchRules(config, dir, kubeContext)
+	result := &RuleTestResult{Dir: redact(dir), KubeContext: redact(kubeContext), Profile: redact(config.Default)}
+
+	selected := -1
+	for i, match := range matches {
+		if match.Selected {
+			selected = i
+			result.Profile, result.Rule = redact(match.Rule.Profile), i+1
+		}
+	}
+	for _, match := range matches {
+		result.Rules = append(result.Rules, RuleTestStatus{
+			Dir:         redact(match.Rule.Dir),
+			KubeContext: redact(match.Rule.KubeContext),
+			Profile:     redact(match.Rule.Profile),
+			Priority:    match.Rule.Priority,
+			Matched:     match.Matched,
+			Selected:    match.Selected,
+			Status:      ruleMatchStatus(match, matches, selected),
+		})
+	}
+	return result
+}
+
+// ruleMatchStatus describes whether a rule matched, and if it lost, to what
+func ruleMatchStatus(match watchRuleMatch, matches []watchRuleMatch, selected int) string {
+	switch {
+	case match.Selected:
+		return fmt.Sprintf("selected (priority %d)", match.Rule.Priority)
+	case !match.Matched:
+		return "no match"
+	}
+
+	winner := matches[selected]
+	switch {
+	case winner.Rule.Priority > match.Rule.Priority:
+		return fmt.Sprintf("matched, but priority %d is not the highest", match.Rule.Priority)
+	case winner.Rule.conditions() > match.Rule.conditions():
+		return fmt.Sprintf("matched, but rule %d sets more conditions", selected+1)
+	case winner.outranks(match):
+		return fmt.Sprintf("matched, but rule %d matches a deeper directory", selected+1)
+	default:
+		return fmt.Sprintf("matched, but rule %d comes first", selected+1)
+	}
+}
+
+// printRuleTest writes a human-readable rule test report
+func printRuleTest(w io.Writer, result *RuleTestResult) {
+	kubeContext := result.KubeContext
+	if kubeContext == "" {
+		kubeContext = "none"
+	}
+	fmt.Fprintf(w, "Directory: %s\n", result.Dir)
+	fmt.Fprintf(w, "Kube context: %s\n", kubeContext)
+
+	if len(result.Rules) > 0 {
+		fmt.Fprintf(w, "\nRules:\n")
+	}
+	for i, rule := range result.Rules {
+		var conditions []string
+		if rule.Dir != "" {
+			conditions = append(conditions, fmt.Sprintf("dir=%q", rule.Dir))
+		}
+		if rule.KubeContext != "" {
+			conditions = append(conditions, fmt.Sprintf("kube_context=%q", rule.KubeContext))
+		}
+		fmt.Fprintf(w, "  %-3d %-40s -> %s\n", i+1, strings.Join(conditions, " "), rule.Profile)
+		fmt.Fprintf(w, "  %-3s %s\n", "", rule.Status)
+	}
+
+	switch {
+	case result.Rule > 0:
+		fmt.Fprintf(w, "\nProfile: %s (rule %d)\n", result.Profile, result.Rule)
+	case result.Profile != "":
+		fmt.Fprintf(w, "\nProfile: %s (default)\n", result.Profile)
+	default:
+		fmt.Fprintf(w, "\nProfile: none\n")
+	}
+}
+
+// runRulesTest shows which [watch] rule selects a profile for the current
+// directory and kubectl context, or the ones given with --dir and --kube-context
+func runRulesTest(args []string) error {
+	usage := fmt.Errorf("usage: rules test [--dir DIR] [--kube-context CONTEXT]")
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	kubeContext := readKubeContext(getKubeconfigPath())
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || (name != "dir" && name != "kube-context") {
+			return usage
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return usage
+			}
+			value = args[i+1]
+			i++
+		}
+		if name == "dir" {
+			if dir, err = filepath.Abs(expandHome(value)); err != nil {
+				return err
+			}
+		} else {
+			kubeContext = value
+		}
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	result := testWatchRules(config.Watch, dir, kubeContext)
+	if jsonOutput {
+		return printJSON(result)
+	}
+	printRuleTest(os.Stdout, result)
+	return nil
+}
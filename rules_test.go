@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestTestWatchRules tests explaining which rule selects a profile
+func TestTestWatchRules(t *testing.T) {
+	config := WatchConfig{
+		Default: "dev",
+		Rules: []WatchRule{
+			{Dir: "/srv", Profile: "srv"},
+			{Dir: "/srv/prod", Profile: "prod"},
+			{Dir: "/srv/prod", KubeContext: "staging", Profile: "prod-staging"},
+			{KubeContext: "admin", Profile: "admin", Priority: 10},
+			{Dir: "/home", Profile: "home"},
+			{Dir: "/srv/prod", Profile: "prod-again"},
+		},
+	}
+
+	result := testWatchRules(config, "/srv/prod/api", "staging")
+	if result.Profile != "prod-staging" || result.Rule != 3 {
+		t.Errorf("Expected rule 3 to select prod-staging, got rule %d, %q", result.Rule, result.Profile)
+	}
+	expected := []string{
+		"matched, but rule 3 sets more conditions",
+		"matched, but rule 3 sets more conditions",
+		"selected (priority 0)",
+		"no match",
+		"no match",
+		"matched, but rule 3 sets more conditions",
+	}
+	for i, status := range expected {
+		if result.Rules[i].Status != status {
+			t.Errorf("Rule %d: expected %q, got %q", i+1, status, result.Rules[i].Status)
+		}
+	}
+
+	result = testWatchRules(config, "/srv/prod/api", "admin")
+	for i, status := range map[int]string{
+		0: "matched, but priority 0 is not the highest",
+		3: "selected (priority 10)",
+	} {
+		if result.Rules[i].Status != status {
+			t.Errorf("Rule %d: expected %q, got %q", i+1, status, result.Rules[i].Status)
+		}
+	}
+
+	result = testWatchRules(config, "/srv/prod/api", "")
+	if result.Rules[0].Status != "matched, but rule 2 matches a deeper directory" || result.Rules[5].Status != "matched, but rule 2 comes first" {
+		t.Errorf("Unexpected statuses %q and %q", result.Rules[0].Status, result.Rules[5].Status)
+	}
+
+	result = testWatchRules(config, "/tmp", "")
+	if result.Profile != "dev" || result.Rule != 0 {
+		t.Errorf("Expected the default profile, got rule %d, %q", result.Rule, result.Profile)
+	}
+
+	var buf bytes.Buffer
+	printRuleTest(&buf, result)
+	for _, line := range []string{"Directory: /tmp\n", "Kube context: none\n", `  1   dir="/srv"`, "      no match\n", "Profile: dev (default)\n"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Expected %q in:\n%s", line, buf.String())
+		}
+	}
+}
+
+// TestTestWatchRulesRedacted tests that rule test output hides redacted names
+func TestTestWatchRulesRedacted(t *testing.T) {
+	if err := setRedactions(RedactConfig{Patterns: []string{"*.corp"}}, []string{"secret-prod"}); err != nil {
+		t.Fatalf("setRedactions() failed: %v", err)
+	}
+	defer setRedactions(RedactConfig{}, nil)
+
+	config := WatchConfig{Rules: []WatchRule{{KubeContext: "eu.corp", Profile: "secret-prod"}}}
+	var buf bytes.Buffer
+	printRuleTest(&buf, testWatchRules(config, "/srv", "eu.corp"))
+	if strings.Contains(buf.String(), "corp") || strings.Contains(buf.String(), "secret-prod") {
+		t.Errorf("Expected names to be redacted, got:\n%s", buf.String())
+	}
+}
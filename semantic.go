@@ -0,0 +1,36 @@
+package main
+
+import "strings"
+
+// semanticColors are built-in names for what a color means rather than what it
+// looks like, so teams can code environments consistently without a [colors] table.
+// Colors are picked to stay apart from each other and readable with black or white text.
+var semanticColors = map[string]string{
+	"env:prod":    "d32f2f",
+	"env:staging": "f57c00",
+	"env:test":    "fbc02d",
+	"env:dev":     "388e3c",
+	"env:local":   "1976d2",
+	"danger":      "d32f2f",
+	"warning":     "f57c00",
+	"safe":        "388e3c",
+	"info":        "1976d2",
+}
+
+// semanticAliases maps alternative spellings to their semantic color name
+var semanticAliases = map[string]string{
+	"env:production":  "env:prod",
+	"env:stage":       "env:staging",
+	"env:qa":          "env:test",
+	"env:development": "env:dev",
+}
+
+// lookupSemanticColor returns the hex for a semantic color name or alias
+func lookupSemanticColor(name string) (string, bool) {
+	name = strings.ToLower(name)
+	if canonical, ok := semanticAliases[name]; ok {
+		name = canonical
+	}
+	hex, ok := semanticColors[name]
+	return hex, ok
+}
@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+// TestSemanticColors tests resolving semantic names, aliases, and modifiers
+func TestSemanticColors(t *testing.T) {
+	defer setCustomColors(nil)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"env:prod", "d32f2f"},
+		{"ENV:Production", "d32f2f"},
+		{"env:stage", "f57c00"},
+		{"env:dev", "388e3c"},
+		{"safe", "388e3c"},
+		{"danger@dark(10%)", "ab2424"},
+		{"env:unknown", ""},
+	}
+	for _, tt := range tests {
+		if result := normalizeColor(tt.input); result != tt.expected {
+			t.Errorf("normalizeColor(%q) = %q, expected %q", tt.input, result, tt.expected)
+		}
+	}
+
+	// Custom colors can redefine semantic names and refer to them
+	if err := setCustomColors(map[string]string{"danger": "#800000", "oncall": "env:prod"}); err != nil {
+		t.Fatalf("setCustomColors() failed: %v", err)
+	}
+	if result := normalizeColor("danger"); result != "800000" {
+		t.Errorf("Expected custom danger to win, got %q", result)
+	}
+	if result := normalizeColor("oncall"); result != "d32f2f" {
+		t.Errorf("Expected oncall to resolve to env:prod, got %q", result)
+	}
+}
+
+// TestSemanticColorsDistinct tests that the environment colors are easy to tell apart
+func TestSemanticColorsDistinct(t *testing.T) {
+	envs := []string{"env:prod", "env:staging", "env:test", "env:dev", "env:local"}
+	for i, a := range envs {
+		for _, b := range envs[i+1:] {
+			if semanticColors[a] == semanticColors[b] {
+				t.Errorf("%s and %s share color %s", a, b, semanticColors[a])
+			}
+		}
+	}
+	for alias, name := range semanticAliases {
+		if _, ok := semanticColors[name]; !ok {
+			t.Errorf("Alias %s refers to unknown color %s", alias, name)
+		}
+	}
+}
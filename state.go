@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// AppliedState records the most recently applied profile or colors
+type AppliedState struct {
+	Profile    string    `json:"profile,omitempty"` // Empty when colors were set directly
+	Tab        string    `json:"tab,omitempty"`     // Normalized hex (without '#') or "default"
+	Foreground string    `json:"fg,omitempty"`
+	Background string    `json:"bg,omitempty"`
+	Preset     string    `json:"preset,omitempty"`
+	AppliedAt  time.Time `json:"applied_at"`
+	ShellPID   int       `json:"shell_pid,omitempty"` // Used to tell whether the session is still alive
+	Locked     bool      `json:"locked,omitempty"`    // A sticky profile that only -force or reset replaces
+	TTY        string    `json:"tty,omitempty"`       // Where the settings were written
+	Digest     string    `json:"digest,omitempty"`    // Fingerprint of every setting, to skip reapplying them unchanged
+}
+
+// getStateDir returns the directory for runtime state, following the XDG base directory
+// spec: $XDG_STATE_HOME/set-tab-color, or ~/.local/state/set-tab-color on every platform
+func getStateDir() (string, error) {
+	if stateHome := xdgBaseDir("XDG_STATE_HOME"); stateHome != "" {
+		return filepath.Join(stateHome, "set-tab-color"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get home dir: %v", err)
+	}
+	return filepath.Join(homeDir, ".local", "state", "set-tab-color"), nil
+}
+
+// getStatePath returns the path of the last-applied state file
+func getStatePath() (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "current.json"), nil
+}
+
+// getSessionsDir returns the directory holding one state file per terminal session
+func getSessionsDir() (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "sessions"), nil
+}
+
+// shellPID returns the PID of the shell that ran set-tab-color. In the -async
+// background process that is the one recorded by the foreground process.
+func shellPID() int {
+	if os.Getenv(asyncChildEnvVar) != "" {
+		if pid, err := strconv.Atoi(os.Getenv(asyncShellPIDEnvVar)); err == nil && pid > 0 {
+			return pid
+		}
+	}
+	return os.Getppid()
+}
+
+// getSessionKey identifies the current terminal session: the iTerm2 session UUID
+// when available, otherwise the parent shell's PID
+func getSessionKey() string {
+	if key := os.Getenv(asyncSessionEnvVar); key != "" && os.Getenv(asyncChildEnvVar) != "" {
+		return key
+	}
+	if id := os.Getenv("ITERM_SESSION_ID"); id != "" {
+		return normalizeSessionID(id)
+	}
+	return fmt.Sprintf("pid-%d", shellPID())
+}
+
+// getSessionStatePath returns the registry entry for the current session
+func getSessionStatePath() (string, error) {
+	sessionsDir, err := getSessionsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(sessionsDir, sanitizeFileName(getSessionKey())+".json"), nil
+}
+
+// sanitizeFileName replaces characters that aren't safe in file names
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// newAppliedState builds the state record for a profile, normalizing its colors
+func newAppliedState(profileName string, profile *Profile) AppliedState {
+	return AppliedState{
+		Profile:    profileName,
+		Tab:        normalizeColor(profile.Tab),
+		Foreground: normalizeColor(profile.Foreground),
+		Background: normalizeColor(profile.Background),
+		Preset:     profile.Preset,
+		AppliedAt:  time.Now(),
+		ShellPID:   shellPID(),
+		TTY:        ttyName(),
+		Digest:     profileDigest(profile),
+	}
+}
+
+// saveAppliedState writes the state file so other tools can see what was applied
+func saveAppliedState(state AppliedState) error {
+	statePath, err := getStatePath()
+	if err != nil {
+		return err
+	}
+	return writeStateFile(statePath, state)
+}
+
+// writeStateFile writes a state record as JSON
+func writeStateFile(statePath string, state AppliedState) error {
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return fmt.Errorf("could not create state directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write via rename so readers like status bars never see a partial file
+	tmpPath := statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("could not write state file: %v", err)
+	}
+	return os.Rename(tmpPath, statePath)
+}
+
+// loadAppliedState reads the state file; it returns nil if nothing was applied yet
+func loadAppliedState() (*AppliedState, error) {
+	statePath, err := getStatePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state AppliedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %v", statePath, err)
+	}
+	return &state, nil
+}
+
+// loadSessionState reads this session's registry entry; it returns nil if nothing
+// was applied in this session yet
+func loadSessionState() (*AppliedState, error) {
+	sessionPath, err := getSessionStatePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(sessionPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state AppliedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %v", sessionPath, err)
+	}
+	return &state, nil
+}
+
+// restoreProfile returns the profile that brings the session back to a recorded
+// state. Colors the state doesn't record go back to the terminal's defaults.
+func restoreProfile(state *AppliedState) *Profile {
+	profile := resetProfile
+	if state == nil {
+		return &profile
+	}
+	for _, color := range []struct {
+		value string
+		dest  *string
+	}{
+		{state.Tab, &profile.Tab},
+		{state.Foreground, &profile.Foreground},
+		{state.Background, &profile.Background},
+	} {
+		if color.value != "" {
+			*color.dest = color.value
+		}
+	}
+	profile.Preset = state.Preset
+	return &profile
+}
+
+// recordAppliedState saves state after a successful apply, warning instead of failing,
+// and announces a newly applied profile that asks for a notification
+func recordAppliedState(profileName string, profile *Profile) {
+	defer timePhase("state")()
+	previous, _ := loadSessionState()
+	notifyProfileApplied(profileName, previous)
+	if dryRunMode {
+		return
+	}
+	recordJournal(profileName, profile)
+
+	state := newAppliedState(profileName, profile)
+	state.Locked = isStickyProfile(profileName)
+	if err := saveAppliedState(state); err != nil {
+		debugf("Could not record applied state: %v\n", err)
+	}
+
+	// Also register the session so other sessions can see which colors are in use
+	sessionPath, err := getSessionStatePath()
+	if err == nil {
+		err = writeStateFile(sessionPath, state)
+	}
+	if err != nil {
+		debugf("Could not record session state: %v\n", err)
+	}
+}
+
+// loadOtherSessions returns the state of every other live session in the registry.
+// Entries whose shell has exited are removed.
+func loadOtherSessions() ([]AppliedState, error) {
+	sessionsDir, err := getSessionsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(sessionsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ownPath, err := getSessionStatePath()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []AppliedState
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(sessionsDir, entry.Name())
+		if path == ownPath {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var state AppliedState
+		if err := json.Unmarshal(data, &state); err != nil {
+			continue
+		}
+
+		if state.ShellPID == 0 {
+			continue
+		}
+		if alive, err := process.PidExists(int32(state.ShellPID)); err == nil && !alive {
+			os.Remove(path)
+			continue
+		}
+		sessions = append(sessions, state)
+	}
+	return sessions, nil
+}
+
+// clearAppliedState removes the state file after colors are reset to defaults
+func clearAppliedState() error {
+	if dryRunMode {
+		return nil
+	}
+	statePath, err := getStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	sessionPath, err := getSessionStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(sessionPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// statusFormats lists the supported status output formats
+var statusFormats = []string{"text", "sketchybar", "polybar", "waybar"}
+
+// statusColor picks the color that best represents a state: tab, then bg, then fg
+func statusColor(state *AppliedState) string {
+	for _, color := range []string{state.Tab, state.Background, state.Foreground} {
+		if color != "" && color != "default" {
+			return color
+		}
+	}
+	return ""
+}
+
+// statusLabel returns the text to show for a state
+func statusLabel(state *AppliedState) string {
+	if state.Profile != "" {
+		return state.Profile
+	}
+	if state.Preset != "" {
+		return state.Preset
+	}
+	return "custom"
+}
+
+// formatStatus renders the applied state in the given bar format. A nil state
+// renders as empty output so bars can hide the item.
+func formatStatus(state *AppliedState, format string) (string, error) {
+	if state == nil {
+		switch format {
+		case "waybar":
+			return `{"text":""}`, nil
+		case "text", "sketchybar", "polybar":
+			return "", nil
+		}
+		return "", fmt.Errorf("unknown status format %q (expected one of: %s)", format, strings.Join(statusFormats, ", "))
+	}
+
+	label := statusLabel(state)
+	color := statusColor(state)
+
+	switch format {
+	case "text":
+		if color == "" {
+			return label, nil
+		}
+		return fmt.Sprintf("%s #%s", label, color), nil
+	case "sketchybar":
+		// Arguments for: sketchybar --set <item> <output>
+		out := fmt.Sprintf("label=%q", label)
+		if color != "" {
+			out += " label.color=0xff" + color
+		}
+		return out, nil
+	case "polybar":
+		// Polybar formatting tags; '%' is escaped so labels can't inject tags
+		escaped := strings.ReplaceAll(label, "%", "%%")
+		if color == "" {
+			return escaped, nil
+		}
+		return fmt.Sprintf("%%{F#%s}%s%%{F-}", color, escaped), nil
+	case "waybar":
+		// JSON for a custom module with "return-type": "json"
+		out := map[string]string{"text": label, "class": label, "tooltip": label}
+		if color != "" {
+			out["tooltip"] = fmt.Sprintf("%s (#%s)", label, color)
+		}
+		data, err := json.Marshal(out)
+		return string(data), err
+	default:
+		return "", fmt.Errorf("unknown status format %q (expected one of: %s)", format, strings.Join(statusFormats, ", "))
+	}
+}
+
+// runStatus prints the most recently applied profile for status bars
+func runStatus(args []string) error {
+	format := "text"
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--format" || args[i] == "-format":
+			if i+1 >= len(args) {
+				return fmt.Errorf("usage: status [--format %s]", strings.Join(statusFormats, "|"))
+			}
+			format = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--format="):
+			format = strings.TrimPrefix(args[i], "--format=")
+		default:
+			return fmt.Errorf("unexpected argument %q", args[i])
+		}
+	}
+
+	state, err := loadAppliedState()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		if state == nil {
+			return printJSON(struct{}{})
+		}
+		return printJSON(state)
+	}
+
+	out, err := formatStatus(state, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(out)
+	return nil
+}
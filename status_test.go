@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestFormatStatus tests rendering the applied state for each status bar
+func TestFormatStatus(t *testing.T) {
+	state := &AppliedState{Profile: "prod", Tab: "ff0000", Background: "000000"}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"text", "prod #ff0000"},
+		{"sketchybar", `label="prod" label.color=0xffff0000`},
+		{"polybar", "%{F#ff0000}prod%{F-}"},
+		{"waybar", `{"class":"prod","text":"prod","tooltip":"prod (#ff0000)"}`},
+	}
+
+	for _, test := range tests {
+		result, err := formatStatus(state, test.format)
+		if err != nil {
+			t.Errorf("formatStatus(%q) failed: %v", test.format, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("formatStatus(%q) = %q, expected %q", test.format, result, test.expected)
+		}
+	}
+
+	// Falls back to the background color and a generic label
+	result, err := formatStatus(&AppliedState{Tab: "default", Background: "00ff00"}, "text")
+	if err != nil || result != "custom #00ff00" {
+		t.Errorf("Expected fallback status %q, got %q (err %v)", "custom #00ff00", result, err)
+	}
+
+	// Nothing applied yet renders empty output
+	if result, err := formatStatus(nil, "polybar"); err != nil || result != "" {
+		t.Errorf("Expected empty status for nil state, got %q (err %v)", result, err)
+	}
+
+	if _, err := formatStatus(state, "tmux"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+// TestAppliedStateRoundTrip tests saving and loading the state file
+func TestAppliedStateRoundTrip(t *testing.T) {
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", t.TempDir())
+	defer os.Setenv("XDG_STATE_HOME", originalState)
+
+	state, err := loadAppliedState()
+	if err != nil || state != nil {
+		t.Fatalf("Expected no state before anything is applied, got %+v (err %v)", state, err)
+	}
+
+	recordAppliedState("prod", &Profile{Tab: "red", Foreground: "#fff", Preset: "Ocean"})
+
+	state, err = loadAppliedState()
+	if err != nil {
+		t.Fatalf("loadAppliedState() failed: %v", err)
+	}
+	if state == nil || state.Profile != "prod" || state.Tab != "ff0000" || state.Foreground != "ffffff" || state.Preset != "Ocean" {
+		t.Errorf("Unexpected state after round trip: %+v", state)
+	}
+
+	// Dry runs don't change the recorded state
+	dryRunMode = true
+	recordAppliedState("dev", &Profile{Tab: "blue"})
+	dryRunMode = false
+
+	state, err = loadAppliedState()
+	if err != nil || state.Profile != "prod" {
+		t.Errorf("Expected dry run to leave state untouched, got %+v (err %v)", state, err)
+	}
+}
+
+// TestRestoreProfile tests restoring this session's recorded colors
+func TestRestoreProfile(t *testing.T) {
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", t.TempDir())
+	defer os.Setenv("XDG_STATE_HOME", originalState)
+
+	state, err := loadSessionState()
+	if err != nil || state != nil {
+		t.Fatalf("Expected no session state before anything is applied, got %+v (err %v)", state, err)
+	}
+	if profile := restoreProfile(state); !reflect.DeepEqual(*profile, resetProfile) {
+		t.Errorf("Expected defaults without state, got %+v", profile)
+	}
+
+	recordAppliedState("prod", &Profile{Tab: "red", Preset: "Ocean"})
+	state, err = loadSessionState()
+	if err != nil {
+		t.Fatalf("loadSessionState() failed: %v", err)
+	}
+	expected := Profile{Tab: "ff0000", Foreground: "default", Background: "default", Preset: "Ocean"}
+	if profile := restoreProfile(state); !reflect.DeepEqual(*profile, expected) {
+		t.Errorf("restoreProfile() = %+v, expected %+v", profile, expected)
+	}
+}
+
+// TestRunReset tests that reset restores defaults and clears the recorded state
+func TestRunReset(t *testing.T) {
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", t.TempDir())
+	defer os.Setenv("XDG_STATE_HOME", originalState)
+
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+
+	setFakeEnvironment(&FakeEnvironment{Env: map[string]string{"TERM_PROGRAM": "iTerm.app"}, ProcessChain: []string{"set-tab-color", "zsh"}})
+	defer setFakeEnvironment(nil)
+	t.Setenv("ITERM_PROFILE", "Work")
+
+	recordAppliedState("prod", &Profile{Tab: "red", ITermProfile: "Prod", Theme: "dracula", Badge: "PROD"})
+
+	if err := runReset(""); err != nil {
+		t.Fatalf("runReset() failed: %v", err)
+	}
+
+	// The iTerm2 profile goes back to the session's own before anything else
+	expected := "\033]1337;SetProfile=Work\a\033]6;1;bg;*;default\a\033]110\a\033]111\a" +
+		"\033]104\a\033]112\a\033]1337;SetBadgeFormat=\a\033]0;\a"
+	if buf.String() != expected {
+		t.Errorf("Expected reset sequences %q, got %q", expected, buf.String())
+	}
+
+	// Nothing more was switched since, so a second reset leaves the iTerm2 profile alone
+	buf.Reset()
+	if err := runReset(""); err != nil {
+		t.Fatalf("runReset() failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "SetProfile") {
+		t.Errorf("Expected no iTerm2 profile switch after a reset, got %q", buf.String())
+	}
+
+	if state, err := loadAppliedState(); err != nil || state != nil {
+		t.Errorf("Expected state to be cleared after reset, got %+v (err %v)", state, err)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// commandOptions carries global flags that subcommands may use
+type commandOptions struct {
+	ProfileName  string
+	TerminalType string
+}
+
+// runSubcommand dispatches positional arguments such as "config validate"
+func runSubcommand(args []string, opts commandOptions) error {
+	switch args[0] {
+	case "detect":
+		return runDetect(opts.TerminalType, opts.ProfileName)
+	case "show-profile":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: show-profile <name>")
+		}
+		return runShowProfile(args[1], opts.TerminalType)
+	case "caps":
+		return runCaps(opts.TerminalType)
+	case "preview":
+		return runPreview(args[1:], opts)
+	case "reset":
+		return runReset(opts.TerminalType)
+	case "session-info":
+		return runSessionInfo()
+	case "status":
+		return runStatus(args[1:])
+	case "history":
+		return runHistory(args[1:])
+	case "reapply":
+		return runReapply(args[1:])
+	case "config":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: config validate|audit|export|import")
+		}
+		switch args[1] {
+		case "validate":
+			return runConfigValidate()
+		case "audit":
+			return runConfigAudit(args[2:])
+		case "export":
+			return runConfigExport(args[2:])
+		case "import":
+			return runConfigImport(args[2:])
+		default:
+			return fmt.Errorf("unknown config command %q", args[1])
+		}
+	case "profile":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: profile add|set|remove <name> [key=value...]")
+		}
+		return runProfileEdit(args[1], args[2:])
+	case "theme":
+		if len(args) < 2 || args[1] != "import" {
+			return fmt.Errorf("usage: theme import <file.itermcolors|scheme.yaml> [name]")
+		}
+		return runThemeImport(args[2:])
+	case "workspace":
+		if len(args) != 3 || args[1] != "apply" {
+			return fmt.Errorf("usage: workspace apply <name>")
+		}
+		return runWorkspaceApply(args[2])
+	case "watch":
+		switch {
+		case len(args) == 1:
+			return runWatch(opts.TerminalType)
+		case len(args) == 3 && args[1] == "cwd":
+			return runWatchCwd(args[2])
+		default:
+			return fmt.Errorf("usage: watch [cwd <dir>]")
+		}
+	case "rules":
+		if len(args) < 2 || args[1] != "test" {
+			return fmt.Errorf("usage: rules test [--dir DIR] [--kube-context CONTEXT]")
+		}
+		return runRulesTest(args[2:])
+	case "client":
+		return runClient(args[1:])
+	case "events":
+		return runEvents(args[1:])
+	case "ssh":
+		return runSSH(args[1:], opts)
+	case "remote":
+		return runRemote(args[1:])
+	case "help":
+		if len(args) < 2 {
+			flag.Usage()
+			return nil
+		}
+		return runTerminalHelp(args[1])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runTerminalHelp prints terminal-specific setup steps and limitations
+func runTerminalHelp(name string) error {
+	caps, ok := getTerminalCapabilities(name)
+	if !ok {
+		return fmt.Errorf("unknown terminal %q (known terminals: %s)", name, strings.Join(knownTerminalNames(), ", "))
+	}
+
+	printTerminalHelp(os.Stdout, TerminalType(strings.ToLower(name)), caps)
+	return nil
+}
+
+// resetProfile restores the tab, foreground, and background to the terminal's defaults
+var resetProfile = Profile{
+	Tab:        "default",
+	Foreground: "default",
+	Background: "default",
+}
+
+// runReset loads the config and detects the terminal, then resets it
+func runReset(terminalType string) error {
+	// The [tmux] table decides whether the tmux chrome is reset too
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	useEscapeBackendFor(detectTerminals(terminalType))
+	return resetTerminal()
+}
+
+// resetTerminal restores everything set-tab-color may have changed in one step: the
+// colors, the palette and cursor, the badge and title, and the iTerm2 profile and
+// window effects when this session's journal shows they were changed
+func resetTerminal() error {
+	profile := resetProfile
+	current := !usesSessionTargeting() && !usesTmuxTargeting()
+	if current {
+		profile.ITermProfile, profile.Transparency, profile.Blur = journaledSessionResets()
+	}
+
+	batch, outermost := startBatch()
+	err := applyProfile(&profile)
+	if err == nil && current {
+		err = resetTerminalExtras(true, true, true)
+	}
+	if outermost {
+		if flushErr := batch.flush(); err == nil {
+			err = flushErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if usesTmuxTargeting() {
+		return nil
+	}
+	recordJournalReset()
+	return clearAppliedState()
+}
+
+// resetTerminalExtras restores the current session's settings that have no recorded
+// value to return to: the 16-color palette and cursor (OSC 104 and 112), and the
+// badge and title, which are cleared
+func resetTerminalExtras(palette, badge, title bool) error {
+	if palette {
+		if err := writeSequence("palette and cursor reset", wrapOSC("104")+wrapOSC("112")); err != nil {
+			return err
+		}
+	}
+	if badge && backendSupports(FeatureBadge) {
+		if err := runSetBadge(""); err != nil {
+			return err
+		}
+	}
+	if title {
+		return runSetTitle("")
+	}
+	return nil
+}
+
+// journaledSessionResets returns the iTerm2 profile and window effects a reset
+// restores. They are only restored when this session's journal shows set-tab-color
+// changed them: the iTerm2 profile goes back to the one the session started with
+// ($ITERM_PROFILE), and the effects take a Python API call.
+func journaledSessionResets() (itermProfile, transparency, blur string) {
+	entries, err := loadJournal()
+	if err != nil {
+		debugf("Could not read the journal: %v\n", err)
+		return "", "", ""
+	}
+	applied, _, ok := journalProfile(filterJournalSession(entries, journalSessionKey()))
+	if !ok {
+		return "", "", ""
+	}
+	if applied.ITermProfile != "" {
+		itermProfile = os.Getenv("ITERM_PROFILE")
+	}
+	if applied.Transparency != "" && applied.Transparency != "default" {
+		transparency = "default"
+	}
+	if applied.Blur != "" && applied.Blur != "default" {
+		blur = "default"
+	}
+	return itermProfile, transparency, blur
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// swatchWidth is the width of the color block in front of each previewed color
+const swatchWidth = 6
+
+// previewSample is the text shown in a profile's foreground on its background
+const previewSample = " user@host:~$ ls -la "
+
+// SwatchEntry is one previewed color: a setting of a profile, or a color given
+// on the command line
+type SwatchEntry struct {
+	Setting string `json:"setting,omitempty"`
+	Value   string `json:"value"`
+	Hex     string `json:"hex,omitempty"` // Empty for "default" and settings that aren't colors
+}
+
+// runPreview renders color swatches: of each color expression given, or of every
+// color the -profile would set here, so colors can be checked before applying them
+func runPreview(args []string, opts commandOptions) error {
+	var entries []SwatchEntry
+	var profile *Profile
+	switch {
+	case len(args) > 0 && opts.ProfileName != "":
+		return fmt.Errorf("preview takes colors or -profile, not both")
+	case len(args) > 0:
+		var err error
+		if entries, err = colorSwatchEntries(args); err != nil {
+			return err
+		}
+	case opts.ProfileName != "":
+		var err error
+		if profile, err = previewProfile(opts.ProfileName, opts.TerminalType); err != nil {
+			return err
+		}
+		entries = profileSwatchEntries(profile)
+	default:
+		return fmt.Errorf("usage: preview <color>... or -profile NAME preview")
+	}
+
+	if jsonOutput {
+		return printJSON(entries)
+	}
+	if profile != nil {
+		fmt.Printf("Profile %s\n", redact(opts.ProfileName))
+	}
+	writeSwatches(os.Stdout, entries, profile)
+	return nil
+}
+
+// colorSwatchEntries normalizes color expressions such as "orange", "#f80", or
+// "navy lighten 20%". An unknown color fails the whole preview.
+func colorSwatchEntries(colors []string) ([]SwatchEntry, error) {
+	// Custom colors are optional here, so a broken config doesn't stop a preview of CSS names
+	if _, err := loadConfig(); err != nil {
+		warnf("could not load custom colors: %v", err)
+	}
+	entries := make([]SwatchEntry, 0, len(colors))
+	for _, color := range colors {
+		hex := normalizeColor(color)
+		if hex == "" {
+			return nil, withExitCode(exitUnknownColor, fmt.Errorf("unknown color: %s", color))
+		}
+		entries = append(entries, newSwatchEntry("", color, hex))
+	}
+	return entries, nil
+}
+
+// previewProfile resolves a profile the way applying it here would, including the
+// colors picked at apply time and the ones its theme fills in
+func previewProfile(profileName, terminalOverride string) (*Profile, error) {
+	info := detectTerminalAndShell(terminalOverride)
+	profile, err := getProfileWithTerminalInfo(profileName, &info)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveDistinctTab(profile); err != nil {
+		return nil, err
+	}
+	if err := resolveAutoForeground(profile); err != nil {
+		return nil, err
+	}
+	profile.Tab = colorblindSafeTab(profile.Tab)
+
+	expanded, err := expandTheme(profile)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the theme's name for the listing; its colors are now filled in
+	result := *expanded
+	result.Theme = profile.Theme
+	return &result, nil
+}
+
+// profileSwatchEntries lists a resolved profile's settings in the order they apply
+func profileSwatchEntries(profile *Profile) []SwatchEntry {
+	var entries []SwatchEntry
+	for _, setting := range []struct{ name, value string }{
+		{"iterm", profile.ITermProfile},
+		{"preset", profile.Preset},
+		{"theme", profile.Theme},
+	} {
+		if setting.value != "" {
+			entries = append(entries, SwatchEntry{Setting: setting.name, Value: setting.value})
+		}
+	}
+	for _, key := range orderedPaletteKeys(profile.Palette) {
+		value := profile.Palette[key]
+		entries = append(entries, newSwatchEntry("palette."+key, value, normalizeColor(value)))
+	}
+	for _, setting := range []struct{ name, value string }{
+		{"tab", profile.Tab},
+		{"fg", profile.Foreground},
+		{"bg", profile.Background},
+	} {
+		if setting.value != "" {
+			entries = append(entries, newSwatchEntry(setting.name, setting.value, normalizeColor(setting.value)))
+		}
+	}
+	for _, setting := range []struct{ name, value string }{
+		{"badge", profile.Badge},
+		{"title", profile.Title},
+	} {
+		if setting.value != "" {
+			entries = append(entries, SwatchEntry{Setting: setting.name, Value: setting.value})
+		}
+	}
+	return entries
+}
+
+// newSwatchEntry records a color with its hex, leaving the hex out for "default"
+func newSwatchEntry(setting, value, hex string) SwatchEntry {
+	entry := SwatchEntry{Setting: setting, Value: value}
+	if hex != "" && hex != "default" {
+		entry.Hex = "#" + hex
+	}
+	return entry
+}
+
+// writeSwatches prints a block of each color with its hex and the expression it came
+// from. For a profile, a sample line in its foreground on its background follows.
+func writeSwatches(w io.Writer, entries []SwatchEntry, profile *Profile) {
+	labelWidth := 0
+	for _, entry := range entries {
+		labelWidth = max(labelWidth, len(entry.Setting))
+	}
+
+	for _, entry := range entries {
+		var line strings.Builder
+		if labelWidth > 0 {
+			fmt.Fprintf(&line, "  %-*s  ", labelWidth, entry.Setting)
+		}
+		switch {
+		case entry.Hex != "":
+			fmt.Fprintf(&line, "%s  %s", colorSwatch(strings.Repeat(" ", swatchWidth), entry.Hex), entry.Hex)
+			// A color given as hex isn't repeated
+			if strings.TrimPrefix(strings.ToLower(entry.Value), "#") != entry.Hex[1:] {
+				fmt.Fprintf(&line, "  %s", entry.Value)
+			}
+		case normalizeColor(entry.Value) == "default":
+			fmt.Fprintf(&line, "%s  %-7s  the terminal's own color", strings.Repeat(" ", swatchWidth), "default")
+		default:
+			line.WriteString(entry.Value)
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+
+	if profile != nil && (profile.Foreground != "" || profile.Background != "") {
+		fmt.Fprintf(w, "  %-*s  %s\n", labelWidth, "sample", colorPair(previewSample, normalizeColor(profile.Foreground), normalizeColor(profile.Background)))
+	}
+}
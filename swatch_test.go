@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestColorSwatchEntries tests normalizing the colors given to preview
+func TestColorSwatchEntries(t *testing.T) {
+	t.Setenv("SET_TAB_COLOR_CONFIG", filepath.Join(t.TempDir(), "missing.toml"))
+
+	entries, err := colorSwatchEntries([]string{"orange", "#F80", "navy lighten 20%", "default"})
+	if err != nil {
+		t.Fatalf("colorSwatchEntries() failed: %v", err)
+	}
+	expected := []SwatchEntry{
+		{Value: "orange", Hex: "#ffa500"},
+		{Value: "#F80", Hex: "#ff8800"},
+		{Value: "navy lighten 20%", Hex: "#0000e6"},
+		{Value: "default"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("colorSwatchEntries() = %+v, expected %+v", entries, expected)
+	}
+
+	if _, err := colorSwatchEntries([]string{"red", "nope"}); err == nil || exitCode(err) != exitUnknownColor {
+		t.Errorf("Expected an unknown color error, got %v", err)
+	}
+}
+
+// TestWriteSwatches tests the swatch lines of colors and of a profile
+func TestWriteSwatches(t *testing.T) {
+	var buf bytes.Buffer
+	writeSwatches(&buf, []SwatchEntry{{Value: "red", Hex: "#ff0000"}, {Value: "#00ff00", Hex: "#00ff00"}, {Value: "default"}}, nil)
+	expected := "\033[48;2;255;0;0m\033[38;2;0;0;0m      \033[0m  #ff0000  red\n" +
+		"\033[48;2;0;255;0m\033[38;2;0;0;0m      \033[0m  #00ff00\n" +
+		"        default  the terminal's own color\n"
+	if buf.String() != expected {
+		t.Errorf("writeSwatches() = %q, expected %q", buf.String(), expected)
+	}
+
+	profile := &Profile{Tab: "red", Foreground: "white", Background: "navy", Preset: "Solarized Dark",
+		Palette: map[string]string{"br_red": "#ff5555"}, Badge: "PROD"}
+	buf.Reset()
+	writeSwatches(&buf, profileSwatchEntries(profile), profile)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	var labels []string
+	for _, line := range lines {
+		labels = append(labels, strings.Fields(line)[0])
+	}
+	if strings.Join(labels, " ") != "preset palette.br_red tab fg bg badge sample" {
+		t.Errorf("Unexpected settings in order: %v", labels)
+	}
+	if sample := lines[len(lines)-1]; !strings.Contains(sample, "\033[38;2;255;255;255;48;2;0;0;128m"+previewSample) {
+		t.Errorf("Expected the sample in white on navy, got %q", sample)
+	}
+}
+
+// TestColorPair tests rendering text in a foreground on a background
+func TestColorPair(t *testing.T) {
+	tests := []struct {
+		fg, bg   string
+		expected string
+	}{
+		{"ffffff", "000080", "\033[38;2;255;255;255;48;2;0;0;128mhi\033[0m"},
+		{"ffffff", "", "\033[38;2;255;255;255mhi\033[0m"},
+		{"default", "000080", "\033[48;2;0;0;128mhi\033[0m"},
+		{"", "", "hi"},
+	}
+	for _, tt := range tests {
+		if got := colorPair("hi", tt.fg, tt.bg); got != tt.expected {
+			t.Errorf("colorPair(%q, %q) = %q, expected %q", tt.fg, tt.bg, got, tt.expected)
+		}
+	}
+}
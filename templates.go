@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// templatePattern matches a reference such as {{ colors.corp_blue }} in a config value
+var templatePattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// templateExpander resolves references to [colors], [vars], and profile values,
+// expanding the templates in referenced values as well
+type templateExpander struct {
+	root      map[string]interface{}
+	resolved  map[string]string
+	resolving map[string]bool
+}
+
+// expandConfigTemplates replaces the {{ ... }} references in [colors], [vars], and
+// profile values with the values they name, so a hex code can be written once.
+// A reference is a dotted path from the top of the config, such as vars.brand,
+// colors.corp_blue, or profiles.prod.tab. Every value that can't be expanded is
+// reported and left as written.
+func expandConfigTemplates(config *Config) []configError {
+	e := &templateExpander{
+		root: map[string]interface{}{
+			"colors":   stringMap(config.Colors),
+			"vars":     stringMap(config.Vars),
+			"profiles": config.RawProfiles,
+		},
+		resolved:  map[string]string{},
+		resolving: map[string]bool{},
+	}
+
+	var errs []configError
+	for _, table := range []struct {
+		name   string
+		values map[string]string
+	}{
+		{"colors", config.Colors},
+		{"vars", config.Vars},
+	} {
+		for _, key := range sortedMapKeys(table.values) {
+			expanded, err := e.expand(table.values[key])
+			if err != nil {
+				errs = append(errs, templateFailure([]string{table.name, key}, err))
+				continue
+			}
+			table.values[key] = expanded
+		}
+	}
+	errs = append(errs, e.expandMap([]string{"profiles"}, config.RawProfiles)...)
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
+}
+
+// expandMap expands the templates in every string of a profile table and its sub-tables
+func (e *templateExpander) expandMap(path []string, m map[string]interface{}) []configError {
+	var errs []configError
+	for _, key := range sortedMapKeys(m) {
+		value := m[key]
+		keyPath := append(append([]string{}, path...), key)
+		switch value := value.(type) {
+		case string:
+			expanded, err := e.expand(value)
+			if err != nil {
+				errs = append(errs, templateFailure(keyPath, err))
+				continue
+			}
+			m[key] = expanded
+		case map[string]interface{}:
+			errs = append(errs, e.expandMap(keyPath, value)...)
+		}
+	}
+	return errs
+}
+
+// expand replaces every reference in a value
+func (e *templateExpander) expand(value string) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+	var firstErr error
+	expanded := templatePattern.ReplaceAllStringFunc(value, func(match string) string {
+		resolved, err := e.resolve(templatePattern.FindStringSubmatch(match)[1])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return match
+		}
+		return resolved
+	})
+	return expanded, firstErr
+}
+
+// resolve returns the expanded value a reference names
+func (e *templateExpander) resolve(reference string) (string, error) {
+	if value, ok := e.resolved[reference]; ok {
+		return value, nil
+	}
+	if e.resolving[reference] {
+		return "", fmt.Errorf("template variable %q is part of a cycle", reference)
+	}
+
+	var node interface{} = e.root
+	for _, part := range strings.Split(reference, ".") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			node = nil
+			break
+		}
+		node = m[part]
+	}
+	if node == nil {
+		return "", fmt.Errorf("unknown template variable %q", reference)
+	}
+	value, ok := node.(string)
+	if !ok {
+		return "", fmt.Errorf("template variable %q is a %T, not a string", reference, node)
+	}
+
+	e.resolving[reference] = true
+	expanded, err := e.expand(value)
+	delete(e.resolving, reference)
+	if err != nil {
+		return "", err
+	}
+	e.resolved[reference] = expanded
+	return expanded, nil
+}
+
+// templateFailure reports a value whose templates couldn't be expanded
+func templateFailure(path []string, err error) configError {
+	return configError{Path: path, Message: fmt.Sprintf("%s: %v", strings.Join(path, "."), err)}
+}
+
+// stringMap copies a string table so it can be walked like the profile tables
+func stringMap(values map[string]string) map[string]interface{} {
+	m := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		m[key] = value
+	}
+	return m
+}
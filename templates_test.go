@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExpandConfigTemplates tests references to colors, vars, and other profile values
+func TestExpandConfigTemplates(t *testing.T) {
+	config := &Config{
+		Colors: map[string]string{"corp_blue": "#0055aa", "brand": "{{ vars.brand }}"},
+		Vars:   map[string]string{"brand": "#f26522", "env": "prod"},
+		RawProfiles: map[string]interface{}{
+			"prod": map[string]interface{}{
+				"tab":   "{{ colors.brand }}",
+				"bg":    "{{colors.corp_blue}} darken 10%",
+				"title": "{{ vars.env }} db",
+				"ssh": map[string]interface{}{
+					"badge": "{{ profiles.prod.title }}",
+				},
+			},
+			"staging": map[string]interface{}{
+				"tab": "{{ profiles.prod.bg }}",
+			},
+		},
+	}
+
+	if errs := expandConfigTemplates(config); len(errs) > 0 {
+		t.Fatalf("expandConfigTemplates() failed: %v", errs)
+	}
+
+	prod := config.RawProfiles["prod"].(map[string]interface{})
+	for key, expected := range map[string]string{"tab": "#f26522", "bg": "#0055aa darken 10%", "title": "prod db"} {
+		if prod[key] != expected {
+			t.Errorf("prod.%s = %q, expected %q", key, prod[key], expected)
+		}
+	}
+	if badge := prod["ssh"].(map[string]interface{})["badge"]; badge != "prod db" {
+		t.Errorf("prod.ssh.badge = %q, expected %q", badge, "prod db")
+	}
+	if tab := config.RawProfiles["staging"].(map[string]interface{})["tab"]; tab != "#0055aa darken 10%" {
+		t.Errorf("staging.tab = %q", tab)
+	}
+	if config.Colors["brand"] != "#f26522" {
+		t.Errorf("colors.brand = %q, expected #f26522", config.Colors["brand"])
+	}
+}
+
+// TestExpandConfigTemplatesErrors tests unknown references, cycles, and non-string values
+func TestExpandConfigTemplatesErrors(t *testing.T) {
+	config := &Config{
+		Vars: map[string]string{"a": "{{ vars.b }}", "b": "{{ vars.a }}"},
+		RawProfiles: map[string]interface{}{
+			"dev": map[string]interface{}{
+				"tab":   "{{ colors.missing }}",
+				"bg":    "{{ profiles.dev.ssh }}",
+				"title": "plain",
+				"ssh":   map[string]interface{}{"tab": "red"},
+			},
+		},
+	}
+
+	errs := expandConfigTemplates(config)
+	expected := []string{
+		`profiles.dev.bg: template variable "profiles.dev.ssh" is a map[string]interface {}, not a string`,
+		`profiles.dev.tab: unknown template variable "colors.missing"`,
+		`vars.a: template variable "vars.b" is part of a cycle`,
+		`vars.b: template variable "vars.a" is part of a cycle`,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(errs), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("Error %d = %q, expected %q", i, err.Error(), expected[i])
+		}
+	}
+	if tab := config.RawProfiles["dev"].(map[string]interface{})["tab"]; tab != "{{ colors.missing }}" {
+		t.Errorf("Expected a failed template to be left as written, got %q", tab)
+	}
+}
+
+// TestLoadConfigTemplates tests that loaded profiles use the expanded values
+func TestLoadConfigTemplates(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	content := "[vars]\nalert = \"crimson\"\n\n[profiles.prod]\ntab = \"{{ vars.alert }}\"\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+
+	profile, err := getProfileWithTerminalInfo("prod", &TerminalShellInfo{})
+	if err != nil {
+		t.Fatalf("getProfileWithTerminalInfo() failed: %v", err)
+	}
+	if profile.Tab != "crimson" {
+		t.Errorf("Expected tab crimson, got %q", profile.Tab)
+	}
+}
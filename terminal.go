@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"os"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/shirou/gopsutil/v3/process"
 )
@@ -11,14 +15,83 @@ import (
 type TerminalType string
 
 const (
-	TerminalTypeUnknown    TerminalType = "unknown"
-	TerminalTypeITerm2     TerminalType = "iterm2"
-	TerminalTypeETTerminal TerminalType = "etterminal"
-	TerminalTypeSSH        TerminalType = "ssh"
-	TerminalTypeTmux       TerminalType = "tmux"
-	TerminalTypeVSCode     TerminalType = "vscode"
+	TerminalTypeUnknown         TerminalType = "unknown"
+	TerminalTypeITerm2          TerminalType = "iterm2"
+	TerminalTypeETTerminal      TerminalType = "etterminal"
+	TerminalTypeMosh            TerminalType = "mosh"
+	TerminalTypeSSH             TerminalType = "ssh"
+	TerminalTypeTmux            TerminalType = "tmux"
+	TerminalTypeVSCode          TerminalType = "vscode"
+	TerminalTypeJetBrains       TerminalType = "jetbrains"
+	TerminalTypeGhostty         TerminalType = "ghostty"
+	TerminalTypeWarp            TerminalType = "warp"
+	TerminalTypeHyper           TerminalType = "hyper"
+	TerminalTypeRio             TerminalType = "rio"
+	TerminalTypeTabby           TerminalType = "tabby"
+	TerminalTypeKitty           TerminalType = "kitty"
+	TerminalTypeWezTerm         TerminalType = "wezterm"
+	TerminalTypeGnome           TerminalType = "gnome-terminal"
+	TerminalTypeKonsole         TerminalType = "konsole"
+	TerminalTypeXterm           TerminalType = "xterm"
+	TerminalTypeWSL             TerminalType = "wsl"
+	TerminalTypeWindowsTerminal TerminalType = "windows-terminal"
+	TerminalTypeConEmu          TerminalType = "conemu"
+	TerminalTypeContainer       TerminalType = "container"
 )
 
+// knownTerminalTypes lists the terminal types usable as sub-profile keys
+var knownTerminalTypes = []TerminalType{
+	TerminalTypeITerm2,
+	TerminalTypeETTerminal,
+	TerminalTypeMosh,
+	TerminalTypeSSH,
+	TerminalTypeTmux,
+	TerminalTypeVSCode,
+	TerminalTypeJetBrains,
+	TerminalTypeGhostty,
+	TerminalTypeWarp,
+	TerminalTypeHyper,
+	TerminalTypeRio,
+	TerminalTypeTabby,
+	TerminalTypeKitty,
+	TerminalTypeWezTerm,
+	TerminalTypeGnome,
+	TerminalTypeKonsole,
+	TerminalTypeXterm,
+	TerminalTypeWSL,
+	TerminalTypeWindowsTerminal,
+	TerminalTypeConEmu,
+	TerminalTypeContainer,
+}
+
+// emulatorProcessNames maps terminal emulator process names to their terminal type.
+// Names are matched case-insensitively since they differ between macOS and Linux.
+var emulatorProcessNames = []struct {
+	name     string
+	terminal TerminalType
+}{
+	{"ghostty", TerminalTypeGhostty},
+	{"Warp", TerminalTypeWarp},
+	{"warp-terminal", TerminalTypeWarp},
+	{"Hyper", TerminalTypeHyper},
+	{"rio", TerminalTypeRio},
+	{"Tabby", TerminalTypeTabby},
+	{"kitty", TerminalTypeKitty},
+	{"wezterm-gui", TerminalTypeWezTerm},
+	{"gnome-terminal-server", TerminalTypeGnome},
+	{"gnome-terminal-", TerminalTypeGnome}, // Truncated to the 15 characters of /proc/<pid>/comm
+	{"konsole", TerminalTypeKonsole},
+	{"xterm", TerminalTypeXterm},
+	{"code", TerminalTypeVSCode}, // VS Code on Linux
+}
+
+// jetbrainsProcessNames lists the JetBrains IDE launchers whose built-in terminal
+// starts the shell
+var jetbrainsProcessNames = []string{
+	"idea", "goland", "pycharm", "webstorm", "phpstorm", "clion", "rider", "rubymine",
+	"datagrip", "dataspell", "rustrover", "studio",
+}
+
 // ShellType represents different shell types
 type ShellType string
 
@@ -33,112 +106,208 @@ const (
 	ShellTypeSh      ShellType = "sh"
 )
 
+// knownShellTypes lists the shell types usable as sub-profile keys
+var knownShellTypes = []ShellType{
+	ShellTypeBash,
+	ShellTypeZsh,
+	ShellTypeFish,
+	ShellTypeTcsh,
+	ShellTypeCsh,
+	ShellTypeKsh,
+	ShellTypeSh,
+}
+
+// Global -shell flag value; empty uses the [detect] shell or the detected shell
+var shellOverride string
+
 // TerminalShellInfo contains both terminal and shell detection results
 type TerminalShellInfo struct {
 	Terminals []TerminalType // All terminals found in process chain, in order
 	Shell     ShellType
 	Valid     bool // true if shell comes before terminal in the process chain
+	// Light or dark mode selecting the "dark"/"light" sub-profile
+	Appearance Appearance
+	// Running as root or through sudo, selecting the "root" sub-profile
+	Root bool
+}
+
+// parseTerminalType converts a sub-profile key such as "iterm2" to a TerminalType,
+// including terminals added in the [detect] table
+func parseTerminalType(name string) (TerminalType, bool) {
+	for _, terminal := range allTerminalTypes() {
+		if string(terminal) == name {
+			return terminal, true
+		}
+	}
+	return TerminalTypeUnknown, false
+}
+
+// parseShellType converts a sub-profile key such as "zsh" to a ShellType, including
+// shells added in the [detect] table
+func parseShellType(name string) (ShellType, bool) {
+	for _, shell := range allShellTypes() {
+		if string(shell) == name {
+			return shell, true
+		}
+	}
+	return ShellTypeUnknown, false
+}
+
+// matchShell returns the shell type a process name corresponds to, if any
+func matchShell(name string) ShellType {
+	if key, ok := matchCustomRule(customShellRules, name); ok {
+		return ShellType(key)
+	}
+	for _, shell := range knownShellTypes {
+		if matchesTerminalName(name, string(shell), true) {
+			return shell
+		}
+	}
+	return ShellTypeUnknown
+}
+
+// matchTerminal returns the terminal type a process name corresponds to, if any
+func matchTerminal(name string) TerminalType {
+	if key, ok := matchCustomRule(customTerminalRules, name); ok {
+		return TerminalType(key)
+	} else if matchesTerminalName(name, "sshd", true) {
+		return TerminalTypeSSH
+	} else if matchesTerminalName(name, "tmux", true) {
+		return TerminalTypeTmux
+	} else if matchesTerminalName(name, "etterminal", true) {
+		return TerminalTypeETTerminal
+	} else if matchesTerminalName(name, "mosh-server", true) {
+		return TerminalTypeMosh
+	} else if matchesTerminalName(name, "iterm2", false) {
+		return TerminalTypeITerm2
+	} else if matchesTerminalName(name, "Code Helper", false) {
+		return TerminalTypeVSCode
+	}
+	for _, emulator := range emulatorProcessNames {
+		if matchesTerminalName(name, emulator.name, false) {
+			return emulator.terminal
+		}
+	}
+	for _, ide := range jetbrainsProcessNames {
+		// Linux launchers are named e.g. "idea.sh" or "pycharm64"
+		if matchesTerminalName(name, ide, false) || matchesTerminalName(name, ide+".sh", false) ||
+			matchesTerminalName(name, ide+"64", false) {
+			return TerminalTypeJetBrains
+		}
+	}
+	return TerminalTypeUnknown
 }
 
 // detectTerminalAndShell detects both terminal and shell types with validation
-// that shell should come before terminal in the process ancestry
+// that shell should come before terminal in the process ancestry, along with the
+// appearance and root state that select sub-profiles.
 // terminalOverride can be used to prepend a specific terminal type to the detected chain
 func detectTerminalAndShell(terminalOverride string) TerminalShellInfo {
-	// Get current process
-	currentPid := int32(os.Getpid())
-	proc, err := process.NewProcess(currentPid)
-	if err != nil {
+	defer timePhase("detection")()
+	info := detectTerminals(terminalOverride)
+	info.Appearance = detectAppearance()
+	info.Root = detectRoot()
+	return info
+}
+
+// detectTerminals detects the terminals and shell without the appearance and root
+// state, which cost a process launch on macOS. Choosing the escape backend for colors
+// given on the command line needs nothing more. Environment variables are checked
+// first; the process tree is only walked when they are ambiguous.
+func detectTerminals(terminalOverride string) TerminalShellInfo {
+	defer timePhase("detection")()
+	if info, ok := detectTerminalAndShellFromEnv(); ok {
+		debugf("Detected terminals from environment variables: %v\n", info.Terminals)
+		return withShellOverride(withTerminalOverride(withHostLayers(info), terminalOverride))
+	}
+
+	tty := detectCacheTTY()
+	if tty != "" {
+		if info, ok := loadDetectCache(tty); ok {
+			debugf("Using cached detection for %s: %v\n", tty, info.Terminals)
+			return withShellOverride(withTerminalOverride(withHostLayers(info), terminalOverride))
+		}
+	}
+
+	chain, err := getProcessAncestorChain()
+	if err != nil || len(chain) == 0 {
 		return TerminalShellInfo{
-			Terminals: []TerminalType{},
-			Shell:     ShellTypeUnknown,
+			Terminals: withHostLayers(TerminalShellInfo{Terminals: []TerminalType{}}).Terminals,
+			Shell:     withShellOverride(TerminalShellInfo{Shell: ShellTypeUnknown}).Shell,
 			Valid:     false,
 		}
 	}
 
-	var foundShell ShellType = ShellTypeUnknown
-	var terminals []TerminalType
-	var shellFoundFirst bool
-
-	// Add terminal override if specified
-	if terminalOverride != "" {
-		var overrideTerminal TerminalType
-		switch terminalOverride {
-		case "iterm2":
-			overrideTerminal = TerminalTypeITerm2
-		case "vscode":
-			overrideTerminal = TerminalTypeVSCode
-		case "ssh":
-			overrideTerminal = TerminalTypeSSH
-		case "tmux":
-			overrideTerminal = TerminalTypeTmux
-		case "etterminal":
-			overrideTerminal = TerminalTypeETTerminal
-		default:
-			// Invalid override, ignore it
+	// Skip the current process
+	info := detectTerminalAndShellFromChain(chain[1:], "")
+	if len(info.Terminals) == 0 && isContainer() {
+		// The terminal outside the container isn't in its process tree, but its
+		// variables may have been passed in (docker exec -e, devcontainers)
+		if terminals, ok := detectTerminalsFromEnv(); ok {
+			info.Terminals = terminals
 		}
-
-		if overrideTerminal != TerminalTypeUnknown {
-			terminals = append(terminals, overrideTerminal)
+	}
+	if tty != "" {
+		if err := saveDetectCache(tty, info); err != nil {
+			debugf("Could not cache detection result: %v\n", err)
 		}
 	}
+	return withShellOverride(withTerminalOverride(withHostLayers(info), terminalOverride))
+}
 
-	// Walk up the process tree looking for both shell and terminal types
-	for {
-		// Get parent process first (skip current process)
-		parentPid, err := proc.Ppid()
-		if err != nil || parentPid <= 1 {
-			break
-		}
+// withHostLayers appends the layers the process tree can't show: the container we
+// run in, and the WSL host
+func withHostLayers(info TerminalShellInfo) TerminalShellInfo {
+	return withWSLHost(withContainer(info))
+}
 
-		// Move to parent process
-		proc, err = process.NewProcess(parentPid)
-		if err != nil {
+// withTerminalOverride prepends a -terminal override to the detected terminals.
+// Like a terminal found before the shell, it makes the result invalid.
+func withTerminalOverride(info TerminalShellInfo, terminalOverride string) TerminalShellInfo {
+	if overrideTerminal, ok := parseTerminalType(terminalOverride); ok {
+		info.Terminals = append([]TerminalType{overrideTerminal}, info.Terminals...)
+		info.Valid = false
+	}
+	return info
+}
+
+// withShellOverride replaces the detected shell with the -shell flag, or else the
+// shell set in the [detect] table. Invalid values are ignored.
+func withShellOverride(info TerminalShellInfo) TerminalShellInfo {
+	for _, name := range []string{shellOverride, string(configShell)} {
+		if shell, ok := parseShellType(name); ok {
+			info.Shell = shell
 			break
 		}
+	}
+	return info
+}
 
-		// Get process name
-		name, err := proc.Name()
-		if err != nil {
-			continue
-		}
+// detectTerminalAndShellFromChain detects terminal and shell types from a list of
+// ancestor process names, nearest ancestor first
+func detectTerminalAndShellFromChain(ancestors []string, terminalOverride string) TerminalShellInfo {
+	var foundShell ShellType = ShellTypeUnknown
+	var terminals []TerminalType
+	var shellFoundFirst bool
+
+	// Add terminal override if specified (invalid overrides are ignored)
+	if overrideTerminal, ok := parseTerminalType(terminalOverride); ok {
+		terminals = append(terminals, overrideTerminal)
+	}
 
+	// Walk up the process tree looking for both shell and terminal types
+	for _, name := range ancestors {
 		// Check for shell types first (if we haven't found one yet)
 		if foundShell == ShellTypeUnknown {
-			if matchesTerminalName(name, "zsh", true) {
-				foundShell = ShellTypeZsh
-				shellFoundFirst = (len(terminals) == 0)
-			} else if matchesTerminalName(name, "bash", true) {
-				foundShell = ShellTypeBash
-				shellFoundFirst = (len(terminals) == 0)
-			} else if matchesTerminalName(name, "fish", true) {
-				foundShell = ShellTypeFish
-				shellFoundFirst = (len(terminals) == 0)
-			} else if matchesTerminalName(name, "tcsh", true) {
-				foundShell = ShellTypeTcsh
-				shellFoundFirst = (len(terminals) == 0)
-			} else if matchesTerminalName(name, "csh", true) {
-				foundShell = ShellTypeCsh
-				shellFoundFirst = (len(terminals) == 0)
-			} else if matchesTerminalName(name, "ksh", true) {
-				foundShell = ShellTypeKsh
-				shellFoundFirst = (len(terminals) == 0)
-			} else if matchesTerminalName(name, "sh", true) {
-				foundShell = ShellTypeSh
+			if shell := matchShell(name); shell != ShellTypeUnknown {
+				foundShell = shell
 				shellFoundFirst = (len(terminals) == 0)
 			}
 		}
 
 		// Check for terminal types and collect all of them
-		if matchesTerminalName(name, "sshd", true) {
-			terminals = append(terminals, TerminalTypeSSH)
-		} else if matchesTerminalName(name, "tmux", true) {
-			terminals = append(terminals, TerminalTypeTmux)
-		} else if matchesTerminalName(name, "etterminal", true) {
-			terminals = append(terminals, TerminalTypeETTerminal)
-		} else if matchesTerminalName(name, "iterm2", false) {
-			terminals = append(terminals, TerminalTypeITerm2)
-		} else if matchesTerminalName(name, "Code Helper", false) {
-			terminals = append(terminals, TerminalTypeVSCode)
+		if terminal := matchTerminal(name); terminal != TerminalTypeUnknown {
+			terminals = append(terminals, terminal)
 		}
 	}
 
@@ -159,50 +328,13 @@ func detectAllTerminalsInChain() []TerminalType {
 
 // detectAllTerminalsInChainImpl is the actual implementation
 func detectAllTerminalsInChainImpl() []TerminalType {
-	// Get current process
-	currentPid := int32(os.Getpid())
-	proc, err := process.NewProcess(currentPid)
-	if err != nil {
+	chain, err := getProcessAncestorChain()
+	if err != nil || len(chain) == 0 {
 		return nil
 	}
 
-	var terminals []TerminalType
-
-	// Walk up the process tree looking for all terminal types
-	for {
-		// Get parent process first (skip current process)
-		parentPid, err := proc.Ppid()
-		if err != nil || parentPid <= 1 {
-			break
-		}
-
-		// Move to parent process
-		proc, err = process.NewProcess(parentPid)
-		if err != nil {
-			break
-		}
-
-		// Get process name
-		name, err := proc.Name()
-		if err != nil {
-			continue
-		}
-
-		// Check for terminal types
-		if matchesTerminalName(name, "sshd", true) {
-			terminals = append(terminals, TerminalTypeSSH)
-		} else if matchesTerminalName(name, "tmux", true) {
-			terminals = append(terminals, TerminalTypeTmux)
-		} else if matchesTerminalName(name, "etterminal", true) {
-			terminals = append(terminals, TerminalTypeETTerminal)
-		} else if matchesTerminalName(name, "iterm2", false) {
-			terminals = append(terminals, TerminalTypeITerm2)
-		} else if matchesTerminalName(name, "Code Helper", false) {
-			terminals = append(terminals, TerminalTypeVSCode)
-		}
-	}
-
-	return terminals
+	// Skip the current process
+	return detectTerminalAndShellFromChain(chain[1:], "").Terminals
 }
 
 // matchesTerminalName checks if a process name matches a terminal name
@@ -233,9 +365,7 @@ func matchesTerminalName(processName, terminalName string, caseSensitive bool) b
 
 // isTerminalInAncestorChain checks if a specific terminal name appears in the process ancestor chain
 func isTerminalInAncestorChain(terminalName string) bool {
-	// Get current process
-	currentPid := int32(os.Getpid())
-	proc, err := process.NewProcess(currentPid)
+	chain, err := getProcessAncestorChain()
 	if err != nil {
 		return false
 	}
@@ -243,65 +373,126 @@ func isTerminalInAncestorChain(terminalName string) bool {
 	// Use case-insensitive matching for iterm, case-sensitive for others
 	caseSensitive := strings.ToLower(terminalName) != "iterm"
 
-	// Walk up the process tree looking for the terminal
-	for {
-		// Get process name
-		name, err := proc.Name()
-		if err != nil {
-			break
-		}
-
-		// Check if the process name matches the terminal name
+	for _, name := range chain {
 		if matchesTerminalName(name, terminalName, caseSensitive) {
 			return true
 		}
+	}
 
-		// Get parent process
-		parentPid, err := proc.Ppid()
-		if err != nil || parentPid <= 1 {
-			break
+	return false
+}
+
+// Limits on the process tree walk, overridable with $SET_TAB_COLOR_WALK_DEPTH and
+// $SET_TAB_COLOR_WALK_TIMEOUT. On a loaded system each lookup can be slow, so the
+// walk gives up and uses the ancestors found so far.
+const (
+	defaultWalkDepth   = 64
+	defaultWalkTimeout = 500 * time.Millisecond
+)
+
+// processChainCache holds the result of the one real process tree walk per run
+var processChainCache struct {
+	done  bool
+	chain []string
+	err   error
+}
+
+// getProcessAncestorChain returns the process names from the current process up to
+// (but excluding) init, or the simulated chain when a fake environment is active.
+// The tree is only walked once per run.
+func getProcessAncestorChain() ([]string, error) {
+	if env := getFakeEnvironment(); env != nil {
+		return env.ProcessChain, nil
+	}
+	if !processChainCache.done {
+		processChainCache.chain, processChainCache.err = getRealProcessAncestorChain(walkDepth(), walkTimeout())
+		processChainCache.done = true
+	}
+	return processChainCache.chain, processChainCache.err
+}
+
+// walkDepth returns the maximum number of processes to look at
+func walkDepth() int {
+	if value := os.Getenv("SET_TAB_COLOR_WALK_DEPTH"); value != "" {
+		if depth, err := strconv.Atoi(value); err == nil && depth > 0 {
+			return depth
 		}
+	}
+	return defaultWalkDepth
+}
 
-		// Move to parent process
-		proc, err = process.NewProcess(parentPid)
-		if err != nil {
-			break
+// walkTimeout returns how long the process tree walk may take
+func walkTimeout() time.Duration {
+	if value := os.Getenv("SET_TAB_COLOR_WALK_TIMEOUT"); value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			return timeout
 		}
 	}
+	return defaultWalkTimeout
+}
 
-	return false
+// initProcessNames lists the init systems that run as PID 1 outside containers
+var initProcessNames = []string{"launchd", "systemd", "init"}
+
+// isInitProcess reports whether a PID 1 process name is the system's init
+func isInitProcess(name string) bool {
+	return containsString(initProcessNames, name)
 }
 
-// getProcessAncestorChain returns the full ancestor chain for debugging/logging purposes
-func getProcessAncestorChain() ([]string, error) {
+// getProcessName returns the name of a process
+func getProcessName(ctx context.Context, pid int32) (string, error) {
+	proc, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return "", err
+	}
+	return proc.NameWithContext(ctx)
+}
+
+// getRealProcessAncestorChain walks the actual process tree, stopping after depth
+// processes or when timeout expires
+func getRealProcessAncestorChain(depth int, timeout time.Duration) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
 	var chain []string
 	currentPid := int32(os.Getpid())
-	proc, err := process.NewProcess(currentPid)
+	proc, err := process.NewProcessWithContext(ctx, currentPid)
 	if err != nil {
 		return nil, err
 	}
 
-	for {
+	for len(chain) < depth {
 		// Get process name
-		name, err := proc.Name()
+		name, err := proc.NameWithContext(ctx)
 		if err != nil {
 			break
 		}
 
 		chain = append(chain, name)
 
-		// Get parent process
-		parentPid, err := proc.Ppid()
-		if err != nil || parentPid <= 1 {
+		// Get parent process. Outside our PID namespace (docker exec) the parent
+		// reads as 0, and kernel threads descend from kthreadd (PID 2).
+		parentPid, err := proc.PpidWithContext(ctx)
+		if err != nil || parentPid <= 0 || (parentPid == 2 && runtime.GOOS == "linux") {
+			break
+		}
+		if parentPid == 1 {
+			// PID 1 is init, except in containers where it is often the shell itself
+			if name, err := getProcessName(ctx, parentPid); err == nil && !isInitProcess(name) && len(chain) < depth {
+				chain = append(chain, name)
+			}
 			break
 		}
 
 		// Move to parent process
-		proc, err = process.NewProcess(parentPid)
+		proc, err = process.NewProcessWithContext(ctx, parentPid)
 		if err != nil {
 			break
 		}
 	}
 
+	if ctx.Err() != nil {
+		infof("Process tree walk timed out after %v; using %d processes\n", timeout, len(chain))
+	}
 	return chain, nil
 }
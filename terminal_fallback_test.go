@@ -184,3 +184,64 @@ tab = "purple"
 		t.Errorf("Expected first fallback etterminal tab='green', got tab=%q", profile.Tab)
 	}
 }
+
+// TestTerminalFallbackPriority tests that a higher priority sub-profile wins over chain order
+func TestTerminalFallbackPriority(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "priority-config.toml")
+
+	configContent := `
+[profiles.test]
+tab = "blue"
+
+[profiles.test.tmux]
+tab = "yellow"
+
+[profiles.test.etterminal]
+tab = "green"
+
+# Outermost terminal, but explicitly preferred
+[profiles.test.iterm2]
+tab = "purple"
+priority = 10
+`
+
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+
+	tests := []struct {
+		name      string
+		terminals []TerminalType
+		expected  string
+	}{
+		{"highest priority wins", []TerminalType{TerminalTypeTmux, TerminalTypeETTerminal, TerminalTypeITerm2}, "purple"},
+		{"equal priority uses chain order", []TerminalType{TerminalTypeETTerminal, TerminalTypeTmux}, "green"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile, err := getProfileWithTerminalInfo("test", &TerminalShellInfo{
+				Terminals: test.terminals,
+				Shell:     ShellTypeZsh,
+				Valid:     true,
+			})
+			if err != nil {
+				t.Fatalf("getProfileWithTerminalInfo failed: %v", err)
+			}
+			if profile.Tab != test.expected {
+				t.Errorf("Expected tab=%q, got tab=%q", test.expected, profile.Tab)
+			}
+		})
+	}
+}
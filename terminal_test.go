@@ -1,7 +1,9 @@
 package main
 
 import (
+	"os"
 	"testing"
+	"time"
 )
 
 func TestDetectTerminalType(t *testing.T) {
@@ -17,6 +19,7 @@ func TestDetectTerminalType(t *testing.T) {
 		TerminalTypeSSH,
 		TerminalTypeTmux,
 		TerminalTypeVSCode,
+		TerminalTypeContainer,
 	}
 
 	for _, terminalType := range info.Terminals {
@@ -223,6 +226,61 @@ func TestIsTerminalInAncestorChain(t *testing.T) {
 	}
 }
 
+// TestProcessWalkLimits tests the depth cap and the single cached walk per run
+func TestProcessWalkLimits(t *testing.T) {
+	chain, err := getRealProcessAncestorChain(1, time.Second)
+	if err != nil {
+		t.Fatalf("getRealProcessAncestorChain() failed: %v", err)
+	}
+	if len(chain) != 1 {
+		t.Errorf("Expected the walk to stop after 1 process, got %v", chain)
+	}
+
+	// The walk never includes the system's init
+	if chain, err := getRealProcessAncestorChain(defaultWalkDepth, time.Second); err == nil && len(chain) > 0 {
+		if last := chain[len(chain)-1]; len(chain) > 1 && isInitProcess(last) {
+			t.Errorf("Expected init to be excluded from the chain, got %v", chain)
+		}
+	}
+	for _, name := range []string{"launchd", "systemd", "init"} {
+		if !isInitProcess(name) {
+			t.Errorf("Expected %q to be recognized as init", name)
+		}
+	}
+	if isInitProcess("bash") {
+		t.Error("Expected a shell running as PID 1 to be kept")
+	}
+
+	originalDepth := os.Getenv("SET_TAB_COLOR_WALK_DEPTH")
+	os.Setenv("SET_TAB_COLOR_WALK_DEPTH", "2")
+	defer os.Setenv("SET_TAB_COLOR_WALK_DEPTH", originalDepth)
+	if depth := walkDepth(); depth != 2 {
+		t.Errorf("walkDepth() = %d, expected 2", depth)
+	}
+	os.Setenv("SET_TAB_COLOR_WALK_DEPTH", "nonsense")
+	if depth := walkDepth(); depth != defaultWalkDepth {
+		t.Errorf("walkDepth() = %d, expected the default for an invalid value", depth)
+	}
+
+	originalTimeout := os.Getenv("SET_TAB_COLOR_WALK_TIMEOUT")
+	os.Setenv("SET_TAB_COLOR_WALK_TIMEOUT", "2s")
+	defer os.Setenv("SET_TAB_COLOR_WALK_TIMEOUT", originalTimeout)
+	if timeout := walkTimeout(); timeout != 2*time.Second {
+		t.Errorf("walkTimeout() = %v, expected 2s", timeout)
+	}
+
+	// Later lookups reuse the first walk
+	saved := processChainCache
+	defer func() { processChainCache = saved }()
+	processChainCache.done, processChainCache.chain, processChainCache.err = true, []string{"set-tab-color", "zsh", "sshd"}, nil
+	if terminals := detectAllTerminalsInChainImpl(); len(terminals) != 1 || terminals[0] != TerminalTypeSSH {
+		t.Errorf("Expected the cached chain to be used, got %v", terminals)
+	}
+	if !isTerminalInAncestorChain("sshd") {
+		t.Error("Expected isTerminalInAncestorChain to use the cached chain")
+	}
+}
+
 // BenchmarkDetectTerminalType benchmarks the terminal detection performance
 func BenchmarkDetectTerminalType(b *testing.B) {
 	for i := 0; i < b.N; i++ {
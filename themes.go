@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// builtinThemes are complete color schemes that work in any terminal supporting
+// the standard xterm color sequences, unlike iTerm2 presets. Names are lowercase
+// with dashes; see themeKey.
+var builtinThemes = map[string]Profile{
+	"solarized-dark": {
+		Foreground: "839496",
+		Background: "002b36",
+		Palette: solarizedPalette(map[string]string{
+			"curbg": "93a1a1",
+		}),
+	},
+	"solarized-light": {
+		Foreground: "657b83",
+		Background: "fdf6e3",
+		Palette: solarizedPalette(map[string]string{
+			"curbg": "586e75",
+		}),
+	},
+	"dracula": {
+		Foreground: "f8f8f2",
+		Background: "282a36",
+		Palette: map[string]string{
+			"black": "21222c", "red": "ff5555", "green": "50fa7b", "yellow": "f1fa8c",
+			"blue": "bd93f9", "magenta": "ff79c6", "cyan": "8be9fd", "white": "f8f8f2",
+			"br_black": "6272a4", "br_red": "ff6e6e", "br_green": "69ff94", "br_yellow": "ffffa5",
+			"br_blue": "d6acff", "br_magenta": "ff92df", "br_cyan": "a4ffff", "br_white": "ffffff",
+			"curbg": "f8f8f2",
+		},
+	},
+	"nord": {
+		Foreground: "d8dee9",
+		Background: "2e3440",
+		Palette: map[string]string{
+			"black": "3b4252", "red": "bf616a", "green": "a3be8c", "yellow": "ebcb8b",
+			"blue": "81a1c1", "magenta": "b48ead", "cyan": "88c0d0", "white": "e5e9f0",
+			"br_black": "4c566a", "br_red": "bf616a", "br_green": "a3be8c", "br_yellow": "ebcb8b",
+			"br_blue": "81a1c1", "br_magenta": "b48ead", "br_cyan": "8fbcbb", "br_white": "eceff4",
+			"curbg": "d8dee9",
+		},
+	},
+	"gruvbox": {
+		Foreground: "ebdbb2",
+		Background: "282828",
+		Palette: map[string]string{
+			"black": "282828", "red": "cc241d", "green": "98971a", "yellow": "d79921",
+			"blue": "458588", "magenta": "b16286", "cyan": "689d6a", "white": "a89984",
+			"br_black": "928374", "br_red": "fb4934", "br_green": "b8bb26", "br_yellow": "fabd2f",
+			"br_blue": "83a598", "br_magenta": "d3869b", "br_cyan": "8ec07c", "br_white": "ebdbb2",
+			"curbg": "ebdbb2",
+		},
+	},
+}
+
+// ThemeConfig is a user-defined theme from the [themes] table of the config file
+type ThemeConfig struct {
+	Foreground string            `toml:"fg"`
+	Background string            `toml:"bg"`
+	Palette    map[string]string `toml:"palette"`
+}
+
+// customThemes holds the themes from the config file, keyed by themeKey.
+// They take precedence over built-in themes with the same name.
+var customThemes = map[string]Profile{}
+
+// setCustomThemes replaces the custom themes, resolving their colors to hex.
+// Custom colors must be registered first so themes can use them.
+func setCustomThemes(themes map[string]ThemeConfig) error {
+	resolved := make(map[string]Profile, len(themes))
+	for name, theme := range themes {
+		resolve := func(field, value string) (string, error) {
+			if value == "" {
+				return "", nil
+			}
+			hex := normalizeColor(value)
+			if hex == "" || hex == "default" {
+				return "", fmt.Errorf("invalid value %q for %s of theme %q", value, field, name)
+			}
+			return hex, nil
+		}
+
+		var p Profile
+		var err error
+		if p.Foreground, err = resolve("fg", theme.Foreground); err != nil {
+			return err
+		}
+		if p.Background, err = resolve("bg", theme.Background); err != nil {
+			return err
+		}
+		p.Palette = make(map[string]string, len(theme.Palette))
+		for key, value := range theme.Palette {
+			if !isPaletteKey(key) {
+				return fmt.Errorf("unknown palette key %q in theme %q", key, name)
+			}
+			if p.Palette[key], err = resolve("palette."+key, value); err != nil {
+				return err
+			}
+		}
+		resolved[themeKey(name)] = p
+	}
+
+	customThemes = resolved
+	return nil
+}
+
+// solarizedPalette returns the ANSI colors shared by Solarized Dark and Light
+func solarizedPalette(extra map[string]string) map[string]string {
+	palette := map[string]string{
+		"black": "073642", "red": "dc322f", "green": "859900", "yellow": "b58900",
+		"blue": "268bd2", "magenta": "d33682", "cyan": "2aa198", "white": "eee8d5",
+		"br_black": "002b36", "br_red": "cb4b16", "br_green": "586e75", "br_yellow": "657b83",
+		"br_blue": "839496", "br_magenta": "6c71c4", "br_cyan": "93a1a1", "br_white": "fdf6e3",
+	}
+	for key, value := range extra {
+		palette[key] = value
+	}
+	return palette
+}
+
+// themeKey normalizes a theme name, so "Solarized Dark" matches "solarized-dark":
+// letters are lowercased and every run of other characters becomes one dash
+func themeKey(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// lookupTheme finds a custom or built-in theme by name
+func lookupTheme(name string) (Profile, error) {
+	if theme, ok := customThemes[themeKey(name)]; ok {
+		return theme, nil
+	}
+	theme, ok := builtinThemes[themeKey(name)]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown theme %q (available themes: %s)", name, strings.Join(listThemeNames(), ", "))
+	}
+	return theme, nil
+}
+
+// listThemeNames returns the names of all custom and built-in themes, sorted
+func listThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes)+len(customThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	for name := range customThemes {
+		if _, builtin := builtinThemes[name]; !builtin {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expandTheme fills in the colors a profile leaves unset from its theme, for
+// backends that can't apply a theme in one step
+func expandTheme(profile *Profile) (*Profile, error) {
+	if profile.Theme == "" {
+		return profile, nil
+	}
+
+	theme, err := lookupTheme(profile.Theme)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := *profile
+	expanded.Theme = ""
+	if expanded.Foreground == "" {
+		expanded.Foreground = theme.Foreground
+	}
+	if expanded.Background == "" {
+		expanded.Background = theme.Background
+	}
+	expanded.Palette = overlayPalette(theme.Palette, profile.Palette)
+	return &expanded, nil
+}
+
+// themeSequences maps the non-ANSI palette keys to their standard xterm OSC number
+var themeSequences = map[string]string{
+	"curbg": "12",
+	"selbg": "17",
+	"selfg": "19",
+}
+
+// buildThemeSequence returns the standard xterm sequences applying a theme:
+// OSC 10/11 for foreground/background, OSC 4 for the ANSI colors, and OSC 12/17/19
+// for the cursor and selection. Colors without a standard sequence (bold, link, ...)
+// use iTerm2's SetColors, which other terminals ignore.
+func buildThemeSequence(theme Profile) string {
+	var b strings.Builder
+	if theme.Foreground != "" {
+		b.WriteString(wrapOSC("10;#" + theme.Foreground))
+	}
+	if theme.Background != "" {
+		b.WriteString(wrapOSC("11;#" + theme.Background))
+	}
+	for i, key := range paletteKeys {
+		value, ok := theme.Palette[key]
+		switch {
+		case !ok:
+			continue
+		case i < 16:
+			b.WriteString(wrapOSC(fmt.Sprintf("4;%d;#%s", i, value)))
+		case themeSequences[key] != "":
+			b.WriteString(wrapOSC(themeSequences[key] + ";#" + value))
+		default:
+			b.WriteString(wrapOSC(fmt.Sprintf("1337;SetColors=%s=%s", key, value)))
+		}
+	}
+	return b.String()
+}
+
+// runSetTheme applies a built-in theme. Themes always use escape sequences, so they
+// are written in a single batch even with -prefer-it2setcolor.
+func runSetTheme(name string) error {
+	theme, err := lookupTheme(name)
+	if err != nil {
+		return err
+	}
+	return writeSequence(fmt.Sprintf("theme %s", themeKey(name)), buildThemeSequence(theme))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestBuiltinThemesComplete tests that every built-in theme defines all colors with valid hex values
+func TestBuiltinThemesComplete(t *testing.T) {
+	for name, theme := range builtinThemes {
+		if themeKey(name) != name {
+			t.Errorf("Theme name %q is not normalized", name)
+		}
+		for _, value := range []string{theme.Foreground, theme.Background} {
+			if len(value) != 6 || !isHex(value) {
+				t.Errorf("Theme %q has invalid color %q", name, value)
+			}
+		}
+		for _, key := range append(paletteKeys[:16:16], "curbg") {
+			value, ok := theme.Palette[key]
+			if !ok || len(value) != 6 || !isHex(value) {
+				t.Errorf("Theme %q has missing or invalid %s: %q", name, key, value)
+			}
+		}
+	}
+}
+
+// TestLookupTheme tests theme name matching
+func TestLookupTheme(t *testing.T) {
+	for _, name := range []string{"solarized-dark", "Solarized Dark", "SOLARIZED_DARK", "  solarized   dark "} {
+		theme, err := lookupTheme(name)
+		if err != nil {
+			t.Errorf("lookupTheme(%q) failed: %v", name, err)
+			continue
+		}
+		if theme.Background != "002b36" {
+			t.Errorf("lookupTheme(%q) returned the wrong theme", name)
+		}
+	}
+
+	if key := themeKey("Gruvbox dark, hard"); key != "gruvbox-dark-hard" {
+		t.Errorf("themeKey() = %q, expected %q", key, "gruvbox-dark-hard")
+	}
+
+	_, err := lookupTheme("monokai")
+	if err == nil || !contains(err.Error(), "available themes: dracula, gruvbox, nord, solarized-dark, solarized-light") {
+		t.Errorf("Expected unknown theme error listing themes, got %v", err)
+	}
+}
+
+// TestApplyProfileTheme tests that a theme is written as one batch of standard
+// sequences between the preset and the explicit colors
+func TestApplyProfileTheme(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	originalTerm := os.Getenv("TERM")
+	os.Setenv("TERM", "xterm-256color")
+	defer os.Setenv("TERM", originalTerm)
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	defer func() { escapeWriter = originalWriter }()
+
+	profile := &Profile{Preset: "Ocean", Theme: "Nord", Background: "black"}
+	if err := applyProfile(profile); err != nil {
+		t.Fatalf("applyProfile() failed: %v", err)
+	}
+
+	nord, _ := lookupTheme("nord")
+	expected := "\033]1337;SetColors=preset=Ocean\a" + buildThemeSequence(nord) + "\033]1337;SetColors=bg=000000\a"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+	if !contains(buf.String(), "\033]4;15;#eceff4\a\033]12;#d8dee9\a") {
+		t.Errorf("Expected ANSI and cursor colors in theme sequence, got %q", buf.String())
+	}
+}
+
+// TestExpandTheme tests that explicit colors win over the theme when it is spelled out
+func TestExpandTheme(t *testing.T) {
+	profile, err := expandTheme(&Profile{Theme: "dracula", Foreground: "white", Palette: map[string]string{"red": "#ff0000"}})
+	if err != nil {
+		t.Fatalf("expandTheme() failed: %v", err)
+	}
+
+	if profile.Theme != "" || profile.Foreground != "white" || profile.Background != "282a36" {
+		t.Errorf("Unexpected expanded profile: %+v", profile)
+	}
+	if profile.Palette["red"] != "#ff0000" || profile.Palette["green"] != "50fa7b" {
+		t.Errorf("Unexpected expanded palette: %v", profile.Palette)
+	}
+	if builtinThemes["dracula"].Palette["red"] != "ff5555" {
+		t.Error("expandTheme() modified the built-in theme")
+	}
+}
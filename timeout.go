@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// defaultBackendTimeout leaves room for the Python API, which starts an interpreter
+// and connects to iTerm2, while still keeping a hung call from stalling a prompt
+const defaultBackendTimeout = 5 * time.Second
+
+// backendTimeout bounds every backend program and terminal write (-timeout);
+// 0 waits indefinitely
+var backendTimeout = defaultBackendTimeout
+
+// asyncChildEnvVar marks the background process started by -async. Its value is
+// the detection fixture to delete, or "1" when none was written.
+const asyncChildEnvVar = "SET_TAB_COLOR_ASYNC_CHILD"
+
+// The background process outlives its parent, so the shell PID and session key
+// it would otherwise derive from os.Getppid are handed down in these variables
+const (
+	asyncShellPIDEnvVar = "SET_TAB_COLOR_SHELL_PID"
+	asyncSessionEnvVar  = "SET_TAB_COLOR_SESSION_KEY"
+)
+
+// newBackendCommand prepares a backend program (it2setcolor, tmux, python3) that is
+// killed once backendTimeout passes. finish must be called with the result of
+// running it; it turns a kill by the timeout into an error naming the program.
+func newBackendCommand(name string, args ...string) (cmd *exec.Cmd, finish func(error) error) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if backendTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, backendTimeout)
+	}
+	cmd = exec.CommandContext(ctx, name, args...)
+	// Don't wait on output pipes held open by a killed program's children
+	cmd.WaitDelay = 100 * time.Millisecond
+
+	finish = func(err error) error {
+		defer cancel()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return withExitCode(exitBackendUnavailable,
+				fmt.Errorf("%s did not finish within %s (see -timeout)", filepath.Base(name), backendTimeout))
+		}
+		return err
+	}
+	return cmd, finish
+}
+
+// writeWithTimeout writes to w, giving up after backendTimeout. A terminal whose
+// output is paused (Ctrl-S) or a pty nobody reads blocks writes indefinitely; the
+// blocked write is abandoned and ends with the process.
+func writeWithTimeout(w io.Writer, s string) error {
+	defer timePhase("backend")()
+	if backendTimeout <= 0 {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.WriteString(w, s)
+		done <- err
+	}()
+	timer := time.NewTimer(backendTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return withExitCode(exitBackendUnavailable, fmt.Errorf("writing to the terminal did not finish within %s (see -timeout)", backendTimeout))
+	}
+}
+
+// startAsync runs this invocation again in the background and returns without
+// waiting for it, so a prompt hook never waits on a backend. The background process
+// writes to the same terminal. Its process tree no longer leads to the terminal once
+// this process exits, so the detection inputs are captured here and handed over as
+// a SET_TAB_COLOR_FAKE_ENV fixture, which the background process deletes.
+func startAsync() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not find the set-tab-color executable: %v", err)
+	}
+
+	env := append(os.Environ(),
+		fmt.Sprintf("%s=%d", asyncShellPIDEnvVar, shellPID()),
+		asyncSessionEnvVar+"="+getSessionKey())
+	fixturePath := ""
+	if os.Getenv(fakeEnvVar) == "" {
+		fixturePath, err = writeAsyncFixture()
+		if err != nil {
+			return err
+		}
+		env = append(env, fakeEnvVar+"="+fixturePath, asyncChildEnvVar+"="+fixturePath)
+	} else {
+		env = append(env, asyncChildEnvVar+"=1")
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	// Errors would otherwise appear in the middle of the next prompt
+	if logEnabled(LogInfo) {
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Start(); err != nil {
+		if fixturePath != "" {
+			os.Remove(fixturePath)
+		}
+		return fmt.Errorf("could not start background process: %v", err)
+	}
+	infof("Continuing in background process %d\n", cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+// writeAsyncFixture saves the detection environment and process chain of this
+// process for the background process started by startAsync
+func writeAsyncFixture() (string, error) {
+	fixture := FakeEnvironment{Env: map[string]string{}}
+	for _, key := range detectionEnvVars {
+		if value, ok := os.LookupEnv(key); ok {
+			fixture.Env[key] = value
+		}
+	}
+	chain, err := getProcessAncestorChain()
+	if err != nil {
+		chain = []string{}
+	}
+	fixture.ProcessChain = chain
+
+	f, err := os.CreateTemp("", "set-tab-color-async-*.json")
+	if err != nil {
+		return "", fmt.Errorf("could not save detection for background process: %v", err)
+	}
+	err = json.NewEncoder(f).Encode(fixture)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("could not save detection for background process: %v", err)
+	}
+	return f.Name(), nil
+}
+
+// claimAsyncFixture loads the fixture handed over by startAsync and deletes it
+func claimAsyncFixture() {
+	if path := os.Getenv(asyncChildEnvVar); path != "" && path == os.Getenv(fakeEnvVar) {
+		getFakeEnvironment()
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestBackendCommandTimeout tests that a hung backend program is killed and reported
+func TestBackendCommandTimeout(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not available")
+	}
+	originalTimeout := backendTimeout
+	backendTimeout = 50 * time.Millisecond
+	defer func() { backendTimeout = originalTimeout }()
+
+	start := time.Now()
+	cmd, finish := newBackendCommand(sleep, "5")
+	err = finish(cmd.Run())
+	if err == nil || !strings.Contains(err.Error(), "sleep did not finish within 50ms") {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+	if exitCode(err) != exitBackendUnavailable {
+		t.Errorf("Expected exit code %d, got %d", exitBackendUnavailable, exitCode(err))
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the program to be killed promptly, took %s", elapsed)
+	}
+
+	// A program that finishes in time reports its own result
+	cmd, finish = newBackendCommand(sleep, "0")
+	if err := finish(cmd.Run()); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+// blockingWriter never completes a write, like a terminal with paused output
+type blockingWriter struct{}
+
+func (blockingWriter) Write(p []byte) (int, error) {
+	select {}
+}
+
+// TestWriteWithTimeout tests that a blocked terminal write is abandoned
+func TestWriteWithTimeout(t *testing.T) {
+	originalTimeout := backendTimeout
+	backendTimeout = 20 * time.Millisecond
+	defer func() { backendTimeout = originalTimeout }()
+
+	err := writeWithTimeout(blockingWriter{}, "\033]6;1;bg;*;default\a")
+	if err == nil || exitCode(err) != exitBackendUnavailable {
+		t.Fatalf("Expected a timeout error with exit code %d, got %v", exitBackendUnavailable, err)
+	}
+
+	var w countingWriter
+	if err := writeWithTimeout(&w, "abc"); err != nil || string(w.data) != "abc" {
+		t.Errorf("Expected \"abc\" to be written, got %q (%v)", w.data, err)
+	}
+}
+
+// TestClaimAsyncFixture tests that the background process deletes only its own fixture
+func TestClaimAsyncFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set-tab-color-async-1.json")
+	if err := os.WriteFile(path, []byte(`{"env":{"TERM_PROGRAM":"iTerm.app"},"process_chain":["set-tab-color","zsh"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer setFakeEnvironment(nil)
+
+	t.Setenv(fakeEnvVar, path)
+	t.Setenv(asyncChildEnvVar, "1")
+	claimAsyncFixture()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected a fixture given by the user to be kept: %v", err)
+	}
+
+	t.Setenv(asyncChildEnvVar, path)
+	fakeEnvOnce = sync.Once{}
+	claimAsyncFixture()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the fixture to be deleted, got %v", err)
+	}
+	if env := getFakeEnvironment(); env == nil || env.Env["TERM_PROGRAM"] != "iTerm.app" {
+		t.Errorf("Expected the fixture to be loaded before deletion, got %+v", env)
+	}
+}
+
+// TestAsyncShellIdentity tests that the background process keeps the shell PID and
+// session key of the foreground process instead of deriving them from its own parent
+func TestAsyncShellIdentity(t *testing.T) {
+	t.Setenv("ITERM_SESSION_ID", "")
+	t.Setenv(asyncChildEnvVar, "")
+	t.Setenv(asyncShellPIDEnvVar, "4242")
+	t.Setenv(asyncSessionEnvVar, "pid-4242")
+	if pid := shellPID(); pid != os.Getppid() {
+		t.Errorf("Expected the variables to be ignored outside -async, got %d", pid)
+	}
+
+	t.Setenv(asyncChildEnvVar, "1")
+	if pid := shellPID(); pid != 4242 {
+		t.Errorf("Expected shell PID 4242, got %d", pid)
+	}
+	if key := getSessionKey(); key != "pid-4242" {
+		t.Errorf("Expected session key \"pid-4242\", got %q", key)
+	}
+	if state := newAppliedState("", &Profile{}); state.ShellPID != 4242 {
+		t.Errorf("Expected the session state to record shell PID 4242, got %d", state.ShellPID)
+	}
+	if entry, err := newDetectCacheEntry("/dev/ttys001", TerminalShellInfo{}); err == nil && entry.ShellPID != 4242 {
+		t.Errorf("Expected the detection cache to record shell PID 4242, got %d", entry.ShellPID)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Global -timing flag: report where the run spent its time on stderr
+var timingMode bool
+
+// processStart is when the program started, as close as package initialization gets
+var processStart = time.Now()
+
+// runningPhase is a phase being timed and when it last started or resumed
+type runningPhase struct {
+	name  string
+	start time.Time
+}
+
+// phaseTimes accumulates the time spent in each phase. A phase started inside another
+// pauses it, so each one counts only its own time and the phases add up to the total.
+var phaseTimes struct {
+	names     []string // In order of first use
+	durations map[string]time.Duration
+	stack     []runningPhase
+}
+
+// timePhase starts timing a phase and returns the function that ends it, for defer.
+// It does nothing without -timing, or inside runParallel.
+func timePhase(name string) func() {
+	if !timingMode || parallelTasks.Load() > 0 {
+		return func() {}
+	}
+	now := time.Now()
+	if n := len(phaseTimes.stack); n > 0 {
+		addPhaseTime(phaseTimes.stack[n-1].name, now.Sub(phaseTimes.stack[n-1].start))
+	}
+	phaseTimes.stack = append(phaseTimes.stack, runningPhase{name: name, start: now})
+
+	return func() {
+		now := time.Now()
+		n := len(phaseTimes.stack)
+		addPhaseTime(name, now.Sub(phaseTimes.stack[n-1].start))
+		phaseTimes.stack = phaseTimes.stack[:n-1]
+		if n > 1 {
+			phaseTimes.stack[n-2].start = now
+		}
+	}
+}
+
+// addPhaseTime adds to a phase's total
+func addPhaseTime(name string, d time.Duration) {
+	if phaseTimes.durations == nil {
+		phaseTimes.durations = map[string]time.Duration{}
+	}
+	if _, ok := phaseTimes.durations[name]; !ok {
+		phaseTimes.names = append(phaseTimes.names, name)
+	}
+	phaseTimes.durations[name] += d
+}
+
+// writeTimings prints the time spent in each phase, the rest as "other", and the total
+func writeTimings(w io.Writer) {
+	total := time.Since(processStart)
+	rest := total
+	fmt.Fprintf(w, "Timing:\n")
+	for _, name := range phaseTimes.names {
+		d := phaseTimes.durations[name]
+		rest -= d
+		fmt.Fprintf(w, "  %-14s %s\n", name, formatMillis(d))
+	}
+	fmt.Fprintf(w, "  %-14s %s\n", "other", formatMillis(rest))
+	fmt.Fprintf(w, "  %-14s %s\n", "total", formatMillis(total))
+}
+
+// formatMillis renders a duration in milliseconds with microsecond precision
+func formatMillis(d time.Duration) string {
+	return fmt.Sprintf("%8.3fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTimePhase tests that nested phases count only their own time
+func TestTimePhase(t *testing.T) {
+	timingMode = true
+	defer func() {
+		timingMode = false
+		phaseTimes.names, phaseTimes.durations, phaseTimes.stack = nil, nil, nil
+	}()
+
+	stopOuter := timePhase("config load")
+	time.Sleep(2 * time.Millisecond)
+	stopInner := timePhase("normalization")
+	time.Sleep(5 * time.Millisecond)
+	stopInner()
+	stopOuter()
+
+	outer, inner := phaseTimes.durations["config load"], phaseTimes.durations["normalization"]
+	if inner < 5*time.Millisecond || outer < 2*time.Millisecond || outer >= inner {
+		t.Errorf("Expected exclusive times, got config load %v, normalization %v", outer, inner)
+	}
+
+	var buf bytes.Buffer
+	writeTimings(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || lines[0] != "Timing:" || !strings.HasPrefix(lines[1], "  config load ") ||
+		!strings.HasPrefix(lines[4], "  total ") || !strings.HasSuffix(lines[4], "ms") {
+		t.Errorf("Unexpected report:\n%s", buf.String())
+	}
+}
+
+// TestTimePhaseDisabled tests that nothing is recorded without -timing
+func TestTimePhaseDisabled(t *testing.T) {
+	timePhase("detection")()
+	if len(phaseTimes.names) != 0 {
+		t.Errorf("Expected no phases recorded, got %v", phaseTimes.names)
+	}
+}
+
+// BenchmarkSetTabColor benchmarks the work of the common -tab path after startup:
+// choosing the backend, normalizing the color, and writing the sequence
+func BenchmarkSetTabColor(b *testing.B) {
+	originalWriter := escapeWriter
+	escapeWriter = io.Discard
+	defer func() { escapeWriter = originalWriter }()
+
+	for i := 0; i < b.N; i++ {
+		useEscapeBackendFor(detectTerminals(""))
+		if err := runSetColor(TabColor, "red"); err != nil {
+			b.Fatalf("runSetColor() failed: %v", err)
+		}
+	}
+}
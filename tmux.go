@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TmuxConfig is the [tmux] table of the config file: the tmux chrome to style
+// from the tab color when running inside tmux
+type TmuxConfig struct {
+	Status      bool `toml:"status"`       // Style the session's status line
+	PaneBorders bool `toml:"pane_borders"` // Style the window's pane borders
+}
+
+// Global tmux targeting flags: when set, colors are applied to that tmux window or
+// pane with tmux's own options instead of escape sequences
+var (
+	targetTmuxWindow string
+	targetTmuxPane   string
+)
+
+// usesTmuxTargeting reports whether colors should go to a tmux window or pane
+func usesTmuxTargeting() bool {
+	return targetTmuxWindow != "" || targetTmuxPane != ""
+}
+
+// applyProfileToTmuxTarget applies a profile to the -tmux-window or -tmux-pane target
+func applyProfileToTmuxTarget(profile *Profile) error {
+	if targetTmuxPane != "" {
+		return applyProfileToTmuxPane(targetTmuxPane, profile)
+	}
+	return applyProfileToTmuxWindow(targetTmuxWindow, profile)
+}
+
+// tmuxChrome holds the [tmux] table of the loaded config
+var tmuxChrome TmuxConfig
+
+// inactiveBorderDarken is how much darker inactive pane borders are than the tab color
+const inactiveBorderDarken = 30
+
+// tmuxOption is a tmux option to set; window options are set with -w
+type tmuxOption struct {
+	name   string
+	value  string
+	window bool
+}
+
+// tmuxChromeOptions returns the tmux options styling the chrome for a tab color
+func tmuxChromeOptions(tab string) ([]tmuxOption, error) {
+	color, err := tmuxColor(tab)
+	if err != nil {
+		return nil, err
+	}
+
+	// "default" resets the chrome to the tmux defaults
+	status, border, activeBorder := "default", "default", "default"
+	if color != "default" {
+		fg, err := pickContrastColor(strings.TrimPrefix(color, "#"))
+		if err != nil {
+			return nil, err
+		}
+		inactive, err := tmuxColor(withLightnessFlags(tab, 0, inactiveBorderDarken))
+		if err != nil {
+			return nil, err
+		}
+		status = fmt.Sprintf("fg=#%s,bg=%s", fg, color)
+		border, activeBorder = "fg="+inactive, "fg="+color
+	}
+
+	var options []tmuxOption
+	if tmuxChrome.Status {
+		options = append(options, tmuxOption{"status-style", status, false})
+	}
+	if tmuxChrome.PaneBorders {
+		options = append(options,
+			tmuxOption{"pane-border-style", border, true},
+			tmuxOption{"pane-active-border-style", activeBorder, true})
+	}
+	return options, nil
+}
+
+// applyTmuxChrome styles the status line and pane borders of the tmux session we
+// run in to match the tab color, as enabled in the [tmux] table
+func applyTmuxChrome(profile *Profile) error {
+	if profile.Tab == "" || getDetectionEnv("TMUX") == "" {
+		return nil
+	}
+	options, err := tmuxChromeOptions(profile.Tab)
+	if err != nil {
+		return err
+	}
+
+	// Target our own pane, not whichever client tmux considers current
+	var target []string
+	if pane := os.Getenv("TMUX_PANE"); pane != "" {
+		target = []string{"-t", pane}
+	}
+	var commands [][]string
+	for _, option := range options {
+		args := []string{"set-option"}
+		if option.window {
+			args = append(args, "-w")
+		}
+		commands = append(commands, append(append(args, target...), option.name, option.value))
+	}
+	return runTmuxCommands(commands)
+}
+
+// applyProfileToTmuxWindow styles a tmux window with tmux's own options: the tab
+// color becomes the window's status-line entry and fg/bg become the pane colors
+func applyProfileToTmuxWindow(target string, profile *Profile) error {
+	if profile.Preset != "" || profile.Theme != "" || len(profile.Palette) > 0 || profile.Badge != "" ||
+		(profile.Attention != "" && profile.Attention != "false") || profile.ITermProfile != "" || profile.Transparency != "" || profile.Blur != "" {
+		if err := unsupportedf("preset, theme, palette, badge, attention, iTerm2 profiles, and transparency are not supported for tmux window %s", target); err != nil {
+			return err
+		}
+	}
+
+	paneStyle, err := tmuxStyle([2]string{"fg", profile.Foreground}, [2]string{"bg", profile.Background})
+	if err != nil {
+		return err
+	}
+	tabStyle, err := tmuxStyle([2]string{"bg", profile.Tab})
+	if err != nil {
+		return err
+	}
+
+	var commands [][]string
+	for _, option := range []struct{ name, value string }{
+		{"window-style", paneStyle},
+		{"window-active-style", paneStyle},
+		{"window-status-style", tabStyle},
+		{"window-status-current-style", tabStyle},
+	} {
+		if option.value == "" {
+			continue
+		}
+		commands = append(commands, []string{"set-option", "-w", "-t", target, option.name, option.value})
+	}
+
+	if profile.Title != "" {
+		commands = append(commands, []string{"rename-window", "-t", target, profile.Title})
+	}
+
+	return runTmuxCommands(commands)
+}
+
+// applyProfileToTmuxPane styles a single tmux pane: fg/bg become the pane colors
+// and the title becomes the pane title. Panes have no status-line entry of their own.
+func applyProfileToTmuxPane(target string, profile *Profile) error {
+	if profile.Tab != "" || profile.Preset != "" || profile.Theme != "" || len(profile.Palette) > 0 || profile.Badge != "" ||
+		(profile.Attention != "" && profile.Attention != "false") || profile.ITermProfile != "" || profile.Transparency != "" || profile.Blur != "" {
+		if err := unsupportedf("tab, preset, theme, palette, badge, attention, iTerm2 profiles, and transparency are not supported for tmux pane %s", target); err != nil {
+			return err
+		}
+	}
+
+	paneStyle, err := tmuxStyle([2]string{"fg", profile.Foreground}, [2]string{"bg", profile.Background})
+	if err != nil {
+		return err
+	}
+
+	var commands [][]string
+	if paneStyle != "" {
+		for _, option := range []string{"window-style", "window-active-style"} {
+			commands = append(commands, []string{"set-option", "-p", "-t", target, option, paneStyle})
+		}
+	}
+
+	if profile.Title != "" {
+		commands = append(commands, []string{"select-pane", "-t", target, "-T", profile.Title})
+	}
+
+	return runTmuxCommands(commands)
+}
+
+// tmuxStyle builds a tmux style such as "fg=#ffffff,bg=#000000" from the non-empty colors
+func tmuxStyle(values ...[2]string) (string, error) {
+	var parts []string
+	for _, v := range values {
+		if v[1] == "" {
+			continue
+		}
+		color, err := tmuxColor(v[1])
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, v[0]+"="+color)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// tmuxColor converts a color to tmux syntax ("#rrggbb" or "default")
+func tmuxColor(color string) (string, error) {
+	normalized := normalizeColor(color)
+	switch normalized {
+	case "":
+		return "", withExitCode(exitUnknownColor, fmt.Errorf("unknown color: %s", color))
+	case "default":
+		return "default", nil
+	}
+	if alpha := hexAlpha(color); alpha != "" && alpha != "ff" {
+		warnf("the alpha of %s is ignored; tmux colors are opaque", color)
+	}
+	return "#" + normalized, nil
+}
+
+// runTmuxCommands runs several tmux commands in order with a single invocation,
+// separated by ";" as on the tmux command line, so they reach the server together
+// and don't race each other or another set-tab-color run
+func runTmuxCommands(commands [][]string) error {
+	var args []string
+	for i, command := range commands {
+		if i > 0 {
+			args = append(args, ";")
+		}
+		for _, arg := range command {
+			// tmux also ends a command at an argument's trailing semicolon
+			if strings.HasSuffix(arg, ";") {
+				arg = strings.TrimSuffix(arg, ";") + `\;`
+			}
+			args = append(args, arg)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return runTmux(args...)
+}
+
+// runTmux runs a tmux command, or prints it in dry-run mode
+func runTmux(args ...string) error {
+	if dryRunMode {
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = shellQuote(arg)
+		}
+		_, err := fmt.Fprintf(escapeWriter, "[dry-run] exec: tmux %s\n", strings.Join(quoted, " "))
+		return err
+	}
+
+	tmux, err := executor.LookPath("tmux")
+	if err != nil {
+		return withExitCode(exitBackendUnavailable, fmt.Errorf("tmux is required: %v", err))
+	}
+	if err := executor.Run(tmux, args); err != nil {
+		return fmt.Errorf("tmux %s failed: %v", args[0], err)
+	}
+	return nil
+}
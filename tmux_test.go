@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestApplyTmuxChrome tests styling the tmux status line and pane borders in dry-run mode
+func TestApplyTmuxChrome(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "tmux-config.toml")
+	configContent := `[tmux]
+status = true
+pane_borders = true
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	originalPane, hadPane := os.LookupEnv("TMUX_PANE")
+	os.Setenv("TMUX_PANE", "%3")
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+		if hadPane {
+			os.Setenv("TMUX_PANE", originalPane)
+		} else {
+			os.Unsetenv("TMUX_PANE")
+		}
+		tmuxChrome = TmuxConfig{}
+	}()
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+	}()
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		tab      string
+		expected string
+	}{
+		{"dark tab", map[string]string{"TMUX": "/tmp/tmux-501/default,1,0"}, "navy",
+			`[dry-run] exec: tmux set-option -t %3 status-style 'fg=#ffffff,bg=#000080' ';' set-option -w -t %3 pane-border-style 'fg=#000000' ';' set-option -w -t %3 pane-active-border-style 'fg=#000080'
+`},
+		{"light tab", map[string]string{"TMUX": "/tmp/tmux-501/default,1,0"}, "yellow",
+			`[dry-run] exec: tmux set-option -t %3 status-style 'fg=#000000,bg=#ffff00' ';' set-option -w -t %3 pane-border-style 'fg=#666600' ';' set-option -w -t %3 pane-active-border-style 'fg=#ffff00'
+`},
+		{"reset", map[string]string{"TMUX": "/tmp/tmux-501/default,1,0"}, "default",
+			`[dry-run] exec: tmux set-option -t %3 status-style default ';' set-option -w -t %3 pane-border-style default ';' set-option -w -t %3 pane-active-border-style default
+`},
+		{"outside tmux", map[string]string{}, "red", ""},
+		{"no tab color", map[string]string{"TMUX": "/tmp/tmux-501/default,1,0"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFakeEnvironment(&FakeEnvironment{Env: tt.env})
+			defer setFakeEnvironment(nil)
+			buf.Reset()
+
+			if err := applyTmuxChrome(&Profile{Tab: tt.tab}); err != nil {
+				t.Fatalf("applyTmuxChrome() failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+
+	// Only the enabled parts are styled
+	tmuxChrome = TmuxConfig{Status: true}
+	options, err := tmuxChromeOptions("red")
+	if err != nil || len(options) != 1 || options[0].name != "status-style" {
+		t.Errorf("Expected only the status style, got %v, %v", options, err)
+	}
+	if _, err := tmuxChromeOptions("not-a-color"); err == nil {
+		t.Error("Expected an error for an unknown color")
+	}
+
+	// A spaced color name still gets its inactive border darkened
+	tmuxChrome = TmuxConfig{PaneBorders: true}
+	options, err = tmuxChromeOptions("Light Blue")
+	if err != nil || len(options) != 2 || options[0].value != "fg=#3a9fc0" || options[1].value != "fg=#add8e6" {
+		t.Errorf("Expected darkened inactive borders for Light Blue, got %v, %v", options, err)
+	}
+}
+
+// TestApplyProfileToTmuxTarget tests applying a profile to a -tmux-window or -tmux-pane target
+func TestApplyProfileToTmuxTarget(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+		targetTmuxWindow, targetTmuxPane = "", ""
+	}()
+
+	profile := &Profile{Tab: "red", Foreground: "white", Background: "black", Title: "api"}
+	tests := []struct {
+		name     string
+		window   string
+		pane     string
+		expected string
+	}{
+		{"window", "2", "", `[dry-run] exec: tmux set-option -w -t 2 window-style 'fg=#ffffff,bg=#000000' ';' set-option -w -t 2 window-active-style 'fg=#ffffff,bg=#000000' ';' set-option -w -t 2 window-status-style 'bg=#ff0000' ';' set-option -w -t 2 window-status-current-style 'bg=#ff0000' ';' rename-window -t 2 api
+`},
+		{"pane", "", "%3", `[dry-run] exec: tmux set-option -p -t %3 window-style 'fg=#ffffff,bg=#000000' ';' set-option -p -t %3 window-active-style 'fg=#ffffff,bg=#000000' ';' select-pane -t %3 -T api
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetTmuxWindow, targetTmuxPane = tt.window, tt.pane
+			buf.Reset()
+
+			if err := applyProfile(profile); err != nil {
+				t.Fatalf("applyProfile() failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+// terminalName would return the device path of the terminal open on fd; device
+// paths aren't looked up here
+func terminalName(fd int) string {
+	return ""
+}
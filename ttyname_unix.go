@@ -0,0 +1,51 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminalName returns the device path of the terminal open on fd, or "" if fd
+// isn't a terminal. Like ttyname(3), it looks for the device node in /dev whose
+// device number matches, after trying the /proc link on Linux.
+func terminalName(fd int) string {
+	if _, err := unix.IoctlGetTermios(fd, ioctlGetTermios); err != nil {
+		return ""
+	}
+	if target, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd)); err == nil && strings.HasPrefix(target, "/dev/") {
+		return target
+	}
+
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return ""
+	}
+	return deviceNodeName(uint64(st.Rdev), "/dev/pts", "/dev")
+}
+
+// deviceNodeName returns the first character device in dirs with device number rdev
+func deviceNodeName(rdev uint64, dirs ...string) string {
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Type()&os.ModeCharDevice == 0 {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			var st unix.Stat_t
+			if unix.Stat(path, &st) == nil && uint64(st.Rdev) == rdev {
+				return path
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// TestTerminalName tests looking up the device of a terminal without running tty(1)
+func TestTerminalName(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if name := terminalName(int(file.Fd())); name != "" {
+		t.Errorf("Expected no terminal name for a regular file, got %q", name)
+	}
+
+	var st unix.Stat_t
+	if err := unix.Stat("/dev/null", &st); err != nil {
+		t.Skip("/dev/null is not available")
+	}
+	if name := deviceNodeName(uint64(st.Rdev), t.TempDir(), "/dev"); name != "/dev/null" {
+		t.Errorf("Expected the device number of /dev/null to be found at /dev/null, got %q", name)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// controllingTerminal is the terminal of the process, whatever its stdout is
+const controllingTerminal = "/dev/tty"
+
+// ttyOutputPath is the terminal named with -tty, which escape sequences are
+// written to instead of stdout
+var ttyOutputPath string
+
+// stdoutIsTerminal reports whether stdout is a terminal rather than a pipe or file
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// /dev/null is a character device too
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
+
+// useTerminalOutput points escapeWriter at the terminal to color. Escape sequences
+// go to stdout as long as it is a terminal; when it is piped or redirected, as in
+// "set-tab-color ... | tee log" or a script capturing output, they would end up in
+// the pipe, so the controlling terminal is used instead. A path given with -tty is
+// always used. Dry-run descriptions stay on stdout.
+func useTerminalOutput(path string) error {
+	if dryRunMode {
+		return nil
+	}
+
+	if path != "" {
+		path = normalizeTTYPath(path)
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("could not open %s for -tty: %v", path, err)
+		}
+		ttyOutputPath = path
+		escapeWriter = f
+		return nil
+	}
+
+	if stdoutIsTerminal() {
+		return nil
+	}
+	// Without a controlling terminal (cron, launchd) there is nothing better than stdout
+	f, err := os.OpenFile(controllingTerminal, os.O_WRONLY, 0)
+	if err != nil {
+		infof("stdout is not a terminal and %s can't be opened (%v); writing escape sequences to stdout\n", controllingTerminal, err)
+		return nil
+	}
+	infof("stdout is not a terminal; writing escape sequences to %s\n", controllingTerminal)
+	escapeWriter = f
+	return nil
+}
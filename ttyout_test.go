@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestUseTerminalOutputPath tests that -tty redirects escape sequences and names the tty
+func TestUseTerminalOutputPath(t *testing.T) {
+	originalWriter := escapeWriter
+	defer func() {
+		escapeWriter = originalWriter
+		ttyOutputPath = ""
+	}()
+
+	path := filepath.Join(t.TempDir(), "ttys003")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := useTerminalOutput(path); err != nil {
+		t.Fatalf("useTerminalOutput() failed: %v", err)
+	}
+	if name := ttyName(); name != path {
+		t.Errorf("Expected ttyName() %q, got %q", path, name)
+	}
+	if err := runSetTitle("build"); err != nil {
+		t.Fatalf("runSetTitle() failed: %v", err)
+	}
+	escapeWriter.(*os.File).Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != buildSetTitleSequence("build") {
+		t.Errorf("Expected the title sequence in %s, got %q", path, data)
+	}
+
+	if err := useTerminalOutput(filepath.Join(t.TempDir(), "missing", "tty")); err == nil {
+		t.Error("Expected an error for a terminal that can't be opened")
+	}
+}
+
+// TestUseTerminalOutputDryRun tests that dry-run descriptions stay on stdout
+func TestUseTerminalOutputDryRun(t *testing.T) {
+	originalWriter := escapeWriter
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+	}()
+
+	if err := useTerminalOutput("/nonexistent/tty"); err != nil {
+		t.Fatalf("useTerminalOutput() failed: %v", err)
+	}
+	if escapeWriter != originalWriter || ttyOutputPath != "" {
+		t.Error("Expected dry-run output to stay on stdout")
+	}
+}
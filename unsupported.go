@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+)
+
+// UnsupportedConfig is the [unsupported] table of the config file: what to do with
+// settings the detected terminal can't show
+type UnsupportedConfig struct {
+	Action string `toml:"action"` // "skip" or "warn"; unset skips escape sequences quietly and warns for tmux targets
+	Tab    string `toml:"tab"`    // Target to set with the tab color instead, "fg" or "bg"
+}
+
+// unsupportedActions lists the valid values of action
+var unsupportedActions = []string{"skip", "warn"}
+
+// unsupportedTabTargets lists the targets the tab color can be moved to
+var unsupportedTabTargets = []ColorTarget{ForegroundColor, BackgroundColor}
+
+// unsupportedPolicy holds the [unsupported] table of the loaded config
+var unsupportedPolicy UnsupportedConfig
+
+// setUnsupportedPolicy replaces the handling of unsupported settings
+func setUnsupportedPolicy(config UnsupportedConfig) error {
+	if config.Action != "" && !containsString(unsupportedActions, config.Action) {
+		return fmt.Errorf("invalid action %q (expected skip or warn)", config.Action)
+	}
+	if config.Tab != "" && !containsColorTarget(unsupportedTabTargets, ColorTarget(config.Tab)) {
+		return fmt.Errorf("invalid tab target %q (expected fg or bg)", config.Tab)
+	}
+	unsupportedPolicy = config
+	return nil
+}
+
+// containsColorTarget reports whether a color target is in a list
+func containsColorTarget(targets []ColorTarget, target ColorTarget) bool {
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestSetUnsupportedPolicy tests checking the [unsupported] table
+func TestSetUnsupportedPolicy(t *testing.T) {
+	defer setUnsupportedPolicy(UnsupportedConfig{})
+
+	tests := []struct {
+		name        string
+		config      UnsupportedConfig
+		expectError string
+	}{
+		{"empty", UnsupportedConfig{}, ""},
+		{"warn and map", UnsupportedConfig{Action: "warn", Tab: "bg"}, ""},
+		{"skip", UnsupportedConfig{Action: "skip", Tab: "fg"}, ""},
+		{"bad action", UnsupportedConfig{Action: "fail"}, `invalid action "fail"`},
+		{"bad tab target", UnsupportedConfig{Tab: "badge"}, `invalid tab target "badge"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setUnsupportedPolicy(tt.config)
+			switch {
+			case tt.expectError == "" && err != nil:
+				t.Errorf("setUnsupportedPolicy() failed: %v", err)
+			case tt.expectError != "" && (err == nil || !strings.Contains(err.Error(), tt.expectError)):
+				t.Errorf("setUnsupportedPolicy() = %v, expected error containing %q", err, tt.expectError)
+			}
+		})
+	}
+}
+
+// TestUnsupportedTabMapping tests sending the tab color to another target in xterm terminals
+func TestUnsupportedTabMapping(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	escapeBackend = BackendXterm
+	defer func() {
+		escapeWriter = originalWriter
+		escapeBackend = BackendITerm2
+		setUnsupportedPolicy(UnsupportedConfig{})
+	}()
+
+	if err := runSetColor(TabColor, "red"); err != nil {
+		t.Fatalf("runSetColor() failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected the tab color to be skipped, got %q", buf.String())
+	}
+
+	if err := setUnsupportedPolicy(UnsupportedConfig{Tab: "bg"}); err != nil {
+		t.Fatalf("setUnsupportedPolicy() failed: %v", err)
+	}
+	if err := runSetColor(TabColor, "red"); err != nil {
+		t.Fatalf("runSetColor() failed: %v", err)
+	}
+	if expected := "\x1b]11;#ff0000\x07"; buf.String() != expected {
+		t.Errorf("Expected the tab color as the background %q, got %q", expected, buf.String())
+	}
+
+	// A background set later in the same profile still wins
+	buf.Reset()
+	if err := applyProfile(&Profile{Tab: "red", Background: "black"}); err != nil {
+		t.Fatalf("applyProfile() failed: %v", err)
+	}
+	if expected := "\x1b]11;#ff0000\x07\x1b]11;#000000\x07"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ValidationIssue describes a single problem found in the config file
+type ValidationIssue struct {
+	Line    int // 1-based line number, or 0 if unknown
+	Message string
+}
+
+// configLocator maps TOML keys to the line where they are defined
+type configLocator struct {
+	tables map[string]int // dotted table path → header line
+	keys   map[string]int // dotted key path → line
+}
+
+// newConfigLocator scans a TOML file for table headers and simple key assignments.
+// It only understands the subset of TOML used by config files (no inline tables
+// or multi-line values), which is enough to point users at the right line.
+func newConfigLocator(path string) (*configLocator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	loc := &configLocator{tables: map[string]int{}, keys: map[string]int{}}
+	var table []string
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && !strings.HasPrefix(line, "[[") {
+			if end := strings.Index(line, "]"); end > 0 {
+				table = splitTOMLKey(line[1:end])
+				loc.tables[strings.Join(table, ".")] = lineNo
+			}
+			continue
+		}
+
+		if eq := strings.Index(line, "="); eq > 0 {
+			key := append(append([]string{}, table...), splitTOMLKey(line[:eq])...)
+			loc.keys[strings.Join(key, ".")] = lineNo
+		}
+	}
+
+	return loc, scanner.Err()
+}
+
+// splitTOMLKey splits a dotted TOML key, honoring quoted segments
+func splitTOMLKey(key string) []string {
+	var parts []string
+	var current strings.Builder
+	var quote rune
+	for _, r := range key {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '.':
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+		case r == ' ' || r == '\t':
+			// Whitespace around dots is insignificant
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(parts, strings.TrimSpace(current.String()))
+}
+
+// line returns the line of a key, falling back to its enclosing table
+func (l *configLocator) line(path ...string) int {
+	if n, ok := l.keys[strings.Join(path, ".")]; ok {
+		return n
+	}
+	for i := len(path); i > 0; i-- {
+		if n, ok := l.tables[strings.Join(path[:i], ".")]; ok {
+			return n
+		}
+	}
+
+	// Implicit tables (e.g. [profiles.x] defined only via [profiles.x.zsh])
+	// are located at their first child table
+	prefix := strings.Join(path, ".") + "."
+	first := 0
+	for table, n := range l.tables {
+		if strings.HasPrefix(table, prefix) && (first == 0 || n < first) {
+			first = n
+		}
+	}
+	return first
+}
+
+// preserveConfigSettings saves the process-wide settings a config registers and
+// returns a function that restores them
+func preserveConfigSettings() (restore func()) {
+	names, colors, themes, contrast := cssColors, customColors, customThemes, contrastPair
+	terminals, shells, shell := customTerminalRules, customShellRules, configShell
+	unsupported, colorblind, iterm2 := unsupportedPolicy, colorblindMode, iterm2Config
+	return func() {
+		cssColors, customColors, customThemes, contrastPair = names, colors, themes, contrast
+		customTerminalRules, customShellRules, configShell = terminals, shells, shell
+		unsupportedPolicy, colorblindMode, iterm2Config = unsupported, colorblind, iterm2
+	}
+}
+
+// validateConfigFile checks the config at path and returns every problem found
+func validateConfigFile(path string) ([]ValidationIssue, error) {
+	return validateConfigLayer(nil, path)
+}
+
+// validateConfigLayer checks a config file as merged over the files before it,
+// the way repeated -config flags load them, so it may use their colors and
+// profiles. Problems are located in the file where it defines the key.
+func validateConfigLayer(base []string, path string) ([]ValidationIssue, error) {
+	var config Config
+	meta, err := toml.DecodeFile(path, &config)
+	if err != nil {
+		return parseIssues(err), nil
+	}
+	if len(base) > 0 {
+		merged, _, err := mergeConfigLayers(append(append([]string{}, base...), path))
+		if err != nil {
+			return nil, err
+		}
+		config = Config{}
+		if meta, err = toml.Decode(merged, &config); err != nil {
+			return parseIssues(err), nil
+		}
+	}
+
+	loc, err := newConfigLocator(path)
+	if err != nil {
+		return nil, err
+	}
+	return validateConfig(meta, config, loc), nil
+}
+
+// parseIssues turns a TOML decoding error into a validation issue
+func parseIssues(err error) []ValidationIssue {
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) {
+		return []ValidationIssue{{Line: parseErr.Position.Line, Message: parseErr.Message}}
+	}
+	return []ValidationIssue{{Message: err.Error()}}
+}
+
+// validateConfig checks a decoded config, locating problems with loc
+func validateConfig(meta toml.MetaData, config Config, loc *configLocator) []ValidationIssue {
+	// Profiles are resolved against the settings being validated, which are put back
+	// afterwards so the loaded config stays in effect
+	defer preserveConfigSettings()()
+
+	var issues []ValidationIssue
+	addIssue := func(line int, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Unknown keys, templates, and the structure of profiles are checked as when the
+	// config is loaded
+	undecoded := map[string]bool{} // Profiles with values that were reported and dropped
+	for _, err := range finishDecoding(meta, &config) {
+		addIssue(loc.line(err.Path...), "%v", err)
+		if len(err.Path) > 1 && err.Path[0] == "profiles" {
+			undecoded[err.Path[1]] = true
+		}
+	}
+
+	if err := setColorNames(config.ColorNames); err != nil {
+		addIssue(loc.line("color_names"), "%v", err)
+	}
+
+	// Custom colors must resolve before profiles can use them
+	if err := setCustomColors(config.Colors); err != nil {
+		addIssue(loc.line("colors"), "%v", err)
+	}
+
+	// Themes too, registered one at a time so each problem gets its own line number
+	themes := map[string]ThemeConfig{}
+	for name, theme := range config.Themes {
+		if err := setCustomThemes(map[string]ThemeConfig{name: theme}); err != nil {
+			addIssue(loc.line("themes", name), "%v", err)
+			continue
+		}
+		themes[name] = theme
+	}
+	setCustomThemes(themes)
+
+	if err := setContrastPair(config.Contrast); err != nil {
+		addIssue(loc.line("contrast"), "%v", err)
+	}
+	if err := setUnsupportedPolicy(config.Unsupported); err != nil {
+		addIssue(loc.line("unsupported"), "%v", err)
+	}
+	if err := setColorblindMode(config.Colorblind); err != nil {
+		addIssue(loc.line("colorblind"), "%v", err)
+	}
+	if err := setITerm2Config(config.ITerm2); err != nil {
+		addIssue(loc.line("iterm2"), "%v", err)
+	}
+
+	for _, glob := range config.Redact.Patterns {
+		if _, err := compileRedactPattern(glob); err != nil {
+			addIssue(loc.line("redact", "patterns"), "%v", err)
+		}
+	}
+
+	// Detection rules must map to usable sub-profile keys; valid ones are registered so
+	// profiles are also resolved for the custom terminals and shells
+	detect := DetectConfig{Terminals: map[string]string{}, Shells: map[string]string{}}
+	for _, table := range []struct {
+		name    string
+		mapping map[string]string
+		valid   map[string]string
+	}{
+		{"terminals", config.Detect.Terminals, detect.Terminals},
+		{"shells", config.Detect.Shells, detect.Shells},
+	} {
+		for process, key := range table.mapping {
+			if _, err := buildDetectRules(table.name, map[string]string{process: key}); err != nil {
+				addIssue(loc.line("detect", table.name, process), "%v", err)
+				continue
+			}
+			table.valid[process] = key
+		}
+	}
+	if err := setDetectRules(detect); err != nil {
+		addIssue(loc.line("detect"), "%v", err)
+		setDetectRules(DetectConfig{})
+	} else if config.Detect.Shell != "" {
+		detect.Shell = config.Detect.Shell
+		if err := setDetectRules(detect); err != nil {
+			addIssue(loc.line("detect", "shell"), "%v", err)
+		}
+	}
+
+	// Workspace layouts must name a target and an existing profile
+	for name, workspace := range config.Workspaces {
+		for i, layout := range workspace.Layouts {
+			err := layout.check()
+			if err == nil {
+				err = checkProfileNames(config.Profiles, layout.Profile)
+			}
+			if err != nil {
+				addIssue(loc.line("workspaces", name), "workspace %q layout %d: %v", name, i+1, err)
+			}
+		}
+	}
+
+	// Watch rules must have a condition and name an existing profile
+	for i, rule := range config.Watch.Rules {
+		err := rule.check()
+		if err == nil {
+			err = checkProfileNames(config.Profiles, rule.Profile)
+		}
+		if err != nil {
+			addIssue(loc.line("watch", "rules"), "watch rule %d: %v", i+1, err)
+		}
+	}
+	if name := config.DefaultProfile; name != "" {
+		if err := checkProfileNames(config.Profiles, name); err != nil {
+			addIssue(loc.line("default_profile"), "default_profile %v", err)
+		}
+	}
+	if name := config.Watch.Default; name != "" {
+		if err := checkProfileNames(config.Profiles, name); err != nil {
+			addIssue(loc.line("watch", "default"), "watch default %v", err)
+		}
+	}
+
+	// Sub-profiles that nothing selects are only warned about when the config is
+	// loaded, but are most likely misspelled
+	for _, err := range unknownSubProfileKeys(&config) {
+		addIssue(loc.line(err.Path...), "%v", err)
+	}
+
+	for _, name := range sortedMapKeys(config.Profiles) {
+		issues = append(issues, validateProfile(loc, name, config.Profiles[name], undecoded[name])...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues
+}
+
+// validateProfile checks the values of a profile and its sub-profiles, and every
+// sub-profile it may resolve to. Their types were checked when it was decoded;
+// undecoded is set when that dropped some of them.
+func validateProfile(loc *configLocator, name string, profile *ProfileConfig, undecoded bool) []ValidationIssue {
+	var issues []ValidationIssue
+	addIssue := func(line int, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !profile.hasValues() && !undecoded {
+		addIssue(loc.line("profiles", name), "profile %q has no colors, theme, badge, title, or attention to apply", name)
+	}
+	issues = append(issues, validateProfileValues(loc, []string{"profiles", name}, profile)...)
+
+	for _, key := range profile.sortedSubProfileKeys() {
+		sub := profile.SubProfiles[key]
+		if isSubProfilePattern(key) {
+			if err := checkSubProfilePattern(key); err != nil {
+				addIssue(loc.line("profiles", name, key), "%v", err)
+			}
+		}
+		issues = append(issues, validateProfileValues(loc, []string{"profiles", name, key}, sub)...)
+		for _, nestedKey := range sub.sortedSubProfileKeys() {
+			issues = append(issues, validateProfileValues(loc, []string{"profiles", name, key, nestedKey}, sub.SubProfiles[nestedKey])...)
+		}
+	}
+
+	// Resolve the profile for every known shell/terminal/mode/root combination so errors that
+	// would only surface at apply time in a particular environment are caught now
+	if !profile.hasValues() {
+		return issues
+	}
+	shells := append([]ShellType{ShellTypeUnknown}, allShellTypes()...)
+	terminals := [][]TerminalType{nil}
+	for _, terminal := range allTerminalTypes() {
+		terminals = append(terminals, []TerminalType{terminal})
+	}
+	appearances := append([]Appearance{AppearanceUnknown}, knownAppearances...)
+	var infos []TerminalShellInfo
+	for _, shell := range shells {
+		for _, chain := range terminals {
+			for _, appearance := range appearances {
+				for _, root := range []bool{false, true} {
+					infos = append(infos, TerminalShellInfo{Terminals: chain, Shell: shell, Appearance: appearance, Root: root})
+				}
+			}
+		}
+	}
+	reported := map[string]bool{}
+	for i := range infos {
+		res, err := resolveProfile(name, profile, &infos[i])
+		if err != nil {
+			continue
+		}
+		for _, field := range []struct {
+			key, value string
+		}{
+			{"tab", res.Result.Tab},
+			{"fg", res.Result.Foreground},
+			{"bg", res.Result.Background},
+		} {
+			if field.value == "" || normalizeColor(field.value) != "" || isComputedColor(field.key, field.value) {
+				continue
+			}
+			id := res.sourceName(field.key) + "." + field.key
+			if reported[id] {
+				continue
+			}
+			reported[id] = true
+			path := []string{"profiles", name}
+			if key := res.Sources[field.key]; key != "" && isSubProfilePattern(key) {
+				path = append(path, key)
+			} else if key != "" {
+				path = append(path, strings.Split(key, ".")...)
+			}
+			addIssue(loc.line(append(path, field.key)...), "unknown color %q for %s", field.value, id)
+		}
+	}
+
+	return issues
+}
+
+// validateProfileValues reports profile values that name no preset, theme, or color
+func validateProfileValues(loc *configLocator, path []string, profile *ProfileConfig) []ValidationIssue {
+	var issues []ValidationIssue
+	prefix := strings.Join(path, ".")
+	addIssue := func(key []string, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{
+			Line:    loc.line(append(append([]string{}, path...), key...)...),
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if preset := profile.Preset; preset != nil && strings.TrimSpace(preset.Value) == "" {
+		addIssue([]string{"preset"}, "%s.preset must not be empty", prefix)
+	}
+	if theme := profile.Theme; theme != nil {
+		if _, err := lookupTheme(theme.Value); err != nil {
+			addIssue([]string{"theme"}, "%s.theme: %v", prefix, err)
+		}
+	}
+	for _, key := range sortedMapKeys(profile.Palette) {
+		value := profile.Palette[key]
+		switch {
+		case value == "default" && !canResetTarget(ColorTarget(key)):
+			addIssue([]string{"palette", key}, "%s.palette.%s cannot be \"default\"", prefix, key)
+		case normalizeColor(value) == "":
+			addIssue([]string{"palette", key}, "unknown color %q for %s.palette.%s", value, prefix, key)
+		}
+	}
+	return issues
+}
+
+// runConfigValidate validates every config file and prints any problems found.
+// With repeated -config flags each file is checked merged over the ones before it,
+// and a problem one file passes on to the next is only reported for the first.
+func runConfigValidate() error {
+	paths, err := getConfigPaths()
+	if err != nil {
+		return err
+	}
+
+	if len(configPaths) == 0 {
+		if _, err := os.Stat(paths[0]); os.IsNotExist(err) {
+			fmt.Printf("No config file at %s\n", paths[0])
+			return nil
+		}
+	}
+
+	reported := map[string]bool{}
+	var problems int
+	var problemFiles []string
+	for i, path := range paths {
+		issues, err := validateConfigLayer(paths[:i], path)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for _, issue := range issues {
+			if reported[issue.Message] {
+				continue
+			}
+			reported[issue.Message] = true
+			count++
+			if issue.Line > 0 {
+				fmt.Fprintf(os.Stderr, "%s:%d: %s\n", path, issue.Line, issue.Message)
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", path, issue.Message)
+			}
+		}
+		if count == 0 {
+			fmt.Printf("%s: OK\n", path)
+			continue
+		}
+		problems += count
+		problemFiles = append(problemFiles, path)
+	}
+
+	if problems > 0 {
+		return withExitCode(exitConfigError, fmt.Errorf("found %d problem(s) in %s", problems, strings.Join(problemFiles, ", ")))
+	}
+	return nil
+}
+
+// checkProfileNames reports a profile in a comma-separated profile list that doesn't exist
+func checkProfileNames(profiles map[string]*ProfileConfig, profileName string) error {
+	names, err := splitProfileNames(profileName)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, ok := profiles[name]; !ok {
+			return fmt.Errorf("profile %q not found", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidateConfigFile tests that config problems are reported with line numbers
+func TestValidateConfigFile(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "validate-config.toml")
+
+	configContent := `default_profile = "good,gone"
+
+[colors]
+corp = "#f26522"
+
+[profiles.good]
+tab = "corp"
+fg = "white"
+
+[profiles.good.iterm2]
+bg = "nonsense"
+
+[profiles.typed]
+tab = 42
+
+[profiles."sub.only".zsh]
+tab = "red"
+
+[profiles.alert]
+badge = "ALERT"
+attention = "loud"
+
+[watch]
+default = "missing"
+rules = [{ profile = "good" }]
+
+[detect.terminals]
+kitty = "Kitty"
+
+[profiles.good."ssh|[mosh"]
+tab = "red"
+
+[profiles.good.ssh.zsh]
+fg = "nonsense"
+
+[profiles.cond]
+tab = { value = "red", when = "ssh" }
+fg = { value = "white", unless_terminal = "vscode" }
+badge = "{{ vars.nope }}"
+`
+
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer setCustomColors(nil)
+	defer setDetectRules(DetectConfig{})
+
+	issues, err := validateConfigFile(configFile)
+	if err != nil {
+		t.Fatalf("validateConfigFile() failed: %v", err)
+	}
+
+	expected := []ValidationIssue{
+		{Line: 1, Message: `default_profile profile "gone" not found`},
+		{Line: 11, Message: `unknown color "nonsense" for profiles.good.iterm2.bg`},
+		{Line: 14, Message: "profiles.typed.tab must be a string, got int64"},
+		{Line: 16, Message: `profile "sub.only" has no colors, theme, badge, title, or attention to apply`},
+		{Line: 21, Message: `profiles.alert.attention must be true, false, "bounce", or "fireworks", got loud`},
+		{Line: 24, Message: `watch default profile "missing" not found`},
+		{Line: 25, Message: "watch rule 1: rule must set dir or kube_context"},
+		{Line: 28, Message: `terminals."kitty": invalid sub-profile key "Kitty" (use lowercase letters, digits, - and _)`},
+		{Line: 30, Message: `sub-profile key "ssh|[mosh" has an invalid pattern "[mosh"`},
+		{Line: 34, Message: `unknown color "nonsense" for profiles.good.ssh.zsh.fg`},
+		{Line: 37, Message: `profiles.cond.tab: unknown condition "when" (expected one of: terminal, unless_terminal, shell, unless_shell, mode, unless_mode)`},
+		{Line: 39, Message: `profiles.cond.badge: unknown template variable "vars.nope"`},
+	}
+
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %v", len(expected), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue != expected[i] {
+			t.Errorf("Issue %d = %+v, expected %+v", i, issue, expected[i])
+		}
+	}
+}
+
+// TestValidateConfigFileValid tests that the example config validates cleanly
+func TestValidateConfigFileValid(t *testing.T) {
+	defer setCustomColors(nil)
+
+	issues, err := validateConfigFile("example-config.toml")
+	if err != nil {
+		t.Fatalf("validateConfigFile() failed: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("Expected example config to be valid, got issues: %v", issues)
+	}
+}
+
+// TestValidateConfigFileParseError tests that TOML syntax errors are reported
+func TestValidateConfigFileParseError(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "broken.toml")
+	if err := os.WriteFile(configFile, []byte("[profiles.broken\ntab = \"red\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	issues, err := validateConfigFile(configFile)
+	if err != nil {
+		t.Fatalf("validateConfigFile() failed: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Line != 2 || !contains(issues[0].Message, "table name") {
+		t.Errorf("Expected a single table name parse error on line 2, got %v", issues)
+	}
+}
+
+// TestValidateConfigFileKeepsSettings tests that validating a config leaves the
+// loaded config's colors, detection rules, and redactions in effect
+func TestValidateConfigFileKeepsSettings(t *testing.T) {
+	loaded := filepath.Join(t.TempDir(), "loaded.toml")
+	if err := os.WriteFile(loaded, []byte(`[colors]
+corp = "#f26522"
+
+[redact]
+patterns = ["*.internal"]
+
+[profiles.prod]
+tab = "corp"
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SET_TAB_COLOR_CONFIG", loaded)
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	defer func() {
+		os.Setenv("SET_TAB_COLOR_CONFIG", filepath.Join(t.TempDir(), "missing.toml"))
+		loadConfig()
+	}()
+
+	other := filepath.Join(t.TempDir(), "other.toml")
+	if err := os.WriteFile(other, []byte(`color_names = "x11"
+
+[colors]
+corp = "#000000"
+
+[detect.terminals]
+foot = "foot"
+
+[colorblind]
+type = "protanopia"
+
+[redact]
+patterns = ["*", "*.example"]
+
+[profiles.prod]
+tab = "corp"
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := validateConfigFile(other); err != nil {
+		t.Fatalf("validateConfigFile() failed: %v", err)
+	}
+
+	if customColors["corp"] != "f26522" {
+		t.Errorf("Expected the loaded custom color to stay, got %q", customColors["corp"])
+	}
+	if len(customTerminalRules) != 0 || colorblindMode.Type != "" {
+		t.Errorf("Expected no detection rules or colorblind mode, got %v and %+v", customTerminalRules, colorblindMode)
+	}
+	if redacted := redact("host db.internal"); redacted == "host db.internal" {
+		t.Errorf("Expected the loaded redactions to stay in effect, got %q", redacted)
+	}
+}
+
+// TestRunConfigValidateLayers tests validating every -config file, each merged over
+// the ones before it
+func TestRunConfigValidateLayers(t *testing.T) {
+	tempDir := t.TempDir()
+	team := filepath.Join(tempDir, "team.toml")
+	personal := filepath.Join(tempDir, "personal.toml")
+	if err := os.WriteFile(team, []byte(`[colors]
+corp = "#0055aa"
+
+[profiles.prod]
+tab = "red"
+badge_text = "PROD"
+`), 0644); err != nil {
+		t.Fatalf("Failed to write team config: %v", err)
+	}
+	if err := os.WriteFile(personal, []byte(`[profiles.me]
+tab = "corp"
+
+[profiles.prod]
+fg = "white"
+
+[profiles.dev]
+tab = "notacolor"
+`), 0644); err != nil {
+		t.Fatalf("Failed to write personal config: %v", err)
+	}
+
+	// The personal file may use the team's colors; the team's problem is only its own
+	issues, err := validateConfigLayer([]string{team}, personal)
+	if err != nil {
+		t.Fatalf("validateConfigLayer() failed: %v", err)
+	}
+	var found bool
+	for _, issue := range issues {
+		if strings.Contains(issue.Message, "corp") {
+			t.Errorf("Unexpected issue for the team color: %v", issue)
+		}
+		if strings.Contains(issue.Message, `"notacolor"`) && issue.Line == 8 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the unknown color on line 8 of the personal file, got %v", issues)
+	}
+
+	defer func() { configPaths = nil }()
+	configPaths = configPathList{team, personal}
+	err = runConfigValidate()
+	if err == nil || exitCode(err) != exitConfigError || !strings.Contains(err.Error(), "found 2 problem(s) in "+team+", "+personal) {
+		t.Errorf("Expected one problem in each file, got %v", err)
+	}
+}
+
+// TestSplitTOMLKey tests splitting dotted keys with quoted segments
+func TestSplitTOMLKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"profiles.dev", []string{"profiles", "dev"}},
+		{"profiles . dev . zsh", []string{"profiles", "dev", "zsh"}},
+		{`profiles."a.b".iterm2`, []string{"profiles", "a.b", "iterm2"}},
+		{"tab ", []string{"tab"}},
+	}
+
+	for _, test := range tests {
+		result := splitTOMLKey(test.input)
+		if len(result) != len(test.expected) {
+			t.Errorf("splitTOMLKey(%q) = %v, expected %v", test.input, result, test.expected)
+			continue
+		}
+		for i := range result {
+			if result[i] != test.expected[i] {
+				t.Errorf("splitTOMLKey(%q) = %v, expected %v", test.input, result, test.expected)
+				break
+			}
+		}
+	}
+}
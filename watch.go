@@ -0,0 +1,558 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// watchPollInterval is how often the watcher checks whether its shell is still
+// running, and the config and kubeconfig files when they can't be watched
+const watchPollInterval = time.Second
+
+// WatchConfig is the [watch] table: the rules that pick a profile for the
+// current directory and Kubernetes context
+type WatchConfig struct {
+	Default string      `toml:"default"` // Profile applied when no rule matches
+	Rules   []WatchRule `toml:"rules"`
+}
+
+// WatchRule selects a profile when all of its conditions match. At least one of
+// Dir and KubeContext must be set. Title, Badge, and TmuxSync are applied together
+// with the profile, overriding its own title and badge.
+type WatchRule struct {
+	Dir         string `toml:"dir"`          // Glob matched against the directory and its parents; ~ is expanded
+	KubeContext string `toml:"kube_context"` // Glob matched against the current kubectl context
+	Profile     string `toml:"profile"`
+	Priority    int    `toml:"priority"`  // Higher wins when several rules match
+	Title       string `toml:"title"`     // Template with {{ dir }}, {{ dir_name }}, {{ kube_context }}, and {{ profile }}
+	Badge       string `toml:"badge"`     // Template like Title
+	TmuxSync    bool   `toml:"tmux_sync"` // Style the tmux status line and pane borders even without the [tmux] table
+}
+
+// watchTemplateNames are the variables a rule's title and badge may use
+var watchTemplateNames = []string{"dir", "dir_name", "kube_context", "profile"}
+
+// check reports a rule without conditions or a profile
+func (r WatchRule) check() error {
+	if r.Dir == "" && r.KubeContext == "" {
+		return fmt.Errorf("rule must set dir or kube_context")
+	}
+	if r.Profile == "" {
+		return fmt.Errorf("rule has no profile")
+	}
+	if _, err := path.Match(r.KubeContext, ""); err != nil {
+		return fmt.Errorf("invalid kube_context pattern %q: %v", r.KubeContext, err)
+	}
+	if _, err := filepath.Match(r.Dir, ""); err != nil {
+		return fmt.Errorf("invalid dir pattern %q: %v", r.Dir, err)
+	}
+	for _, template := range []string{r.Title, r.Badge} {
+		if _, err := expandWatchTemplate(template, map[string]string{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// followsDir reports whether the rule's title or badge change with the directory
+func (r WatchRule) followsDir() bool {
+	for _, match := range templatePattern.FindAllStringSubmatch(r.Title+r.Badge, -1) {
+		if match[1] == "dir" || match[1] == "dir_name" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyActions adds the rule's title, badge, and tmux styling to the profile it selected
+func (r WatchRule) applyActions(profile *Profile, dir, kubeContext string) error {
+	values := map[string]string{
+		"dir":          abbreviateHome(dir),
+		"dir_name":     filepath.Base(dir),
+		"kube_context": kubeContext,
+		"profile":      r.Profile,
+	}
+	var err error
+	if r.Title != "" {
+		if profile.Title, err = expandWatchTemplate(r.Title, values); err != nil {
+			return err
+		}
+	}
+	if r.Badge != "" {
+		if profile.Badge, err = expandWatchTemplate(r.Badge, values); err != nil {
+			return err
+		}
+	}
+	if r.TmuxSync {
+		tmuxChrome = TmuxConfig{Status: true, PaneBorders: true}
+	}
+	return nil
+}
+
+// expandWatchTemplate fills in the {{ name }} references of a rule's title or badge.
+// Names are checked even where values is empty, so check() can validate templates.
+func expandWatchTemplate(template string, values map[string]string) (string, error) {
+	var err error
+	expanded := templatePattern.ReplaceAllStringFunc(template, func(ref string) string {
+		name := templatePattern.FindStringSubmatch(ref)[1]
+		if !slices.Contains(watchTemplateNames, name) {
+			if err == nil {
+				err = fmt.Errorf("unknown template variable %q in %q (known: %s)", name, template, strings.Join(watchTemplateNames, ", "))
+			}
+			return ref
+		}
+		return values[name]
+	})
+	return expanded, err
+}
+
+// matches reports whether the rule applies to a directory and kubectl context
+func (r WatchRule) matches(dir, kubeContext string) bool {
+	_, ok := r.match(dir, kubeContext)
+	return ok
+}
+
+// match reports whether the rule applies to a directory and kubectl context, and
+// how deep the directory its dir pattern matched is. A dir pattern also matches
+// every subdirectory of a matching directory.
+func (r WatchRule) match(dir, kubeContext string) (depth int, ok bool) {
+	if r.KubeContext != "" {
+		if ok, _ := path.Match(r.KubeContext, kubeContext); !ok {
+			return 0, false
+		}
+	}
+	if r.Dir != "" {
+		pattern := expandHome(r.Dir)
+		for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+			if ok, _ := filepath.Match(pattern, d); ok {
+				return strings.Count(d, string(filepath.Separator)), true
+			}
+			if d == filepath.Dir(d) {
+				return 0, false
+			}
+		}
+	}
+	return 0, true
+}
+
+// conditions counts the conditions a rule sets
+func (r WatchRule) conditions() int {
+	count := 0
+	for _, condition := range []string{r.Dir, r.KubeContext} {
+		if condition != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// watchRuleMatch is how one rule fared against a directory and kubectl context
+type watchRuleMatch struct {
+	Rule     WatchRule
+	Matched  bool
+	Depth    int // Depth of the directory the dir pattern matched
+	Selected bool
+}
+
+// outranks reports whether a matching rule wins over another: the higher priority
+// wins, then the rule with more conditions, then of two dir rules the one that
+// matched a deeper directory. Otherwise the rule listed first wins.
+func (m watchRuleMatch) outranks(other watchRuleMatch) bool {
+	if m.Rule.Priority != other.Rule.Priority {
+		return m.Rule.Priority > other.Rule.Priority
+	}
+	if m.Rule.conditions() != other.Rule.conditions() {
+		return m.Rule.conditions() > other.Rule.conditions()
+	}
+	return m.Rule.Dir != "" && other.Rule.Dir != "" && m.Depth > other.Depth
+}
+
+// rankWatchRules matches every rule and marks the one that wins
+func rankWatchRules(config WatchConfig, dir, kubeContext string) []watchRuleMatch {
+	matches := make([]watchRuleMatch, len(config.Rules))
+	selected := -1
+	for i, rule := range config.Rules {
+		depth, ok := rule.match(dir, kubeContext)
+		matches[i] = watchRuleMatch{Rule: rule, Matched: ok, Depth: depth}
+		if ok && (selected < 0 || matches[i].outranks(matches[selected])) {
+			selected = i
+		}
+	}
+	if selected >= 0 {
+		matches[selected].Selected = true
+	}
+	return matches
+}
+
+// selectWatchProfile returns the profile of the winning rule, or the default
+func selectWatchProfile(config WatchConfig, dir, kubeContext string) string {
+	if rule := matchWatchRule(config, dir, kubeContext); rule != nil {
+		return rule.Profile
+	}
+	return config.Default
+}
+
+// matchWatchRule returns the winning rule, or nil if none matches
+func matchWatchRule(config WatchConfig, dir, kubeContext string) *WatchRule {
+	for _, match := range rankWatchRules(config, dir, kubeContext) {
+		if match.Selected {
+			return &match.Rule
+		}
+	}
+	return nil
+}
+
+// abbreviateHome replaces the home directory at the start of a path with ~
+func abbreviateHome(p string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return p
+	}
+	if p == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(p, home+string(filepath.Separator)); ok {
+		return "~/" + rest
+	}
+	return p
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, p[1:])
+}
+
+// getKubeconfigPath returns the kubeconfig kubectl reads its current context from
+func getKubeconfigPath() string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)[0]
+	}
+	return expandHome("~/.kube/config")
+}
+
+// readKubeContext returns the current-context of a kubeconfig file, or "" if it
+// can't be read. Only the top-level key is parsed, so kubectl isn't needed.
+func readKubeContext(kubeconfig string) string {
+	f, err := os.Open(kubeconfig)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "current-context:"); ok {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
+
+// getWatchSocketPath returns the socket the shell hook uses to reach this session's watcher
+func getWatchSocketPath() (string, error) {
+	sessionsDir, err := getSessionsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(sessionsDir, sanitizeFileName(getSessionKey())+".sock"), nil
+}
+
+// modTime returns a file's modification time, or the zero time if it doesn't exist
+func modTime(p string) time.Time {
+	info, err := os.Stat(p)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// latestModTime returns the newest modification time of several files
+func latestModTime(paths []string) time.Time {
+	var latest time.Time
+	for _, p := range paths {
+		if mod := modTime(p); mod.After(latest) {
+			latest = mod
+		}
+	}
+	return latest
+}
+
+// watchFileDirs watches the directories holding some files, and the directories
+// of the files symlinks point to. Watching the directory rather than the file sees
+// editors that save by renaming a new file over the old one.
+func watchFileDirs(paths []string) (*fsnotify.Watcher, []string, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []string
+	for _, p := range paths {
+		files = append(files, filepath.Clean(p))
+		if target, err := filepath.EvalSymlinks(p); err == nil && filepath.Clean(target) != filepath.Clean(p) {
+			files = append(files, filepath.Clean(target))
+		}
+	}
+	for _, file := range files {
+		if dir := filepath.Dir(file); !slices.Contains(fw.WatchList(), dir) {
+			if err := fw.Add(dir); err != nil {
+				fw.Close()
+				return nil, nil, fmt.Errorf("could not watch %s: %v", dir, err)
+			}
+		}
+	}
+	return fw, files, nil
+}
+
+// watcher tracks the inputs that select a profile and what was last applied
+type watcher struct {
+	terminalType string
+	dir          string
+	kubeContext  string
+	applied      string             // Profile name last applied, "" before the first apply
+	values       string             // Resolved values of the applied profile, as formatProfileValues renders them
+	recent       []watchEvent       // The last watchEventHistory events, for new subscribers
+	subscribers  []*eventSubscriber // Connections following the events
+}
+
+// reapply applies the profile selected for the current inputs, with the actions of
+// the rule that selected it. Nothing is emitted when the selected profile hasn't
+// changed, unless the rule's title or badge follow the directory; after a config
+// change (reload), it is resolved again and applied only if its values changed, so
+// editing other profiles leaves the terminal alone.
+func (w *watcher) reapply(reload bool) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	rule := matchWatchRule(config.Watch, w.dir, w.kubeContext)
+	name := config.Watch.Default
+	if rule != nil {
+		name = rule.Profile
+	}
+	if name == "" || (name == w.applied && !reload && (rule == nil || !rule.followsDir())) {
+		return nil
+	}
+	terminalInfo := detectTerminalAndShell(w.terminalType)
+	profile, err := getProfileWithTerminalInfo(name, &terminalInfo)
+	if err != nil {
+		return err
+	}
+	if rule != nil {
+		if err := rule.applyActions(profile, w.dir, w.kubeContext); err != nil {
+			return err
+		}
+	}
+	values := formatProfileValues(profile)
+	if rule != nil && rule.TmuxSync {
+		values += " tmux_sync"
+	}
+	if name == w.applied && values == w.values {
+		debugf("Watch: profile %q is unchanged\n", redact(name))
+		return nil
+	}
+	debugf("Watch: applying profile %q (dir=%s, kube context=%q)\n", redact(name), redact(w.dir), redact(w.kubeContext))
+	if rule != nil {
+		w.emit(matchedEvent(*rule))
+	}
+	if err := applyResolvedProfile(name, terminalInfo, profile); err != nil {
+		return err
+	}
+	w.applied, w.values = name, values
+	w.emit(appliedEvent(name, profile))
+	return nil
+}
+
+// applyNamed resolves and applies a profile for the watcher's terminal
+func (w *watcher) applyNamed(name string) error {
+	terminalInfo := detectTerminalAndShell(w.terminalType)
+	profile, err := getProfileWithTerminalInfo(name, &terminalInfo)
+	if err != nil {
+		return err
+	}
+	if err := applyResolvedProfile(name, terminalInfo, profile); err != nil {
+		return err
+	}
+	w.emit(appliedEvent(name, profile))
+	return nil
+}
+
+// applyResolvedProfile applies a resolved profile to the terminal it was resolved for
+func applyResolvedProfile(name string, terminalInfo TerminalShellInfo, profile *Profile) error {
+	if err := checkProfileLock(name); err != nil {
+		return err
+	}
+	useEscapeBackendFor(terminalInfo)
+	if err := resolveDistinctTab(profile); err != nil {
+		return err
+	}
+	if err := resolveAutoForeground(profile); err != nil {
+		return err
+	}
+	if err := applyProfile(profile); err != nil {
+		return err
+	}
+	recordAppliedState(name, profile)
+	return nil
+}
+
+// runWatch stays resident, reapplying the matching [watch] profile when the shell
+// reports a new directory, a config change alters its values, or the kubectl context
+// changes. Clients can also send it requests over its socket (see runClient). It
+// exits with its parent shell.
+func runWatch(terminalType string) error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+
+	configPaths, err := getConfigPaths()
+	if err != nil {
+		return err
+	}
+	kubeconfig := getKubeconfigPath()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	w := &watcher{terminalType: terminalType, dir: cwd, kubeContext: readKubeContext(kubeconfig)}
+
+	socketPath, err := getWatchSocketPath()
+	if err != nil {
+		return err
+	}
+	listener, err := listenWatchSocket(socketPath)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	if tty := ttyName(); tty != "" {
+		if err := linkTTYSocket(tty, socketPath); err != nil {
+			debugf("Could not register %s for the client command: %v\n", tty, err)
+		}
+		defer removeTTYSocket(tty, socketPath)
+	}
+
+	requests := make(chan watchRequest)
+	go acceptWatchRequests(listener, requests)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	report := func(err error) {
+		if err != nil {
+			errorf("watch: %v", err)
+			w.emitError(err)
+		}
+	}
+	report(w.reapply(true))
+
+	// The files are checked when their directories change, or on every tick if a
+	// directory can't be watched, such as a ~/.kube that doesn't exist yet. An
+	// unchanged modification time filters out changes to other files.
+	configMod, kubeMod := latestModTime(configPaths), modTime(kubeconfig)
+	checkFiles := func() {
+		if mod := latestModTime(configPaths); !mod.Equal(configMod) {
+			configMod = mod
+			report(w.reapply(true))
+		}
+		if mod := modTime(kubeconfig); !mod.Equal(kubeMod) {
+			kubeMod = mod
+			if context := readKubeContext(kubeconfig); context != w.kubeContext {
+				w.kubeContext = context
+				report(w.reapply(false))
+			}
+		}
+	}
+
+	var fileEvents <-chan fsnotify.Event
+	var fileErrors <-chan error
+	fw, files, err := watchFileDirs(append(slices.Clone(configPaths), kubeconfig))
+	poll := err != nil
+	if poll {
+		debugf("Watch: %v; checking the config files every %v instead\n", err, watchPollInterval)
+	} else {
+		defer fw.Close()
+		fileEvents, fileErrors = fw.Events, fw.Errors
+	}
+
+	shellPID := int32(os.Getppid())
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case req := <-requests:
+			req.reply <- w.serve(req)
+		case event := <-fileEvents:
+			if slices.Contains(files, filepath.Clean(event.Name)) {
+				checkFiles()
+			}
+		case err := <-fileErrors:
+			debugf("Watch: %v\n", err)
+		case <-ticker.C:
+			if alive, err := process.PidExists(shellPID); err == nil && !alive {
+				return nil
+			}
+			if poll {
+				checkFiles()
+			}
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// listenWatchSocket creates the session's socket, replacing one left by a watcher
+// that didn't exit cleanly
+func listenWatchSocket(socketPath string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a watcher is already running for this session (%s)", socketPath)
+	}
+	os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on %s: %v", socketPath, err)
+	}
+	return listener, nil
+}
+
+// runWatchCwd tells this session's watcher about a new working directory.
+// Shell hooks call it on every directory change.
+func runWatchCwd(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	socketPath, err := getWatchSocketPath()
+	if err != nil {
+		return err
+	}
+	return sendWatchRequest(socketPath, "cwd", dir)
+}
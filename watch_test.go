@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWatchRuleMatches tests directory and kubectl context matching
+func TestWatchRuleMatches(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	tests := []struct {
+		name        string
+		rule        WatchRule
+		dir, kube   string
+		expectMatch bool
+	}{
+		{"exact dir", WatchRule{Dir: "/srv/prod"}, "/srv/prod", "", true},
+		{"subdirectory", WatchRule{Dir: "/srv/prod"}, "/srv/prod/api/src", "", true},
+		{"sibling", WatchRule{Dir: "/srv/prod"}, "/srv/production", "", false},
+		{"glob", WatchRule{Dir: "/srv/*-prod"}, "/srv/api-prod/logs", "", true},
+		{"home", WatchRule{Dir: "~/work"}, filepath.Join(home, "work", "repo"), "", true},
+		{"kube context", WatchRule{KubeContext: "prod-*"}, "/tmp", "prod-eu", true},
+		{"other kube context", WatchRule{KubeContext: "prod-*"}, "/tmp", "staging", false},
+		{"both conditions", WatchRule{Dir: "/srv", KubeContext: "prod"}, "/srv/x", "prod", true},
+		{"one condition fails", WatchRule{Dir: "/srv", KubeContext: "prod"}, "/home", "prod", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if matched := test.rule.matches(test.dir, test.kube); matched != test.expectMatch {
+				t.Errorf("matches(%q, %q) = %v, expected %v", test.dir, test.kube, matched, test.expectMatch)
+			}
+		})
+	}
+}
+
+// TestSelectWatchProfile tests that the first of equally specific rules wins over the default
+func TestSelectWatchProfile(t *testing.T) {
+	config := WatchConfig{
+		Default: "dev",
+		Rules: []WatchRule{
+			{KubeContext: "prod*", Profile: "prod-kube"},
+			{Dir: "/srv/prod", Profile: "prod"},
+		},
+	}
+
+	if name := selectWatchProfile(config, "/srv/prod", "prod-us"); name != "prod-kube" {
+		t.Errorf("Expected the first matching rule, got %q", name)
+	}
+	if name := selectWatchProfile(config, "/srv/prod", ""); name != "prod" {
+		t.Errorf("Expected the dir rule, got %q", name)
+	}
+	if name := selectWatchProfile(config, "/home", ""); name != "dev" {
+		t.Errorf("Expected the default profile, got %q", name)
+	}
+}
+
+// TestSelectWatchProfileRanking tests choosing between matching rules by priority,
+// then by specificity
+func TestSelectWatchProfileRanking(t *testing.T) {
+	config := WatchConfig{
+		Rules: []WatchRule{
+			{Dir: "/srv", Profile: "srv"},
+			{Dir: "/srv/prod", Profile: "prod"},
+			{Dir: "/srv/prod", KubeContext: "staging", Profile: "prod-staging"},
+			{KubeContext: "admin", Profile: "admin", Priority: 10},
+		},
+	}
+
+	tests := []struct {
+		dir, kube string
+		expected  string
+	}{
+		{"/srv/api", "", "srv"},
+		{"/srv/prod/api", "", "prod"},                // The deeper directory wins
+		{"/srv/prod/api", "staging", "prod-staging"}, // More conditions win
+		{"/srv/prod/api", "admin", "admin"},          // The higher priority wins
+		{"/home", "admin", "admin"},
+	}
+	for _, test := range tests {
+		if name := selectWatchProfile(config, test.dir, test.kube); name != test.expected {
+			t.Errorf("selectWatchProfile(%q, %q) = %q, expected %q", test.dir, test.kube, name, test.expected)
+		}
+	}
+}
+
+// TestWatchRuleCheck tests rejecting incomplete rules
+func TestWatchRuleCheck(t *testing.T) {
+	if err := (WatchRule{Dir: "/srv", Profile: "prod"}).check(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, rule := range []WatchRule{
+		{Profile: "prod"},
+		{Dir: "/srv"},
+		{KubeContext: "[", Profile: "prod"},
+		{Dir: "/srv", Profile: "prod", Title: "{{ cluster }}"},
+	} {
+		if err := rule.check(); err == nil {
+			t.Errorf("Expected error for %+v", rule)
+		}
+	}
+}
+
+// TestReadKubeContext tests reading current-context from a kubeconfig
+func TestReadKubeContext(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	content := "apiVersion: v1\nclusters: []\ncurrent-context: \"prod-eu\"\nkind: Config\n"
+	if err := os.WriteFile(kubeconfig, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+
+	if context := readKubeContext(kubeconfig); context != "prod-eu" {
+		t.Errorf("Expected prod-eu, got %q", context)
+	}
+	if context := readKubeContext(filepath.Join(t.TempDir(), "missing")); context != "" {
+		t.Errorf("Expected no context for a missing file, got %q", context)
+	}
+}
+
+// TestWatchFileDirs tests that saving a file by renaming a new one over it, and
+// editing the target of a symlinked file, are both seen
+func TestWatchFileDirs(t *testing.T) {
+	configDir, dotfiles := t.TempDir(), t.TempDir()
+	configFile := filepath.Join(configDir, "config.toml")
+	target := filepath.Join(dotfiles, "kubeconfig")
+	kubeconfig := filepath.Join(configDir, "kubeconfig")
+	for _, file := range []string{configFile, target} {
+		if err := os.WriteFile(file, []byte("# old\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+	if err := os.Symlink(target, kubeconfig); err != nil {
+		t.Fatalf("Failed to link kubeconfig: %v", err)
+	}
+
+	fw, files, err := watchFileDirs([]string{configFile, kubeconfig})
+	if err != nil {
+		t.Fatalf("watchFileDirs() failed: %v", err)
+	}
+	defer fw.Close()
+
+	next := func(file string) {
+		t.Helper()
+		for {
+			select {
+			case event := <-fw.Events:
+				if filepath.Clean(event.Name) == filepath.Clean(file) && slices.Contains(files, filepath.Clean(event.Name)) {
+					return
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Timed out waiting for a change to %s", file)
+			}
+		}
+	}
+
+	temp := filepath.Join(configDir, ".config.toml.swp")
+	if err := os.WriteFile(temp, []byte("# new\n"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", temp, err)
+	}
+	if err := os.Rename(temp, configFile); err != nil {
+		t.Fatalf("Failed to rename over the config: %v", err)
+	}
+	next(configFile)
+
+	if err := os.WriteFile(target, []byte("# new\n"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", target, err)
+	}
+	resolved, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		t.Fatalf("Failed to resolve %s: %v", target, err)
+	}
+	next(resolved)
+
+	if _, _, err := watchFileDirs([]string{filepath.Join(configDir, "missing", "config")}); err == nil {
+		t.Error("Expected an error for a directory that doesn't exist")
+	}
+}
+
+// TestWatchSocket tests delivering directory changes from the shell hook to the watcher
+func TestWatchSocket(t *testing.T) {
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", t.TempDir())
+	defer os.Setenv("XDG_STATE_HOME", originalState)
+
+	if err := runWatchCwd("/tmp"); err == nil {
+		t.Error("Expected error without a running watcher")
+	}
+
+	socketPath, err := getWatchSocketPath()
+	if err != nil {
+		t.Fatalf("getWatchSocketPath() failed: %v", err)
+	}
+	listener, err := listenWatchSocket(socketPath)
+	if err != nil {
+		t.Fatalf("listenWatchSocket() failed: %v", err)
+	}
+	defer listener.Close()
+
+	if _, err := listenWatchSocket(socketPath); err == nil {
+		t.Error("Expected error starting a second watcher for the session")
+	}
+
+	requests := make(chan watchRequest)
+	go acceptWatchRequests(listener, requests)
+
+	errs := make(chan error, 1)
+	go func() { errs <- runWatchCwd("/srv/prod") }()
+	select {
+	case req := <-requests:
+		if len(req.args) != 2 || req.args[0] != "cwd" || req.args[1] != "/srv/prod" {
+			t.Errorf("Unexpected request %q", req.args)
+		}
+		req.reply <- nil
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the directory update")
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("runWatchCwd() failed: %v", err)
+	}
+}
+
+// TestWatcherReapply tests that a profile is only reapplied when the selection changes
+func TestWatcherReapply(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+	}()
+
+	content := `[watch]
+default = "dev"
+rules = [{ dir = "/srv/prod", profile = "prod" }]
+
+[profiles.dev]
+tab = "blue"
+
+[profiles.prod]
+tab = "red"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	w := &watcher{terminalType: "iterm2", dir: "/home"}
+	if err := w.reapply(false); err != nil {
+		t.Fatalf("reapply() failed: %v", err)
+	}
+	if w.applied != "dev" || !strings.Contains(buf.String(), "6;1;bg;blue;brightness;255") {
+		t.Fatalf("Expected the default profile, got %q: %q", w.applied, buf.String())
+	}
+
+	buf.Reset()
+	w.dir = "/home/other"
+	if err := w.reapply(false); err != nil {
+		t.Fatalf("reapply() failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing emitted for an unchanged profile, got %q", buf.String())
+	}
+
+	w.dir = "/srv/prod/api"
+	if err := w.reapply(false); err != nil {
+		t.Fatalf("reapply() failed: %v", err)
+	}
+	if w.applied != "prod" || !strings.Contains(buf.String(), "6;1;bg;red;brightness;255") {
+		t.Errorf("Expected the prod profile, got %q: %q", w.applied, buf.String())
+	}
+
+	// Editing another profile leaves the terminal alone; editing prod reapplies it
+	for _, test := range []struct {
+		old, new string
+		applied  bool
+	}{
+		{`tab = "blue"`, `tab = "navy"`, false},
+		{`tab = "red"`, `tab = "crimson"`, true},
+	} {
+		content = strings.Replace(content, test.old, test.new, 1)
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to update test config file: %v", err)
+		}
+		buf.Reset()
+		if err := w.reapply(true); err != nil {
+			t.Fatalf("reapply() failed: %v", err)
+		}
+		if applied := buf.Len() != 0; applied != test.applied {
+			t.Errorf("After changing %s to %s: expected applied=%v, got %q", test.old, test.new, test.applied, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "tab dc143c") {
+		t.Errorf("Expected the edited prod profile, got %q", buf.String())
+	}
+}
+
+// TestWatcherRuleActions tests applying a rule's title and badge with its profile
+func TestWatcherRuleActions(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.toml")
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+	}()
+
+	content := `[watch]
+rules = [{ dir = "/srv/prod", profile = "prod", title = "{{ profile }}: {{ dir_name }}", badge = "{{ kube_context }}" }]
+
+[profiles.prod]
+tab = "red"
+title = "production"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	w := &watcher{terminalType: "iterm2", dir: "/srv/prod/api", kubeContext: "prod-eu"}
+	if err := w.reapply(false); err != nil {
+		t.Fatalf("reapply() failed: %v", err)
+	}
+	for _, expected := range []string{"6;1;bg;red;brightness;255", `title "prod: api"`, "SetBadgeFormat=" + base64.StdEncoding.EncodeToString([]byte("prod-eu"))} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in %q", expected, buf.String())
+		}
+	}
+
+	// The title follows the directory while the profile stays the same
+	buf.Reset()
+	w.dir = "/srv/prod/web"
+	if err := w.reapply(false); err != nil {
+		t.Fatalf("reapply() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `title "prod: web"`) {
+		t.Errorf("Expected the title for the new directory, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := w.reapply(true); err != nil {
+		t.Fatalf("reapply() failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing emitted for unchanged values, got %q", buf.String())
+	}
+}
+
+// TestExpandWatchTemplate tests filling in a rule's title and badge templates
+func TestExpandWatchTemplate(t *testing.T) {
+	values := map[string]string{"dir": "~/src", "dir_name": "src", "kube_context": "prod-eu", "profile": "prod"}
+	if expanded, err := expandWatchTemplate("{{profile}} @ {{ kube_context }} in {{ dir }}", values); err != nil || expanded != "prod @ prod-eu in ~/src" {
+		t.Errorf("Unexpected expansion %q, %v", expanded, err)
+	}
+	if _, err := expandWatchTemplate("{{ cluster }}", values); err == nil || !strings.Contains(err.Error(), `unknown template variable "cluster"`) {
+		t.Errorf("Expected an unknown variable error, got %v", err)
+	}
+	if !(WatchRule{Title: "{{ dir_name }}"}).followsDir() || (WatchRule{Badge: "{{ profile }}"}).followsDir() {
+		t.Error("Expected only templates using the directory to follow it")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WorkspaceConfig is a named set of layouts from the [workspaces] table
+type WorkspaceConfig struct {
+	Layouts []WorkspaceLayout `toml:"layouts"`
+}
+
+// WorkspaceLayout maps one iTerm2 session or tmux window to a profile. Exactly one
+// of Title and TmuxWindow must be set.
+type WorkspaceLayout struct {
+	Title      string `toml:"title"`       // Glob matched against iTerm2 session names
+	TmuxWindow string `toml:"tmux_window"` // tmux target window, e.g. "work:logs"
+	Profile    string `toml:"profile"`
+}
+
+// describe returns a short label for the layout's target
+func (l WorkspaceLayout) describe() string {
+	if l.TmuxWindow != "" {
+		return "tmux window " + l.TmuxWindow
+	}
+	return fmt.Sprintf("iTerm2 sessions matching %q", l.Title)
+}
+
+// check reports a layout that doesn't name exactly one target and a profile
+func (l WorkspaceLayout) check() error {
+	if (l.Title == "") == (l.TmuxWindow == "") {
+		return fmt.Errorf("layout must set exactly one of title and tmux_window")
+	}
+	if l.Profile == "" {
+		return fmt.Errorf("layout for %s has no profile", l.describe())
+	}
+	return nil
+}
+
+// overlaps reports whether two layouts may target the same window or session. Two
+// titles can only be told apart when neither is a glob.
+func (l WorkspaceLayout) overlaps(other WorkspaceLayout) bool {
+	switch {
+	case l.TmuxWindow != "" || other.TmuxWindow != "":
+		return l.TmuxWindow == other.TmuxWindow
+	case strings.ContainsAny(l.Title, "*?[") || strings.ContainsAny(other.Title, "*?["):
+		return true
+	default:
+		return l.Title == other.Title
+	}
+}
+
+// workspaceLayoutChains groups the indexes of layouts that overlap, directly or
+// through another layout, each group in layout order
+func workspaceLayoutChains(layouts []WorkspaceLayout) [][]int {
+	var chains [][]int
+	for i, layout := range layouts {
+		chain := []int{i}
+		kept := chains[:0]
+		for _, other := range chains {
+			overlapping := false
+			for _, j := range other {
+				overlapping = overlapping || layout.overlaps(layouts[j])
+			}
+			if overlapping {
+				chain = append(other, chain...)
+			} else {
+				kept = append(kept, other)
+			}
+		}
+		chains = append(kept, chain)
+	}
+	for _, chain := range chains {
+		sort.Ints(chain)
+	}
+	sort.Slice(chains, func(a, b int) bool { return chains[a][0] < chains[b][0] })
+	return chains
+}
+
+// runWorkspaceApply applies every layout of a workspace in one command
+func runWorkspaceApply(name string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	workspace, ok := config.Workspaces[name]
+	if !ok {
+		names := make([]string, 0, len(config.Workspaces))
+		for n := range config.Workspaces {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("workspace %q not found (available workspaces: %s)", name, strings.Join(names, ", "))
+	}
+
+	// Resolve every layout first, then apply them at once: each is a separate tmux
+	// or Python API invocation on its own window or sessions
+	apply := make([]func() error, len(workspace.Layouts))
+	applied := make([]bool, len(workspace.Layouts))
+	for i, layout := range workspace.Layouts {
+		if err := layout.check(); err != nil {
+			return fmt.Errorf("workspace %q layout %d: %v", name, i+1, err)
+		}
+
+		// Resolve with the terminal the layout targets, so its sub-profile applies
+		info := TerminalShellInfo{Terminals: []TerminalType{TerminalTypeITerm2}, Appearance: detectAppearance()}
+		if layout.TmuxWindow != "" {
+			info.Terminals = []TerminalType{TerminalTypeTmux}
+		}
+		profile, err := getProfileWithTerminalInfo(layout.Profile, &info)
+		if err != nil {
+			return fmt.Errorf("workspace %q layout %d: %v", name, i+1, err)
+		}
+
+		apply[i] = func() error {
+			var err error
+			if layout.TmuxWindow != "" {
+				err = applyProfileToTmuxWindow(layout.TmuxWindow, profile)
+			} else {
+				err = applyProfileToMatchingSessions(layout.Title, profile)
+			}
+			if err != nil {
+				return fmt.Errorf("workspace %q: applying %q to %s: %v", name, layout.Profile, layout.describe(), err)
+			}
+			applied[i] = true
+			return nil
+		}
+	}
+
+	// Layouts that may reach the same window or session run one after another,
+	// in layout order, so the later one wins as it would when applied by hand
+	var tasks []func() error
+	for _, chain := range workspaceLayoutChains(workspace.Layouts) {
+		tasks = append(tasks, func() error {
+			var errs []error
+			for _, i := range chain {
+				errs = append(errs, apply[i]())
+			}
+			return errors.Join(errs...)
+		})
+	}
+
+	err = runParallel(tasks)
+	if !dryRunMode {
+		for i, layout := range workspace.Layouts {
+			if applied[i] {
+				fmt.Printf("Applied %q to %s\n", layout.Profile, layout.describe())
+			}
+		}
+	}
+	return err
+}
+
+// applyProfileToMatchingSessions applies a profile to the iTerm2 sessions whose
+// names match a glob, using the Python API
+func applyProfileToMatchingSessions(pattern string, profile *Profile) error {
+	req, err := buildSessionRequest(profile)
+	if err != nil {
+		return err
+	}
+	req.Session, req.All, req.Match = "", false, pattern
+	return runSessionRequest(req)
+}
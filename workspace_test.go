@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRunWorkspaceApply tests applying every layout of a workspace in dry-run mode
+func TestRunWorkspaceApply(t *testing.T) {
+	if err := initColors(); err != nil {
+		t.Fatalf("Failed to initialize CSS colors: %v", err)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "workspace-config.toml")
+	configContent := `[profiles.dev]
+tab = "blue"
+
+[profiles.prod]
+tab = "red"
+bg = "black"
+badge = "PROD"
+
+[profiles.prod.tmux]
+title = "prod logs"
+
+[workspaces.backend]
+layouts = [
+  { title = "api*", profile = "dev" },
+  { tmux_window = "work:logs", profile = "prod" },
+]
+
+[workspaces.broken]
+layouts = [
+  { title = "api*", tmux_window = "work:1", profile = "dev" },
+]
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	originalEnv := os.Getenv("SET_TAB_COLOR_CONFIG")
+	os.Setenv("SET_TAB_COLOR_CONFIG", configFile)
+	defer func() {
+		if originalEnv == "" {
+			os.Unsetenv("SET_TAB_COLOR_CONFIG")
+		} else {
+			os.Setenv("SET_TAB_COLOR_CONFIG", originalEnv)
+		}
+	}()
+
+	var buf bytes.Buffer
+	originalWriter := escapeWriter
+	escapeWriter = &buf
+	dryRunMode = true
+	defer func() {
+		escapeWriter = originalWriter
+		dryRunMode = false
+	}()
+
+	if err := runWorkspaceApply("backend"); err != nil {
+		t.Fatalf("runWorkspaceApply() failed: %v", err)
+	}
+
+	expected := `[dry-run] iTerm2 Python API: {"all":false,"match":"api*","tab":"0000ff"}
+[dry-run] exec: tmux set-option -w -t work:logs window-style 'bg=#000000' ';' set-option -w -t work:logs window-active-style 'bg=#000000' ';' set-option -w -t work:logs window-status-style 'bg=#ff0000' ';' set-option -w -t work:logs window-status-current-style 'bg=#ff0000' ';' rename-window -t work:logs 'prod logs'
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	if err := runWorkspaceApply("broken"); err == nil || !contains(err.Error(), "exactly one of title and tmux_window") {
+		t.Errorf("Expected layout error, got %v", err)
+	}
+	if err := runWorkspaceApply("missing"); err == nil || !contains(err.Error(), "available workspaces: backend, broken") {
+		t.Errorf("Expected missing workspace error, got %v", err)
+	}
+
+	issues, err := validateConfigFile(configFile)
+	if err != nil {
+		t.Fatalf("validateConfigFile() failed: %v", err)
+	}
+	if len(issues) != 1 || !contains(issues[0].Message, `workspace "broken" layout 1`) {
+		t.Errorf("Expected one workspace issue, got %v", issues)
+	}
+}
+
+// TestWorkspaceLayoutChains tests that layouts which may reach the same target are
+// applied one after another, in layout order
+func TestWorkspaceLayoutChains(t *testing.T) {
+	layouts := []WorkspaceLayout{
+		{Title: "api", Profile: "dev"},
+		{TmuxWindow: "work:logs", Profile: "prod"},
+		{Title: "web", Profile: "dev"},
+		{Title: "api*", Profile: "prod"},
+		{TmuxWindow: "work:logs", Profile: "dev"},
+		{TmuxWindow: "work:1", Profile: "dev"},
+	}
+	chains := workspaceLayoutChains(layouts)
+	expected := [][]int{{0, 2, 3}, {1, 4}, {5}}
+	if fmt.Sprint(chains) != fmt.Sprint(expected) {
+		t.Errorf("Expected chains %v, got %v", expected, chains)
+	}
+
+	// Distinct literal titles don't overlap
+	chains = workspaceLayoutChains(layouts[:3])
+	if expected := [][]int{{0}, {1}, {2}}; fmt.Sprint(chains) != fmt.Sprint(expected) {
+		t.Errorf("Expected chains %v, got %v", expected, chains)
+	}
+}
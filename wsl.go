@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// The process tree inside WSL ends at the WSL init process, so the Windows terminal
+// hosting it is never an ancestor. It is recognized from the variables it shares
+// with WSL instead.
+
+// wslHostEnvVars maps variables set by Windows terminals to their terminal type.
+// Windows Terminal shares WT_SESSION with WSL; ConEmu's variables are only visible
+// when listed in WSLENV.
+var wslHostEnvVars = []struct {
+	key      string
+	terminal TerminalType
+}{
+	{"WT_SESSION", TerminalTypeWindowsTerminal},
+	{"ConEmuPID", TerminalTypeConEmu},
+}
+
+// remoteTerminals are the terminals that put the emulator on another machine
+var remoteTerminals = []TerminalType{TerminalTypeSSH, TerminalTypeETTerminal, TerminalTypeMosh}
+
+// isWSL reports whether we run under the Windows Subsystem for Linux
+func isWSL() bool {
+	if getDetectionEnv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	if getFakeEnvironment() != nil {
+		return false
+	}
+	data, err := os.ReadFile("/proc/version")
+	return err == nil && strings.Contains(strings.ToLower(string(data)), "microsoft")
+}
+
+// wslHostTerminal returns the Windows terminal hosting WSL, if it can be told
+func wslHostTerminal() TerminalType {
+	for _, v := range wslHostEnvVars {
+		if getDetectionEnv(v.key) != "" {
+			return v.terminal
+		}
+	}
+	return TerminalTypeUnknown
+}
+
+// withWSLHost appends the wsl layer and the Windows terminal hosting it to a
+// detected chain, unless the session is remote and the emulator is elsewhere
+func withWSLHost(info TerminalShellInfo) TerminalShellInfo {
+	if !isWSL() {
+		return info
+	}
+	for _, terminal := range info.Terminals {
+		if containsTerminal(remoteTerminals, terminal) {
+			return info
+		}
+	}
+
+	info.Terminals = append(info.Terminals, TerminalTypeWSL)
+	if host := wslHostTerminal(); host != TerminalTypeUnknown {
+		info.Terminals = append(info.Terminals, host)
+	}
+	return info
+}
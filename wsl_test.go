@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestDetectWSL tests adding the wsl layer and the Windows terminal hosting it
+func TestDetectWSL(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		chain     []string
+		terminals []TerminalType
+	}{
+		{"Windows Terminal", map[string]string{"WSL_DISTRO_NAME": "Ubuntu", "WT_SESSION": "b2d5e9c4"},
+			[]string{"set-tab-color", "bash"}, []TerminalType{TerminalTypeWSL, TerminalTypeWindowsTerminal}},
+		{"ConEmu through WSLENV", map[string]string{"WSL_DISTRO_NAME": "Debian", "ConEmuPID": "4242"},
+			[]string{"set-tab-color", "zsh"}, []TerminalType{TerminalTypeWSL, TerminalTypeConEmu}},
+		{"unknown host", map[string]string{"WSL_DISTRO_NAME": "Ubuntu"},
+			[]string{"set-tab-color", "bash"}, []TerminalType{TerminalTypeWSL}},
+		{"tmux inside WSL", map[string]string{"WSL_DISTRO_NAME": "Ubuntu", "TMUX": "/tmp/tmux-1000/default,1,0", "WT_SESSION": "b2d5e9c4"},
+			[]string{"set-tab-color", "bash"}, []TerminalType{TerminalTypeTmux, TerminalTypeWSL, TerminalTypeWindowsTerminal}},
+		{"SSH into WSL", map[string]string{"WSL_DISTRO_NAME": "Ubuntu", "SSH_TTY": "/dev/pts/2", "WT_SESSION": "stale"},
+			[]string{"set-tab-color", "bash"}, []TerminalType{TerminalTypeSSH}},
+		{"not WSL", map[string]string{"WT_SESSION": "b2d5e9c4"},
+			[]string{"set-tab-color", "bash"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFakeEnvironment(&FakeEnvironment{Env: tt.env, ProcessChain: tt.chain})
+			defer setFakeEnvironment(nil)
+
+			info := detectTerminalAndShell("")
+			if len(info.Terminals) != len(tt.terminals) {
+				t.Fatalf("Expected terminals %v, got %v", tt.terminals, info.Terminals)
+			}
+			for i := range tt.terminals {
+				if info.Terminals[i] != tt.terminals[i] {
+					t.Errorf("Expected terminals %v, got %v", tt.terminals, info.Terminals)
+					break
+				}
+			}
+		})
+	}
+
+	if backend := selectEscapeBackend([]TerminalType{TerminalTypeWSL, TerminalTypeWindowsTerminal}); backend != BackendXterm {
+		t.Errorf("Expected the xterm backend under Windows Terminal, got %s", backend)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// xdgBaseDir returns an XDG base directory variable such as $XDG_STATE_HOME, or ""
+// when it is unset. The spec says relative paths are invalid and must be ignored.
+func xdgBaseDir(envVar string) string {
+	if dir := os.Getenv(envVar); filepath.IsAbs(dir) {
+		return dir
+	}
+	return ""
+}
+
+// getCacheDir returns the directory for data that can be rebuilt at any time:
+// $XDG_CACHE_HOME/set-tab-color, or the OS cache directory (~/.cache on Linux,
+// ~/Library/Caches on macOS)
+func getCacheDir() (string, error) {
+	if cacheHome := xdgBaseDir("XDG_CACHE_HOME"); cacheHome != "" {
+		return filepath.Join(cacheHome, "set-tab-color"), nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		// os.UserCacheDir rejects a relative $XDG_CACHE_HOME instead of ignoring it
+		homeDir, homeErr := os.UserHomeDir()
+		if homeErr != nil {
+			return "", fmt.Errorf("could not get cache directory: %v", err)
+		}
+		cacheDir = filepath.Join(homeDir, ".cache")
+	}
+	return filepath.Join(cacheDir, "set-tab-color"), nil
+}
+
+// findDefaultConfigPath returns the config file used without $SET_TAB_COLOR_CONFIG
+// or -config: an existing file in $XDG_CONFIG_HOME, then in ~/.config, then in the
+// OS config directory (~/Library/Application Support on macOS). When none exists,
+// it returns where a new config belongs: $XDG_CONFIG_HOME if set, ~/.config if that
+// directory exists, or the OS config directory.
+func findDefaultConfigPath() (string, error) {
+	const name = "set-tab-color.toml"
+
+	var candidates []string
+	if configHome := xdgBaseDir("XDG_CONFIG_HOME"); configHome != "" {
+		candidates = append(candidates, filepath.Join(configHome, name))
+	}
+	dotConfig := ""
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		dotConfig = filepath.Join(homeDir, ".config", name)
+		candidates = append(candidates, dotConfig)
+	}
+	osConfig := ""
+	if configDir, err := os.UserConfigDir(); err == nil {
+		osConfig = filepath.Join(configDir, name)
+		candidates = append(candidates, osConfig)
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	if configHome := xdgBaseDir("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, name), nil
+	}
+	if dotConfig != "" {
+		if _, err := os.Stat(filepath.Dir(dotConfig)); err == nil || osConfig == "" {
+			return dotConfig, nil
+		}
+	}
+	if osConfig == "" {
+		return "", fmt.Errorf("could not get config directory")
+	}
+	return osConfig, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestXDGDirectories tests the config, state, and cache locations with and without
+// the XDG base directory variables
+func TestXDGDirectories(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SET_TAB_COLOR_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "xdg-state"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, "xdg-cache"))
+
+	for name, test := range map[string]struct {
+		get      func() (string, error)
+		expected string
+	}{
+		"config": {getConfigPath, filepath.Join(home, "xdg-config", "set-tab-color.toml")},
+		"state":  {getStateDir, filepath.Join(home, "xdg-state", "set-tab-color")},
+		"cache":  {getCacheDir, filepath.Join(home, "xdg-cache", "set-tab-color")},
+	} {
+		if got, err := test.get(); err != nil || got != test.expected {
+			t.Errorf("%s: got %q, %v, expected %q", name, got, err, test.expected)
+		}
+	}
+
+	// An existing ~/.config file is still found when $XDG_CONFIG_HOME has none
+	dotConfig := filepath.Join(home, ".config", "set-tab-color.toml")
+	if err := os.MkdirAll(filepath.Dir(dotConfig), 0755); err != nil {
+		t.Fatalf("Failed to create ~/.config: %v", err)
+	}
+	if err := os.WriteFile(dotConfig, nil, 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if got, _ := getConfigPath(); got != dotConfig {
+		t.Errorf("Expected the existing ~/.config file, got %q", got)
+	}
+
+	// Relative paths are invalid and ignored
+	t.Setenv("XDG_CONFIG_HOME", "relative")
+	t.Setenv("XDG_STATE_HOME", "relative")
+	t.Setenv("XDG_CACHE_HOME", "relative")
+	if got, _ := getStateDir(); got != filepath.Join(home, ".local", "state", "set-tab-color") {
+		t.Errorf("Expected the default state directory, got %q", got)
+	}
+	if got, _ := getCacheDir(); runtime.GOOS == "linux" && got != filepath.Join(home, ".cache", "set-tab-color") {
+		t.Errorf("Expected the default cache directory, got %q", got)
+	}
+	if got, _ := getConfigPath(); got != dotConfig {
+		t.Errorf("Expected the ~/.config file, got %q", got)
+	}
+}